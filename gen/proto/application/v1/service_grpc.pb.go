@@ -27,6 +27,7 @@ type ApplicationServiceClient interface {
 	ListApplications(ctx context.Context, in *ListApplicationsRequest, opts ...grpc.CallOption) (*ListApplicationsResponse, error)
 	GetApplication(ctx context.Context, in *GetApplicationRequest, opts ...grpc.CallOption) (*GetApplicationResponse, error)
 	DeleteApplication(ctx context.Context, in *DeleteApplicationRequest, opts ...grpc.CallOption) (*DeleteApplicationResponse, error)
+	WatchApplications(ctx context.Context, in *WatchApplicationsRequest, opts ...grpc.CallOption) (ApplicationService_WatchApplicationsClient, error)
 }
 
 type applicationServiceClient struct {
@@ -82,6 +83,38 @@ func (c *applicationServiceClient) DeleteApplication(ctx context.Context, in *De
 	return out, nil
 }
 
+func (c *applicationServiceClient) WatchApplications(ctx context.Context, in *WatchApplicationsRequest, opts ...grpc.CallOption) (ApplicationService_WatchApplicationsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &ApplicationService_ServiceDesc.Streams[0], "/application.v1.ApplicationService/WatchApplications", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &applicationServiceWatchApplicationsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type ApplicationService_WatchApplicationsClient interface {
+	Recv() (*WatchApplicationsResponse, error)
+	grpc.ClientStream
+}
+
+type applicationServiceWatchApplicationsClient struct {
+	grpc.ClientStream
+}
+
+func (x *applicationServiceWatchApplicationsClient) Recv() (*WatchApplicationsResponse, error) {
+	m := new(WatchApplicationsResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // ApplicationServiceServer is the server API for ApplicationService service.
 // All implementations must embed UnimplementedApplicationServiceServer
 // for forward compatibility
@@ -91,6 +124,7 @@ type ApplicationServiceServer interface {
 	ListApplications(context.Context, *ListApplicationsRequest) (*ListApplicationsResponse, error)
 	GetApplication(context.Context, *GetApplicationRequest) (*GetApplicationResponse, error)
 	DeleteApplication(context.Context, *DeleteApplicationRequest) (*DeleteApplicationResponse, error)
+	WatchApplications(*WatchApplicationsRequest, ApplicationService_WatchApplicationsServer) error
 	mustEmbedUnimplementedApplicationServiceServer()
 }
 
@@ -113,6 +147,9 @@ func (UnimplementedApplicationServiceServer) GetApplication(context.Context, *Ge
 func (UnimplementedApplicationServiceServer) DeleteApplication(context.Context, *DeleteApplicationRequest) (*DeleteApplicationResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method DeleteApplication not implemented")
 }
+func (UnimplementedApplicationServiceServer) WatchApplications(*WatchApplicationsRequest, ApplicationService_WatchApplicationsServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchApplications not implemented")
+}
 func (UnimplementedApplicationServiceServer) mustEmbedUnimplementedApplicationServiceServer() {}
 
 // UnsafeApplicationServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -216,6 +253,27 @@ func _ApplicationService_DeleteApplication_Handler(srv interface{}, ctx context.
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ApplicationService_WatchApplications_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchApplicationsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ApplicationServiceServer).WatchApplications(m, &applicationServiceWatchApplicationsServer{stream})
+}
+
+type ApplicationService_WatchApplicationsServer interface {
+	Send(*WatchApplicationsResponse) error
+	grpc.ServerStream
+}
+
+type applicationServiceWatchApplicationsServer struct {
+	grpc.ServerStream
+}
+
+func (x *applicationServiceWatchApplicationsServer) Send(m *WatchApplicationsResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 // ApplicationService_ServiceDesc is the grpc.ServiceDesc for ApplicationService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -244,6 +302,12 @@ var ApplicationService_ServiceDesc = grpc.ServiceDesc{
 			Handler:    _ApplicationService_DeleteApplication_Handler,
 		},
 	},
-	Streams:  []grpc.StreamDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchApplications",
+			Handler:       _ApplicationService_WatchApplications_Handler,
+			ServerStreams: true,
+		},
+	},
 	Metadata: "application/v1/service.proto",
 }
@@ -0,0 +1,249 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.2.0
+// - protoc             (unknown)
+// source: feature/v1/service.proto
+
+package featurev1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// FeatureServiceClient is the client API for FeatureService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type FeatureServiceClient interface {
+	ListFeatures(ctx context.Context, in *ListFeaturesRequest, opts ...grpc.CallOption) (*ListFeaturesResponse, error)
+	GetFeature(ctx context.Context, in *GetFeatureRequest, opts ...grpc.CallOption) (*GetFeatureResponse, error)
+	EnableFeature(ctx context.Context, in *EnableFeatureRequest, opts ...grpc.CallOption) (*EnableFeatureResponse, error)
+	UpdateFeature(ctx context.Context, in *UpdateFeatureRequest, opts ...grpc.CallOption) (*UpdateFeatureResponse, error)
+	DisableFeature(ctx context.Context, in *DisableFeatureRequest, opts ...grpc.CallOption) (*DisableFeatureResponse, error)
+}
+
+type featureServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewFeatureServiceClient(cc grpc.ClientConnInterface) FeatureServiceClient {
+	return &featureServiceClient{cc}
+}
+
+func (c *featureServiceClient) ListFeatures(ctx context.Context, in *ListFeaturesRequest, opts ...grpc.CallOption) (*ListFeaturesResponse, error) {
+	out := new(ListFeaturesResponse)
+	err := c.cc.Invoke(ctx, "/feature.v1.FeatureService/ListFeatures", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *featureServiceClient) GetFeature(ctx context.Context, in *GetFeatureRequest, opts ...grpc.CallOption) (*GetFeatureResponse, error) {
+	out := new(GetFeatureResponse)
+	err := c.cc.Invoke(ctx, "/feature.v1.FeatureService/GetFeature", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *featureServiceClient) EnableFeature(ctx context.Context, in *EnableFeatureRequest, opts ...grpc.CallOption) (*EnableFeatureResponse, error) {
+	out := new(EnableFeatureResponse)
+	err := c.cc.Invoke(ctx, "/feature.v1.FeatureService/EnableFeature", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *featureServiceClient) UpdateFeature(ctx context.Context, in *UpdateFeatureRequest, opts ...grpc.CallOption) (*UpdateFeatureResponse, error) {
+	out := new(UpdateFeatureResponse)
+	err := c.cc.Invoke(ctx, "/feature.v1.FeatureService/UpdateFeature", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *featureServiceClient) DisableFeature(ctx context.Context, in *DisableFeatureRequest, opts ...grpc.CallOption) (*DisableFeatureResponse, error) {
+	out := new(DisableFeatureResponse)
+	err := c.cc.Invoke(ctx, "/feature.v1.FeatureService/DisableFeature", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// FeatureServiceServer is the server API for FeatureService service.
+// All implementations must embed UnimplementedFeatureServiceServer
+// for forward compatibility
+type FeatureServiceServer interface {
+	ListFeatures(context.Context, *ListFeaturesRequest) (*ListFeaturesResponse, error)
+	GetFeature(context.Context, *GetFeatureRequest) (*GetFeatureResponse, error)
+	EnableFeature(context.Context, *EnableFeatureRequest) (*EnableFeatureResponse, error)
+	UpdateFeature(context.Context, *UpdateFeatureRequest) (*UpdateFeatureResponse, error)
+	DisableFeature(context.Context, *DisableFeatureRequest) (*DisableFeatureResponse, error)
+	mustEmbedUnimplementedFeatureServiceServer()
+}
+
+// UnimplementedFeatureServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedFeatureServiceServer struct {
+}
+
+func (UnimplementedFeatureServiceServer) ListFeatures(context.Context, *ListFeaturesRequest) (*ListFeaturesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListFeatures not implemented")
+}
+func (UnimplementedFeatureServiceServer) GetFeature(context.Context, *GetFeatureRequest) (*GetFeatureResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetFeature not implemented")
+}
+func (UnimplementedFeatureServiceServer) EnableFeature(context.Context, *EnableFeatureRequest) (*EnableFeatureResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method EnableFeature not implemented")
+}
+func (UnimplementedFeatureServiceServer) UpdateFeature(context.Context, *UpdateFeatureRequest) (*UpdateFeatureResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateFeature not implemented")
+}
+func (UnimplementedFeatureServiceServer) DisableFeature(context.Context, *DisableFeatureRequest) (*DisableFeatureResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DisableFeature not implemented")
+}
+func (UnimplementedFeatureServiceServer) mustEmbedUnimplementedFeatureServiceServer() {}
+
+// UnsafeFeatureServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to FeatureServiceServer will
+// result in compilation errors.
+type UnsafeFeatureServiceServer interface {
+	mustEmbedUnimplementedFeatureServiceServer()
+}
+
+func RegisterFeatureServiceServer(s grpc.ServiceRegistrar, srv FeatureServiceServer) {
+	s.RegisterService(&FeatureService_ServiceDesc, srv)
+}
+
+func _FeatureService_ListFeatures_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListFeaturesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FeatureServiceServer).ListFeatures(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/feature.v1.FeatureService/ListFeatures",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FeatureServiceServer).ListFeatures(ctx, req.(*ListFeaturesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FeatureService_GetFeature_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetFeatureRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FeatureServiceServer).GetFeature(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/feature.v1.FeatureService/GetFeature",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FeatureServiceServer).GetFeature(ctx, req.(*GetFeatureRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FeatureService_EnableFeature_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EnableFeatureRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FeatureServiceServer).EnableFeature(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/feature.v1.FeatureService/EnableFeature",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FeatureServiceServer).EnableFeature(ctx, req.(*EnableFeatureRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FeatureService_UpdateFeature_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateFeatureRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FeatureServiceServer).UpdateFeature(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/feature.v1.FeatureService/UpdateFeature",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FeatureServiceServer).UpdateFeature(ctx, req.(*UpdateFeatureRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FeatureService_DisableFeature_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DisableFeatureRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FeatureServiceServer).DisableFeature(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/feature.v1.FeatureService/DisableFeature",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FeatureServiceServer).DisableFeature(ctx, req.(*DisableFeatureRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// FeatureService_ServiceDesc is the grpc.ServiceDesc for FeatureService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var FeatureService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "feature.v1.FeatureService",
+	HandlerType: (*FeatureServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListFeatures",
+			Handler:    _FeatureService_ListFeatures_Handler,
+		},
+		{
+			MethodName: "GetFeature",
+			Handler:    _FeatureService_GetFeature_Handler,
+		},
+		{
+			MethodName: "EnableFeature",
+			Handler:    _FeatureService_EnableFeature_Handler,
+		},
+		{
+			MethodName: "UpdateFeature",
+			Handler:    _FeatureService_UpdateFeature_Handler,
+		},
+		{
+			MethodName: "DisableFeature",
+			Handler:    _FeatureService_DisableFeature_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "feature/v1/service.proto",
+}
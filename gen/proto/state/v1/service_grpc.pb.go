@@ -0,0 +1,177 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.2.0
+// - protoc             (unknown)
+// source: state/v1/service.proto
+
+package statev1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// StateServiceClient is the client API for StateService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type StateServiceClient interface {
+	ListRevisions(ctx context.Context, in *ListRevisionsRequest, opts ...grpc.CallOption) (*ListRevisionsResponse, error)
+	GetRevision(ctx context.Context, in *GetRevisionRequest, opts ...grpc.CallOption) (*GetRevisionResponse, error)
+	RollbackToRevision(ctx context.Context, in *RollbackToRevisionRequest, opts ...grpc.CallOption) (*RollbackToRevisionResponse, error)
+}
+
+type stateServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewStateServiceClient(cc grpc.ClientConnInterface) StateServiceClient {
+	return &stateServiceClient{cc}
+}
+
+func (c *stateServiceClient) ListRevisions(ctx context.Context, in *ListRevisionsRequest, opts ...grpc.CallOption) (*ListRevisionsResponse, error) {
+	out := new(ListRevisionsResponse)
+	err := c.cc.Invoke(ctx, "/state.v1.StateService/ListRevisions", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *stateServiceClient) GetRevision(ctx context.Context, in *GetRevisionRequest, opts ...grpc.CallOption) (*GetRevisionResponse, error) {
+	out := new(GetRevisionResponse)
+	err := c.cc.Invoke(ctx, "/state.v1.StateService/GetRevision", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *stateServiceClient) RollbackToRevision(ctx context.Context, in *RollbackToRevisionRequest, opts ...grpc.CallOption) (*RollbackToRevisionResponse, error) {
+	out := new(RollbackToRevisionResponse)
+	err := c.cc.Invoke(ctx, "/state.v1.StateService/RollbackToRevision", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// StateServiceServer is the server API for StateService service.
+// All implementations must embed UnimplementedStateServiceServer
+// for forward compatibility
+type StateServiceServer interface {
+	ListRevisions(context.Context, *ListRevisionsRequest) (*ListRevisionsResponse, error)
+	GetRevision(context.Context, *GetRevisionRequest) (*GetRevisionResponse, error)
+	RollbackToRevision(context.Context, *RollbackToRevisionRequest) (*RollbackToRevisionResponse, error)
+	mustEmbedUnimplementedStateServiceServer()
+}
+
+// UnimplementedStateServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedStateServiceServer struct {
+}
+
+func (UnimplementedStateServiceServer) ListRevisions(context.Context, *ListRevisionsRequest) (*ListRevisionsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListRevisions not implemented")
+}
+func (UnimplementedStateServiceServer) GetRevision(context.Context, *GetRevisionRequest) (*GetRevisionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetRevision not implemented")
+}
+func (UnimplementedStateServiceServer) RollbackToRevision(context.Context, *RollbackToRevisionRequest) (*RollbackToRevisionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RollbackToRevision not implemented")
+}
+func (UnimplementedStateServiceServer) mustEmbedUnimplementedStateServiceServer() {}
+
+// UnsafeStateServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to StateServiceServer will
+// result in compilation errors.
+type UnsafeStateServiceServer interface {
+	mustEmbedUnimplementedStateServiceServer()
+}
+
+func RegisterStateServiceServer(s grpc.ServiceRegistrar, srv StateServiceServer) {
+	s.RegisterService(&StateService_ServiceDesc, srv)
+}
+
+func _StateService_ListRevisions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListRevisionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StateServiceServer).ListRevisions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/state.v1.StateService/ListRevisions",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StateServiceServer).ListRevisions(ctx, req.(*ListRevisionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StateService_GetRevision_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRevisionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StateServiceServer).GetRevision(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/state.v1.StateService/GetRevision",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StateServiceServer).GetRevision(ctx, req.(*GetRevisionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StateService_RollbackToRevision_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RollbackToRevisionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StateServiceServer).RollbackToRevision(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/state.v1.StateService/RollbackToRevision",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StateServiceServer).RollbackToRevision(ctx, req.(*RollbackToRevisionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// StateService_ServiceDesc is the grpc.ServiceDesc for StateService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var StateService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "state.v1.StateService",
+	HandlerType: (*StateServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListRevisions",
+			Handler:    _StateService_ListRevisions_Handler,
+		},
+		{
+			MethodName: "GetRevision",
+			Handler:    _StateService_GetRevision_Handler,
+		},
+		{
+			MethodName: "RollbackToRevision",
+			Handler:    _StateService_RollbackToRevision_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "state/v1/service.proto",
+}
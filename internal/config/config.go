@@ -1,18 +1,30 @@
 package config
 
 import (
+	"time"
+
 	"github.com/mbaitar/gco/agent/internal/flag"
 )
 
 type Config struct {
 	// General reflects the general agent configuration.
-	General General
+	General General `yaml:"general"`
 	// Grpc reflects the configuration for the gRPC server.
-	Grpc Grpc
+	Grpc Grpc `yaml:"grpc"`
 	// Http reflects the configuration for the HTTP server.
-	Http Http
+	Http Http `yaml:"http"`
+	// Persistence reflects the configuration of the storage used for the desired state.
+	Persistence Persistence `yaml:"persistence"`
 	// Docker reflects the configuration when the docker provider has been enabled.
-	Docker DockerProvider
+	Docker DockerProvider `yaml:"docker"`
+	// Swarm reflects the configuration when the swarm provider has been enabled.
+	Swarm SwarmProvider `yaml:"swarm"`
+	// Kubernetes reflects the configuration when the kubernetes provider has been enabled.
+	Kubernetes KubernetesProvider `yaml:"kubernetes"`
+	// Podman reflects the configuration when the podman provider has been enabled.
+	Podman PodmanProvider `yaml:"podman"`
+	// Containerd reflects the configuration when the containerd provider has been enabled.
+	Containerd ContainerdProvider `yaml:"containerd"`
 }
 
 // DefaultConfig returns the default configuration for the agent.
@@ -20,6 +32,8 @@ func DefaultConfig() *Config {
 	return &Config{
 		General: General{
 			ResetProviderOnStartup: false,
+			ResyncInterval:         30 * time.Second,
+			DataDirectory:          defaultDataDirectory(),
 		},
 		Grpc: Grpc{
 			Enabled:          true,
@@ -32,21 +46,51 @@ func DefaultConfig() *Config {
 			Port:    8080,
 			Address: "0.0.0.0",
 		},
+		Persistence: Persistence{
+			Backend:   LocalBackend,
+			Backups:   3,
+			Revisions: 3,
+			Etcd: EtcdPersistence{
+				Endpoints:   []string{"http://127.0.0.1:2379"},
+				Key:         "/gco/state",
+				DialTimeout: 5 * time.Second,
+			},
+		},
 		Docker: DockerProvider{
 			Enabled:                  true,
 			UseDockerComposeGrouping: true,
+			ObserveEvents:            true,
+		},
+		Kubernetes: KubernetesProvider{
+			Namespace: "default",
+		},
+		Podman: PodmanProvider{
+			UsePodGrouping:        true,
+			UnprivilegedPortStart: 1024,
+		},
+		Containerd: ContainerdProvider{
+			Address:    "/run/containerd/containerd.sock",
+			Namespace:  "gco",
+			CNIConfDir: "/etc/cni/net.d",
+			CNIBinDir:  "/opt/cni/bin",
 		},
 	}
 }
 
+// GetStateFile returns the location of the state file used by the local persistence backend.
+func (c *Config) GetStateFile() string {
+	return c.Persistence.GetStateFile(c.General.DataDirectory)
+}
+
+// GetDatabase returns the location of the database used by the sqlite persistence backend.
+func (c *Config) GetDatabase() string {
+	return c.Persistence.GetDatabase(c.General.DataDirectory)
+}
+
 func (c *Config) SetFlags() {
 	// reset all flags before continuing
 	flag.Reset()
 
-	if c.Docker.Enabled {
-		flag.Set(flag.IgnoreInstanceDiff)
-	}
-
 	if c.General.ResetProviderOnStartup {
 		flag.Set(flag.RemoveAllOnStartup)
 	}
@@ -1,6 +1,27 @@
 package config
 
+import (
+	"os"
+	"path"
+	"time"
+)
+
 type General struct {
 	// Enabled the flag.RemoveAllOnStartup.
-	ResetProviderOnStartup bool
-}
\ No newline at end of file
+	ResetProviderOnStartup bool `yaml:"resetProviderOnStartup"`
+	// ResyncInterval specifies how often the actual state is pulled from the provider (0 disables the resync).
+	ResyncInterval time.Duration `yaml:"resyncInterval"`
+	// DataDirectory specifies the directory containing the state, generated configuration files and secrets.
+	DataDirectory string `yaml:"dataDirectory"`
+}
+
+// defaultDataDirectory returns the 'gco' directory within the user configuration directory,
+// or '/etc/gco' when the user configuration directory is unknown.
+func defaultDataDirectory() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "/etc/gco"
+	}
+
+	return path.Join(dir, "gco")
+}
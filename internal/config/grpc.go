@@ -4,13 +4,13 @@ import "fmt"
 
 type Grpc struct {
 	// Enabled is used to enable or disable the gRPC server.
-	Enabled bool
+	Enabled bool `yaml:"enabled"`
 	// Port specifies the TCP port to use for listening to gRPC connections.
-	Port int
+	Port int `yaml:"port"`
 	// Address specifies the address to use for listening to gRPC connections.
-	Address string
+	Address string `yaml:"address"`
 	// EnableReflection enables gRPC reflection mode (useful for development).
-	EnableReflection bool
+	EnableReflection bool `yaml:"enableReflection"`
 }
 
 func (g *Grpc) GetNetworkAddress() string {
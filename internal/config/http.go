@@ -4,11 +4,11 @@ import "fmt"
 
 type Http struct {
 	// Enabled is used to enable or disable the HTTP server.
-	Enabled bool
+	Enabled bool `yaml:"enabled"`
 	// Port specifies the TCP port to use for listening to HTTP connections.
-	Port int
+	Port int `yaml:"port"`
 	// Address specifies the address to use for listening to HTTP connections.
-	Address string
+	Address string `yaml:"address"`
 }
 
 func (h *Http) GetNetworkAddress() string {
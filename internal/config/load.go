@@ -0,0 +1,433 @@
+package config
+
+import (
+	"bytes"
+	goflag "flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// envPrefix is the prefix of the environment variables overriding the configuration.
+const envPrefix = "GCO_"
+
+// redactedValue replaces the passwords when printing the configuration.
+const redactedValue = "<redacted>"
+
+// ErrHelp is returned by Load when the usage has been requested using '-h' or '--help'.
+var ErrHelp = goflag.ErrHelp
+
+// Options contains the command-line options which are not part of the configuration itself.
+type Options struct {
+	// ConfigFile is the location of the configuration file, set using '--config' or GCO_CONFIG.
+	ConfigFile string
+	// PrintConfig prints the effective configuration instead of starting the agent.
+	PrintConfig bool
+}
+
+// LookupEnv defines a function which returns the value of an environment variable, e.g. os.LookupEnv.
+type LookupEnv func(key string) (string, bool)
+
+// setting binds a configuration property to a command-line flag and an environment variable.
+type setting struct {
+	flag  string
+	usage string
+	bind  func(fs *goflag.FlagSet, c *Config, name string, usage string)
+}
+
+// env returns the name of the environment variable, e.g. 'grpc.port' is overridden using GCO_GRPC_PORT.
+func (s setting) env() string {
+	replacer := strings.NewReplacer(".", "_", "-", "_")
+	return envPrefix + strings.ToUpper(replacer.Replace(s.flag))
+}
+
+var settings = []setting{
+	{flag: "general.reset-provider-on-startup", usage: "remove all managed resources from the provider on startup", bind: func(fs *goflag.FlagSet, c *Config, name string, usage string) {
+		fs.BoolVar(&c.General.ResetProviderOnStartup, name, c.General.ResetProviderOnStartup, usage)
+	}},
+	{flag: "general.resync-interval", usage: "interval between pulling the actual state from the provider (0 disables the resync)", bind: func(fs *goflag.FlagSet, c *Config, name string, usage string) {
+		fs.DurationVar(&c.General.ResyncInterval, name, c.General.ResyncInterval, usage)
+	}},
+	{flag: "general.data-directory", usage: "directory containing the state, generated configuration files and secrets", bind: func(fs *goflag.FlagSet, c *Config, name string, usage string) {
+		fs.StringVar(&c.General.DataDirectory, name, c.General.DataDirectory, usage)
+	}},
+	{flag: "persistence.backend", usage: "storage used for persisting the desired state", bind: func(fs *goflag.FlagSet, c *Config, name string, usage string) {
+		fs.StringVar(&c.Persistence.Backend, name, c.Persistence.Backend, usage)
+	}},
+	{flag: "persistence.state-file", usage: "location of the state file, relative to the data directory", bind: func(fs *goflag.FlagSet, c *Config, name string, usage string) {
+		fs.StringVar(&c.Persistence.StateFile, name, c.Persistence.StateFile, usage)
+	}},
+	{flag: "persistence.backups", usage: "number of previous states kept next to the state file", bind: func(fs *goflag.FlagSet, c *Config, name string, usage string) {
+		fs.IntVar(&c.Persistence.Backups, name, c.Persistence.Backups, usage)
+	}},
+	{flag: "persistence.database", usage: "location of the database used by the sqlite backend, relative to the data directory", bind: func(fs *goflag.FlagSet, c *Config, name string, usage string) {
+		fs.StringVar(&c.Persistence.Database, name, c.Persistence.Database, usage)
+	}},
+	{flag: "persistence.revisions", usage: "number of previous revisions kept by the sqlite backend", bind: func(fs *goflag.FlagSet, c *Config, name string, usage string) {
+		fs.IntVar(&c.Persistence.Revisions, name, c.Persistence.Revisions, usage)
+	}},
+	{flag: "persistence.etcd.endpoints", usage: "comma separated client URLs of the etcd cluster", bind: func(fs *goflag.FlagSet, c *Config, name string, usage string) {
+		fs.Var((*stringList)(&c.Persistence.Etcd.Endpoints), name, usage)
+	}},
+	{flag: "persistence.etcd.key", usage: "etcd key containing the desired state", bind: func(fs *goflag.FlagSet, c *Config, name string, usage string) {
+		fs.StringVar(&c.Persistence.Etcd.Key, name, c.Persistence.Etcd.Key, usage)
+	}},
+	{flag: "persistence.etcd.dial-timeout", usage: "timeout for connecting to the etcd cluster", bind: func(fs *goflag.FlagSet, c *Config, name string, usage string) {
+		fs.DurationVar(&c.Persistence.Etcd.DialTimeout, name, c.Persistence.Etcd.DialTimeout, usage)
+	}},
+	{flag: "persistence.etcd.username", usage: "username used to authenticate with the etcd cluster", bind: func(fs *goflag.FlagSet, c *Config, name string, usage string) {
+		fs.StringVar(&c.Persistence.Etcd.Username, name, c.Persistence.Etcd.Username, usage)
+	}},
+	{flag: "persistence.etcd.password", usage: "password used to authenticate with the etcd cluster", bind: func(fs *goflag.FlagSet, c *Config, name string, usage string) {
+		fs.StringVar(&c.Persistence.Etcd.Password, name, c.Persistence.Etcd.Password, usage)
+	}},
+	{flag: "grpc.enabled", usage: "enable the gRPC server", bind: func(fs *goflag.FlagSet, c *Config, name string, usage string) {
+		fs.BoolVar(&c.Grpc.Enabled, name, c.Grpc.Enabled, usage)
+	}},
+	{flag: "grpc.port", usage: "TCP port of the gRPC server", bind: func(fs *goflag.FlagSet, c *Config, name string, usage string) {
+		fs.IntVar(&c.Grpc.Port, name, c.Grpc.Port, usage)
+	}},
+	{flag: "grpc.address", usage: "listen address of the gRPC server", bind: func(fs *goflag.FlagSet, c *Config, name string, usage string) {
+		fs.StringVar(&c.Grpc.Address, name, c.Grpc.Address, usage)
+	}},
+	{flag: "grpc.enable-reflection", usage: "enable gRPC reflection", bind: func(fs *goflag.FlagSet, c *Config, name string, usage string) {
+		fs.BoolVar(&c.Grpc.EnableReflection, name, c.Grpc.EnableReflection, usage)
+	}},
+	{flag: "http.enabled", usage: "enable the HTTP server", bind: func(fs *goflag.FlagSet, c *Config, name string, usage string) {
+		fs.BoolVar(&c.Http.Enabled, name, c.Http.Enabled, usage)
+	}},
+	{flag: "http.port", usage: "TCP port of the HTTP server", bind: func(fs *goflag.FlagSet, c *Config, name string, usage string) {
+		fs.IntVar(&c.Http.Port, name, c.Http.Port, usage)
+	}},
+	{flag: "http.address", usage: "listen address of the HTTP server", bind: func(fs *goflag.FlagSet, c *Config, name string, usage string) {
+		fs.StringVar(&c.Http.Address, name, c.Http.Address, usage)
+	}},
+	{flag: "docker.enabled", usage: "enable the docker provider", bind: func(fs *goflag.FlagSet, c *Config, name string, usage string) {
+		fs.BoolVar(&c.Docker.Enabled, name, c.Docker.Enabled, usage)
+	}},
+	{flag: "docker.use-docker-compose-grouping", usage: "group the managed containers in docker desktop", bind: func(fs *goflag.FlagSet, c *Config, name string, usage string) {
+		fs.BoolVar(&c.Docker.UseDockerComposeGrouping, name, c.Docker.UseDockerComposeGrouping, usage)
+	}},
+	{flag: "docker.observe-events", usage: "observe the docker event stream for external changes", bind: func(fs *goflag.FlagSet, c *Config, name string, usage string) {
+		fs.BoolVar(&c.Docker.ObserveEvents, name, c.Docker.ObserveEvents, usage)
+	}},
+	{flag: "swarm.enabled", usage: "enable the swarm provider", bind: func(fs *goflag.FlagSet, c *Config, name string, usage string) {
+		fs.BoolVar(&c.Swarm.Enabled, name, c.Swarm.Enabled, usage)
+	}},
+	{flag: "kubernetes.enabled", usage: "enable the kubernetes provider", bind: func(fs *goflag.FlagSet, c *Config, name string, usage string) {
+		fs.BoolVar(&c.Kubernetes.Enabled, name, c.Kubernetes.Enabled, usage)
+	}},
+	{flag: "kubernetes.kubeconfig", usage: "location of the kubeconfig file, defaults to the in-cluster configuration or ~/.kube/config", bind: func(fs *goflag.FlagSet, c *Config, name string, usage string) {
+		fs.StringVar(&c.Kubernetes.Kubeconfig, name, c.Kubernetes.Kubeconfig, usage)
+	}},
+	{flag: "kubernetes.namespace", usage: "namespace of the resources managed by the kubernetes provider", bind: func(fs *goflag.FlagSet, c *Config, name string, usage string) {
+		fs.StringVar(&c.Kubernetes.Namespace, name, c.Kubernetes.Namespace, usage)
+	}},
+	{flag: "podman.enabled", usage: "enable the podman provider", bind: func(fs *goflag.FlagSet, c *Config, name string, usage string) {
+		fs.BoolVar(&c.Podman.Enabled, name, c.Podman.Enabled, usage)
+	}},
+	{flag: "podman.socket", usage: "location of the podman API socket, defaults to the socket of the current user", bind: func(fs *goflag.FlagSet, c *Config, name string, usage string) {
+		fs.StringVar(&c.Podman.Socket, name, c.Podman.Socket, usage)
+	}},
+	{flag: "podman.use-pod-grouping", usage: "group the managed containers in the gco pod", bind: func(fs *goflag.FlagSet, c *Config, name string, usage string) {
+		fs.BoolVar(&c.Podman.UsePodGrouping, name, c.Podman.UsePodGrouping, usage)
+	}},
+	{flag: "podman.unprivileged-port-start", usage: "first host port which can be published by a rootless podman", bind: func(fs *goflag.FlagSet, c *Config, name string, usage string) {
+		fs.IntVar(&c.Podman.UnprivilegedPortStart, name, c.Podman.UnprivilegedPortStart, usage)
+	}},
+	{flag: "containerd.enabled", usage: "enable the containerd provider", bind: func(fs *goflag.FlagSet, c *Config, name string, usage string) {
+		fs.BoolVar(&c.Containerd.Enabled, name, c.Containerd.Enabled, usage)
+	}},
+	{flag: "containerd.address", usage: "location of the containerd socket", bind: func(fs *goflag.FlagSet, c *Config, name string, usage string) {
+		fs.StringVar(&c.Containerd.Address, name, c.Containerd.Address, usage)
+	}},
+	{flag: "containerd.namespace", usage: "containerd namespace of the containers managed by the containerd provider", bind: func(fs *goflag.FlagSet, c *Config, name string, usage string) {
+		fs.StringVar(&c.Containerd.Namespace, name, c.Containerd.Namespace, usage)
+	}},
+	{flag: "containerd.cni-conf-dir", usage: "directory containing the CNI network configuration", bind: func(fs *goflag.FlagSet, c *Config, name string, usage string) {
+		fs.StringVar(&c.Containerd.CNIConfDir, name, c.Containerd.CNIConfDir, usage)
+	}},
+	{flag: "containerd.cni-bin-dir", usage: "directory containing the CNI plugins", bind: func(fs *goflag.FlagSet, c *Config, name string, usage string) {
+		fs.StringVar(&c.Containerd.CNIBinDir, name, c.Containerd.CNIBinDir, usage)
+	}},
+}
+
+// Load creates the configuration of the agent. The defaults are overridden by the configuration file,
+// which is overridden by the GCO_* environment variables, which are overridden by the command-line flags.
+// The returned configuration has been validated.
+func Load(args []string, lookupEnv LookupEnv) (*Config, *Options, error) {
+	conf := DefaultConfig()
+	opts := &Options{}
+	fs := newFlagSet(conf, opts)
+
+	// parse the flags first to find the configuration file, remembering them to re-apply after the other sources
+	if err := fs.Parse(args); err != nil {
+		if err == goflag.ErrHelp {
+			return nil, nil, ErrHelp
+		}
+
+		return nil, nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+
+	if fs.NArg() > 0 {
+		return nil, nil, fmt.Errorf("invalid arguments: unexpected argument '%s'", fs.Arg(0))
+	}
+
+	explicit := make(map[string]string)
+	fs.Visit(func(f *goflag.Flag) {
+		explicit[f.Name] = f.Value.String()
+	})
+
+	if opts.ConfigFile == "" {
+		opts.ConfigFile, _ = lookupEnv(envPrefix + "CONFIG")
+	}
+
+	// reset the values parsed from the flags before applying the configuration file
+	*conf = *DefaultConfig()
+
+	if opts.ConfigFile != "" {
+		if err := conf.loadFile(opts.ConfigFile); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	for _, s := range settings {
+		if value, found := lookupEnv(s.env()); found {
+			if err := fs.Set(s.flag, value); err != nil {
+				return nil, nil, fmt.Errorf("invalid value '%s' for %s: %w", value, s.env(), err)
+			}
+		}
+	}
+
+	for name, value := range explicit {
+		if err := fs.Set(name, value); err != nil {
+			return nil, nil, fmt.Errorf("invalid value '%s' for --%s: %w", value, name, err)
+		}
+	}
+
+	if err := conf.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	return conf, opts, nil
+}
+
+// Usage returns the description of the command-line flags and environment variables.
+func Usage() string {
+	var builder strings.Builder
+	builder.WriteString("Usage of agent:\n")
+
+	fs := newFlagSet(DefaultConfig(), &Options{})
+	fs.SetOutput(&builder)
+	fs.PrintDefaults()
+	return builder.String()
+}
+
+// newFlagSet creates the command-line flags bound to the configuration and options.
+func newFlagSet(conf *Config, opts *Options) *goflag.FlagSet {
+	fs := goflag.NewFlagSet("agent", goflag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.StringVar(&opts.ConfigFile, "config", "", "location of the YAML or JSON configuration file (GCO_CONFIG)")
+	fs.BoolVar(&opts.PrintConfig, "print-config", false, "print the effective configuration and exit")
+	for _, s := range settings {
+		s.bind(fs, conf, s.flag, fmt.Sprintf("%s (%s)", s.usage, s.env()))
+	}
+
+	return fs
+}
+
+// loadFile reads the YAML or JSON configuration file on top of the current configuration,
+// properties missing from the file keep their current value.
+func (c *Config) loadFile(location string) error {
+	content, err := os.ReadFile(location)
+	if err != nil {
+		return fmt.Errorf("unable to read config file: %w", err)
+	}
+
+	// JSON is a subset of YAML, which allows a single decoder to handle both formats
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	if err = decoder.Decode(c); err != nil && err != io.EOF {
+		return fmt.Errorf("unable to parse config file '%s': %w", location, err)
+	}
+
+	return nil
+}
+
+// Validate verifies the configuration and returns an error describing every invalid property.
+func (c *Config) Validate() error {
+	problems := make([]string, 0)
+
+	if c.General.ResyncInterval < 0 {
+		problems = append(problems, fmt.Sprintf("general.resyncInterval must not be negative, got %s", c.General.ResyncInterval))
+	} else if c.General.ResyncInterval > 0 && c.General.ResyncInterval < time.Second {
+		problems = append(problems, fmt.Sprintf("general.resyncInterval must be at least 1s, got %s", c.General.ResyncInterval))
+	}
+
+	if c.General.DataDirectory == "" {
+		problems = append(problems, "general.dataDirectory must not be empty")
+	}
+
+	if !isSupportedBackend(c.Persistence.Backend) {
+		problems = append(problems, fmt.Sprintf("persistence.backend must be one of [%s], got '%s'", strings.Join(backends, ", "), c.Persistence.Backend))
+	}
+
+	if c.Persistence.Backups < 0 {
+		problems = append(problems, fmt.Sprintf("persistence.backups must not be negative, got %d", c.Persistence.Backups))
+	}
+
+	if c.Persistence.Revisions < 0 {
+		problems = append(problems, fmt.Sprintf("persistence.revisions must not be negative, got %d", c.Persistence.Revisions))
+	}
+
+	if c.Persistence.Backend == EtcdBackend {
+		if len(c.Persistence.Etcd.Endpoints) == 0 {
+			problems = append(problems, "persistence.etcd.endpoints must not be empty")
+		}
+
+		if c.Persistence.Etcd.Key == "" {
+			problems = append(problems, "persistence.etcd.key must not be empty")
+		}
+
+		if c.Persistence.Etcd.DialTimeout <= 0 {
+			problems = append(problems, fmt.Sprintf("persistence.etcd.dialTimeout must be positive, got %s", c.Persistence.Etcd.DialTimeout))
+		}
+	}
+
+	if c.Kubernetes.Enabled && c.Kubernetes.Namespace == "" {
+		problems = append(problems, "kubernetes.namespace must not be empty")
+	}
+
+	if c.Podman.Enabled && (c.Podman.UnprivilegedPortStart < 0 || c.Podman.UnprivilegedPortStart > 65535) {
+		problems = append(problems, fmt.Sprintf("podman.unprivilegedPortStart must be between 0 and 65535, got %d", c.Podman.UnprivilegedPortStart))
+	}
+
+	if c.Containerd.Enabled {
+		if c.Containerd.Address == "" {
+			problems = append(problems, "containerd.address must not be empty")
+		}
+
+		if c.Containerd.Namespace == "" {
+			problems = append(problems, "containerd.namespace must not be empty")
+		}
+	}
+
+	if c.Grpc.Enabled {
+		problems = append(problems, validateListener("grpc", c.Grpc.Address, c.Grpc.Port)...)
+	}
+
+	if c.Http.Enabled {
+		problems = append(problems, validateListener("http", c.Http.Address, c.Http.Port)...)
+	}
+
+	if c.Grpc.Enabled && c.Http.Enabled && c.Grpc.Port == c.Http.Port && c.Grpc.Address == c.Http.Address {
+		problems = append(problems, fmt.Sprintf("grpc and http can not both listen on '%s'", c.Http.GetNetworkAddress()))
+	}
+
+	if enabled := c.enabledProviders(); len(enabled) == 0 {
+		problems = append(problems, "no provider has been enabled, enable the docker provider using docker.enabled")
+	} else if len(enabled) > 1 {
+		problems = append(problems, fmt.Sprintf("only a single provider can be enabled, got [%s]", strings.Join(enabled, ", ")))
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid configuration: %s", strings.Join(problems, "; "))
+	}
+
+	return nil
+}
+
+// stringList is a flag.Value for a comma separated list of strings.
+type stringList []string
+
+func (s *stringList) String() string {
+	if s == nil {
+		return ""
+	}
+
+	return strings.Join(*s, ",")
+}
+
+func (s *stringList) Set(value string) error {
+	list := make([]string, 0)
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+
+	*s = list
+	return nil
+}
+
+// enabledProviders returns the names of the enabled providers.
+func (c *Config) enabledProviders() []string {
+	enabled := make([]string, 0)
+	if c.Docker.Enabled {
+		enabled = append(enabled, "docker")
+	}
+
+	if c.Swarm.Enabled {
+		enabled = append(enabled, "swarm")
+	}
+
+	if c.Kubernetes.Enabled {
+		enabled = append(enabled, "kubernetes")
+	}
+
+	if c.Podman.Enabled {
+		enabled = append(enabled, "podman")
+	}
+
+	if c.Containerd.Enabled {
+		enabled = append(enabled, "containerd")
+	}
+
+	return enabled
+}
+
+// isSupportedBackend returns true when the persistence backend is known.
+func isSupportedBackend(backend string) bool {
+	for _, supported := range backends {
+		if backend == supported {
+			return true
+		}
+	}
+
+	return false
+}
+
+// validateListener verifies the listen address and port of a server.
+func validateListener(name string, address string, port int) []string {
+	problems := make([]string, 0)
+	if address == "" {
+		problems = append(problems, fmt.Sprintf("%s.address must not be empty", name))
+	}
+
+	if port < 1 || port > 65535 {
+		problems = append(problems, fmt.Sprintf("%s.port must be between 1 and 65535, got %d", name, port))
+	}
+
+	return problems
+}
+
+// String returns the configuration in the YAML format, as used by the configuration file. Passwords are redacted.
+func (c *Config) String() string {
+	redacted := *c
+	if redacted.Persistence.Etcd.Password != "" {
+		redacted.Persistence.Etcd.Password = redactedValue
+	}
+
+	var buffer bytes.Buffer
+	encoder := yaml.NewEncoder(&buffer)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&redacted); err != nil {
+		return ""
+	}
+
+	return buffer.String()
+}
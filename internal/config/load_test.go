@@ -0,0 +1,236 @@
+package config
+
+import (
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testEnv(values map[string]string) LookupEnv {
+	return func(key string) (string, bool) {
+		value, found := values[key]
+		return value, found
+	}
+}
+
+func writeConfigFile(t *testing.T, name string, content string) string {
+	location := path.Join(t.TempDir(), name)
+	if err := os.WriteFile(location, []byte(content), 0644); err != nil {
+		t.Fatalf("unable to write config file: %v", err)
+	}
+
+	return location
+}
+
+func TestLoad_defaults(t *testing.T) {
+	conf, opts, err := Load([]string{}, testEnv(nil))
+	if assert.Nil(t, err) {
+		assert.Equal(t, DefaultConfig(), conf)
+		assert.False(t, opts.PrintConfig)
+	}
+}
+
+func TestLoad_precedence(t *testing.T) {
+	file := writeConfigFile(t, "agent.yaml", `
+general:
+  resyncInterval: 1m
+grpc:
+  port: 9100
+http:
+  port: 8100
+  address: 127.0.0.1
+`)
+
+	env := testEnv(map[string]string{
+		"GCO_CONFIG":    file,
+		"GCO_HTTP_PORT": "8200",
+		"GCO_GRPC_PORT": "9200",
+	})
+
+	conf, opts, err := Load([]string{"--grpc.port", "9300", "--print-config"}, env)
+	if assert.Nil(t, err) {
+		assert.Equal(t, file, opts.ConfigFile, "should have used the config file from the environment")
+		assert.True(t, opts.PrintConfig)
+
+		assert.Equal(t, time.Minute, conf.General.ResyncInterval, "should have used the config file")
+		assert.Equal(t, "127.0.0.1", conf.Http.Address, "should have used the config file")
+		assert.Equal(t, 8200, conf.Http.Port, "environment should override the config file")
+		assert.Equal(t, 9300, conf.Grpc.Port, "flags should override the environment")
+		assert.True(t, conf.Docker.Enabled, "should have kept the defaults")
+	}
+}
+
+func TestLoad_jsonFile(t *testing.T) {
+	file := writeConfigFile(t, "agent.json", `{"general": {"resetProviderOnStartup": true}, "grpc": {"enabled": false}}`)
+
+	conf, _, err := Load([]string{"--config", file}, testEnv(nil))
+	if assert.Nil(t, err) {
+		assert.True(t, conf.General.ResetProviderOnStartup)
+		assert.False(t, conf.Grpc.Enabled)
+		assert.True(t, conf.Http.Enabled)
+	}
+}
+
+func TestLoad_unknownProperty(t *testing.T) {
+	file := writeConfigFile(t, "agent.yaml", "grpc:\n  prot: 9000\n")
+
+	_, _, err := Load([]string{"--config", file}, testEnv(nil))
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "field prot not found")
+	}
+}
+
+func TestLoad_invalidEnv(t *testing.T) {
+	_, _, err := Load([]string{}, testEnv(map[string]string{"GCO_HTTP_PORT": "http"}))
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "GCO_HTTP_PORT")
+	}
+}
+
+func TestLoad_help(t *testing.T) {
+	_, _, err := Load([]string{"--help"}, testEnv(nil))
+	assert.ErrorIs(t, err, ErrHelp)
+	assert.Contains(t, Usage(), "GCO_GRPC_PORT")
+}
+
+func TestConfig_Validate(t *testing.T) {
+	conf := DefaultConfig()
+	assert.Nil(t, conf.Validate())
+
+	conf.Grpc.Port = 0
+	conf.Http.Address = ""
+	conf.General.ResyncInterval = -time.Second
+	conf.Docker.Enabled = false
+
+	err := conf.Validate()
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "grpc.port must be between 1 and 65535")
+		assert.Contains(t, err.Error(), "http.address must not be empty")
+		assert.Contains(t, err.Error(), "general.resyncInterval must not be negative")
+		assert.Contains(t, err.Error(), "no provider has been enabled")
+	}
+
+	conf = DefaultConfig()
+	conf.Persistence.Revisions = -1
+	err = conf.Validate()
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "persistence.revisions must not be negative, got -1")
+	}
+
+	conf = DefaultConfig()
+	conf.Http.Port = conf.Grpc.Port
+	assert.NotNil(t, conf.Validate(), "should not allow both servers on the same address")
+
+	conf = DefaultConfig()
+	conf.Swarm.Enabled = true
+	err = conf.Validate()
+	if assert.NotNil(t, err, "should not allow multiple providers") {
+		assert.Contains(t, err.Error(), "only a single provider can be enabled, got [docker, swarm]")
+	}
+
+	conf = DefaultConfig()
+	conf.Docker.Enabled = false
+	conf.Kubernetes.Enabled = true
+	assert.Nil(t, conf.Validate())
+
+	conf.Kubernetes.Namespace = ""
+	err = conf.Validate()
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "kubernetes.namespace must not be empty")
+	}
+
+	conf = DefaultConfig()
+	conf.Docker.Enabled = false
+	conf.Podman.Enabled = true
+	assert.Nil(t, conf.Validate())
+
+	conf.Podman.UnprivilegedPortStart = 70000
+	err = conf.Validate()
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "podman.unprivilegedPortStart must be between 0 and 65535, got 70000")
+	}
+
+	conf = DefaultConfig()
+	conf.Docker.Enabled = false
+	conf.Containerd.Enabled = true
+	assert.Nil(t, conf.Validate())
+
+	conf.Containerd.Address = ""
+	conf.Containerd.Namespace = ""
+	err = conf.Validate()
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "containerd.address must not be empty")
+		assert.Contains(t, err.Error(), "containerd.namespace must not be empty")
+	}
+}
+
+func TestConfig_String(t *testing.T) {
+	conf := DefaultConfig()
+	file := writeConfigFile(t, "agent.yaml", conf.String())
+
+	loaded, _, err := Load([]string{"--config", file}, testEnv(nil))
+	if assert.Nil(t, err) {
+		assert.Equal(t, conf, loaded, "printed configuration should be loadable")
+	}
+}
+
+func TestConfig_String_redactsPassword(t *testing.T) {
+	conf := DefaultConfig()
+	assert.NotContains(t, conf.String(), "password", "should omit an empty password")
+
+	conf.Persistence.Etcd.Password = "s3cret"
+	printed := conf.String()
+	assert.NotContains(t, printed, "s3cret")
+	assert.Contains(t, printed, "password: <redacted>")
+	assert.Equal(t, "s3cret", conf.Persistence.Etcd.Password, "should not have modified the configuration")
+}
+
+func TestConfig_GetStateFile(t *testing.T) {
+	conf := DefaultConfig()
+	conf.General.DataDirectory = "/var/lib/gco"
+	assert.Equal(t, "/var/lib/gco/gco.state", conf.GetStateFile())
+
+	conf.Persistence.StateFile = "agent-1.state"
+	assert.Equal(t, "/var/lib/gco/agent-1.state", conf.GetStateFile(), "should resolve relative paths within the data directory")
+
+	conf.Persistence.StateFile = "/data/gco.state"
+	assert.Equal(t, "/data/gco.state", conf.GetStateFile())
+
+	assert.Equal(t, "/var/lib/gco/gco.db", conf.GetDatabase())
+	conf.Persistence.Database = "agent-1.db"
+	assert.Equal(t, "/var/lib/gco/agent-1.db", conf.GetDatabase())
+}
+
+func TestLoad_persistence(t *testing.T) {
+	env := testEnv(map[string]string{"GCO_GENERAL_DATA_DIRECTORY": "/data"})
+
+	conf, _, err := Load([]string{"--persistence.state-file", "agent.state"}, env)
+	if assert.Nil(t, err) {
+		assert.Equal(t, "/data", conf.General.DataDirectory)
+		assert.Equal(t, "/data/agent.state", conf.GetStateFile())
+	}
+
+	_, _, err = Load([]string{"--persistence.backend", "unknown"}, testEnv(nil))
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "persistence.backend must be one of [local, sqlite, etcd]")
+	}
+}
+
+func TestLoad_etcd(t *testing.T) {
+	env := testEnv(map[string]string{"GCO_PERSISTENCE_ETCD_ENDPOINTS": "http://10.0.0.1:2379, http://10.0.0.2:2379"})
+
+	conf, _, err := Load([]string{"--persistence.backend", "etcd", "--persistence.etcd.key", "/agents/a"}, env)
+	if assert.Nil(t, err) {
+		assert.Equal(t, EtcdBackend, conf.Persistence.Backend)
+		assert.Equal(t, []string{"http://10.0.0.1:2379", "http://10.0.0.2:2379"}, conf.Persistence.Etcd.Endpoints)
+		assert.Equal(t, "/agents/a", conf.Persistence.Etcd.Key)
+	}
+
+	_, _, err = Load([]string{"--persistence.backend", "etcd", "--persistence.etcd.endpoints", ""}, testEnv(nil))
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "persistence.etcd.endpoints must not be empty")
+	}
+}
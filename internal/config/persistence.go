@@ -0,0 +1,75 @@
+package config
+
+import (
+	"path"
+	"time"
+)
+
+const (
+	// LocalBackend persists the desired state to a file on the local filesystem.
+	LocalBackend = "local"
+	// SqliteBackend persists every desired state as a new revision to a SQLite database on the local filesystem.
+	SqliteBackend = "sqlite"
+	// EtcdBackend persists the desired state to a key within an etcd cluster.
+	EtcdBackend = "etcd"
+
+	// defaultStateFile is the name of the state file within the data directory.
+	defaultStateFile = "gco.state"
+	// defaultDatabase is the name of the database within the data directory.
+	defaultDatabase = "gco.db"
+)
+
+// backends lists the supported persistence backends.
+var backends = []string{LocalBackend, SqliteBackend, EtcdBackend}
+
+type Persistence struct {
+	// Backend selects the storage used for persisting the desired state.
+	Backend string `yaml:"backend"`
+	// StateFile specifies the location of the state file used by the local backend, relative paths are resolved
+	// within the data directory. Defaults to 'gco.state' within the data directory.
+	StateFile string `yaml:"stateFile"`
+	// Backups specifies the number of previous states kept next to the state file by the local backend.
+	Backups int `yaml:"backups"`
+	// Database specifies the location of the database used by the sqlite backend, relative paths are resolved
+	// within the data directory. Defaults to 'gco.db' within the data directory.
+	Database string `yaml:"database"`
+	// Revisions specifies the number of previous revisions kept next to the most recent one by the sqlite backend.
+	Revisions int `yaml:"revisions"`
+	// Etcd reflects the configuration of the etcd backend.
+	Etcd EtcdPersistence `yaml:"etcd"`
+}
+
+type EtcdPersistence struct {
+	// Endpoints lists the client URLs of the etcd cluster, e.g. 'http://127.0.0.1:2379'.
+	Endpoints []string `yaml:"endpoints"`
+	// Key specifies the key containing the desired state, agents sharing a key share the same desired state.
+	Key string `yaml:"key"`
+	// DialTimeout specifies how long to wait for the connection to the cluster.
+	DialTimeout time.Duration `yaml:"dialTimeout"`
+	// Username and Password are used to authenticate when the cluster has authentication enabled.
+	Username string `yaml:"username"`
+	Password string `yaml:"password,omitempty"`
+}
+
+// GetStateFile returns the location of the state file within the data directory unless an absolute path has been configured.
+func (p *Persistence) GetStateFile(dataDirectory string) string {
+	return resolve(dataDirectory, p.StateFile, defaultStateFile)
+}
+
+// GetDatabase returns the location of the database within the data directory unless an absolute path has been configured.
+func (p *Persistence) GetDatabase(dataDirectory string) string {
+	return resolve(dataDirectory, p.Database, defaultDatabase)
+}
+
+// resolve returns the location within the data directory, using the default name when no location has been configured.
+func resolve(dataDirectory string, location string, defaultName string) string {
+	if location == "" {
+		return path.Join(dataDirectory, defaultName)
+	}
+
+	if path.IsAbs(location) {
+		return location
+	}
+
+	return path.Join(dataDirectory, location)
+}
@@ -2,7 +2,47 @@ package config
 
 type DockerProvider struct {
 	// Enabled is used to enable or disable the docker provider.
-	Enabled bool
+	Enabled bool `yaml:"enabled"`
 	// UseDockerComposeGrouping will add the 'gco' label to the managed containers resulting in a grouped view with docker desktop.
-	UseDockerComposeGrouping bool
+	UseDockerComposeGrouping bool `yaml:"useDockerComposeGrouping"`
+	// ObserveEvents enables listening to the docker event stream to detect changes made outside the agent.
+	ObserveEvents bool `yaml:"observeEvents"`
+}
+
+type SwarmProvider struct {
+	// Enabled is used to enable or disable the swarm provider, the agent has to run on a manager node of the swarm.
+	Enabled bool `yaml:"enabled"`
+}
+
+type KubernetesProvider struct {
+	// Enabled is used to enable or disable the kubernetes provider.
+	Enabled bool `yaml:"enabled"`
+	// Kubeconfig is the location of the kubeconfig file, the in-cluster configuration or the default kubeconfig is used when empty.
+	Kubeconfig string `yaml:"kubeconfig"`
+	// Namespace is the namespace containing the managed resources.
+	Namespace string `yaml:"namespace"`
+}
+
+type PodmanProvider struct {
+	// Enabled is used to enable or disable the podman provider.
+	Enabled bool `yaml:"enabled"`
+	// Socket is the location of the podman API socket, the default socket of the current user is used when empty.
+	Socket string `yaml:"socket"`
+	// UsePodGrouping will create the managed containers within the 'gco' pod.
+	UsePodGrouping bool `yaml:"usePodGrouping"`
+	// UnprivilegedPortStart is the first host port a rootless podman is able to publish.
+	UnprivilegedPortStart int `yaml:"unprivilegedPortStart"`
+}
+
+type ContainerdProvider struct {
+	// Enabled is used to enable or disable the containerd provider.
+	Enabled bool `yaml:"enabled"`
+	// Address is the location of the containerd socket.
+	Address string `yaml:"address"`
+	// Namespace is the containerd namespace containing the managed containers.
+	Namespace string `yaml:"namespace"`
+	// CNIConfDir is the directory containing the CNI network configuration, the first configuration is used.
+	CNIConfDir string `yaml:"cniConfDir"`
+	// CNIBinDir is the directory containing the CNI plugins.
+	CNIBinDir string `yaml:"cniBinDir"`
 }
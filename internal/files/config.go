@@ -17,7 +17,8 @@ func SetDirectory(dir string) {
 	directory = dir
 }
 
-// GetDirectory returns the directory for storing configuration files or defaults to '/etc/gco' when not configured.
+// GetDirectory returns the data directory for storing configuration files, as configured using SetDirectory,
+// or defaults to '/etc/gco' when not configured.
 func GetDirectory() (string, error) {
 	dir := directory
 	if dir == "" {
@@ -0,0 +1,56 @@
+package files
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/mbaitar/gco/agent/internal/log"
+)
+
+// secretDirectory is the directory within the configuration directory which contains the secrets.
+const secretDirectory = "secrets"
+
+// legacyDirectory is the configuration directory of previous versions, before the data directory was configurable.
+const legacyDirectory = "/etc/gco"
+
+// ReadSecret reads the secret with the given name from the secrets directory.
+// Trailing newlines are removed from the secret value.
+func ReadSecret(name string) (string, error) {
+	if name == "" || strings.ContainsAny(name, `/\`) || name == "." || name == ".." {
+		return "", fmt.Errorf("invalid secret name '%s'", name)
+	}
+
+	dir, err := GetDirectory()
+	if err != nil {
+		return "", err
+	}
+
+	content, err := os.ReadFile(path.Join(dir, secretDirectory, name))
+	if err != nil {
+		return "", fmt.Errorf("unable to read secret '%s': %v", name, err)
+	}
+
+	return strings.TrimRight(string(content), "\r\n"), nil
+}
+
+// WarnOnLegacySecrets warns when the secrets directory of previous versions exists while the data directory does not
+// contain any secrets, as the secrets of previous versions are no longer read.
+func WarnOnLegacySecrets(dataDirectory string) {
+	if path.Clean(dataDirectory) == legacyDirectory {
+		return
+	}
+
+	legacy := path.Join(legacyDirectory, secretDirectory)
+	if info, err := os.Stat(legacy); err != nil || !info.IsDir() {
+		return
+	}
+
+	current := path.Join(dataDirectory, secretDirectory)
+	if _, err := os.Stat(current); err == nil {
+		return
+	}
+
+	log.Warnf("Found secrets of a previous version in '%s', which are ignored: move them to '%s' or set general.dataDirectory to '%s'", legacy, current, legacyDirectory)
+}
@@ -0,0 +1,83 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// namespace is the prefix of every metric exposed by the agent.
+const namespace = "gco"
+
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+)
+
+var (
+	// ReconcilePasses counts the reconciliation passes by their trigger (apply or observe).
+	ReconcilePasses = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "reconcile_passes_total",
+		Help:      "Number of reconciliation passes by trigger.",
+	}, []string{"trigger"})
+
+	// ReconcileActions counts the actions performed by the reconciler by their outcome.
+	ReconcileActions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "reconcile_actions_total",
+		Help:      "Number of actions performed by the reconciler by action and outcome.",
+	}, []string{"action", "outcome"})
+
+	// DriftDetected counts the differences found between the desired and actual state.
+	DriftDetected = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "drift_detected_total",
+		Help:      "Number of differences detected between the desired and actual state by kind and change.",
+	}, []string{"kind", "change"})
+
+	// ProviderCallDuration observes the latency of the calls to the container provider.
+	ProviderCallDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "provider_call_duration_seconds",
+		Help:      "Latency of the calls to the container provider by method and outcome.",
+		Buckets:   prometheus.ExponentialBuckets(0.005, 2, 14),
+	}, []string{"method", "outcome"})
+
+	// ImagePullDuration observes the time it takes to pull an image.
+	ImagePullDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "image_pull_duration_seconds",
+		Help:      "Time it took to pull an image by outcome.",
+		Buckets:   prometheus.ExponentialBuckets(0.25, 2, 12),
+	}, []string{"outcome"})
+
+	// LastResync records the time of the last successful periodic resync of the actual state.
+	LastResync = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "last_resync_timestamp_seconds",
+		Help:      "Unix time of the last successful resync of the actual state.",
+	})
+
+	// ApiRequests counts the API requests by transport, method and status code.
+	ApiRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "api_requests_total",
+		Help:      "Number of API requests by transport, method and status code.",
+	}, []string{"transport", "method", "code"})
+)
+
+// Outcome returns the outcome label value for the error.
+func Outcome(err error) string {
+	if err != nil {
+		return OutcomeFailure
+	}
+
+	return OutcomeSuccess
+}
+
+// ObserveDuration records the time since start in the histogram using the outcome of the error.
+func ObserveDuration(histogram *prometheus.HistogramVec, start time.Time, err error, labels ...string) {
+	histogram.WithLabelValues(append(labels, Outcome(err))...).Observe(time.Since(start).Seconds())
+}
@@ -0,0 +1,81 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/mbaitar/gco/agent/internal/provider"
+	"github.com/mbaitar/gco/agent/internal/state"
+	"github.com/mbaitar/gco/agent/pkg/feature"
+	"github.com/mbaitar/gco/agent/pkg/resource"
+)
+
+// instrumentedProvider records the latency of every call to the wrapped provider.
+type instrumentedProvider struct {
+	provider provider.Provider
+}
+
+// InstrumentProvider wraps the provider to record the latency of its calls in ProviderCallDuration.
+func InstrumentProvider(p provider.Provider) provider.Provider {
+	return &instrumentedProvider{provider: p}
+}
+
+func (i *instrumentedProvider) CreateApplication(app *resource.Application) error {
+	start := time.Now()
+	err := i.provider.CreateApplication(app)
+	ObserveDuration(ProviderCallDuration, start, err, "create_application")
+	return err
+}
+
+func (i *instrumentedProvider) UpdateApplication(app *resource.Application) error {
+	start := time.Now()
+	err := i.provider.UpdateApplication(app)
+	ObserveDuration(ProviderCallDuration, start, err, "update_application")
+	return err
+}
+
+func (i *instrumentedProvider) RemoveApplication(app *resource.Application) error {
+	start := time.Now()
+	err := i.provider.RemoveApplication(app)
+	ObserveDuration(ProviderCallDuration, start, err, "remove_application")
+	return err
+}
+
+func (i *instrumentedProvider) CreateFeature(feat feature.Feature) error {
+	start := time.Now()
+	err := i.provider.CreateFeature(feat)
+	ObserveDuration(ProviderCallDuration, start, err, "create_feature")
+	return err
+}
+
+func (i *instrumentedProvider) UpdateFeature(feat feature.Feature) error {
+	start := time.Now()
+	err := i.provider.UpdateFeature(feat)
+	ObserveDuration(ProviderCallDuration, start, err, "update_feature")
+	return err
+}
+
+func (i *instrumentedProvider) RemoveFeature(feat feature.Feature) error {
+	start := time.Now()
+	err := i.provider.RemoveFeature(feat)
+	ObserveDuration(ProviderCallDuration, start, err, "remove_feature")
+	return err
+}
+
+func (i *instrumentedProvider) ActualState() (*state.Spec, error) {
+	start := time.Now()
+	spec, err := i.provider.ActualState()
+	ObserveDuration(ProviderCallDuration, start, err, "actual_state")
+	return spec, err
+}
+
+// SupportsFeature forwards to the wrapped provider.
+func (i *instrumentedProvider) SupportsFeature(name string) bool {
+	return provider.SupportsFeature(i.provider, name)
+}
+
+// Watch forwards to the wrapped provider if it is able to observe external changes.
+func (i *instrumentedProvider) Watch(exit <-chan struct{}, handler provider.ObserveHandler) {
+	if observer, ok := i.provider.(provider.Observer); ok {
+		observer.Watch(exit, handler)
+	}
+}
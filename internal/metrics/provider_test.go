@@ -0,0 +1,62 @@
+package metrics
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/mbaitar/gco/agent/internal/provider"
+	"github.com/mbaitar/gco/agent/internal/state"
+	"github.com/mbaitar/gco/agent/pkg/feature"
+	"github.com/mbaitar/gco/agent/pkg/resource"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+type testProvider struct {
+	err     error
+	watched bool
+}
+
+func (t *testProvider) CreateApplication(app *resource.Application) error { return t.err }
+func (t *testProvider) UpdateApplication(app *resource.Application) error { return t.err }
+func (t *testProvider) RemoveApplication(app *resource.Application) error { return t.err }
+func (t *testProvider) CreateFeature(feat feature.Feature) error          { return t.err }
+func (t *testProvider) UpdateFeature(feat feature.Feature) error          { return t.err }
+func (t *testProvider) RemoveFeature(feat feature.Feature) error          { return t.err }
+func (t *testProvider) ActualState() (*state.Spec, error)                 { return state.EmptySpec(), t.err }
+
+func (t *testProvider) Watch(exit <-chan struct{}, handler provider.ObserveHandler) {
+	t.watched = true
+}
+
+func TestInstrumentProvider(t *testing.T) {
+	ProviderCallDuration.Reset()
+
+	inner := &testProvider{}
+	p := InstrumentProvider(inner)
+
+	assert.Nil(t, p.CreateApplication(&resource.Application{Name: "app"}))
+	inner.err = errors.New("test error")
+	assert.Equal(t, inner.err, p.CreateApplication(&resource.Application{Name: "app"}))
+
+	_, err := p.ActualState()
+	assert.Equal(t, inner.err, err, "should return the error of the wrapped provider")
+
+	assert.Equal(t, 3, testutil.CollectAndCount(ProviderCallDuration), "should have observed each method and outcome")
+}
+
+func TestInstrumentProvider_Watch(t *testing.T) {
+	inner := &testProvider{}
+	p := InstrumentProvider(inner)
+
+	observer, ok := p.(provider.Observer)
+	if assert.True(t, ok, "should implement the observer") {
+		observer.Watch(make(chan struct{}), func(spec state.Spec) {})
+		assert.True(t, inner.watched, "should have forwarded the watch to the wrapped provider")
+	}
+}
+
+func TestOutcome(t *testing.T) {
+	assert.Equal(t, OutcomeSuccess, Outcome(nil))
+	assert.Equal(t, OutcomeFailure, Outcome(errors.New("test error")))
+}
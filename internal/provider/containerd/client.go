@@ -0,0 +1,318 @@
+package containerd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/containerd/containerd"
+	"github.com/containerd/containerd/cio"
+	"github.com/containerd/containerd/containers"
+	"github.com/containerd/containerd/errdefs"
+	"github.com/containerd/containerd/oci"
+	"github.com/containerd/containerd/reference/docker"
+	gocni "github.com/containerd/go-cni"
+	"github.com/mbaitar/gco/agent/internal/config"
+	"github.com/mbaitar/gco/agent/internal/log"
+	"github.com/mbaitar/gco/agent/internal/provider"
+	"github.com/mbaitar/gco/agent/pkg/resource"
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+)
+
+// stopTimeout is the time a task has to exit after SIGTERM before it is killed.
+const stopTimeout = 10 * time.Second
+
+// containerdClient defines the operations of containerd and CNI used by the containerd provider.
+type containerdClient interface {
+	// ImageExists returns true when the image has been pulled and unpacked.
+	ImageExists(ctx context.Context, ref string) (bool, error)
+	// PullImage pulls and unpacks the image.
+	PullImage(ctx context.Context, ref string) error
+	// CreateContainer creates the container using a new snapshot of its image.
+	CreateContainer(ctx context.Context, c *internalContainer) error
+	// StartTask creates the task of the container, connects it to the CNI network publishing the ports and starts it.
+	StartTask(ctx context.Context, id string, ports []resource.Port) error
+	// StopTask disconnects the task from the network, stops and deletes it. Containers without a task are ignored.
+	StopTask(ctx context.Context, id string, ports []resource.Port) error
+	// DeleteContainer deletes the container and its snapshot.
+	DeleteContainer(ctx context.Context, id string) error
+	// ListContainers returns the containers matching all labels together with the status of their task.
+	ListContainers(ctx context.Context, labels ...provider.Label) ([]containerInfo, error)
+}
+
+// runtimeClient implements the containerdClient using the containerd API and the CNI plugins.
+type runtimeClient struct {
+	client  *containerd.Client
+	network gocni.CNI
+}
+
+func newContainerdClient(conf config.ContainerdProvider) containerdClient {
+	client, err := containerd.New(conf.Address, containerd.WithDefaultNamespace(conf.Namespace))
+	if err != nil {
+		log.Errorf("Unable to create new containerd client: %v", err)
+		os.Exit(1)
+	}
+
+	network, err := gocni.New(
+		gocni.WithMinNetworkCount(2),
+		gocni.WithPluginConfDir(conf.CNIConfDir),
+		gocni.WithPluginDir([]string{conf.CNIBinDir}),
+	)
+	if err != nil {
+		log.Errorf("Unable to initialize CNI: %v", err)
+		os.Exit(1)
+	}
+
+	// the loopback network and the first network configuration within the configuration directory are used
+	if err := network.Load(gocni.WithLoNetwork, gocni.WithDefaultConf); err != nil {
+		log.Errorf("Unable to load CNI network configuration from %s: %v", conf.CNIConfDir, err)
+		os.Exit(1)
+	}
+
+	return &runtimeClient{client: client, network: network}
+}
+
+// normalizeImage returns the fully qualified reference required by containerd, e.g. docker.io/library/nginx:latest.
+func normalizeImage(ref string) (string, error) {
+	named, err := docker.ParseDockerRef(ref)
+	if err != nil {
+		return "", err
+	}
+
+	return named.String(), nil
+}
+
+func (r *runtimeClient) ImageExists(ctx context.Context, ref string) (bool, error) {
+	normalized, err := normalizeImage(ref)
+	if err != nil {
+		return false, err
+	}
+
+	image, err := r.client.GetImage(ctx, normalized)
+	if errdefs.IsNotFound(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	return image.IsUnpacked(ctx, containerd.DefaultSnapshotter)
+}
+
+func (r *runtimeClient) PullImage(ctx context.Context, ref string) error {
+	normalized, err := normalizeImage(ref)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.client.Pull(ctx, normalized, containerd.WithPullUnpack)
+	return err
+}
+
+func (r *runtimeClient) CreateContainer(ctx context.Context, c *internalContainer) error {
+	normalized, err := normalizeImage(c.image)
+	if err != nil {
+		return err
+	}
+
+	image, err := r.client.GetImage(ctx, normalized)
+	if err != nil {
+		return err
+	}
+
+	opts := []oci.SpecOpts{oci.WithImageConfig(image), oci.WithHostname(c.id), withHostResolvConf()}
+	if len(c.args) > 0 {
+		opts = append(opts, oci.WithImageConfigArgs(image, c.args))
+	}
+
+	if c.user != "" {
+		opts = append(opts, oci.WithUser(c.user))
+	}
+
+	opts = append(opts, func(_ context.Context, _ oci.Client, _ *containers.Container, spec *oci.Spec) error {
+		return c.applySpec(spec)
+	})
+
+	_, err = r.client.NewContainer(ctx, c.id,
+		containerd.WithImage(image),
+		containerd.WithNewSnapshot(snapshotName(c.id), image),
+		containerd.WithNewSpec(opts...),
+		containerd.WithContainerLabels(c.labels),
+	)
+
+	return err
+}
+
+// withHostResolvConf mounts the resolv.conf of the host, as the CNI network does not provide name resolution.
+func withHostResolvConf() oci.SpecOpts {
+	return oci.WithMounts([]specs.Mount{
+		{Destination: "/etc/resolv.conf", Type: "bind", Source: "/etc/resolv.conf", Options: []string{"rbind", "ro"}},
+	})
+}
+
+func (r *runtimeClient) StartTask(ctx context.Context, id string, ports []resource.Port) error {
+	container, err := r.client.LoadContainer(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	task, err := container.NewTask(ctx, cio.NullIO)
+	if err != nil {
+		return err
+	}
+
+	// the network is set up before the task is started, the process is waiting within its new network namespace
+	netns := netnsPath(task.Pid())
+	if _, err = r.network.Setup(ctx, id, netns, gocni.WithCapabilityPortMap(toPortMappings(ports))); err != nil {
+		_, _ = task.Delete(ctx, containerd.WithProcessKill)
+		return fmt.Errorf("unable to set up network of container %s: %w", id, err)
+	}
+
+	if err = task.Start(ctx); err != nil {
+		r.removeNetwork(ctx, id, netns, ports)
+		_, _ = task.Delete(ctx, containerd.WithProcessKill)
+		return err
+	}
+
+	return nil
+}
+
+func (r *runtimeClient) StopTask(ctx context.Context, id string, ports []resource.Port) error {
+	container, err := r.client.LoadContainer(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	task, err := container.Task(ctx, nil)
+	if errdefs.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	// the network of exited tasks is removed as well, which releases the allocated address
+	r.removeNetwork(ctx, id, netnsPath(task.Pid()), ports)
+
+	status, err := task.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	if status.Status != containerd.Stopped && status.Status != containerd.Created {
+		exited, err := task.Wait(ctx)
+		if err != nil {
+			return err
+		}
+
+		if err := task.Kill(ctx, syscall.SIGTERM); err != nil && !errdefs.IsNotFound(err) {
+			return err
+		}
+
+		select {
+		case <-exited:
+		case <-time.After(stopTimeout):
+			log.Warnf("Container=%s did not stop within %s, killing it", id, stopTimeout)
+			if err := task.Kill(ctx, syscall.SIGKILL); err != nil && !errdefs.IsNotFound(err) {
+				return err
+			}
+			<-exited
+		}
+	}
+
+	_, err = task.Delete(ctx, containerd.WithProcessKill)
+	return err
+}
+
+// removeNetwork disconnects the task from the network, failures are logged as the task is removed anyway.
+func (r *runtimeClient) removeNetwork(ctx context.Context, id string, netns string, ports []resource.Port) {
+	if err := r.network.Remove(ctx, id, netns, gocni.WithCapabilityPortMap(toPortMappings(ports))); err != nil {
+		log.Warnf("Failed to remove network of container=%s: %v", id, err)
+	}
+}
+
+func (r *runtimeClient) DeleteContainer(ctx context.Context, id string) error {
+	container, err := r.client.LoadContainer(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	return container.Delete(ctx, containerd.WithSnapshotCleanup)
+}
+
+func (r *runtimeClient) ListContainers(ctx context.Context, labels ...provider.Label) ([]containerInfo, error) {
+	filters := make([]string, 0, len(labels))
+	for _, l := range labels {
+		filters = append(filters, filter(l))
+	}
+
+	// the conditions of a single filter are combined, multiple filters would match any of them
+	found, err := r.client.Containers(ctx, strings.Join(filters, ","))
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]containerInfo, 0, len(found))
+	for _, container := range found {
+		info := containerInfo{id: container.ID()}
+		if info.labels, err = container.Labels(ctx); err != nil {
+			log.Warnf("Failed to read labels of container=%s: %v", container.ID(), err)
+			continue
+		}
+
+		if info.spec, err = container.Spec(ctx); err != nil {
+			log.Warnf("Failed to read spec of container=%s: %v", container.ID(), err)
+			continue
+		}
+
+		task, err := container.Task(ctx, nil)
+		if err == nil {
+			status, statusErr := task.Status(ctx)
+			if statusErr != nil {
+				log.Warnf("Failed to read task status of container=%s: %v", container.ID(), statusErr)
+			}
+
+			info.status = string(status.Status)
+		} else if !errdefs.IsNotFound(err) {
+			log.Warnf("Failed to load task of container=%s: %v", container.ID(), err)
+		}
+
+		result = append(result, info)
+	}
+
+	return result, nil
+}
+
+// toPortMappings returns the CNI port mappings, ports without a host port are only reachable within the network.
+func toPortMappings(ports []resource.Port) []gocni.PortMapping {
+	mappings := make([]gocni.PortMapping, 0, len(ports))
+	for _, port := range ports {
+		if port.HostPort == 0 {
+			continue
+		}
+
+		protocol := string(port.Protocol)
+		if protocol == "" {
+			protocol = "tcp"
+		}
+
+		mappings = append(mappings, gocni.PortMapping{
+			HostPort:      int32(port.HostPort),
+			ContainerPort: int32(port.ContainerPort),
+			Protocol:      protocol,
+		})
+	}
+
+	return mappings
+}
+
+// netnsPath returns the network namespace of the task process.
+func netnsPath(pid uint32) string {
+	return fmt.Sprintf("/proc/%d/ns/net", pid)
+}
+
+// snapshotName returns the name of the writable snapshot of the container.
+func snapshotName(id string) string {
+	return fmt.Sprintf("%s-snapshot", id)
+}
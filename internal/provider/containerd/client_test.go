@@ -0,0 +1,156 @@
+package containerd
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/mbaitar/gco/agent/internal/files"
+	providers "github.com/mbaitar/gco/agent/internal/provider"
+	"github.com/mbaitar/gco/agent/pkg/resource"
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+)
+
+// testContainer is a container kept by the TestClient, the status is empty when the container has no task.
+type testContainer struct {
+	labels map[string]string
+	spec   *specs.Spec
+	status string
+	ports  []resource.Port
+}
+
+// TestClient mimics containerd and the CNI network, the containers are kept in memory.
+type TestClient struct {
+	images     map[string]bool
+	containers map[string]*testContainer
+	pulls      []string
+	creates    []string
+
+	pullErr  error
+	startErr error
+}
+
+func NewTestClient() *TestClient {
+	return &TestClient{
+		images:     make(map[string]bool),
+		containers: make(map[string]*testContainer),
+	}
+}
+
+func NewTestProvider(t *testing.T, client *TestClient) *Provider {
+	files.SetDirectory(t.TempDir())
+	return &Provider{client: client}
+}
+
+func (t *TestClient) ImageExists(_ context.Context, ref string) (bool, error) {
+	return t.images[ref], nil
+}
+
+func (t *TestClient) PullImage(_ context.Context, ref string) error {
+	t.pulls = append(t.pulls, ref)
+	if t.pullErr != nil {
+		return t.pullErr
+	}
+
+	t.images[ref] = true
+	return nil
+}
+
+func (t *TestClient) CreateContainer(_ context.Context, c *internalContainer) error {
+	if _, found := t.containers[c.id]; found {
+		return fmt.Errorf("container %s: already exists", c.id)
+	}
+
+	// the image configuration provides the initial environment
+	spec := &specs.Spec{Process: &specs.Process{Env: []string{"PATH=/usr/local/bin:/usr/bin"}}}
+	if err := c.applySpec(spec); err != nil {
+		return err
+	}
+
+	labels := make(map[string]string, len(c.labels))
+	for key, value := range c.labels {
+		labels[key] = value
+	}
+
+	t.creates = append(t.creates, c.id)
+	t.containers[c.id] = &testContainer{labels: labels, spec: spec}
+	return nil
+}
+
+func (t *TestClient) StartTask(_ context.Context, id string, ports []resource.Port) error {
+	c, found := t.containers[id]
+	if !found {
+		return fmt.Errorf("container %s: not found", id)
+	}
+
+	if c.status != "" {
+		return fmt.Errorf("task %s: already exists", id)
+	}
+
+	if t.startErr != nil {
+		return t.startErr
+	}
+
+	c.status = "running"
+	c.ports = ports
+	return nil
+}
+
+func (t *TestClient) StopTask(_ context.Context, id string, _ []resource.Port) error {
+	c, found := t.containers[id]
+	if !found {
+		return fmt.Errorf("container %s: not found", id)
+	}
+
+	c.status = ""
+	c.ports = nil
+	return nil
+}
+
+func (t *TestClient) DeleteContainer(_ context.Context, id string) error {
+	c, found := t.containers[id]
+	if !found {
+		return fmt.Errorf("container %s: not found", id)
+	}
+
+	if c.status != "" {
+		return fmt.Errorf("container %s: task must be deleted before deleting the container", id)
+	}
+
+	delete(t.containers, id)
+	return nil
+}
+
+func (t *TestClient) ListContainers(_ context.Context, labels ...providers.Label) ([]containerInfo, error) {
+	ids := make([]string, 0, len(t.containers))
+	for id := range t.containers {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	result := make([]containerInfo, 0)
+	for _, id := range ids {
+		c := t.containers[id]
+		if matchesLabels(c.labels, labels) {
+			result = append(result, containerInfo{id: id, labels: c.labels, spec: c.spec, status: c.status})
+		}
+	}
+
+	return result, nil
+}
+
+// setStatus changes the status of the task, e.g. to mimic a task which exited.
+func (t *TestClient) setStatus(id string, status string) {
+	t.containers[id].status = status
+}
+
+func matchesLabels(labels map[string]string, selectors []providers.Label) bool {
+	for _, selector := range selectors {
+		if value, found := labels[selector.Tag.String()]; !found || value != selector.Value {
+			return false
+		}
+	}
+
+	return true
+}
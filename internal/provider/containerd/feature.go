@@ -0,0 +1,102 @@
+package containerd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/mbaitar/gco/agent/internal/files"
+	"github.com/mbaitar/gco/agent/internal/provider"
+	"github.com/mbaitar/gco/agent/pkg/feature"
+	"github.com/mbaitar/gco/agent/pkg/resource"
+)
+
+// providerName is the name used to look up containerd specific feature materializers.
+const providerName = "containerd"
+
+// SupportsFeature returns true when a materializer of the feature has been registered for the provider.
+func (p *Provider) SupportsFeature(name string) bool {
+	return feature.SupportedBy(name, providerName)
+}
+
+// createFeatureContainers creates the internal container definitions using the workloads of the registered feature.
+func createFeatureContainers(feat feature.Feature) ([]*internalContainer, error) {
+	def, found := feature.Lookup(feat.Name())
+	if !found {
+		return nil, provider.ErrFeatureNotSupported
+	}
+
+	materialize := def.MaterializerFor(providerName)
+	if materialize == nil {
+		return nil, provider.ErrFeatureNotSupported
+	}
+
+	workloads, err := materialize(feat)
+	if err != nil {
+		return nil, err
+	}
+
+	containers := make([]*internalContainer, 0, len(workloads))
+	for _, workload := range workloads {
+		ic, err := createWorkloadContainer(feat, workload)
+		if err != nil {
+			return nil, err
+		}
+
+		containers = append(containers, ic)
+	}
+
+	return containers, nil
+}
+
+// createWorkloadContainer creates an internal container definition for a single workload of the feature.
+// Global workloads run once, as the provider manages a single host.
+func createWorkloadContainer(feat feature.Feature, workload feature.Workload) (*internalContainer, error) {
+	id := fmt.Sprintf("%s.%s", provider.LabelPrefix, feat.Name())
+	if workload.Name != "" {
+		id = fmt.Sprintf("%s.%s", id, workload.Name)
+	}
+
+	ic := &internalContainer{
+		id:     id,
+		image:  workload.Image,
+		args:   workload.Command,
+		user:   workload.User,
+		labels: make(map[string]string),
+		ports:  workload.Ports,
+		mounts: append([]resource.Mount{}, workload.Mounts...),
+		env:    workload.Env,
+	}
+
+	// bind the generated configuration files, these are removed together with the feature
+	for _, configFile := range workload.ConfigFiles {
+		location, err := files.WriteConfigFileFromString(configFile.Content, configFile.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		ic.configFiles = append(ic.configFiles, location)
+		ic.mounts = append(ic.mounts, resource.Mount{Type: resource.BindMountType, Source: location, Target: configFile.Target, ReadOnly: true})
+	}
+
+	ic.addLabel(provider.ManagedByLabel())
+	ic.addLabel(provider.KindLabel(resource.FeatureKind))
+	ic.addLabel(provider.FeatureLabel(feat.Name()))
+	ic.addLabel(provider.ConfigLabel(feature.EncodeFeature(feat)))
+	ic.addLabel(imageLabel(workload.Image))
+
+	if len(ic.ports) > 0 {
+		encoded, _ := json.Marshal(ic.ports)
+		ic.addLabel(portsLabel(string(encoded)))
+	}
+
+	if len(ic.mounts) > 0 {
+		encoded, _ := json.Marshal(ic.mounts)
+		ic.addLabel(mountsLabel(string(encoded)))
+	}
+
+	if len(ic.configFiles) > 0 {
+		ic.addLabel(configFilesLabel(ic.configFiles))
+	}
+
+	return ic, nil
+}
@@ -0,0 +1,37 @@
+package containerd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mbaitar/gco/agent/internal/provider"
+)
+
+// The parts of an application which are not part of the OCI spec are kept as encoded labels.
+var (
+	imageLabelTag       = provider.PlatformLabelTag("image")
+	portsLabelTag       = provider.PlatformLabelTag("ports")
+	mountsLabelTag      = provider.PlatformLabelTag("mounts")
+	configFilesLabelTag = provider.PlatformLabelTag("config-files")
+)
+
+// filter returns the containerd filter matching the label.
+func filter(l provider.Label) string {
+	return fmt.Sprintf("labels.%q==%q", l.Tag.String(), l.Value)
+}
+
+func imageLabel(image string) provider.Label {
+	return provider.Label{Tag: imageLabelTag, Value: image}
+}
+
+func portsLabel(value string) provider.Label {
+	return provider.Label{Tag: portsLabelTag, Value: value}
+}
+
+func mountsLabel(value string) provider.Label {
+	return provider.Label{Tag: mountsLabelTag, Value: value}
+}
+
+func configFilesLabel(locations []string) provider.Label {
+	return provider.Label{Tag: configFilesLabelTag, Value: strings.Join(locations, ",")}
+}
@@ -0,0 +1,381 @@
+package containerd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mbaitar/gco/agent/internal/config"
+	"github.com/mbaitar/gco/agent/internal/files"
+	"github.com/mbaitar/gco/agent/internal/log"
+	"github.com/mbaitar/gco/agent/internal/metrics"
+	"github.com/mbaitar/gco/agent/internal/provider"
+	"github.com/mbaitar/gco/agent/internal/state"
+	"github.com/mbaitar/gco/agent/pkg/feature"
+	"github.com/mbaitar/gco/agent/pkg/resource"
+)
+
+var (
+	// ErrHealthCheckUnsupported is returned for applications with a health check, containerd does not run health checks.
+	ErrHealthCheckUnsupported = errors.New("health checks are not supported by the containerd provider")
+	// ErrRestartPolicyUnsupported is returned for applications with a restart policy, containerd does not restart
+	// stopped tasks and the provider restarts them on every reconciliation instead.
+	ErrRestartPolicyUnsupported = errors.New("restart policies are not supported by the containerd provider")
+)
+
+// Provider defines a containerd provider which manages the containers of a single host using containerd directly.
+// The containers are connected to a CNI network which publishes their ports.
+type Provider struct {
+	// client represents containerd and the CNI network
+	client containerdClient
+}
+
+func NewContainerdProvider(conf config.ContainerdProvider) *Provider {
+	return &Provider{
+		client: newContainerdClient(conf),
+	}
+}
+
+func (p *Provider) CreateApplication(app *resource.Application) error {
+	if err := validateApplication(app); err != nil {
+		return err
+	}
+
+	return p.scaleApplication(app, nil)
+}
+
+func (p *Provider) UpdateApplication(app *resource.Application) error {
+	if err := validateApplication(app); err != nil {
+		return err
+	}
+
+	containers, err := p.getContainers(provider.NameLabel(app.Name))
+	if err != nil {
+		return err
+	}
+
+	if len(containers) == 0 {
+		return provider.ErrAppNotFound
+	}
+
+	return p.scaleApplication(app, containers)
+}
+
+func (p *Provider) RemoveApplication(app *resource.Application) error {
+	containers, err := p.getContainers(provider.NameLabel(app.Name))
+	if err != nil {
+		return err
+	}
+
+	if len(containers) == 0 {
+		return provider.ErrAppNotFound
+	}
+
+	for _, container := range containers {
+		if err = p.removeContainer(container); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// scaleApplication makes sure the requested instances of the application are running using the existing containers.
+// Containers which are outdated are recreated, instances which are missing are created and surplus instances are removed.
+// Containerd does not restart stopped tasks, as such the tasks of stopped instances are recreated.
+func (p *Provider) scaleApplication(app *resource.Application, containers []internalContainer) error {
+	instances := app.GetInstances()
+	desiredHash := app.CalculateHash()
+
+	// remove surplus instances
+	existing := make(map[int]internalContainer)
+	for _, container := range containers {
+		ordinal := container.getInstance()
+		_, duplicate := existing[ordinal]
+		if duplicate || ordinal >= instances {
+			log.Debugf("Removing surplus instance=%d of application=%s", ordinal, app.Name)
+			if err := p.removeContainer(container); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		existing[ordinal] = container
+	}
+
+	template := fromApplicationResource(app)
+	pulled := false
+	for ordinal := 0; ordinal < instances; ordinal++ {
+		container, found := existing[ordinal]
+		if found {
+			current := container.toApplicationResource()
+			if current.CalculateHash() == desiredHash {
+				if !container.isRunning() {
+					log.Debugf("Restarting stopped instance=%d of application=%s", ordinal, app.Name)
+					if err := p.restartContainer(container); err != nil {
+						return err
+					}
+				}
+
+				continue
+			}
+		}
+
+		// the image is pulled before removing an outdated instance, which keeps running when the pull fails
+		if !pulled {
+			if err := p.verifyImage(context.Background(), template); err != nil {
+				log.Debugf("Unable to pull image '%s' for application '%s'", template.image, app.Name)
+				return err
+			}
+
+			pulled = true
+		}
+
+		if found {
+			log.Debugf("Replacing outdated instance=%d of application=%s", ordinal, app.Name)
+			if err := p.removeContainer(container); err != nil {
+				return err
+			}
+		}
+
+		if err := p.runContainer(template.withInstance(ordinal)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateApplication verifies that the application can be run by containerd.
+// Multiple instances can not bind the same host port, which is why those are rejected.
+func validateApplication(app *resource.Application) error {
+	if app.HealthCheck != nil {
+		return ErrHealthCheckUnsupported
+	}
+
+	if !app.RestartPolicy.IsDefault() {
+		return ErrRestartPolicyUnsupported
+	}
+
+	if app.GetInstances() > 1 {
+		for _, port := range app.Ports {
+			if port.HostPort != 0 {
+				return provider.ErrHostPortConflict
+			}
+		}
+	}
+
+	if err := provider.ValidateLabels(app); err != nil {
+		return err
+	}
+
+	if app.LogConfig != nil {
+		log.Warnf("Log driver '%s' is not supported by containerd, the logs of application=%s are discarded", app.LogConfig.Driver, app.Name)
+	}
+
+	return nil
+}
+
+func (p *Provider) CreateFeature(feat feature.Feature) error {
+	containers, err := createFeatureContainers(feat)
+	if err != nil {
+		return err
+	}
+
+	for idx, container := range containers {
+		if err = p.createContainer(container); err != nil {
+			for _, created := range containers[:idx] {
+				warnOnError(p.removeContainer(*created), "Failed to remove container=%s of partially created feature", created.id)
+			}
+
+			// the config files of every container have been written up front
+			for _, c := range containers {
+				cleanUpConfigFiles(c)
+			}
+
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (p *Provider) UpdateFeature(feat feature.Feature) error {
+	if err := p.RemoveFeature(feat); err != nil {
+		return err
+	}
+
+	return p.CreateFeature(feat)
+}
+
+func (p *Provider) RemoveFeature(feat feature.Feature) error {
+	containers, err := p.getContainers(provider.KindLabel(resource.FeatureKind), provider.FeatureLabel(feat.Name()))
+	if err != nil {
+		return err
+	}
+
+	if len(containers) == 0 {
+		return provider.ErrFeatureNotFound
+	}
+
+	for _, container := range containers {
+		if err = p.removeContainer(container); err != nil {
+			return err
+		}
+
+		cleanUpConfigFiles(&container)
+	}
+
+	return nil
+}
+
+func (p *Provider) ActualState() (*state.Spec, error) {
+	appContainers, err := p.getContainers(provider.KindLabel(resource.ApplicationKind))
+	if err != nil {
+		return nil, err
+	}
+
+	// group the application instances by name
+	applications := make([]resource.Application, 0)
+	lookup := make(map[string]int)
+	for _, container := range appContainers {
+		app := container.toApplicationResource()
+
+		if idx, found := lookup[app.Name]; found {
+			applications[idx].Instances += app.Instances
+			applications[idx].ExitedInstances += app.ExitedInstances
+		} else {
+			lookup[app.Name] = len(applications)
+			applications = append(applications, app)
+		}
+	}
+
+	featContainers, err := p.getContainers(provider.KindLabel(resource.FeatureKind))
+	if err != nil {
+		return nil, err
+	}
+
+	features := state.Feature{}
+	for _, container := range featContainers {
+		featureName := container.getLabel(provider.FeatureLabelTag)
+		def, found := feature.Lookup(featureName)
+		if !found {
+			log.Warnf("Ignoring container of unknown feature=%s", featureName)
+			continue
+		}
+
+		features[def.Name] = feature.DecodeFeature(container.getLabel(provider.ConfigLabelTag), def.New())
+	}
+
+	spec := &state.Spec{
+		Applications: applications,
+		Feature:      features,
+	}
+
+	return spec, nil
+}
+
+// getContainers returns the managed containers matching all labels.
+func (p *Provider) getContainers(labels ...provider.Label) ([]internalContainer, error) {
+	infos, err := p.client.ListContainers(context.Background(), append([]provider.Label{provider.ManagedByLabel()}, labels...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	containers := make([]internalContainer, len(infos))
+	for idx, info := range infos {
+		containers[idx] = fromContainerInfo(info)
+	}
+
+	return containers, nil
+}
+
+// createContainer pulls the image when needed, creates the container and starts its task.
+func (p *Provider) createContainer(c *internalContainer) error {
+	if err := p.verifyImage(context.Background(), c); err != nil {
+		log.Debugf("Unable to pull image '%s' for container '%s'", c.image, c.id)
+		return err
+	}
+
+	return p.runContainer(c)
+}
+
+// runContainer creates the container of a present image and starts its task. The container is deleted when the task
+// could not be started.
+func (p *Provider) runContainer(c *internalContainer) error {
+	ctx := context.Background()
+
+	if err := c.createVolumes(); err != nil {
+		return err
+	}
+
+	if err := p.client.CreateContainer(ctx, c); err != nil {
+		return err
+	}
+
+	if err := p.client.StartTask(ctx, c.id, c.ports); err != nil {
+		warnOnError(p.client.DeleteContainer(ctx, c.id), "Failed to delete container=%s", c.id)
+		return err
+	}
+
+	return nil
+}
+
+// restartContainer replaces the stopped task of the container with a new task.
+func (p *Provider) restartContainer(c internalContainer) error {
+	ctx := context.Background()
+	if err := p.client.StopTask(ctx, c.id, c.ports); err != nil {
+		return err
+	}
+
+	return p.client.StartTask(ctx, c.id, c.ports)
+}
+
+// removeContainer stops the task of the container and deletes the container.
+func (p *Provider) removeContainer(c internalContainer) error {
+	ctx := context.Background()
+	if err := p.client.StopTask(ctx, c.id, c.ports); err != nil {
+		return err
+	}
+
+	return p.client.DeleteContainer(ctx, c.id)
+}
+
+// verifyImage pulls the image when it is not present or when the container always pulls its image.
+func (p *Provider) verifyImage(ctx context.Context, c *internalContainer) error {
+	if !c.pullAlways {
+		exists, err := p.client.ImageExists(ctx, c.image)
+		if err != nil {
+			return err
+		}
+
+		if exists {
+			return nil
+		}
+	}
+
+	start := time.Now()
+	err := p.client.PullImage(ctx, c.image)
+	metrics.ObserveDuration(metrics.ImagePullDuration, start, err)
+	if err != nil {
+		return err
+	}
+
+	log.Debugf("Successfully pulled image '%s' in %s", c.image, time.Since(start))
+	return nil
+}
+
+// cleanUpConfigFiles removes the generated configuration files of a feature container.
+func cleanUpConfigFiles(c *internalContainer) {
+	for _, location := range c.configFiles {
+		files.RemoveConfigFile(location)
+	}
+}
+
+func warnOnError(err error, format string, args ...interface{}) {
+	if err != nil {
+		log.Warnf("%s: %v", fmt.Sprintf(format, args...), err)
+	}
+}
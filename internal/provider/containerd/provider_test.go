@@ -0,0 +1,313 @@
+package containerd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mbaitar/gco/agent/internal/files"
+	providers "github.com/mbaitar/gco/agent/internal/provider"
+	"github.com/mbaitar/gco/agent/pkg/feature"
+	"github.com/mbaitar/gco/agent/pkg/resource"
+	"github.com/stretchr/testify/assert"
+)
+
+func exampleApplication() *resource.Application {
+	return &resource.Application{
+		Name:      "nginx",
+		Instances: 1,
+		Image: resource.Image{
+			Name: "nginx",
+			Tag:  "latest",
+		},
+		Ports: []resource.Port{
+			{HostPort: 8080, ContainerPort: 80, Protocol: "tcp"},
+		},
+	}
+}
+
+func TestProvider_CreateApplication(t *testing.T) {
+	client := NewTestClient()
+	provider := NewTestProvider(t, client)
+
+	err := provider.CreateApplication(exampleApplication())
+	assert.Nil(t, err, "should not have thrown an error")
+	assert.Equal(t, []string{"nginx:latest"}, client.pulls)
+
+	container, found := client.containers["nginx-0"]
+	if assert.True(t, found, "should have created the first instance") {
+		assert.Equal(t, "running", container.status)
+		assert.Equal(t, exampleApplication().Ports, container.ports, "should have published the ports")
+		assert.Equal(t, "nginx", container.labels[providers.NameLabelTag.String()])
+		assert.Equal(t, string(resource.ApplicationKind), container.labels[providers.KindLabelTag.String()])
+	}
+}
+
+func TestProvider_CreateApplication_imagePresent(t *testing.T) {
+	client := NewTestClient()
+	provider := NewTestProvider(t, client)
+	client.images["nginx:latest"] = true
+
+	assert.Nil(t, provider.CreateApplication(exampleApplication()))
+	assert.Empty(t, client.pulls, "should not have pulled the present image")
+
+	app := exampleApplication()
+	app.Name = "nginx-always"
+	app.Image.PullPolicy = "always"
+	assert.Nil(t, provider.CreateApplication(app))
+	assert.Equal(t, []string{"nginx:latest"}, client.pulls, "should always pull the image")
+}
+
+func TestProvider_CreateApplication_pullError(t *testing.T) {
+	client := NewTestClient()
+	provider := NewTestProvider(t, client)
+	client.pullErr = errors.New("test error")
+
+	err := provider.CreateApplication(exampleApplication())
+	assert.NotNil(t, err, "should have thrown an error")
+	assert.Empty(t, client.containers)
+}
+
+func TestProvider_CreateApplication_startError(t *testing.T) {
+	client := NewTestClient()
+	provider := NewTestProvider(t, client)
+	client.startErr = errors.New("test error")
+
+	err := provider.CreateApplication(exampleApplication())
+	assert.NotNil(t, err, "should have thrown an error")
+	assert.Empty(t, client.containers, "should have deleted the container")
+}
+
+func TestProvider_CreateApplication_invalid(t *testing.T) {
+	client := NewTestClient()
+	provider := NewTestProvider(t, client)
+
+	app := exampleApplication()
+	app.HealthCheck = &resource.HealthCheck{Type: resource.TcpHealthCheck, Port: 80}
+	assert.ErrorIs(t, provider.CreateApplication(app), ErrHealthCheckUnsupported)
+
+	app = exampleApplication()
+	app.Instances = 2
+	assert.ErrorIs(t, provider.CreateApplication(app), providers.ErrHostPortConflict)
+
+	app = exampleApplication()
+	app.Labels = map[string]string{"gco.io/name": "other"}
+	assert.ErrorIs(t, provider.CreateApplication(app), providers.ErrReservedLabel)
+
+	app = exampleApplication()
+	app.RestartPolicy = &resource.RestartPolicy{Name: resource.AlwaysRestartPolicy}
+	assert.ErrorIs(t, provider.CreateApplication(app), ErrRestartPolicyUnsupported)
+
+	assert.Empty(t, client.containers, "should not have created a container")
+}
+
+func TestProvider_CreateApplication_multipleInstances(t *testing.T) {
+	client := NewTestClient()
+	provider := NewTestProvider(t, client)
+
+	app := exampleApplication()
+	app.Instances = 3
+	app.Ports = []resource.Port{{ContainerPort: 80, Protocol: "tcp"}}
+
+	assert.Nil(t, provider.CreateApplication(app))
+	assert.Equal(t, []string{"nginx-0", "nginx-1", "nginx-2"}, client.creates)
+}
+
+func TestProvider_UpdateApplication(t *testing.T) {
+	client := NewTestClient()
+	provider := NewTestProvider(t, client)
+	assert.Nil(t, provider.CreateApplication(exampleApplication()))
+
+	app := exampleApplication()
+	app.Image.Tag = "1.23"
+	err := provider.UpdateApplication(app)
+	assert.Nil(t, err, "should not have thrown an error")
+
+	assert.Equal(t, []string{"nginx-0", "nginx-0"}, client.creates, "should have replaced the instance")
+	assert.Equal(t, "nginx:1.23", client.containers["nginx-0"].labels[imageLabelTag.String()])
+	assert.Equal(t, "running", client.containers["nginx-0"].status)
+}
+
+func TestProvider_UpdateApplication_pullError(t *testing.T) {
+	client := NewTestClient()
+	provider := NewTestProvider(t, client)
+	assert.Nil(t, provider.CreateApplication(exampleApplication()))
+	client.pullErr = errors.New("test error")
+
+	app := exampleApplication()
+	app.Image.Tag = "1.23"
+	assert.NotNil(t, provider.UpdateApplication(app), "should have thrown an error")
+	assert.Equal(t, "running", client.containers["nginx-0"].status, "should have kept the outdated instance")
+	assert.Equal(t, "nginx:latest", client.containers["nginx-0"].labels[imageLabelTag.String()])
+}
+
+func TestProvider_UpdateApplication_unchanged(t *testing.T) {
+	client := NewTestClient()
+	provider := NewTestProvider(t, client)
+	assert.Nil(t, provider.CreateApplication(exampleApplication()))
+
+	assert.Nil(t, provider.UpdateApplication(exampleApplication()))
+	assert.Equal(t, []string{"nginx-0"}, client.creates, "should have kept the instance")
+}
+
+func TestProvider_UpdateApplication_restartStopped(t *testing.T) {
+	client := NewTestClient()
+	provider := NewTestProvider(t, client)
+	assert.Nil(t, provider.CreateApplication(exampleApplication()))
+	client.setStatus("nginx-0", "stopped")
+
+	assert.Nil(t, provider.UpdateApplication(exampleApplication()))
+	assert.Equal(t, []string{"nginx-0"}, client.creates, "should have kept the container")
+	assert.Equal(t, "running", client.containers["nginx-0"].status, "should have started a new task")
+}
+
+func TestProvider_UpdateApplication_scaleDown(t *testing.T) {
+	client := NewTestClient()
+	provider := NewTestProvider(t, client)
+
+	app := exampleApplication()
+	app.Instances = 3
+	app.Ports = nil
+	assert.Nil(t, provider.CreateApplication(app))
+
+	app.Instances = 1
+	assert.Nil(t, provider.UpdateApplication(app))
+	assert.Equal(t, 1, len(client.containers))
+	assert.Contains(t, client.containers, "nginx-0")
+}
+
+func TestProvider_UpdateApplication_notFound(t *testing.T) {
+	provider := NewTestProvider(t, NewTestClient())
+
+	err := provider.UpdateApplication(exampleApplication())
+	assert.ErrorIs(t, err, providers.ErrAppNotFound)
+}
+
+func TestProvider_RemoveApplication(t *testing.T) {
+	client := NewTestClient()
+	provider := NewTestProvider(t, client)
+	assert.Nil(t, provider.CreateApplication(exampleApplication()))
+
+	err := provider.RemoveApplication(exampleApplication())
+	assert.Nil(t, err, "should not have thrown an error")
+	assert.Empty(t, client.containers)
+
+	err = provider.RemoveApplication(exampleApplication())
+	assert.ErrorIs(t, err, providers.ErrAppNotFound)
+}
+
+func TestProvider_ActualState(t *testing.T) {
+	client := NewTestClient()
+	provider := NewTestProvider(t, client)
+
+	dir, _ := files.GetDirectory()
+	assert.Nil(t, os.MkdirAll(filepath.Join(dir, "secrets"), 0700))
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "secrets", "db-password"), []byte("s3cret"), 0600))
+
+	app := exampleApplication()
+	app.Env = map[string]string{"MODE": "production", "DB_PASSWORD": "secret:db-password"}
+	app.Mounts = []resource.Mount{
+		{Type: resource.VolumeMountType, Source: "data", Target: "/data"},
+		{Type: resource.TmpfsMountType, Target: "/tmp"},
+	}
+	app.Resources = &resource.Resources{CPULimit: 0.5, MemoryLimit: 64 << 20, MemoryReservation: 32 << 20, PidsLimit: 100}
+	app.Labels = map[string]string{"team": "web"}
+	app.Metrics = &resource.Metrics{Port: 9113, Path: "/metrics"}
+
+	assert.Nil(t, provider.CreateApplication(app))
+	assert.Nil(t, provider.CreateFeature(&feature.FluentBit{LogLevel: "info", Version: "2.0.0"}))
+
+	// a container without a running task is not an instance
+	other := exampleApplication()
+	other.Name = "stopped"
+	assert.Nil(t, provider.CreateApplication(other))
+	client.setStatus("stopped-0", "stopped")
+
+	spec, err := provider.ActualState()
+	assert.Nil(t, err, "should not have thrown an error")
+	if assert.Equal(t, 2, len(spec.Applications)) {
+		actual := spec.Applications[0]
+		assert.Equal(t, "nginx", actual.Name)
+		assert.Equal(t, 1, actual.Instances)
+		assert.Equal(t, "secret:db-password", actual.Env["DB_PASSWORD"], "should only report the secret reference")
+		assert.Equal(t, app.CalculateHash(), actual.CalculateHash(), "should report the application as created")
+
+		assert.Equal(t, "stopped", spec.Applications[1].Name)
+		assert.Equal(t, 0, spec.Applications[1].Instances)
+		assert.Equal(t, 1, spec.Applications[1].ExitedInstances, "should report the stopped task as exited")
+	}
+
+	if assert.Equal(t, 1, len(spec.Feature)) {
+		fluentBit, ok := spec.Feature[feature.NameFluentBit].(*feature.FluentBit)
+		if assert.True(t, ok, "should have decoded the feature") {
+			assert.Equal(t, "2.0.0", fluentBit.Version)
+		}
+	}
+}
+
+func TestProvider_CreateFeature(t *testing.T) {
+	client := NewTestClient()
+	provider := NewTestProvider(t, client)
+
+	err := provider.CreateFeature(&feature.FluentBit{LogLevel: "info", Version: "2.0.0"})
+	assert.Nil(t, err, "should not have thrown an error")
+
+	container, found := client.containers["gco.io.fluent-bit"]
+	if assert.True(t, found, "should have created the container") {
+		assert.Equal(t, "running", container.status)
+		assert.NotEmpty(t, container.ports, "should have published the ports")
+
+		ic := fromContainerInfo(containerInfo{labels: container.labels})
+		if assert.Equal(t, 1, len(ic.configFiles), "should have written the config file") {
+			assert.FileExists(t, ic.configFiles[0])
+		}
+	}
+}
+
+func TestProvider_CreateFeature_unsupported(t *testing.T) {
+	client := NewTestClient()
+	provider := NewTestProvider(t, client)
+
+	err := provider.CreateFeature(&feature.Ingress{})
+	assert.ErrorIs(t, err, providers.ErrFeatureNotSupported)
+
+	err = provider.CreateFeature(&feature.Monitoring{Prometheus: true})
+	assert.ErrorIs(t, err, providers.ErrFeatureNotSupported)
+	assert.Empty(t, client.containers)
+	assert.False(t, provider.SupportsFeature(feature.NameIngress))
+	assert.False(t, provider.SupportsFeature(feature.NameMonitoring))
+	assert.True(t, provider.SupportsFeature(feature.NameFluentBit))
+}
+
+func TestProvider_CreateFeature_startError(t *testing.T) {
+	client := NewTestClient()
+	provider := NewTestProvider(t, client)
+	client.startErr = errors.New("test error")
+
+	err := provider.CreateFeature(&feature.FluentBit{LogLevel: "info", Version: "2.0.0"})
+	assert.NotNil(t, err, "should have thrown an error")
+	assert.Empty(t, client.containers)
+
+	dir, _ := files.GetDirectory()
+	written, _ := filepath.Glob(filepath.Join(dir, "*.conf"))
+	assert.Empty(t, written, "should have removed the config files")
+}
+
+func TestProvider_RemoveFeature(t *testing.T) {
+	client := NewTestClient()
+	provider := NewTestProvider(t, client)
+	assert.Nil(t, provider.CreateFeature(&feature.FluentBit{LogLevel: "info", Version: "2.0.0"}))
+
+	ic := fromContainerInfo(containerInfo{labels: client.containers["gco.io.fluent-bit"].labels})
+
+	err := provider.RemoveFeature(&feature.FluentBit{})
+	assert.Nil(t, err, "should not have thrown an error")
+	assert.Empty(t, client.containers)
+	for _, location := range ic.configFiles {
+		assert.NoFileExists(t, location)
+	}
+
+	err = provider.RemoveFeature(&feature.FluentBit{})
+	assert.ErrorIs(t, err, providers.ErrFeatureNotFound)
+}
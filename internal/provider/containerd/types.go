@@ -0,0 +1,475 @@
+package containerd
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mbaitar/gco/agent/internal/files"
+	"github.com/mbaitar/gco/agent/internal/provider"
+	"github.com/mbaitar/gco/agent/pkg/resource"
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+)
+
+// cpuPeriod is the CFS period used to translate the cpu limit into a quota.
+const cpuPeriod = uint64(100000)
+
+// volumesDirectory is the directory within the data directory containing the named volumes.
+const volumesDirectory = "volumes"
+
+// containerInfo describes a container as reported by containerd, the status is empty when the container has no task.
+type containerInfo struct {
+	id     string
+	labels map[string]string
+	spec   *specs.Spec
+	status string
+}
+
+// internalContainer describes the internal structure on how the containerd provider handles container data.
+type internalContainer struct {
+	id          string
+	image       string
+	args        []string
+	user        string
+	labels      map[string]string
+	ports       []resource.Port
+	mounts      []resource.Mount
+	configFiles []string
+	env         map[string]string
+	resources   *resource.Resources
+	pullAlways  bool
+	status      string
+}
+
+func fromApplicationResource(app *resource.Application) *internalContainer {
+	ic := &internalContainer{
+		id:         app.Name,
+		image:      fmt.Sprintf("%s:%s", app.Image.Name, app.Image.Tag),
+		labels:     make(map[string]string),
+		ports:      app.Ports,
+		mounts:     app.Mounts,
+		resources:  app.Resources,
+		pullAlways: strings.ToLower(app.Image.PullPolicy) == "always",
+	}
+
+	ic.addLabel(provider.ManagedByLabel())
+	ic.addLabel(provider.KindLabel(resource.ApplicationKind))
+	ic.addLabel(provider.NameLabel(app.Name))
+	ic.addLabel(imageLabel(ic.image))
+
+	if len(app.Ports) > 0 {
+		encoded, _ := json.Marshal(app.Ports)
+		ic.addLabel(portsLabel(string(encoded)))
+	}
+
+	if len(app.Mounts) > 0 {
+		encoded, _ := json.Marshal(app.Mounts)
+		ic.addLabel(mountsLabel(string(encoded)))
+	}
+
+	// keep track of the managed environment variables and secret references
+	if len(app.Env) > 0 {
+		ic.env = make(map[string]string, len(app.Env))
+		keys := make([]string, 0, len(app.Env))
+		for key, value := range app.Env {
+			ic.env[key] = value
+			keys = append(keys, key)
+
+			if resource.IsSecretReference(value) {
+				ic.addLabel(provider.SecretLabel(key, value))
+			}
+		}
+
+		sort.Strings(keys)
+		ic.addLabel(provider.EnvLabel(keys))
+	}
+
+	// keep track of the additional labels, labels using the platform prefix are rejected by provider.ValidateLabels
+	if len(app.Labels) > 0 {
+		keys := make([]string, 0, len(app.Labels))
+		for key, value := range app.Labels {
+			ic.labels[key] = value
+			keys = append(keys, key)
+		}
+
+		sort.Strings(keys)
+		ic.addLabel(provider.LabelsLabel(keys))
+	}
+
+	if app.Ingress != nil {
+		encoded, _ := json.Marshal(app.Ingress)
+		ic.addLabel(provider.IngressLabel(string(encoded)))
+	}
+
+	if app.Metrics != nil {
+		encoded, _ := json.Marshal(app.Metrics)
+		ic.addLabel(provider.MetricsLabel(string(encoded)))
+	}
+
+	return ic
+}
+
+// fromContainerInfo creates the container definition using the labels and the OCI spec of the container.
+func fromContainerInfo(info containerInfo) internalContainer {
+	ic := internalContainer{
+		id:     info.id,
+		labels: info.labels,
+		status: info.status,
+	}
+
+	if ic.labels == nil {
+		ic.labels = make(map[string]string)
+	}
+
+	ic.image = ic.getLabel(imageLabelTag)
+
+	if encoded := ic.getLabel(portsLabelTag); encoded != "" {
+		_ = json.Unmarshal([]byte(encoded), &ic.ports)
+	}
+
+	if encoded := ic.getLabel(mountsLabelTag); encoded != "" {
+		_ = json.Unmarshal([]byte(encoded), &ic.mounts)
+	}
+
+	if locations := ic.getLabel(configFilesLabelTag); locations != "" {
+		ic.configFiles = strings.Split(locations, ",")
+	}
+
+	if info.spec == nil {
+		return ic
+	}
+
+	// only include the environment variables managed by the agent, secrets are replaced by their reference
+	if managedEnv := ic.getLabel(provider.EnvLabelTag); managedEnv != "" && info.spec.Process != nil {
+		ic.env = make(map[string]string)
+		values := parseEnv(info.spec.Process.Env)
+		for _, key := range strings.Split(managedEnv, ",") {
+			if reference := ic.getLabel(provider.SecretLabelTag(key)); reference != "" {
+				ic.env[key] = reference
+			} else {
+				ic.env[key] = values[key]
+			}
+		}
+	}
+
+	if info.spec.Linux != nil && info.spec.Linux.Resources != nil {
+		ic.resources = fromLinuxResources(info.spec.Linux.Resources)
+	}
+
+	return ic
+}
+
+// fromLinuxResources returns the limits of the OCI spec, nil is returned when no limits have been set.
+func fromLinuxResources(linux *specs.LinuxResources) *resource.Resources {
+	resources := &resource.Resources{}
+	if linux.CPU != nil && linux.CPU.Quota != nil && linux.CPU.Period != nil && *linux.CPU.Quota > 0 && *linux.CPU.Period > 0 {
+		resources.CPULimit = float64(*linux.CPU.Quota) / float64(*linux.CPU.Period)
+	}
+
+	if linux.Memory != nil {
+		if linux.Memory.Limit != nil && *linux.Memory.Limit > 0 {
+			resources.MemoryLimit = *linux.Memory.Limit
+		}
+
+		if linux.Memory.Reservation != nil && *linux.Memory.Reservation > 0 {
+			resources.MemoryReservation = *linux.Memory.Reservation
+		}
+	}
+
+	if linux.Pids != nil && linux.Pids.Limit > 0 {
+		resources.PidsLimit = linux.Pids.Limit
+	}
+
+	if resources.IsEmpty() {
+		return nil
+	}
+
+	return resources
+}
+
+// applySpec applies the container definition to the OCI spec generated from the image configuration.
+func (i *internalContainer) applySpec(spec *specs.Spec) error {
+	env, err := i.resolveEnv()
+	if err != nil {
+		return err
+	}
+
+	if spec.Process == nil {
+		spec.Process = &specs.Process{}
+	}
+	spec.Process.Env = replaceEnv(spec.Process.Env, env)
+
+	mounts, err := i.specMounts()
+	if err != nil {
+		return err
+	}
+	spec.Mounts = append(spec.Mounts, mounts...)
+
+	if i.resources.IsEmpty() {
+		return nil
+	}
+
+	if spec.Linux == nil {
+		spec.Linux = &specs.Linux{}
+	}
+
+	if spec.Linux.Resources == nil {
+		spec.Linux.Resources = &specs.LinuxResources{}
+	}
+
+	linux := spec.Linux.Resources
+	if i.resources.CPULimit > 0 {
+		quota := int64(math.Round(i.resources.CPULimit * float64(cpuPeriod)))
+		period := cpuPeriod
+		linux.CPU = &specs.LinuxCPU{Quota: &quota, Period: &period}
+	}
+
+	if i.resources.MemoryLimit > 0 || i.resources.MemoryReservation > 0 {
+		linux.Memory = &specs.LinuxMemory{}
+		if i.resources.MemoryLimit > 0 {
+			limit := i.resources.MemoryLimit
+			linux.Memory.Limit = &limit
+		}
+
+		if i.resources.MemoryReservation > 0 {
+			reservation := i.resources.MemoryReservation
+			linux.Memory.Reservation = &reservation
+		}
+	}
+
+	if i.resources.PidsLimit > 0 {
+		linux.Pids = &specs.LinuxPids{Limit: i.resources.PidsLimit}
+	}
+
+	return nil
+}
+
+// specMounts translates the mounts, named volumes are directories within the data directory as containerd does
+// not manage volumes.
+func (i *internalContainer) specMounts() ([]specs.Mount, error) {
+	mounts := make([]specs.Mount, 0, len(i.mounts))
+	for _, m := range i.mounts {
+		access := "rw"
+		if m.ReadOnly {
+			access = "ro"
+		}
+
+		switch m.Type {
+		case resource.TmpfsMountType:
+			mounts = append(mounts, specs.Mount{Destination: m.Target, Type: "tmpfs", Source: "tmpfs", Options: []string{"nosuid", "nodev", access}})
+		case resource.VolumeMountType:
+			source, err := volumePath(m.Source)
+			if err != nil {
+				return nil, err
+			}
+
+			mounts = append(mounts, specs.Mount{Destination: m.Target, Type: "bind", Source: source, Options: []string{"rbind", access}})
+		default:
+			mounts = append(mounts, specs.Mount{Destination: m.Target, Type: "bind", Source: m.Source, Options: []string{"rbind", access}})
+		}
+	}
+
+	return mounts, nil
+}
+
+// volumePath returns the directory of the named volume within the data directory.
+func volumePath(name string) (string, error) {
+	dir, err := files.GetDirectory()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, volumesDirectory, name), nil
+}
+
+// createVolumes creates the directories of the named volumes which do not exist yet.
+func (i *internalContainer) createVolumes() error {
+	for _, m := range i.mounts {
+		if m.Type != resource.VolumeMountType {
+			continue
+		}
+
+		path, err := volumePath(m.Source)
+		if err != nil {
+			return err
+		}
+
+		if err := os.MkdirAll(path, 0755); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// resolveEnv returns the environment variables in the OCI format with the referenced secrets resolved.
+func (i *internalContainer) resolveEnv() ([]string, error) {
+	keys := make([]string, 0, len(i.env))
+	for key := range i.env {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	env := make([]string, 0, len(keys))
+	for _, key := range keys {
+		value := i.env[key]
+		if resource.IsSecretReference(value) {
+			secret, err := files.ReadSecret(resource.SecretName(value))
+			if err != nil {
+				return nil, err
+			}
+
+			value = secret
+		}
+
+		env = append(env, fmt.Sprintf("%s=%s", key, value))
+	}
+
+	return env, nil
+}
+
+// replaceEnv adds the environment variables, replacing the variables of the image with the same key.
+func replaceEnv(current []string, env []string) []string {
+	replaced := make([]string, 0, len(current)+len(env))
+	values := parseEnv(env)
+	for _, entry := range current {
+		key := strings.SplitN(entry, "=", 2)[0]
+		if _, found := values[key]; !found {
+			replaced = append(replaced, entry)
+		}
+	}
+
+	return append(replaced, env...)
+}
+
+// parseEnv parses the environment variables from the OCI format.
+func parseEnv(env []string) map[string]string {
+	values := make(map[string]string, len(env))
+	for _, entry := range env {
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) == 2 {
+			values[parts[0]] = parts[1]
+		} else {
+			values[parts[0]] = ""
+		}
+	}
+
+	return values
+}
+
+func (i *internalContainer) addLabel(label provider.Label) {
+	i.labels[label.Tag.String()] = label.Value
+}
+
+func (i *internalContainer) getLabel(tag provider.LabelTag) string {
+	return i.labels[tag.String()]
+}
+
+// getInstance returns the instance ordinal of the container, containers without an instance label are the first instance.
+func (i *internalContainer) getInstance() int {
+	ordinal, err := strconv.Atoi(i.getLabel(provider.InstanceLabelTag))
+	if err != nil {
+		return 0
+	}
+
+	return ordinal
+}
+
+// withInstance returns a copy of the container definition for the instance with the given ordinal.
+func (i *internalContainer) withInstance(ordinal int) *internalContainer {
+	instance := *i
+	instance.id = instanceName(i.id, ordinal)
+	instance.labels = make(map[string]string, len(i.labels)+1)
+	for key, value := range i.labels {
+		instance.labels[key] = value
+	}
+
+	instance.addLabel(provider.InstanceLabel(ordinal))
+	return &instance
+}
+
+// instanceName returns the container id used for the instance of an application.
+func instanceName(name string, ordinal int) string {
+	return fmt.Sprintf("%s-%d", name, ordinal)
+}
+
+// isRunning returns true when the task of the container is running.
+func (i *internalContainer) isRunning() bool {
+	return i.status == "running"
+}
+
+// isExited returns true when the task of the container stopped, containerd does not restart stopped tasks.
+func (i *internalContainer) isExited() bool {
+	return i.status == "stopped"
+}
+
+// getImageResource splits the image reference into name and tag, the name may contain a registry port.
+func (i *internalContainer) getImageResource() resource.Image {
+	idx := strings.LastIndex(i.image, ":")
+	if idx < 0 || strings.Contains(i.image[idx:], "/") {
+		return resource.Image{Name: i.image, Tag: "latest"}
+	}
+
+	return resource.Image{Name: i.image[:idx], Tag: i.image[idx+1:]}
+}
+
+// getLabelResources returns the additional labels of the application.
+func (i *internalContainer) getLabelResources() map[string]string {
+	managed := i.getLabel(provider.LabelsLabelTag)
+	if managed == "" {
+		return nil
+	}
+
+	labels := make(map[string]string)
+	for _, key := range strings.Split(managed, ",") {
+		labels[key] = i.labels[key]
+	}
+
+	return labels
+}
+
+func (i *internalContainer) toApplicationResource() resource.Application {
+	instances := 0
+	if i.isRunning() {
+		instances = 1
+	}
+
+	exited := 0
+	if i.isExited() {
+		exited = 1
+	}
+
+	app := resource.Application{
+		Name:      i.getLabel(provider.NameLabelTag),
+		Image:     i.getImageResource(),
+		Ports:     i.ports,
+		Mounts:    i.mounts,
+		Instances: instances,
+		Env:       i.env,
+		Resources: i.resources,
+		Labels:    i.getLabelResources(),
+
+		ExitedInstances: exited,
+	}
+
+	if encoded := i.getLabel(provider.IngressLabelTag); encoded != "" {
+		ingress := &resource.Ingress{}
+		if err := json.Unmarshal([]byte(encoded), ingress); err == nil {
+			app.Ingress = ingress
+		}
+	}
+
+	if encoded := i.getLabel(provider.MetricsLabelTag); encoded != "" {
+		metrics := &resource.Metrics{}
+		if err := json.Unmarshal([]byte(encoded), metrics); err == nil {
+			app.Metrics = metrics
+		}
+	}
+
+	return app
+}
@@ -1,13 +1,34 @@
 package docker
 
 import (
+	"context"
+	"io"
 	"os"
 
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/network"
 	docker "github.com/docker/docker/client"
 	"github.com/mbaitar/gco/agent/internal/log"
+	v1 "github.com/opencontainers/image-spec/specs-go/v1"
 )
 
-func newDockerClient() docker.APIClient {
+// Client defines the calls of the docker API used by the provider, engines offering a docker compatible API only
+// have to implement these calls to be managed by the provider.
+type Client interface {
+	ContainerCreate(ctx context.Context, config *container.Config, hostConfig *container.HostConfig, networkingConfig *network.NetworkingConfig, platform *v1.Platform, containerName string) (container.ContainerCreateCreatedBody, error)
+	ContainerInspect(ctx context.Context, container string) (types.ContainerJSON, error)
+	ContainerList(ctx context.Context, options types.ContainerListOptions) ([]types.Container, error)
+	ContainerRemove(ctx context.Context, container string, options types.ContainerRemoveOptions) error
+	ContainerRename(ctx context.Context, container, newContainerName string) error
+	ContainerStart(ctx context.Context, container string, options types.ContainerStartOptions) error
+	Events(ctx context.Context, options types.EventsOptions) (<-chan events.Message, <-chan error)
+	ImageList(ctx context.Context, options types.ImageListOptions) ([]types.ImageSummary, error)
+	ImagePull(ctx context.Context, ref string, options types.ImagePullOptions) (io.ReadCloser, error)
+}
+
+func newDockerClient() Client {
 	//client, err := docker.NewClientWithOpts(docker.FromEnv, docker.WithAPIVersionNegotiation())
 	//if err != nil {
 	//	log.Errorf("Unable to create new docker client: %v", err)
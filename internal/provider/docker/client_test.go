@@ -5,6 +5,7 @@ import (
 	"context"
 	opts "github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/events"
 	"github.com/docker/docker/api/types/network"
 	"github.com/docker/docker/client"
 	v1 "github.com/opencontainers/image-spec/specs-go/v1"
@@ -40,6 +41,15 @@ type TestClient struct {
 	// ImagePull
 	imagePullArgs      [][]any
 	imagePullReturnErr error
+
+	// ContainerRename
+	containerRenameArgs   [][]any
+	containerRenameReturn error
+
+	// Events
+	eventsArgs     [][]any
+	eventsMessages chan events.Message
+	eventsErrs     chan error
 }
 
 func NewTestClient() *TestClient {
@@ -60,6 +70,13 @@ func NewTestClient() *TestClient {
 
 		imagePullArgs:      make([][]any, 0),
 		imagePullReturnErr: nil,
+
+		containerRenameArgs:   make([][]any, 0),
+		containerRenameReturn: nil,
+
+		eventsArgs:     make([][]any, 0),
+		eventsMessages: make(chan events.Message),
+		eventsErrs:     make(chan error),
 	}
 }
 
@@ -135,3 +152,22 @@ func (t *TestClient) ImageList(ctx context.Context, options opts.ImageListOption
 	summary := make([]opts.ImageSummary, 0)
 	return summary, nil
 }
+
+func (t *TestClient) ContainerRename(ctx context.Context, container string, newContainerName string) error {
+	args := make([]any, 3)
+	args[0] = ctx
+	args[1] = container
+	args[2] = newContainerName
+	t.containerRenameArgs = append(t.containerRenameArgs, args)
+
+	return t.containerRenameReturn
+}
+
+func (t *TestClient) Events(ctx context.Context, options opts.EventsOptions) (<-chan events.Message, <-chan error) {
+	args := make([]any, 2)
+	args[0] = ctx
+	args[1] = options
+	t.eventsArgs = append(t.eventsArgs, args)
+
+	return t.eventsMessages, t.eventsErrs
+}
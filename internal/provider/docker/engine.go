@@ -3,11 +3,14 @@ package docker
 import (
 	"context"
 	"io"
+	"time"
 
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/filters"
 	"github.com/mbaitar/gco/agent/internal/files"
 	"github.com/mbaitar/gco/agent/internal/log"
+	"github.com/mbaitar/gco/agent/internal/metrics"
+	"github.com/mbaitar/gco/agent/internal/provider"
 	"github.com/mbaitar/gco/agent/pkg/resource"
 )
 
@@ -21,18 +24,24 @@ func (p *Provider) startContainer(id string) error {
 func (p *Provider) createContainer(c *internalContainer) (string, error) {
 	ctx := context.Background()
 
-	err := p.verifyImage(c.image, c.pullPolicy)
+	env, err := c.resolveEnv()
+	if err != nil {
+		log.Debugf("Unable to resolve environment for container '%s'", c.name)
+		return "", err
+	}
+
+	err = p.verifyImage(c.image, c.pullPolicy)
 	if err != nil {
 		log.Debugf("Unable to pull image '%s' for application '%s'", c.image, c.name)
 		return "", err
 	}
 
 	// set required labels
-	c.addLabel(managedByLabel())
+	c.addLabel(provider.ManagedByLabel())
 
 	// set name label if not given
-	if c.getLabel(nameLabelTag) == "" {
-		c.addLabel(nameLabel(c.name))
+	if c.getLabel(provider.NameLabelTag) == "" {
+		c.addLabel(provider.NameLabel(c.name))
 	}
 
 	if p.addComposeLabel {
@@ -40,6 +49,7 @@ func (p *Provider) createContainer(c *internalContainer) (string, error) {
 	}
 
 	config := c.config()
+	config.Env = env
 	hostConfig := c.hostConfig()
 
 	body, err := p.client.ContainerCreate(ctx, config, hostConfig, nil, nil, c.name)
@@ -47,6 +57,7 @@ func (p *Provider) createContainer(c *internalContainer) (string, error) {
 }
 
 // removeContainer removes a container on the system with the referenced id.
+// Only the anonymous volumes of the container are removed, named volumes are kept for the next container.
 func (p *Provider) removeContainer(id string) error {
 	ctx := context.Background()
 	return p.client.ContainerRemove(ctx, id, types.ContainerRemoveOptions{Force: true, RemoveVolumes: true})
@@ -72,7 +83,7 @@ func (p *Provider) getFilteredContainers(opts *types.ContainerListOptions) ([]in
 		opts.Filters = filters.NewArgs()
 	}
 
-	opts.Filters.Add("label", managedByLabel().string())
+	opts.Filters.Add("label", provider.ManagedByLabel().String())
 	containers, err := p.client.ContainerList(ctx, *opts)
 	if err != nil {
 		return nil, err
@@ -95,11 +106,7 @@ func (p *Provider) getFilteredContainers(opts *types.ContainerListOptions) ([]in
 
 // getContainerByName searched for a container with a matching name label.
 func (p *Provider) getContainerByName(name string) (*internalContainer, error) {
-	opts := &types.ContainerListOptions{All: true}
-	opts.Filters = filters.NewArgs()
-	opts.Filters.Add("label", nameLabel(name).string())
-
-	containers, err := p.getFilteredContainers(opts)
+	containers, err := p.getContainersByName(name)
 	if err != nil {
 		return nil, err
 	}
@@ -111,22 +118,22 @@ func (p *Provider) getContainerByName(name string) (*internalContainer, error) {
 	}
 }
 
-// getFeatureByName searches for a feature with the matching name using the feature label.
-func (p *Provider) getFeatureByName(name string) (*internalContainer, error) {
+// getContainersByName searches for all the containers (instances) with a matching name label.
+func (p *Provider) getContainersByName(name string) ([]internalContainer, error) {
 	opts := &types.ContainerListOptions{All: true}
 	opts.Filters = filters.NewArgs()
-	opts.Filters.Add("label", featureLabel(name).string())
+	opts.Filters.Add("label", provider.NameLabel(name).String())
 
-	containers, err := p.getFilteredContainers(opts)
-	if err != nil {
-		return nil, err
-	}
+	return p.getFilteredContainers(opts)
+}
 
-	if len(containers) == 0 {
-		return nil, nil
-	} else {
-		return &containers[0], nil
-	}
+// getFeatureContainersByName searches for the containers of a feature with the matching name using the feature label.
+func (p *Provider) getFeatureContainersByName(name string) ([]internalContainer, error) {
+	opts := &types.ContainerListOptions{All: true}
+	opts.Filters = filters.NewArgs()
+	opts.Filters.Add("label", provider.FeatureLabel(name).String())
+
+	return p.getFilteredContainers(opts)
 }
 
 // verifyImage verify if the image adheres to the requested imagePullPolicy.
@@ -152,6 +159,19 @@ func (p *Provider) verifyImage(image string, policy imagePullPolicy) error {
 	}
 
 	log.Debugf("Pulling image '%s' (policy=%s)", image, policy)
+	start := time.Now()
+	err := p.pullImage(ctx, image)
+	metrics.ObserveDuration(metrics.ImagePullDuration, start, err)
+	if err != nil {
+		return err
+	}
+
+	log.Debugf("Successfully pulled image '%s' in %s", image, time.Since(start))
+	return nil
+}
+
+// pullImage pulls the image and waits until the pull has been completed.
+func (p *Provider) pullImage(ctx context.Context, image string) error {
 	reader, err := p.client.ImagePull(ctx, image, types.ImagePullOptions{})
 	if reader != nil {
 		defer reader.Close()
@@ -161,12 +181,7 @@ func (p *Provider) verifyImage(image string, policy imagePullPolicy) error {
 	}
 
 	_, err = io.ReadAll(reader)
-	if err != nil {
-		return err
-	}
-
-	log.Debugf("Successfully pulled image '%s'", image)
-	return nil
+	return err
 }
 
 // getApplicationContainers searches for all containers which are known to be applications.
@@ -175,7 +190,7 @@ func (p *Provider) getApplicationContainers() ([]internalContainer, error) {
 	opts.Filters = filters.NewArgs()
 
 	// append kind label filter
-	opts.Filters.Add("label", kindLabel(resource.ApplicationKind).string())
+	opts.Filters.Add("label", provider.KindLabel(resource.ApplicationKind).String())
 	return p.getFilteredContainers(opts)
 }
 
@@ -185,6 +200,6 @@ func (p *Provider) getFeatureContainers() ([]internalContainer, error) {
 	opts.Filters = filters.NewArgs()
 
 	// append kind label filter
-	opts.Filters.Add("label", kindLabel(resource.FeatureKind).string())
+	opts.Filters.Add("label", provider.KindLabel(resource.FeatureKind).String())
 	return p.getFilteredContainers(opts)
 }
@@ -8,6 +8,7 @@ import (
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/go-connections/nat"
+	providers "github.com/mbaitar/gco/agent/internal/provider"
 	"github.com/mbaitar/gco/agent/pkg/resource"
 	"github.com/stretchr/testify/assert"
 )
@@ -93,8 +94,8 @@ func TestProvider_createContainer(t *testing.T) {
 			assert.Equal(t, con.labels, config.Labels)
 
 			// verify that platform labels are present
-			assert.Equal(t, "gco", con.labels[managedByLabelTag.string()])
-			assert.Equal(t, "my-container", con.labels[nameLabelTag.string()])
+			assert.Equal(t, "gco", con.labels[providers.ManagedByLabelTag.String()])
+			assert.Equal(t, "my-container", con.labels[providers.NameLabelTag.String()])
 		}
 
 		if assert.NotNil(t, hostConfig, "should have included container host config") {
@@ -185,7 +186,7 @@ func TestProvider_getFilteredContainers(t *testing.T) {
 			if assert.NotNil(t, opts.Filters, "should include filters") {
 				labels := opts.Filters.Get("label")
 				if assert.Equal(t, 1, len(labels), "should include 1 label") {
-					assert.Equal(t, managedByLabel().string(), labels[0])
+					assert.Equal(t, providers.ManagedByLabel().String(), labels[0])
 				}
 			}
 		}
@@ -226,11 +227,11 @@ func TestProvider_getContainerByName(t *testing.T) {
 		labels := opts.Filters.Get("label")
 
 		for _, l := range labels {
-			if l == managedByLabel().string() {
+			if l == providers.ManagedByLabel().String() {
 				hasManagedByLabel = true
 			}
 
-			if l == nameLabel(dc.Names[0]).string() {
+			if l == providers.NameLabel(dc.Names[0]).String() {
 				hasNameLabel = true
 			}
 		}
@@ -287,12 +288,12 @@ func TestProvider_getApplicationContainers(t *testing.T) {
 
 			managedFound, kindFound := false, false
 			for _, value := range labels {
-				if value == managedByLabel().string() {
+				if value == providers.ManagedByLabel().String() {
 					managedFound = true
 					continue
 				}
 
-				if value == kindLabel(resource.ApplicationKind).string() {
+				if value == providers.KindLabel(resource.ApplicationKind).String() {
 					kindFound = true
 					continue
 				}
@@ -0,0 +1,106 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/mbaitar/gco/agent/internal/log"
+	"github.com/mbaitar/gco/agent/internal/provider"
+)
+
+const (
+	// defaultEventDebounce is the time to wait after the first event before rebuilding the actual state.
+	defaultEventDebounce = 500 * time.Millisecond
+	// defaultEventReconnectDelay is the time to wait before reconnecting to the event stream after an error.
+	defaultEventReconnectDelay = 5 * time.Second
+)
+
+// observedEventActions lists the container actions which could result in a change of the actual state.
+var observedEventActions = []string{"start", "die", "destroy", "health_status"}
+
+var errEventStreamClosed = errors.New("docker event stream has been closed")
+
+// Watch subscribes to the docker event stream and calls the handler with the latest actual state
+// whenever a managed container changed. It will reconnect when the stream fails and blocks until
+// the 'exit' channel has been closed.
+func (p *Provider) Watch(exit <-chan struct{}, handler provider.ObserveHandler) {
+	if !p.observeEvents {
+		log.Debug("Docker event observer has not been enabled")
+		return
+	}
+
+	log.Info("Started observing docker events")
+	for {
+		err := p.watchEvents(exit, handler)
+		if err == nil {
+			log.Debug("Stopped observing docker events")
+			return
+		}
+
+		log.Warnf("Docker event stream failed, reconnecting in %s: %v", p.eventReconnectDelay, err)
+		select {
+		case <-exit:
+			return
+		case <-time.After(p.eventReconnectDelay):
+		}
+	}
+}
+
+// watchEvents handles a single subscription to the docker event stream.
+// It returns nil when the 'exit' channel has been closed or the error which ended the subscription.
+func (p *Provider) watchEvents(exit <-chan struct{}, handler provider.ObserveHandler) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	opts := types.EventsOptions{Filters: filters.NewArgs()}
+	opts.Filters.Add("type", events.ContainerEventType)
+	opts.Filters.Add("label", provider.ManagedByLabel().String())
+	for _, action := range observedEventActions {
+		opts.Filters.Add("event", action)
+	}
+
+	messages, errs := p.client.Events(ctx, opts)
+
+	// debounce bursts of events into a single observation
+	var debounce <-chan time.Time
+
+	for {
+		select {
+		case <-exit:
+			return nil
+		case err, ok := <-errs:
+			if !ok || err == nil {
+				return errEventStreamClosed
+			}
+
+			return err
+		case msg, ok := <-messages:
+			if !ok {
+				return errEventStreamClosed
+			}
+
+			log.Debugf("Received docker event (action=%s, container=%s)", msg.Action, msg.Actor.Attributes[provider.NameLabelTag.String()])
+			if debounce == nil {
+				debounce = time.After(p.eventDebounce)
+			}
+		case <-debounce:
+			debounce = nil
+			p.observe(handler)
+		}
+	}
+}
+
+// observe rebuilds the actual state and passes it to the handler.
+func (p *Provider) observe(handler provider.ObserveHandler) {
+	actual, err := p.ActualState()
+	if err != nil {
+		log.Warnf("Unable to rebuild actual state after docker event: %v", err)
+		return
+	}
+
+	handler(*actual)
+}
@@ -0,0 +1,92 @@
+package docker
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/events"
+	"github.com/mbaitar/gco/agent/internal/state"
+	"github.com/stretchr/testify/assert"
+)
+
+func NewTestEventProvider(client *TestClient) *Provider {
+	return &Provider{
+		client:              client,
+		observeEvents:       true,
+		eventDebounce:       time.Millisecond * 10,
+		eventReconnectDelay: time.Millisecond * 10,
+	}
+}
+
+func TestProvider_Watch_disabled(t *testing.T) {
+	client := NewTestClient()
+	provider := &Provider{client: client}
+
+	exit := make(chan struct{})
+	provider.Watch(exit, func(spec state.Spec) {})
+
+	assert.Equal(t, 0, len(client.eventsArgs), "should not have subscribed to the event stream")
+}
+
+func TestProvider_Watch_debounceEvents(t *testing.T) {
+	client := NewTestClient()
+	provider := NewTestEventProvider(client)
+
+	observed := make(chan state.Spec, 10)
+	exit := make(chan struct{})
+	done := make(chan struct{})
+
+	go func() {
+		provider.Watch(exit, func(spec state.Spec) {
+			observed <- spec
+		})
+		close(done)
+	}()
+
+	// burst of events should result in a single observation
+	for i := 0; i < 3; i++ {
+		client.eventsMessages <- events.Message{Type: events.ContainerEventType, Action: "die"}
+	}
+
+	select {
+	case spec := <-observed:
+		assert.NotNil(t, spec.Applications, "should have rebuilt the actual state")
+	case <-time.After(time.Second):
+		t.Fatalf("timeout reached before observing the actual state")
+	}
+
+	close(exit)
+	<-done
+
+	assert.Equal(t, 0, len(observed), "should have debounced the remaining events")
+	if assert.Equal(t, 1, len(client.eventsArgs), "should have subscribed once") {
+		opts := client.eventsArgs[0][1].(types.EventsOptions)
+		ShouldIncludeLabel(t, "gco.io/managed-by=gco", opts.Filters.Get("label"))
+		ShouldIncludeLabel(t, events.ContainerEventType, opts.Filters.Get("type"))
+	}
+}
+
+func TestProvider_Watch_reconnectOnError(t *testing.T) {
+	client := NewTestClient()
+	provider := NewTestEventProvider(client)
+
+	exit := make(chan struct{})
+	done := make(chan struct{})
+
+	go func() {
+		provider.Watch(exit, func(spec state.Spec) {})
+		close(done)
+	}()
+
+	client.eventsErrs <- errors.New("test error")
+
+	// second subscription should receive this event
+	client.eventsMessages <- events.Message{Type: events.ContainerEventType, Action: "start"}
+
+	close(exit)
+	<-done
+
+	assert.Equal(t, 2, len(client.eventsArgs), "should have reconnected to the event stream")
+}
@@ -3,42 +3,96 @@ package docker
 import (
 	"fmt"
 
+	"github.com/docker/docker/api/types/mount"
 	"github.com/mbaitar/gco/agent/internal/files"
+	"github.com/mbaitar/gco/agent/internal/provider"
 	"github.com/mbaitar/gco/agent/pkg/feature"
 	"github.com/mbaitar/gco/agent/pkg/resource"
 )
 
-// createFluentBitContainer creates an internal container definition based on the feature.FluentBit configuration.
-func (p *Provider) createFluentBitContainer(fb *feature.FluentBit) (*internalContainer, error) {
-	version := "latest"
-	if fb.Version != "" {
-		version = fb.Version
+// providerName is the name used to look up docker specific feature materializers.
+const providerName = "docker"
+
+// SupportsFeature returns true when a materializer of the feature has been registered for the provider.
+func (p *Provider) SupportsFeature(name string) bool {
+	return feature.SupportedBy(name, p.materializerName)
+}
+
+// createFeatureContainers creates the internal container definitions using the workloads of the registered feature.
+func (p *Provider) createFeatureContainers(feat feature.Feature) ([]*internalContainer, error) {
+	def, found := feature.Lookup(feat.Name())
+	if !found {
+		return nil, provider.ErrFeatureNotSupported
+	}
+
+	materialize := def.MaterializerFor(p.materializerName)
+	if materialize == nil {
+		return nil, provider.ErrFeatureNotSupported
 	}
 
-	// create volume binding
-	config := fb.CreateConfig()
-	configLocation, err := files.WriteConfigFileFromString(config, "fluent-bit.conf")
+	workloads, err := materialize(feat)
 	if err != nil {
 		return nil, err
 	}
 
+	containers := make([]*internalContainer, 0, len(workloads))
+	for _, workload := range workloads {
+		ic, err := createWorkloadContainer(feat, workload)
+		if err != nil {
+			return nil, err
+		}
+
+		containers = append(containers, ic)
+	}
+
+	return containers, nil
+}
+
+// createWorkloadContainer creates an internal container definition for a single workload of the feature.
+func createWorkloadContainer(feat feature.Feature, workload feature.Workload) (*internalContainer, error) {
+	name := fmt.Sprintf("%s.%s", provider.LabelPrefix, feat.Name())
+	if workload.Name != "" {
+		name = fmt.Sprintf("%s.%s", name, workload.Name)
+	}
+
 	ic := &internalContainer{
-		name:   fmt.Sprintf("%s.%s", labelPrefix, fb.Name()),
-		labels: make(map[string]string),
-		image:  fmt.Sprintf("cr.fluentbit.io/fluent/fluent-bit:%s", version),
-		ports: []containerPort{
-			newContainerPort(24224, 24224, "tcp"),
-		},
-		volumes: []volumeMount{
-			{destination: "/fluent-bit/etc/fluent-bit.conf", source: configLocation, readonly: true},
-		},
+		name:       name,
+		labels:     make(map[string]string),
+		image:      workload.Image,
+		cmd:        workload.Command,
+		user:       workload.User,
+		ports:      make([]containerPort, len(workload.Ports)),
+		env:        workload.Env,
 		pullPolicy: whenNotPresentPolicy,
 	}
 
+	for i, port := range workload.Ports {
+		ic.ports[i] = newContainerPort(port.ContainerPort, port.HostPort, string(port.Protocol))
+	}
+
+	for _, m := range workload.Mounts {
+		ic.mounts = append(ic.mounts, mount.Mount{
+			Type:     mount.Type(m.Type),
+			Source:   m.Source,
+			Target:   m.Target,
+			ReadOnly: m.ReadOnly,
+		})
+	}
+
+	// create volume bindings for the generated configuration files, these are removed together with the feature
+	for _, configFile := range workload.ConfigFiles {
+		location, err := files.WriteConfigFileFromString(configFile.Content, configFile.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		ic.volumes = append(ic.volumes, volumeMount{destination: configFile.Target, source: location, readonly: true})
+	}
+
 	// add container labels
-	ic.addLabel(kindLabel(resource.FeatureKind))
-	ic.addLabel(featureLabel(fb.Name()))
-	ic.addLabel(configLabel(feature.EncodeFeature(fb)))
+	ic.addLabel(provider.KindLabel(resource.FeatureKind))
+	ic.addLabel(provider.FeatureLabel(feat.Name()))
+	ic.addLabel(provider.ConfigLabel(feature.EncodeFeature(feat)))
 
 	return ic, nil
 }
@@ -4,6 +4,7 @@ import (
 	"strings"
 	"testing"
 
+	providers "github.com/mbaitar/gco/agent/internal/provider"
 	"github.com/mbaitar/gco/agent/pkg/feature"
 	"github.com/mbaitar/gco/agent/pkg/resource"
 	"github.com/stretchr/testify/assert"
@@ -20,7 +21,7 @@ func (u *UnsupportedFeature) Name() string {
 	return "unsupported"
 }
 
-func TestProvider_createFluentBitContainer(t *testing.T) {
+func TestProvider_createFeatureContainers(t *testing.T) {
 	SetupForTests(t)
 	client := NewTestClient()
 	provider := &Provider{client: client}
@@ -31,12 +32,13 @@ func TestProvider_createFluentBitContainer(t *testing.T) {
 		Version:  "2.0.0",
 	}
 
-	ic, err := provider.createFluentBitContainer(fluentBit)
+	containers, err := provider.createFeatureContainers(fluentBit)
 	assert.Nil(t, err, "should not have thrown an error")
-	if assert.NotNil(t, ic, "should not have returned a nil container") {
+	if assert.Equal(t, 1, len(containers), "should have returned a single container") {
+		ic := containers[0]
 
-		assert.Equal(t, string(resource.FeatureKind), ic.getLabel(kindLabelTag), "should have 'feature' kind label")
-		assert.Equal(t, fluentBit.Name(), ic.getLabel(featureLabelTag), "should have 'fluent-bit' as feature name")
+		assert.Equal(t, string(resource.FeatureKind), ic.getLabel(providers.KindLabelTag), "should have 'feature' kind label")
+		assert.Equal(t, fluentBit.Name(), ic.getLabel(providers.FeatureLabelTag), "should have 'fluent-bit' as feature name")
 		assert.Equal(t, "gco.io.fluent-bit", ic.name, "should have container name 'gco.io.fluent-bit'")
 		assert.Equal(t, "cr.fluentbit.io/fluent/fluent-bit:2.0.0", ic.image)
 
@@ -1,8 +1,10 @@
 package docker
 
 import (
-	docker "github.com/docker/docker/client"
+	"time"
+
 	"github.com/mbaitar/gco/agent/internal/config"
+	"github.com/mbaitar/gco/agent/internal/log"
 	"github.com/mbaitar/gco/agent/internal/provider"
 	"github.com/mbaitar/gco/agent/internal/state"
 	"github.com/mbaitar/gco/agent/pkg/feature"
@@ -12,91 +14,215 @@ import (
 // Provider defines a docker provider which can communicate with the local docker socket.
 type Provider struct {
 	// client represents the Docker SDK client
-	client docker.CommonAPIClient
+	client Client
+	// materializerName is the name used to look up provider specific feature materializers.
+	materializerName string
 	// addComposeLabel adds the docker compose project label.
 	addComposeLabel bool
+	// observeEvents enables observing the docker event stream for changes to the actual state.
+	observeEvents bool
+	// eventDebounce is the time to wait after receiving an event before rebuilding the actual state.
+	eventDebounce time.Duration
+	// eventReconnectDelay is the time to wait before reconnecting to a failed event stream.
+	eventReconnectDelay time.Duration
+	// rolloutTimeout is the maximum time to wait for a replacement container during a rolling update.
+	rolloutTimeout time.Duration
+	// rolloutPollInterval is the time between two readiness checks during a rolling update.
+	rolloutPollInterval time.Duration
 }
 
 func NewDockerProvider() *Provider {
-	client := newDockerClient()
+	return NewDockerProviderWithClient(newDockerClient())
+}
+
+// NewDockerProviderWithClient creates a provider using the given client, which allows managing the containers of
+// engines offering a docker compatible API.
+func NewDockerProviderWithClient(client Client) *Provider {
 	return &Provider{
-		client:          client,
-		addComposeLabel: false,
+		client:              client,
+		materializerName:    providerName,
+		addComposeLabel:     false,
+		observeEvents:       false,
+		eventDebounce:       defaultEventDebounce,
+		eventReconnectDelay: defaultEventReconnectDelay,
+		rolloutTimeout:      defaultRolloutTimeout,
+		rolloutPollInterval: defaultRolloutPollInterval,
 	}
 }
 
+// WithMaterializerName changes the name used to look up the feature materializers, which allows engines to reject
+// features materialized for docker only, e.g. features mounting the docker socket.
+func (p *Provider) WithMaterializerName(name string) *Provider {
+	p.materializerName = name
+	return p
+}
+
 func (p *Provider) WithConfig(conf config.DockerProvider) *Provider {
 	p.addComposeLabel = conf.UseDockerComposeGrouping
+	p.observeEvents = conf.ObserveEvents
 	return p
 }
 
 func (p *Provider) CreateApplication(app *resource.Application) error {
-	container := fromApplicationResource(app)
-
-	// TODO: retrieve configuration hash for this resource?
-
-	id, err := p.createContainer(container)
-	if err != nil {
+	if err := validateInstances(app); err != nil {
 		return err
 	}
 
-	err = p.startContainer(id)
-	if err != nil {
+	if err := provider.ValidateLabels(app); err != nil {
 		return err
 	}
 
-	return nil
+	return p.scaleApplication(app, nil)
 }
 
 func (p *Provider) UpdateApplication(app *resource.Application) error {
-	if err := p.RemoveApplication(app); err != nil {
+	if err := validateInstances(app); err != nil {
+		return err
+	}
+
+	if err := provider.ValidateLabels(app); err != nil {
+		return err
+	}
+
+	containers, err := p.getContainersByName(app.Name)
+	if err != nil {
 		return err
 	}
 
-	return p.CreateApplication(app)
+	if len(containers) == 0 {
+		return provider.ErrAppNotFound
+	}
+
+	return p.scaleApplication(app, containers)
 }
 
 func (p *Provider) RemoveApplication(app *resource.Application) error {
-	container, err := p.getContainerByName(app.Name)
+	containers, err := p.getContainersByName(app.Name)
 	if err != nil {
 		return err
 	}
 
-	if container == nil {
+	if len(containers) == 0 {
 		return provider.ErrAppNotFound
-	} else {
-		return p.removeContainer(container.id)
 	}
+
+	for _, container := range containers {
+		if err = p.removeContainer(container.id); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
-func (p *Provider) CreateFeature(feat feature.Feature) error {
+// scaleApplication makes sure the requested instances of the application are running using the existing containers.
+// Containers which are outdated are recreated, instances which are missing are created and surplus instances are removed.
+func (p *Provider) scaleApplication(app *resource.Application, containers []internalContainer) error {
+	instances := app.GetInstances()
+	desiredHash := app.CalculateHash()
+
+	// remove surplus instances
+	existing := make(map[int]internalContainer)
+	for _, container := range containers {
+		ordinal := container.getInstance()
+		_, duplicate := existing[ordinal]
+		if duplicate || ordinal >= instances || isRolloutContainer(container) {
+			log.Debugf("Removing surplus instance=%d of application=%s", ordinal, app.Name)
+			if err := p.removeContainer(container.id); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		existing[ordinal] = container
+	}
 
-	var container *internalContainer
+	template := fromApplicationResource(app)
+	for ordinal := 0; ordinal < instances; ordinal++ {
+		container, found := existing[ordinal]
+		if found {
+			current := container.toApplicationResource()
+			if current.CalculateHash() == desiredHash && !container.isUnhealthy() {
+				if container.state != "running" {
+					log.Debugf("Starting stopped instance=%d of application=%s", ordinal, app.Name)
+					if err := p.startContainer(container.id); err != nil {
+						return err
+					}
+				}
+
+				continue
+			}
 
-	switch v := feat.(type) {
-	case *feature.FluentBit:
-		{
-			fluentBitContainer, err := p.createFluentBitContainer(v)
-			if err != nil {
+			if err := p.replaceInstance(app, container, template.withInstance(ordinal)); err != nil {
 				return err
 			}
 
-			container = fluentBitContainer
+			continue
+		}
+
+		if err := p.createInstance(template.withInstance(ordinal)); err != nil {
+			return err
 		}
-	default:
-		return provider.ErrFeatureNotSupported
 	}
 
+	return nil
+}
+
+// createInstance creates and starts a new container for an application instance.
+func (p *Provider) createInstance(container *internalContainer) error {
 	id, err := p.createContainer(container)
 	if err != nil {
 		return err
 	}
 
-	err = p.startContainer(id)
+	return p.startContainer(id)
+}
+
+// validateInstances verifies that the application can be run with the requested instances.
+// Multiple instances can not bind the same host port, which is why those are rejected.
+func validateInstances(app *resource.Application) error {
+	if app.GetInstances() <= 1 {
+		return nil
+	}
+
+	if hasHostPorts(app) {
+		return provider.ErrHostPortConflict
+	}
+
+	return nil
+}
+
+func (p *Provider) CreateFeature(feat feature.Feature) error {
+	containers, err := p.createFeatureContainers(feat)
 	if err != nil {
 		return err
 	}
 
+	created := make([]*internalContainer, 0, len(containers))
+	for _, container := range containers {
+		container.id, err = p.createContainer(container)
+		if err == nil {
+			created = append(created, container)
+			err = p.startContainer(container.id)
+		}
+
+		if err != nil {
+			// remove the partially created feature, otherwise it would be reported as enabled
+			for _, c := range created {
+				if removeErr := p.removeContainer(c.id); removeErr != nil {
+					log.Warnf("Unable to remove container of partially created feature=%s: %v", feat.Name(), removeErr)
+				}
+			}
+
+			for _, c := range containers {
+				p.cleanUpBinds(c)
+			}
+
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -109,21 +235,23 @@ func (p *Provider) UpdateFeature(feat feature.Feature) error {
 }
 
 func (p *Provider) RemoveFeature(feat feature.Feature) error {
-	container, err := p.getFeatureByName(feat.Name())
+	containers, err := p.getFeatureContainersByName(feat.Name())
 	if err != nil {
 		return err
 	}
 
-	if container == nil {
+	if len(containers) == 0 {
 		return provider.ErrFeatureNotFound
 	}
 
-	err = p.removeContainer(container.id)
-	if err != nil {
-		return err
-	}
+	for _, container := range containers {
+		err = p.removeContainer(container.id)
+		if err != nil {
+			return err
+		}
 
-	p.cleanUpBinds(container)
+		p.cleanUpBinds(&container)
+	}
 
 	return nil
 }
@@ -136,10 +264,20 @@ func (p *Provider) ActualState() (*state.Spec, error) {
 		return nil, err
 	}
 
+	// group the application instances by name
 	applications := make([]resource.Application, 0)
+	lookup := make(map[string]int)
 	for _, container := range appContainers {
 		app := container.toApplicationResource()
-		applications = append(applications, app)
+
+		if idx, found := lookup[app.Name]; found {
+			applications[idx].Instances += app.Instances
+			applications[idx].UnhealthyInstances += app.UnhealthyInstances
+			applications[idx].ExitedInstances += app.ExitedInstances
+		} else {
+			lookup[app.Name] = len(applications)
+			applications = append(applications, app)
+		}
 	}
 
 	// extract features
@@ -150,17 +288,16 @@ func (p *Provider) ActualState() (*state.Spec, error) {
 
 	features := state.Feature{}
 	for _, container := range featContainers {
-		featureName := container.getLabel(featureLabelTag)
-		configValue := container.getLabel(configLabelTag)
-
-		switch featureName {
-		case feature.NameFluentBit:
-			{
-				features.FluentBit = &feature.FluentBit{}
-				feature.DecodeFeature(configValue, features.FluentBit)
-			}
+		featureName := container.getLabel(provider.FeatureLabelTag)
+		configValue := container.getLabel(provider.ConfigLabelTag)
+
+		def, found := feature.Lookup(featureName)
+		if !found {
+			log.Warnf("Ignoring container of unknown feature=%s", featureName)
+			continue
 		}
 
+		features[def.Name] = feature.DecodeFeature(configValue, def.New())
 	}
 
 	spec := &state.Spec{
@@ -2,11 +2,17 @@ package docker
 
 import (
 	"errors"
+	"fmt"
+	"strconv"
 	"strings"
 	"testing"
 
 	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/strslice"
+	"github.com/docker/go-connections/nat"
 	"github.com/mbaitar/gco/agent/internal/files"
+	providers "github.com/mbaitar/gco/agent/internal/provider"
 	"github.com/mbaitar/gco/agent/pkg/feature"
 	"github.com/mbaitar/gco/agent/pkg/resource"
 	"github.com/stretchr/testify/assert"
@@ -210,12 +216,13 @@ func TestProvider_UpdateApplication_removeError(t *testing.T) {
 	assert.NotNil(t, err, "should have thrown an error")
 
 	assert.Equal(t, 1, len(client.containerListArgs))
-	assert.Equal(t, 1, len(client.containerRemoveArgs))
 
-	// all default create calls
-	assert.Equal(t, 0, len(client.imagePullArgs))
-	assert.Equal(t, 0, len(client.containerCreateArgs))
+	// the replacement has been created before removing the outdated container and is removed again
+	assert.Equal(t, 2, len(client.containerRemoveArgs))
+	assert.Equal(t, 1, len(client.imagePullArgs))
+	assert.Equal(t, 1, len(client.containerCreateArgs))
 	assert.Equal(t, 0, len(client.containerStartArgs))
+	assert.Equal(t, 0, len(client.containerRenameArgs))
 }
 
 func TestProvider_UpdateApplication_getContainerError(t *testing.T) {
@@ -448,6 +455,45 @@ func TestProvider_CreateFeature_startError(t *testing.T) {
 	assert.Equal(t, 1, len(client.containerStartArgs))
 }
 
+func TestProvider_CreateFeature_multipleWorkloads(t *testing.T) {
+	SetupForTests(t)
+	client := NewTestClient()
+	provider := Provider{client: client, materializerName: providerName}
+
+	monitoring := &feature.Monitoring{Prometheus: true}
+
+	err := provider.CreateFeature(monitoring)
+	assert.Nil(t, err, "should not have thrown an error")
+
+	// verify a container has been created for every workload
+	if assert.Equal(t, 3, len(client.containerCreateArgs)) {
+		assert.Equal(t, "gco.io.monitoring.cadvisor", client.containerCreateArgs[0][5])
+		assert.Equal(t, "gco.io.monitoring.node-exporter", client.containerCreateArgs[1][5])
+		assert.Equal(t, "gco.io.monitoring.prometheus", client.containerCreateArgs[2][5])
+
+		config := client.containerCreateArgs[1][1].(*container.Config)
+		assert.Equal(t, strslice.StrSlice{"--path.procfs=/host/proc", "--path.sysfs=/host/sys", "--path.rootfs=/rootfs"}, config.Cmd)
+	}
+	assert.Equal(t, 3, len(client.containerStartArgs))
+}
+
+func TestProvider_CreateFeature_removePartialFeature(t *testing.T) {
+	SetupForTests(t)
+	client := NewTestClient()
+	client.containerCreateReturnId = "test-id"
+	provider := Provider{client: client, materializerName: providerName}
+
+	client.containerStartReturn = errors.New("test error")
+	err := provider.CreateFeature(&feature.Monitoring{})
+	assert.NotNil(t, err, "should have thrown an error upon starting the container")
+
+	// the first container failed to start and should have been removed again
+	assert.Equal(t, 1, len(client.containerCreateArgs))
+	if assert.Equal(t, 1, len(client.containerRemoveArgs), "should have removed the created container") {
+		assert.Equal(t, "test-id", client.containerRemoveArgs[0][1])
+	}
+}
+
 func TestProvider_CreateFeature_pullError(t *testing.T) {
 	SetupForTests(t)
 	client := NewTestClient()
@@ -481,6 +527,7 @@ func TestProvider_CreateFeature_unsupported(t *testing.T) {
 	assert.Equal(t, 0, len(client.imagePullArgs))
 	assert.Equal(t, 0, len(client.containerCreateArgs))
 	assert.Equal(t, 0, len(client.containerStartArgs))
+	assert.False(t, provider.SupportsFeature("unsupported"))
 }
 
 func TestProvider_UpdateFeature(t *testing.T) {
@@ -659,3 +706,173 @@ func TestProvider_removeFeature_removeError(t *testing.T) {
 	assert.Equal(t, 1, len(client.containerListArgs))
 	assert.Equal(t, 1, len(client.containerRemoveArgs))
 }
+
+func exampleInstanceContainerJson(app *resource.Application, ordinal int, status string) types.ContainerJSON {
+	c := exampleDockerContainerJson()
+	c.ID = instanceName(app.Name, ordinal)
+	c.Name = instanceName(app.Name, ordinal)
+	c.State.Status = status
+	c.Config.Image = fmt.Sprintf("%s:%s", app.Image.Name, app.Image.Tag)
+	c.Config.Labels = map[string]string{
+		providers.NameLabelTag.String():     app.Name,
+		providers.InstanceLabelTag.String(): strconv.Itoa(ordinal),
+	}
+	c.HostConfig.PortBindings = nat.PortMap{}
+
+	return c
+}
+
+func TestProvider_CreateApplication_multipleInstances(t *testing.T) {
+	client := NewTestClient()
+	provider := &Provider{client: client}
+
+	app := &resource.Application{
+		Name:      "nginx",
+		Image:     resource.Image{Name: "nginx", Tag: "latest"},
+		Ports:     []resource.Port{{ContainerPort: 80, Protocol: "tcp"}},
+		Instances: 3,
+	}
+
+	err := provider.CreateApplication(app)
+	assert.Nil(t, err, "should not have thrown an error")
+
+	if assert.Equal(t, 3, len(client.containerCreateArgs), "should have created three instances") {
+		for i, args := range client.containerCreateArgs {
+			config := args[1].(*container.Config)
+			assert.Equal(t, instanceName("nginx", i), args[5].(string))
+			assert.Equal(t, strconv.Itoa(i), config.Labels[providers.InstanceLabelTag.String()])
+			assert.Equal(t, "nginx", config.Labels[providers.NameLabelTag.String()])
+		}
+	}
+	assert.Equal(t, 3, len(client.containerStartArgs))
+}
+
+func TestProvider_CreateApplication_hostPortConflict(t *testing.T) {
+	client := NewTestClient()
+	provider := &Provider{client: client}
+
+	app := &resource.Application{
+		Name:      "nginx",
+		Image:     resource.Image{Name: "nginx", Tag: "latest"},
+		Ports:     []resource.Port{{ContainerPort: 80, HostPort: 8080, Protocol: "tcp"}},
+		Instances: 2,
+	}
+
+	err := provider.CreateApplication(app)
+	assert.ErrorIs(t, err, providers.ErrHostPortConflict)
+	assert.Equal(t, 0, len(client.containerCreateArgs), "should not have created any instance")
+}
+
+func TestProvider_UpdateApplication_scaleUp(t *testing.T) {
+	client := NewTestClient()
+	provider := &Provider{client: client}
+
+	app := &resource.Application{
+		Name:      "nginx",
+		Image:     resource.Image{Name: "nginx", Tag: "latest"},
+		Instances: 3,
+	}
+
+	client.containerListReturnContainers = []types.Container{exampleDockerContainer()}
+	client.containerInspectReturn = []types.ContainerJSON{exampleInstanceContainerJson(app, 0, "running")}
+
+	err := provider.UpdateApplication(app)
+	assert.Nil(t, err, "should not have thrown an error")
+
+	assert.Equal(t, 0, len(client.containerRemoveArgs), "should have kept the up to date instance")
+	if assert.Equal(t, 2, len(client.containerCreateArgs), "should only have created the missing instances") {
+		assert.Equal(t, instanceName("nginx", 1), client.containerCreateArgs[0][5].(string))
+		assert.Equal(t, instanceName("nginx", 2), client.containerCreateArgs[1][5].(string))
+	}
+}
+
+func TestProvider_UpdateApplication_scaleDown(t *testing.T) {
+	client := NewTestClient()
+	provider := &Provider{client: client}
+
+	app := &resource.Application{
+		Name:      "nginx",
+		Image:     resource.Image{Name: "nginx", Tag: "latest"},
+		Instances: 1,
+	}
+
+	client.containerListReturnContainers = []types.Container{exampleDockerContainer(), exampleDockerContainer(), exampleDockerContainer()}
+	client.containerInspectReturn = []types.ContainerJSON{
+		exampleInstanceContainerJson(app, 0, "running"),
+		exampleInstanceContainerJson(app, 1, "running"),
+		exampleInstanceContainerJson(app, 2, "running"),
+	}
+
+	err := provider.UpdateApplication(app)
+	assert.Nil(t, err, "should not have thrown an error")
+
+	assert.Equal(t, 0, len(client.containerCreateArgs), "should not have created any instance")
+	if assert.Equal(t, 2, len(client.containerRemoveArgs), "should have removed the surplus instances") {
+		assert.Equal(t, instanceName("nginx", 1), client.containerRemoveArgs[0][1].(string))
+		assert.Equal(t, instanceName("nginx", 2), client.containerRemoveArgs[1][1].(string))
+	}
+}
+
+func TestProvider_UpdateApplication_startStoppedInstance(t *testing.T) {
+	client := NewTestClient()
+	provider := &Provider{client: client}
+
+	app := &resource.Application{
+		Name:  "nginx",
+		Image: resource.Image{Name: "nginx", Tag: "latest"},
+	}
+
+	client.containerListReturnContainers = []types.Container{exampleDockerContainer()}
+	client.containerInspectReturn = []types.ContainerJSON{exampleInstanceContainerJson(app, 0, "exited")}
+
+	err := provider.UpdateApplication(app)
+	assert.Nil(t, err, "should not have thrown an error")
+
+	assert.Equal(t, 0, len(client.containerCreateArgs), "should not have recreated the instance")
+	if assert.Equal(t, 1, len(client.containerStartArgs), "should have started the stopped instance") {
+		assert.Equal(t, instanceName("nginx", 0), client.containerStartArgs[0][1].(string))
+	}
+}
+
+func TestProvider_ActualState_groupsInstances(t *testing.T) {
+	client := NewTestClient()
+	provider := &Provider{client: client}
+
+	app := &resource.Application{
+		Name:  "nginx",
+		Image: resource.Image{Name: "nginx", Tag: "latest"},
+	}
+
+	client.containerListReturnContainers = []types.Container{exampleDockerContainer(), exampleDockerContainer(), exampleDockerContainer()}
+	client.containerInspectReturn = []types.ContainerJSON{
+		exampleInstanceContainerJson(app, 0, "running"),
+		exampleInstanceContainerJson(app, 1, "exited"),
+		exampleInstanceContainerJson(app, 2, "running"),
+		// the test client returns the same containers when listing the features
+		exampleDockerContainerJson(),
+		exampleDockerContainerJson(),
+		exampleDockerContainerJson(),
+	}
+
+	spec, err := provider.ActualState()
+	assert.Nil(t, err, "should not have thrown")
+	if assert.Equal(t, 1, len(spec.Applications), "should have grouped the instances") {
+		assert.Equal(t, "nginx", spec.Applications[0].Name)
+		assert.Equal(t, 2, spec.Applications[0].Instances, "should only count running instances")
+	}
+}
+
+func TestProvider_CreateApplication_reservedLabel(t *testing.T) {
+	client := NewTestClient()
+	provider := &Provider{client: client}
+
+	app := &resource.Application{
+		Name:   "nginx",
+		Image:  resource.Image{Name: "nginx", Tag: "latest"},
+		Labels: map[string]string{"gco.io/name": "other"},
+	}
+
+	err := provider.CreateApplication(app)
+	assert.True(t, errors.Is(err, providers.ErrReservedLabel), "should have rejected the reserved label")
+	assert.Equal(t, 0, len(client.containerCreateArgs), "should not have created a container")
+}
@@ -0,0 +1,183 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/mbaitar/gco/agent/internal/log"
+	"github.com/mbaitar/gco/agent/pkg/resource"
+)
+
+const (
+	// defaultRolloutTimeout is the maximum time to wait for a replacement container to become ready.
+	defaultRolloutTimeout = 60 * time.Second
+	// defaultRolloutPollInterval is the time between two readiness checks of a replacement container.
+	defaultRolloutPollInterval = 1 * time.Second
+
+	// dockerHealthInterval, dockerHealthTimeout and dockerHealthRetries are used by docker for the unset values
+	// of a health check.
+	dockerHealthInterval = 30 * time.Second
+	dockerHealthTimeout  = 30 * time.Second
+	dockerHealthRetries  = 3
+)
+
+// replaceInstance replaces an outdated application instance using the update strategy of the application.
+func (p *Provider) replaceInstance(app *resource.Application, outdated internalContainer, replacement *internalContainer) error {
+	if app.GetUpdateStrategy() == resource.RollingUpdateStrategy {
+		if !hasHostPorts(app) {
+			return p.rollInstance(outdated, replacement)
+		}
+
+		log.Warnf("Application=%s binds host ports, falling back to the recreate strategy", app.Name)
+	}
+
+	return p.recreateInstance(outdated, replacement)
+}
+
+// recreateInstance creates the replacement container under a temporary name, which pulls the image before the
+// outdated container is removed. The replacement is only started once the outdated container has been removed.
+func (p *Provider) recreateInstance(outdated internalContainer, replacement *internalContainer) error {
+	name := replacement.name
+	replacement.name = rolloutName(name)
+	id, err := p.createContainer(replacement)
+	if err != nil {
+		return err
+	}
+
+	if err = p.removeContainer(outdated.id); err != nil {
+		if removeErr := p.removeContainer(id); removeErr != nil {
+			log.Warnf("Failed to remove replacement container=%s: %v", replacement.name, removeErr)
+		}
+
+		return err
+	}
+
+	ctx := context.Background()
+	log.Debugf("Renaming replacement container=%s to %s", replacement.name, name)
+	if err = p.client.ContainerRename(ctx, id, name); err != nil {
+		return err
+	}
+
+	return p.startContainer(id)
+}
+
+// rollInstance starts the replacement container under a temporary name and only removes the outdated
+// container once the replacement is running. The outdated container keeps running if the replacement fails.
+func (p *Provider) rollInstance(outdated internalContainer, replacement *internalContainer) error {
+	name := replacement.name
+	replacement.name = rolloutName(name)
+	id, err := p.createContainer(replacement)
+	if err != nil {
+		return err
+	}
+
+	err = p.startContainer(id)
+	if err == nil {
+		err = p.waitForContainer(id, p.readinessTimeout(replacement.check))
+	}
+
+	if err != nil {
+		log.Warnf("Replacement container=%s failed, keeping the current instance: %v", replacement.name, err)
+		if removeErr := p.removeContainer(id); removeErr != nil {
+			log.Warnf("Failed to remove replacement container=%s: %v", replacement.name, removeErr)
+		}
+
+		return err
+	}
+
+	if err = p.removeContainer(outdated.id); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	log.Debugf("Renaming replacement container=%s to %s", replacement.name, name)
+	return p.client.ContainerRename(ctx, id, name)
+}
+
+// waitForContainer waits until the container is running and healthy (when a health check has been configured).
+func (p *Provider) waitForContainer(id string, timeout time.Duration) error {
+	ctx := context.Background()
+	deadline := time.Now().Add(timeout)
+
+	for {
+		inspected, err := p.client.ContainerInspect(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if containerState := inspected.State; containerState != nil {
+			if containerState.Running && (containerState.Health == nil || containerState.Health.Status == types.Healthy) {
+				return nil
+			}
+
+			if containerState.Health != nil && containerState.Health.Status == types.Unhealthy {
+				return fmt.Errorf("container '%s' is unhealthy", id)
+			}
+
+			if !containerState.Running && containerState.Status == "exited" {
+				return fmt.Errorf("container '%s' exited with code %d", id, containerState.ExitCode)
+			}
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("container '%s' did not become ready within %s", id, timeout)
+		}
+
+		time.Sleep(p.rolloutPollInterval)
+	}
+}
+
+// readinessTimeout returns the time to wait for a replacement container to become ready. Docker only reports a
+// container as unhealthy after the start period and every retry of the health check, which could take longer than
+// the rollout timeout.
+func (p *Provider) readinessTimeout(check *resource.HealthCheck) time.Duration {
+	if check == nil {
+		return p.rolloutTimeout
+	}
+
+	interval := check.IntervalDuration()
+	if interval == 0 {
+		interval = dockerHealthInterval
+	}
+
+	timeout := check.TimeoutDuration()
+	if timeout == 0 {
+		timeout = dockerHealthTimeout
+	}
+
+	retries := int(check.Retries)
+	if retries == 0 {
+		retries = dockerHealthRetries
+	}
+
+	healthTimeout := check.StartPeriodDuration() + time.Duration(retries)*(interval+timeout)
+	if healthTimeout > p.rolloutTimeout {
+		return healthTimeout
+	}
+
+	return p.rolloutTimeout
+}
+
+// rolloutName returns the temporary container name used while rolling out a replacement container.
+func rolloutName(name string) string {
+	return fmt.Sprintf("%s.next", name)
+}
+
+// isRolloutContainer checks if the container is a leftover replacement container of an interrupted rolling update.
+func isRolloutContainer(c internalContainer) bool {
+	return strings.HasSuffix(c.name, rolloutName(""))
+}
+
+// hasHostPorts checks if the application binds any host port, which can not be shared by two containers.
+func hasHostPorts(app *resource.Application) bool {
+	for _, port := range app.Ports {
+		if port.HostPort != 0 {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,127 @@
+package docker
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/mbaitar/gco/agent/pkg/resource"
+	"github.com/stretchr/testify/assert"
+)
+
+func exampleRollingApp() *resource.Application {
+	return &resource.Application{
+		Name:           "nginx",
+		Image:          resource.Image{Name: "nginx", Tag: "v2.0.0"},
+		UpdateStrategy: resource.RollingUpdateStrategy,
+	}
+}
+
+func exampleOutdatedContainerJson(app *resource.Application) types.ContainerJSON {
+	outdated := *app
+	outdated.Image.Tag = "v1.0.0"
+	return exampleInstanceContainerJson(&outdated, 0, "running")
+}
+
+func TestProvider_UpdateApplication_rolling(t *testing.T) {
+	client := NewTestClient()
+	provider := &Provider{client: client}
+	app := exampleRollingApp()
+
+	running := exampleInstanceContainerJson(app, 0, "running")
+	running.State.Running = true
+
+	client.containerCreateReturnId = "replacement_id"
+	client.containerListReturnContainers = []types.Container{exampleDockerContainer()}
+	client.containerInspectReturn = []types.ContainerJSON{exampleOutdatedContainerJson(app), running}
+
+	err := provider.UpdateApplication(app)
+	assert.Nil(t, err, "should not have thrown an error")
+
+	if assert.Equal(t, 1, len(client.containerCreateArgs), "should have created the replacement") {
+		assert.Equal(t, rolloutName(instanceName("nginx", 0)), client.containerCreateArgs[0][5].(string))
+	}
+
+	if assert.Equal(t, 1, len(client.containerRemoveArgs), "should have removed the outdated container") {
+		assert.Equal(t, instanceName("nginx", 0), client.containerRemoveArgs[0][1].(string))
+	}
+
+	if assert.Equal(t, 1, len(client.containerRenameArgs), "should have renamed the replacement") {
+		assert.Equal(t, "replacement_id", client.containerRenameArgs[0][1].(string))
+		assert.Equal(t, instanceName("nginx", 0), client.containerRenameArgs[0][2].(string))
+	}
+}
+
+func TestProvider_UpdateApplication_rollingReplacementFailed(t *testing.T) {
+	client := NewTestClient()
+	provider := &Provider{client: client}
+	app := exampleRollingApp()
+
+	exited := exampleInstanceContainerJson(app, 0, "exited")
+	exited.State.ExitCode = 1
+
+	client.containerCreateReturnId = "replacement_id"
+	client.containerListReturnContainers = []types.Container{exampleDockerContainer()}
+	client.containerInspectReturn = []types.ContainerJSON{exampleOutdatedContainerJson(app), exited}
+
+	err := provider.UpdateApplication(app)
+	assert.NotNil(t, err, "should have thrown an error")
+
+	if assert.Equal(t, 1, len(client.containerRemoveArgs), "should only have removed the replacement") {
+		assert.Equal(t, "replacement_id", client.containerRemoveArgs[0][1].(string))
+	}
+	assert.Equal(t, 0, len(client.containerRenameArgs), "should not have renamed the replacement")
+}
+
+func TestProvider_UpdateApplication_rollingWithHostPorts(t *testing.T) {
+	client := NewTestClient()
+	provider := &Provider{client: client}
+	app := exampleRollingApp()
+	app.Ports = []resource.Port{{ContainerPort: 80, HostPort: 8080, Protocol: resource.TcpProtocol}}
+
+	client.containerListReturnContainers = []types.Container{exampleDockerContainer()}
+	client.containerInspectReturn = []types.ContainerJSON{exampleOutdatedContainerJson(app)}
+
+	err := provider.UpdateApplication(app)
+	assert.Nil(t, err, "should not have thrown an error")
+
+	// falls back to the recreate strategy, which starts the replacement after removing the outdated container
+	assert.Equal(t, 1, len(client.containerRemoveArgs))
+	if assert.Equal(t, 1, len(client.containerCreateArgs)) {
+		assert.Equal(t, rolloutName(instanceName("nginx", 0)), client.containerCreateArgs[0][5].(string))
+	}
+	if assert.Equal(t, 1, len(client.containerRenameArgs)) {
+		assert.Equal(t, instanceName("nginx", 0), client.containerRenameArgs[0][2].(string))
+	}
+	assert.Equal(t, 1, len(client.containerStartArgs))
+}
+
+func TestProvider_UpdateApplication_recreatePullError(t *testing.T) {
+	client := NewTestClient()
+	provider := &Provider{client: client}
+	app := exampleRollingApp()
+	app.Ports = []resource.Port{{ContainerPort: 80, HostPort: 8080, Protocol: resource.TcpProtocol}}
+
+	client.imagePullReturnErr = errors.New("test error")
+	client.containerListReturnContainers = []types.Container{exampleDockerContainer()}
+	client.containerInspectReturn = []types.ContainerJSON{exampleOutdatedContainerJson(app)}
+
+	err := provider.UpdateApplication(app)
+	assert.NotNil(t, err, "should have thrown an error")
+	assert.Equal(t, 0, len(client.containerRemoveArgs), "should have kept the outdated container")
+	assert.Equal(t, 0, len(client.containerCreateArgs))
+}
+
+func TestProvider_readinessTimeout(t *testing.T) {
+	provider := &Provider{rolloutTimeout: defaultRolloutTimeout}
+
+	assert.Equal(t, defaultRolloutTimeout, provider.readinessTimeout(nil))
+	assert.Equal(t, defaultRolloutTimeout, provider.readinessTimeout(&resource.HealthCheck{Interval: 5, Timeout: 2, Retries: 3}))
+
+	check := &resource.HealthCheck{Interval: 10, Timeout: 5, Retries: 5, StartPeriod: 30}
+	assert.Equal(t, 105*time.Second, provider.readinessTimeout(check), "should wait for every retry after the start period")
+
+	// docker uses an interval and timeout of 30 seconds with 3 retries when unset
+	assert.Equal(t, 180*time.Second, provider.readinessTimeout(&resource.HealthCheck{}))
+}
@@ -1,13 +1,19 @@
 package docker
 
 import (
+	"encoding/json"
 	"fmt"
+	"math"
+	"sort"
 	"strconv"
 	"strings"
 
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
 	"github.com/docker/go-connections/nat"
+	"github.com/mbaitar/gco/agent/internal/files"
+	"github.com/mbaitar/gco/agent/internal/provider"
 	"github.com/mbaitar/gco/agent/pkg/resource"
 )
 
@@ -81,9 +87,19 @@ type internalContainer struct {
 	id         string
 	name       string
 	image      string
+	cmd        []string
+	user       string
 	labels     map[string]string
 	ports      []containerPort
 	volumes    []volumeMount
+	mounts     []mount.Mount
+	env        map[string]string
+	check      *resource.HealthCheck
+	ingress    *resource.Ingress
+	metrics    *resource.Metrics
+	resources  container.Resources
+	restart    container.RestartPolicy
+	health     string
 	state      string
 	logConfig  container.LogConfig
 	pullPolicy imagePullPolicy
@@ -104,12 +120,70 @@ func fromDockerContainer(c types.ContainerJSON) internalContainer {
 		ic.image = c.Config.Image
 	}
 
-	for port, bindings := range c.HostConfig.PortBindings {
-		for _, binding := range bindings {
+	// sort the ports for a stable result
+	exposed := make([]nat.Port, 0, len(c.HostConfig.PortBindings))
+	for port := range c.HostConfig.PortBindings {
+		exposed = append(exposed, port)
+	}
+
+	sort.Slice(exposed, func(a, b int) bool {
+		if exposed[a].Int() == exposed[b].Int() {
+			return exposed[a].Proto() < exposed[b].Proto()
+		}
+
+		return exposed[a].Int() < exposed[b].Int()
+	})
+
+	for _, port := range exposed {
+		for _, binding := range c.HostConfig.PortBindings[port] {
 			ic.ports = append(ic.ports, newContainerPortFromBinding(port, binding))
 		}
 	}
 
+	// only include the environment variables managed by the agent, secrets are replaced by their reference
+	managedEnv := ic.getLabel(provider.EnvLabelTag)
+	if managedEnv != "" {
+		ic.env = make(map[string]string)
+		values := parseEnv(c.Config.Env)
+		for _, key := range strings.Split(managedEnv, ",") {
+			if reference := ic.getLabel(provider.SecretLabelTag(key)); reference != "" {
+				ic.env[key] = reference
+			} else {
+				ic.env[key] = values[key]
+			}
+		}
+	}
+
+	ic.mounts = append(ic.mounts, c.HostConfig.Mounts...)
+	ic.resources = c.HostConfig.Resources
+	ic.restart = c.HostConfig.RestartPolicy
+
+	// the health check definition is kept in a label as the docker health check can not be translated back
+	if encoded := ic.getLabel(provider.HealthLabelTag); encoded != "" {
+		health := &resource.HealthCheck{}
+		if err := json.Unmarshal([]byte(encoded), health); err == nil {
+			ic.check = health
+		}
+	}
+
+	if encoded := ic.getLabel(provider.IngressLabelTag); encoded != "" {
+		ingress := &resource.Ingress{}
+		if err := json.Unmarshal([]byte(encoded), ingress); err == nil {
+			ic.ingress = ingress
+		}
+	}
+
+	if encoded := ic.getLabel(provider.MetricsLabelTag); encoded != "" {
+		metrics := &resource.Metrics{}
+		if err := json.Unmarshal([]byte(encoded), metrics); err == nil {
+			ic.metrics = metrics
+		}
+	}
+
+	if c.State.Health != nil {
+		ic.health = c.State.Health.Status
+	}
+
 	for _, binding := range c.HostConfig.Binds {
 		mount := volumeMountFromBind(binding)
 		if mount != nil {
@@ -132,9 +206,85 @@ func fromApplicationResource(app *resource.Application) *internalContainer {
 		ic.ports[i] = newContainerPort(port.ContainerPort, port.HostPort, string(port.Protocol))
 	}
 
+	for _, m := range app.Mounts {
+		ic.mounts = append(ic.mounts, mount.Mount{
+			Type:     mount.Type(m.Type),
+			Source:   m.Source,
+			Target:   m.Target,
+			ReadOnly: m.ReadOnly,
+		})
+	}
+
 	// set default label
-	ic.addLabel(kindLabel(resource.ApplicationKind))
-	ic.addLabel(nameLabel(app.Name))
+	ic.addLabel(provider.KindLabel(resource.ApplicationKind))
+	ic.addLabel(provider.NameLabel(app.Name))
+
+	// keep track of the managed environment variables and secret references
+	if len(app.Env) > 0 {
+		ic.env = make(map[string]string, len(app.Env))
+		keys := make([]string, 0, len(app.Env))
+		for key, value := range app.Env {
+			ic.env[key] = value
+			keys = append(keys, key)
+
+			if resource.IsSecretReference(value) {
+				ic.addLabel(provider.SecretLabel(key, value))
+			}
+		}
+
+		sort.Strings(keys)
+		ic.addLabel(provider.EnvLabel(keys))
+	}
+
+	if app.Resources != nil {
+		ic.resources = container.Resources{
+			NanoCPUs:          int64(math.Round(app.Resources.CPULimit * 1e9)),
+			Memory:            app.Resources.MemoryLimit,
+			MemoryReservation: app.Resources.MemoryReservation,
+		}
+
+		if app.Resources.PidsLimit > 0 {
+			pids := app.Resources.PidsLimit
+			ic.resources.PidsLimit = &pids
+		}
+	}
+
+	// keep track of the additional labels, labels using the platform prefix are rejected by provider.ValidateLabels
+	if len(app.Labels) > 0 {
+		keys := make([]string, 0, len(app.Labels))
+		for key, value := range app.Labels {
+			ic.labels[key] = value
+			keys = append(keys, key)
+		}
+
+		sort.Strings(keys)
+		ic.addLabel(provider.LabelsLabel(keys))
+	}
+
+	if app.Ingress != nil {
+		ic.ingress = app.Ingress
+		encoded, _ := json.Marshal(app.Ingress)
+		ic.addLabel(provider.IngressLabel(string(encoded)))
+	}
+
+	if app.Metrics != nil {
+		ic.metrics = app.Metrics
+		encoded, _ := json.Marshal(app.Metrics)
+		ic.addLabel(provider.MetricsLabel(string(encoded)))
+	}
+
+	if !app.RestartPolicy.IsDefault() {
+		ic.restart = container.RestartPolicy{Name: string(app.RestartPolicy.Name)}
+		if app.RestartPolicy.Name == resource.OnFailureRestartPolicy {
+			ic.restart.MaximumRetryCount = app.RestartPolicy.MaxRetries
+		}
+	}
+
+	if app.HealthCheck != nil {
+		ic.check = app.HealthCheck
+		encoded, _ := json.Marshal(app.HealthCheck)
+		ic.addLabel(provider.HealthLabel(string(encoded)))
+	}
 
 	// parse log config
 	if app.LogConfig != nil {
@@ -142,7 +292,7 @@ func fromApplicationResource(app *resource.Application) *internalContainer {
 			ic.logConfig = container.LogConfig{
 				Type: "fluentd",
 				Config: map[string]string{
-					"labels":          strings.Join([]string{kindLabelTag.string(), managedByLabelTag.string(), nameLabelTag.string()}, ","),
+					"labels":          strings.Join([]string{provider.KindLabelTag.String(), provider.ManagedByLabelTag.String(), provider.NameLabelTag.String(), provider.InstanceLabelTag.String()}, ","),
 					"fluentd-async":   "true",
 					"fluentd-address": app.LogConfig.Config["address"],
 				},
@@ -170,10 +320,104 @@ func (i *internalContainer) config() *container.Config {
 	return &container.Config{
 		Labels:       i.labels,
 		Image:        i.image,
+		Cmd:          i.cmd,
+		User:         i.user,
 		ExposedPorts: ports,
+		Healthcheck:  i.healthConfig(),
 	}
 }
 
+// healthConfig translates the health check of the application to the docker health check.
+// The HTTP and TCP checks are executed within the container and require wget or nc to be available in the image.
+func (i *internalContainer) healthConfig() *container.HealthConfig {
+	if i.check == nil {
+		return nil
+	}
+
+	config := &container.HealthConfig{
+		Interval:    i.check.IntervalDuration(),
+		Timeout:     i.check.TimeoutDuration(),
+		StartPeriod: i.check.StartPeriodDuration(),
+		Retries:     int(i.check.Retries),
+	}
+
+	switch i.check.Type {
+	case resource.CommandHealthCheck:
+		config.Test = append([]string{"CMD"}, i.check.Command...)
+	case resource.HttpHealthCheck:
+		url := fmt.Sprintf("http://localhost:%d%s", i.check.Port, i.check.Path)
+		config.Test = []string{"CMD-SHELL", fmt.Sprintf("wget -q -O /dev/null %s || exit 1", url)}
+	case resource.TcpHealthCheck:
+		config.Test = []string{"CMD-SHELL", fmt.Sprintf("nc -z localhost %d || exit 1", i.check.Port)}
+	default:
+		return nil
+	}
+
+	return config
+}
+
+// isHealthy returns true when the container is running and either healthy or without a health check.
+func (i *internalContainer) isHealthy() bool {
+	if i.state != "running" {
+		return false
+	}
+
+	return i.health == "" || i.health == types.NoHealthcheck || i.health == types.Healthy
+}
+
+// isUnhealthy returns true when docker reported the container as unhealthy, which means the health check
+// failed for the configured number of retries.
+func (i *internalContainer) isUnhealthy() bool {
+	return i.state == "running" && i.health == types.Unhealthy
+}
+
+// isExited returns true when the container stopped after it has been started, including containers which are
+// being restarted by docker because of their restart policy.
+func (i *internalContainer) isExited() bool {
+	return i.state == "exited" || i.state == "restarting" || i.state == "dead"
+}
+
+// resolveEnv returns the environment variables in the docker format with the referenced secrets resolved.
+func (i *internalContainer) resolveEnv() ([]string, error) {
+	keys := make([]string, 0, len(i.env))
+	for key := range i.env {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	env := make([]string, 0, len(keys))
+	for _, key := range keys {
+		value := i.env[key]
+		if resource.IsSecretReference(value) {
+			secret, err := files.ReadSecret(resource.SecretName(value))
+			if err != nil {
+				return nil, err
+			}
+
+			value = secret
+		}
+
+		env = append(env, fmt.Sprintf("%s=%s", key, value))
+	}
+
+	return env, nil
+}
+
+// parseEnv parses the environment variables from the docker format.
+func parseEnv(env []string) map[string]string {
+	values := make(map[string]string, len(env))
+	for _, entry := range env {
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) == 2 {
+			values[parts[0]] = parts[1]
+		} else {
+			values[parts[0]] = ""
+		}
+	}
+
+	return values
+}
+
 func (i *internalContainer) hostConfig() *container.HostConfig {
 	// map ports
 	ports := nat.PortMap{}
@@ -193,18 +437,49 @@ func (i *internalContainer) hostConfig() *container.HostConfig {
 	}
 
 	return &container.HostConfig{
-		PortBindings: ports,
-		LogConfig:    i.logConfig,
-		Binds:        binds,
+		PortBindings:  ports,
+		LogConfig:     i.logConfig,
+		Binds:         binds,
+		Mounts:        i.mounts,
+		Resources:     i.resources,
+		RestartPolicy: i.restart,
 	}
 }
 
-func (i *internalContainer) addLabel(label label) {
-	i.labels[label.tag.string()] = label.value
+func (i *internalContainer) addLabel(label provider.Label) {
+	i.labels[label.Tag.String()] = label.Value
 }
 
-func (i *internalContainer) getLabel(tag labelTag) string {
-	return i.labels[tag.string()]
+func (i *internalContainer) getLabel(tag provider.LabelTag) string {
+	return i.labels[tag.String()]
+}
+
+// getInstance returns the instance ordinal of the container, containers without an instance label are the first instance.
+func (i *internalContainer) getInstance() int {
+	ordinal, err := strconv.Atoi(i.getLabel(provider.InstanceLabelTag))
+	if err != nil {
+		return 0
+	}
+
+	return ordinal
+}
+
+// withInstance returns a copy of the container definition for the instance with the given ordinal.
+func (i *internalContainer) withInstance(ordinal int) *internalContainer {
+	instance := *i
+	instance.name = instanceName(i.name, ordinal)
+	instance.labels = make(map[string]string, len(i.labels)+1)
+	for key, value := range i.labels {
+		instance.labels[key] = value
+	}
+
+	instance.addLabel(provider.InstanceLabel(ordinal))
+	return &instance
+}
+
+// instanceName returns the container name used for the instance of an application.
+func instanceName(name string, ordinal int) string {
+	return fmt.Sprintf("%s-%d", name, ordinal)
 }
 
 func (i *internalContainer) getImageResource() resource.Image {
@@ -223,12 +498,9 @@ func (i *internalContainer) getPortResources() []resource.Port {
 	ports := make([]resource.Port, 0, len(i.ports))
 	for _, port := range i.ports {
 		private, _ := strconv.ParseUint(port.privatePort(), 10, 16)
-		public, _ := strconv.ParseUint(port.publicPort(), 10, 16)
 
-		if public <= 0 {
-			// skip non exposed ports
-			continue
-		}
+		// an unset public port results in a host port assigned by docker
+		public, _ := strconv.ParseUint(port.publicPort(), 10, 16)
 
 		ports = append(ports, resource.Port{
 			ContainerPort: uint16(private),
@@ -240,16 +512,101 @@ func (i *internalContainer) getPortResources() []resource.Port {
 	return ports
 }
 
+func (i *internalContainer) getMountResources() []resource.Mount {
+	if len(i.mounts) == 0 {
+		return nil
+	}
+
+	mounts := make([]resource.Mount, len(i.mounts))
+	for idx, m := range i.mounts {
+		mounts[idx] = resource.Mount{
+			Type:     resource.MountType(m.Type),
+			Source:   m.Source,
+			Target:   m.Target,
+			ReadOnly: m.ReadOnly,
+		}
+	}
+
+	return mounts
+}
+
+func (i *internalContainer) getResources() *resource.Resources {
+	resources := &resource.Resources{
+		CPULimit:          float64(i.resources.NanoCPUs) / 1e9,
+		MemoryLimit:       i.resources.Memory,
+		MemoryReservation: i.resources.MemoryReservation,
+	}
+
+	// docker reports an unlimited pids limit as 0 or -1
+	if i.resources.PidsLimit != nil && *i.resources.PidsLimit > 0 {
+		resources.PidsLimit = *i.resources.PidsLimit
+	}
+
+	if resources.IsEmpty() {
+		return nil
+	}
+
+	return resources
+}
+
+// getLabelResources returns the additional labels of the application.
+func (i *internalContainer) getLabelResources() map[string]string {
+	managed := i.getLabel(provider.LabelsLabelTag)
+	if managed == "" {
+		return nil
+	}
+
+	labels := make(map[string]string)
+	for _, key := range strings.Split(managed, ",") {
+		labels[key] = i.labels[key]
+	}
+
+	return labels
+}
+
+// getRestartPolicy returns the restart policy of the container, the docker default "no" is equal to no policy.
+func (i *internalContainer) getRestartPolicy() *resource.RestartPolicy {
+	switch {
+	case i.restart.IsAlways():
+		return &resource.RestartPolicy{Name: resource.AlwaysRestartPolicy}
+	case i.restart.IsOnFailure():
+		return &resource.RestartPolicy{Name: resource.OnFailureRestartPolicy, MaxRetries: i.restart.MaximumRetryCount}
+	default:
+		return nil
+	}
+}
+
 func (i *internalContainer) toApplicationResource() resource.Application {
 	instances := 0
-	if i.state == "running" {
+	if i.isHealthy() {
 		instances = 1
 	}
 
+	unhealthy := 0
+	if i.isUnhealthy() {
+		unhealthy = 1
+	}
+
+	exited := 0
+	if i.isExited() {
+		exited = 1
+	}
+
 	return resource.Application{
-		Name:      i.getLabel(nameLabelTag),
-		Image:     i.getImageResource(),
-		Ports:     i.getPortResources(),
-		Instances: instances,
+		Name:          i.getLabel(provider.NameLabelTag),
+		Image:         i.getImageResource(),
+		Ports:         i.getPortResources(),
+		Mounts:        i.getMountResources(),
+		Instances:     instances,
+		Env:           i.env,
+		HealthCheck:   i.check,
+		Resources:     i.getResources(),
+		RestartPolicy: i.getRestartPolicy(),
+		Ingress:       i.ingress,
+		Metrics:       i.metrics,
+		Labels:        i.getLabelResources(),
+
+		UnhealthyInstances: unhealthy,
+		ExitedInstances:    exited,
 	}
 }
@@ -1,12 +1,18 @@
 package docker
 
 import (
+	"os"
+	"path"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
 	"github.com/docker/go-connections/nat"
+	"github.com/mbaitar/gco/agent/internal/files"
+	providers "github.com/mbaitar/gco/agent/internal/provider"
 	"github.com/mbaitar/gco/agent/pkg/resource"
 	"github.com/stretchr/testify/assert"
 )
@@ -158,9 +164,9 @@ func TestInternalContainer_fromApplicationResource_withLogConfig(t *testing.T) {
 		assert.Equal(t, "127.0.0.1:24224", config.LogConfig.Config["fluentd-address"])
 
 		allowedLabels := strings.Split(config.LogConfig.Config["labels"], ",")
-		ShouldIncludeLabel(t, kindLabelTag.string(), allowedLabels)
-		ShouldIncludeLabel(t, managedByLabelTag.string(), allowedLabels)
-		ShouldIncludeLabel(t, nameLabelTag.string(), allowedLabels)
+		ShouldIncludeLabel(t, providers.KindLabelTag.String(), allowedLabels)
+		ShouldIncludeLabel(t, providers.ManagedByLabelTag.String(), allowedLabels)
+		ShouldIncludeLabel(t, providers.NameLabelTag.String(), allowedLabels)
 	}
 }
 
@@ -184,3 +190,300 @@ func TestInternalContainer_fromDockerContainer_withBinds(t *testing.T) {
 	assert.Equal(t, "/source/readonly", m2.destination)
 	assert.True(t, m2.readonly)
 }
+
+func TestInternalContainer_env(t *testing.T) {
+	application := &resource.Application{
+		Name:  "postgres",
+		Image: resource.Image{Name: "postgres", Tag: "latest"},
+		Env: map[string]string{
+			"POSTGRES_USER":     "admin",
+			"POSTGRES_PASSWORD": "secret:postgres-password",
+		},
+	}
+
+	ic := fromApplicationResource(application)
+	assert.Equal(t, "POSTGRES_PASSWORD,POSTGRES_USER", ic.getLabel(providers.EnvLabelTag))
+	assert.Equal(t, "secret:postgres-password", ic.getLabel(providers.SecretLabelTag("POSTGRES_PASSWORD")))
+
+	// docker returns the resolved secret and the environment of the image
+	con := exampleDockerContainerJson()
+	con.Config.Labels = ic.labels
+	con.Config.Image = "postgres:latest"
+	con.HostConfig.PortBindings = nat.PortMap{}
+	con.Config.Env = []string{"PATH=/usr/bin", "POSTGRES_USER=admin", "POSTGRES_PASSWORD=plaintext"}
+
+	parsedContainer := fromDockerContainer(con)
+	parsed := parsedContainer.toApplicationResource()
+	assert.Equal(t, application.Env, parsed.Env, "should only include managed env and secret references")
+	assert.Equal(t, application.CalculateHash(), parsed.CalculateHash())
+}
+
+func TestInternalContainer_resolveEnv(t *testing.T) {
+	SetupForTests(t)
+	dir, _ := files.GetDirectory()
+	_ = os.MkdirAll(path.Join(dir, "secrets"), 0700)
+	_ = os.WriteFile(path.Join(dir, "secrets", "postgres-password"), []byte("plaintext\n"), 0600)
+
+	ic := &internalContainer{
+		env: map[string]string{
+			"POSTGRES_USER":     "admin",
+			"POSTGRES_PASSWORD": "secret:postgres-password",
+		},
+	}
+
+	env, err := ic.resolveEnv()
+	if assert.Nil(t, err, "should have resolved the secret") {
+		assert.Equal(t, []string{"POSTGRES_PASSWORD=plaintext", "POSTGRES_USER=admin"}, env)
+	}
+
+	ic.env["MISSING"] = "secret:missing"
+	_, err = ic.resolveEnv()
+	assert.NotNil(t, err, "should not resolve a missing secret")
+}
+
+func TestInternalContainer_mounts(t *testing.T) {
+	application := &resource.Application{
+		Name:  "postgres",
+		Image: resource.Image{Name: "postgres", Tag: "latest"},
+		Mounts: []resource.Mount{
+			{Type: resource.VolumeMountType, Source: "postgres-data", Target: "/var/lib/postgresql/data"},
+			{Type: resource.BindMountType, Source: "/etc/postgres", Target: "/etc/postgresql", ReadOnly: true},
+			{Type: resource.TmpfsMountType, Target: "/tmp"},
+		},
+	}
+
+	ic := fromApplicationResource(application)
+	hostConfig := ic.hostConfig()
+
+	if assert.Equal(t, 3, len(hostConfig.Mounts), "should have mapped all mounts") {
+		assert.Equal(t, mount.TypeVolume, hostConfig.Mounts[0].Type)
+		assert.Equal(t, "postgres-data", hostConfig.Mounts[0].Source)
+		assert.Equal(t, mount.TypeBind, hostConfig.Mounts[1].Type)
+		assert.True(t, hostConfig.Mounts[1].ReadOnly, "should be read-only")
+		assert.Equal(t, mount.TypeTmpfs, hostConfig.Mounts[2].Type)
+		assert.Equal(t, "/tmp", hostConfig.Mounts[2].Target)
+	}
+
+	// read the mounts back from docker
+	con := exampleDockerContainerJson()
+	con.Config.Labels = ic.labels
+	con.Config.Image = "postgres:latest"
+	con.HostConfig.PortBindings = nat.PortMap{}
+	con.HostConfig.Mounts = hostConfig.Mounts
+
+	parsedContainer := fromDockerContainer(con)
+	parsed := parsedContainer.toApplicationResource()
+	assert.Equal(t, application.Mounts, parsed.Mounts)
+	assert.Equal(t, application.CalculateHash(), parsed.CalculateHash())
+}
+
+func TestInternalContainer_healthCheck(t *testing.T) {
+	application := &resource.Application{
+		Name:  "nginx",
+		Image: resource.Image{Name: "nginx", Tag: "latest"},
+		HealthCheck: &resource.HealthCheck{
+			Type:     resource.HttpHealthCheck,
+			Path:     "/health",
+			Port:     80,
+			Interval: 10,
+			Timeout:  2,
+			Retries:  3,
+		},
+	}
+
+	ic := fromApplicationResource(application)
+	config := ic.config()
+
+	if assert.NotNil(t, config.Healthcheck, "should have mapped the health check") {
+		assert.Equal(t, []string{"CMD-SHELL", "wget -q -O /dev/null http://localhost:80/health || exit 1"}, config.Healthcheck.Test)
+		assert.Equal(t, 10*time.Second, config.Healthcheck.Interval)
+		assert.Equal(t, 2*time.Second, config.Healthcheck.Timeout)
+		assert.Equal(t, 3, config.Healthcheck.Retries)
+	}
+
+	// read the health check back from docker
+	con := exampleDockerContainerJson()
+	con.Config.Labels = ic.labels
+	con.Config.Image = "nginx:latest"
+	con.HostConfig.PortBindings = nat.PortMap{}
+	con.State.Health = &types.Health{Status: types.Healthy}
+
+	parsedContainer := fromDockerContainer(con)
+	parsed := parsedContainer.toApplicationResource()
+	assert.Equal(t, application.HealthCheck, parsed.HealthCheck)
+	assert.Equal(t, application.CalculateHash(), parsed.CalculateHash())
+	assert.Equal(t, 1, parsed.Instances, "should count the healthy container")
+	assert.Equal(t, 0, parsed.UnhealthyInstances)
+
+	con.State.Health.Status = types.Starting
+	parsedContainer = fromDockerContainer(con)
+	parsed = parsedContainer.toApplicationResource()
+	assert.Equal(t, 0, parsed.Instances, "should not count the starting container")
+	assert.Equal(t, 0, parsed.UnhealthyInstances)
+	assert.Equal(t, 0, parsed.ExitedInstances, "should not report the starting container as exited")
+
+	con.State.Health.Status = types.Unhealthy
+	parsedContainer = fromDockerContainer(con)
+	parsed = parsedContainer.toApplicationResource()
+	assert.Equal(t, 0, parsed.Instances, "should not count the unhealthy container")
+	assert.Equal(t, 1, parsed.UnhealthyInstances)
+
+	con.State.Status = "exited"
+	con.State.Health.Status = types.Unhealthy
+	parsedContainer = fromDockerContainer(con)
+	parsed = parsedContainer.toApplicationResource()
+	assert.Equal(t, 0, parsed.UnhealthyInstances)
+	assert.Equal(t, 1, parsed.ExitedInstances, "should report the exited container")
+}
+
+func TestInternalContainer_healthCheck_command(t *testing.T) {
+	ic := &internalContainer{
+		check: &resource.HealthCheck{Type: resource.CommandHealthCheck, Command: []string{"pg_isready"}},
+	}
+
+	assert.Equal(t, []string{"CMD", "pg_isready"}, ic.healthConfig().Test)
+
+	ic.check = &resource.HealthCheck{Type: resource.TcpHealthCheck, Port: 5432}
+	assert.Equal(t, []string{"CMD-SHELL", "nc -z localhost 5432 || exit 1"}, ic.healthConfig().Test)
+
+	ic.check = nil
+	assert.Nil(t, ic.healthConfig(), "should not define a health check")
+}
+
+func TestInternalContainer_resources(t *testing.T) {
+	application := &resource.Application{
+		Name:  "nginx",
+		Image: resource.Image{Name: "nginx", Tag: "latest"},
+		Resources: &resource.Resources{
+			CPULimit:          0.5,
+			MemoryLimit:       256 * 1024 * 1024,
+			MemoryReservation: 128 * 1024 * 1024,
+			PidsLimit:         100,
+		},
+	}
+
+	ic := fromApplicationResource(application)
+	hostConfig := ic.hostConfig()
+
+	assert.Equal(t, int64(500000000), hostConfig.NanoCPUs)
+	assert.Equal(t, int64(256*1024*1024), hostConfig.Memory)
+	assert.Equal(t, int64(128*1024*1024), hostConfig.MemoryReservation)
+	if assert.NotNil(t, hostConfig.PidsLimit) {
+		assert.Equal(t, int64(100), *hostConfig.PidsLimit)
+	}
+
+	// read the limits back from docker
+	con := exampleDockerContainerJson()
+	con.Config.Labels = ic.labels
+	con.Config.Image = "nginx:latest"
+	con.HostConfig.PortBindings = nat.PortMap{}
+	con.HostConfig.Resources = hostConfig.Resources
+
+	parsedContainer := fromDockerContainer(con)
+	parsed := parsedContainer.toApplicationResource()
+	assert.Equal(t, application.Resources, parsed.Resources)
+	assert.Equal(t, application.CalculateHash(), parsed.CalculateHash())
+
+	// changed limits should be detected as drift
+	con.HostConfig.Memory = 512 * 1024 * 1024
+	parsedContainer = fromDockerContainer(con)
+	parsed = parsedContainer.toApplicationResource()
+	assert.NotEqual(t, application.CalculateHash(), parsed.CalculateHash())
+}
+
+func TestInternalContainer_restartPolicy(t *testing.T) {
+	application := &resource.Application{
+		Name:          "nginx",
+		Image:         resource.Image{Name: "nginx", Tag: "latest"},
+		RestartPolicy: &resource.RestartPolicy{Name: resource.OnFailureRestartPolicy, MaxRetries: 5},
+	}
+
+	ic := fromApplicationResource(application)
+	hostConfig := ic.hostConfig()
+	assert.Equal(t, container.RestartPolicy{Name: "on-failure", MaximumRetryCount: 5}, hostConfig.RestartPolicy)
+
+	// read the restart policy back from docker
+	con := exampleDockerContainerJson()
+	con.Config.Labels = ic.labels
+	con.Config.Image = "nginx:latest"
+	con.HostConfig.PortBindings = nat.PortMap{}
+	con.HostConfig.RestartPolicy = hostConfig.RestartPolicy
+
+	parsedContainer := fromDockerContainer(con)
+	parsed := parsedContainer.toApplicationResource()
+	assert.Equal(t, application.RestartPolicy, parsed.RestartPolicy)
+	assert.Equal(t, application.CalculateHash(), parsed.CalculateHash())
+
+	// the docker default is equal to the never restart policy
+	never := &resource.Application{
+		Name:          "nginx",
+		Image:         resource.Image{Name: "nginx", Tag: "latest"},
+		RestartPolicy: &resource.RestartPolicy{Name: resource.NeverRestartPolicy},
+	}
+
+	con.Config.Labels = fromApplicationResource(never).labels
+	con.HostConfig.RestartPolicy = container.RestartPolicy{Name: "no"}
+	parsedContainer = fromDockerContainer(con)
+	parsed = parsedContainer.toApplicationResource()
+	assert.Nil(t, parsed.RestartPolicy)
+	assert.Equal(t, never.CalculateHash(), parsed.CalculateHash())
+
+	// the retries only apply to the on-failure policy
+	always := &resource.Application{
+		Name:          "nginx",
+		Image:         resource.Image{Name: "nginx", Tag: "latest"},
+		RestartPolicy: &resource.RestartPolicy{Name: resource.AlwaysRestartPolicy, MaxRetries: 3},
+	}
+
+	ic = fromApplicationResource(always)
+	con.Config.Labels = ic.labels
+	con.HostConfig.RestartPolicy = ic.hostConfig().RestartPolicy
+	parsedContainer = fromDockerContainer(con)
+	parsed = parsedContainer.toApplicationResource()
+	assert.Equal(t, always.CalculateHash(), parsed.CalculateHash(), "should ignore the retries of the always policy")
+}
+
+func TestInternalContainer_labelsAndIngress(t *testing.T) {
+	application := &resource.Application{
+		Name:    "nginx",
+		Image:   resource.Image{Name: "nginx", Tag: "latest"},
+		Ingress: &resource.Ingress{Hosts: []string{"example.com"}, Port: 80},
+		Labels:  map[string]string{"traefik.enable": "true"},
+	}
+
+	ic := fromApplicationResource(application)
+	assert.Equal(t, "true", ic.labels["traefik.enable"], "should have added the label to the container")
+
+	// read the labels and ingress back from docker
+	con := exampleDockerContainerJson()
+	con.Config.Labels = ic.labels
+	con.Config.Image = "nginx:latest"
+	con.HostConfig.PortBindings = nat.PortMap{}
+
+	parsedContainer := fromDockerContainer(con)
+	parsed := parsedContainer.toApplicationResource()
+	assert.Equal(t, application.Ingress, parsed.Ingress)
+	assert.Equal(t, application.Labels, parsed.Labels)
+	assert.Equal(t, application.CalculateHash(), parsed.CalculateHash())
+}
+
+func TestInternalContainer_metrics(t *testing.T) {
+	application := &resource.Application{
+		Name:    "nginx",
+		Image:   resource.Image{Name: "nginx", Tag: "latest"},
+		Metrics: &resource.Metrics{Port: 9113, Path: "/stats"},
+	}
+
+	ic := fromApplicationResource(application)
+
+	// read the metrics back from docker
+	con := exampleDockerContainerJson()
+	con.Config.Labels = ic.labels
+	con.Config.Image = "nginx:latest"
+	con.HostConfig.PortBindings = nat.PortMap{}
+
+	parsedContainer := fromDockerContainer(con)
+	parsed := parsedContainer.toApplicationResource()
+	assert.Equal(t, application.Metrics, parsed.Metrics)
+	assert.Equal(t, application.CalculateHash(), parsed.CalculateHash())
+}
@@ -6,4 +6,6 @@ var (
 	ErrFeatureNotSupported = errors.New("feature is not supported by provider")
 	ErrFeatureNotFound     = errors.New("feature not found")
 	ErrAppNotFound         = errors.New("application not found")
+	ErrHostPortConflict    = errors.New("host ports can not be bound by multiple application instances")
+	ErrReservedLabel       = errors.New("label uses a prefix reserved for the agent")
 )
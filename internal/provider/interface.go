@@ -30,3 +30,30 @@ type Provider interface {
 	// ActualState defines a function which will analyze the current state and return it in the form of a specification.
 	ActualState() (*state.Spec, error)
 }
+
+// FeatureSupporter defines an optional extension of a Provider which is not able to create every registered feature.
+type FeatureSupporter interface {
+	// SupportsFeature returns true when the provider is able to create the feature matching the name.
+	SupportsFeature(name string) bool
+}
+
+// SupportsFeature returns true when the provider is able to create the feature matching the name, providers
+// which do not implement FeatureSupporter support every feature.
+func SupportsFeature(p Provider, name string) bool {
+	if supporter, ok := p.(FeatureSupporter); ok {
+		return supporter.SupportsFeature(name)
+	}
+
+	return true
+}
+
+// ObserveHandler defines a function which will be called when a provider detected a change of the actual state.
+type ObserveHandler func(spec state.Spec)
+
+// Observer defines an optional extension of a Provider which is able to detect changes made
+// to the external container system outside the agent (crashes, manual removals, ...).
+type Observer interface {
+	// Watch defines a function which will observe the external system and call the handler with the latest actual state.
+	// This method will block until the 'exit' channel has been closed.
+	Watch(exit <-chan struct{}, handler ObserveHandler)
+}
@@ -0,0 +1,41 @@
+package kubernetes
+
+import (
+	"os"
+
+	"github.com/mbaitar/gco/agent/internal/log"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// newKubernetesClient creates the client using the kubeconfig file, when no file has been configured the
+// in-cluster configuration is used and the default kubeconfig (KUBECONFIG or ~/.kube/config) otherwise.
+func newKubernetesClient(kubeconfig string) kubernetes.Interface {
+	conf, err := restConfig(kubeconfig)
+	if err != nil {
+		log.Errorf("Unable to load kubernetes configuration: %v", err)
+		os.Exit(1)
+	}
+
+	cli, err := kubernetes.NewForConfig(conf)
+	if err != nil {
+		log.Errorf("Unable to create new kubernetes client: %v", err)
+		os.Exit(1)
+	}
+
+	return cli
+}
+
+func restConfig(kubeconfig string) (*rest.Config, error) {
+	if kubeconfig != "" {
+		return clientcmd.BuildConfigFromFlags("", kubeconfig)
+	}
+
+	if conf, err := rest.InClusterConfig(); err == nil {
+		return conf, nil
+	}
+
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, &clientcmd.ConfigOverrides{}).ClientConfig()
+}
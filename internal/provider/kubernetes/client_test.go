@@ -0,0 +1,95 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+const testNamespace = "gco"
+
+// NewTestProvider creates a provider using the fake clientset, which keeps the objects in memory.
+func NewTestProvider(objects ...runtime.Object) (*Provider, *fake.Clientset) {
+	client := fake.NewSimpleClientset(objects...)
+	return &Provider{client: client, namespace: testNamespace}, client
+}
+
+func getDeployment(t *testing.T, client *fake.Clientset, name string) *appsv1.Deployment {
+	deployment, err := client.AppsV1().Deployments(testNamespace).Get(context.Background(), name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("unable to get deployment '%s': %v", name, err)
+	}
+
+	return deployment
+}
+
+func getService(t *testing.T, client *fake.Clientset, name string) *corev1.Service {
+	service, err := client.CoreV1().Services(testNamespace).Get(context.Background(), name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("unable to get service '%s': %v", name, err)
+	}
+
+	return service
+}
+
+// setReadyReplicas reports the number of ready pods of the deployment, which is done by the deployment controller.
+func setReadyReplicas(t *testing.T, client *fake.Clientset, name string, ready int32) {
+	deployment := getDeployment(t, client, name)
+	deployment.Status.ReadyReplicas = ready
+	if _, err := client.AppsV1().Deployments(testNamespace).UpdateStatus(context.Background(), deployment, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("unable to update status of deployment '%s': %v", name, err)
+	}
+}
+
+// countObjects returns the number of objects of the resource within the namespace.
+func countObjects(t *testing.T, client *fake.Clientset, resource string) int {
+	ctx := context.Background()
+	var count int
+	var err error
+
+	switch resource {
+	case "deployments":
+		var list *appsv1.DeploymentList
+		list, err = client.AppsV1().Deployments(testNamespace).List(ctx, metav1.ListOptions{})
+		if err == nil {
+			count = len(list.Items)
+		}
+	case "daemonsets":
+		var list *appsv1.DaemonSetList
+		list, err = client.AppsV1().DaemonSets(testNamespace).List(ctx, metav1.ListOptions{})
+		if err == nil {
+			count = len(list.Items)
+		}
+	case "services":
+		var list *corev1.ServiceList
+		list, err = client.CoreV1().Services(testNamespace).List(ctx, metav1.ListOptions{})
+		if err == nil {
+			count = len(list.Items)
+		}
+	case "configmaps":
+		var list *corev1.ConfigMapList
+		list, err = client.CoreV1().ConfigMaps(testNamespace).List(ctx, metav1.ListOptions{})
+		if err == nil {
+			count = len(list.Items)
+		}
+	case "secrets":
+		var list *corev1.SecretList
+		list, err = client.CoreV1().Secrets(testNamespace).List(ctx, metav1.ListOptions{})
+		if err == nil {
+			count = len(list.Items)
+		}
+	default:
+		t.Fatalf("unknown resource '%s'", resource)
+	}
+
+	if err != nil {
+		t.Fatalf("unable to list %s: %v", resource, err)
+	}
+
+	return count
+}
@@ -0,0 +1,202 @@
+package kubernetes
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mbaitar/gco/agent/internal/provider"
+	"github.com/mbaitar/gco/agent/pkg/feature"
+	"github.com/mbaitar/gco/agent/pkg/resource"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// providerName is the name used to look up kubernetes specific feature materializers.
+const providerName = "kubernetes"
+
+// configVolumeName is the name of the volume containing the config map of a feature workload.
+const configVolumeName = "config"
+
+// featureWorkload contains the kubernetes resources of a feature workload. Global workloads run as a daemon set,
+// other workloads as a deployment exposed by a service. The config map is nil when no configuration files are used.
+type featureWorkload struct {
+	deployment *appsv1.Deployment
+	daemonSet  *appsv1.DaemonSet
+	service    *corev1.Service
+	configMap  *corev1.ConfigMap
+}
+
+// SupportsFeature returns true when a materializer of the feature has been registered for the provider.
+func (p *Provider) SupportsFeature(name string) bool {
+	return feature.SupportedBy(name, providerName)
+}
+
+// createFeatureWorkloads creates the resources using the workloads of the registered feature.
+func createFeatureWorkloads(feat feature.Feature, namespace string) ([]featureWorkload, error) {
+	def, found := feature.Lookup(feat.Name())
+	if !found {
+		return nil, provider.ErrFeatureNotSupported
+	}
+
+	materialize := def.MaterializerFor(providerName)
+	if materialize == nil {
+		return nil, provider.ErrFeatureNotSupported
+	}
+
+	workloads, err := materialize(feat)
+	if err != nil {
+		return nil, err
+	}
+
+	resources := make([]featureWorkload, 0, len(workloads))
+	for _, workload := range workloads {
+		resources = append(resources, createFeatureWorkload(feat, workload, namespace))
+	}
+
+	return resources, nil
+}
+
+// createFeatureWorkload creates the resources for a single workload of the feature. Global workloads run on every
+// node and bind their ports on the node itself, other workloads run a single replica exposed by a service.
+// The generated configuration files are stored in a config map, each file is mounted read-only at its target.
+func createFeatureWorkload(feat feature.Feature, workload feature.Workload, namespace string) featureWorkload {
+	name := workloadName(feat.Name(), workload.Name)
+
+	labels := make(map[string]string)
+	provider.AddLabel(labels, provider.ManagedByLabel())
+	provider.AddLabel(labels, provider.KindLabel(resource.FeatureKind))
+	provider.AddLabel(labels, provider.NameLabel(name))
+	provider.AddLabel(labels, provider.FeatureLabel(feat.Name()))
+
+	annotations := make(map[string]string)
+	provider.AddLabel(annotations, provider.ConfigLabel(feature.EncodeFeature(feat)))
+
+	keys := make([]string, 0, len(workload.Env))
+	for key := range workload.Env {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	env := make([]corev1.EnvVar, 0, len(keys))
+	for _, key := range keys {
+		env = append(env, corev1.EnvVar{Name: key, Value: workload.Env[key]})
+	}
+
+	volumes, mounts := toVolumes(workload.Mounts)
+	container := corev1.Container{
+		Name:         name,
+		Image:        workload.Image,
+		Args:         workload.Command,
+		Env:          env,
+		Ports:        toContainerPorts(workload.Ports, workload.Global),
+		VolumeMounts: mounts,
+	}
+
+	if workload.User != "" {
+		container.SecurityContext = toSecurityContext(workload.User)
+	}
+
+	result := featureWorkload{}
+	if len(workload.ConfigFiles) > 0 {
+		result.configMap = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: configMapName(name), Namespace: namespace, Labels: provider.CopyLabels(labels)},
+			Data:       make(map[string]string),
+		}
+
+		volumes = append(volumes, corev1.Volume{
+			Name: configVolumeName,
+			VolumeSource: corev1.VolumeSource{
+				ConfigMap: &corev1.ConfigMapVolumeSource{LocalObjectReference: corev1.LocalObjectReference{Name: configMapName(name)}},
+			},
+		})
+
+		for _, configFile := range workload.ConfigFiles {
+			result.configMap.Data[configFile.Name] = configFile.Content
+			container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+				Name:      configVolumeName,
+				MountPath: configFile.Target,
+				SubPath:   configFile.Name,
+				ReadOnly:  true,
+			})
+		}
+	}
+
+	meta := metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: labels, Annotations: annotations}
+	template := corev1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{Labels: provider.CopyLabels(labels)},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{container},
+			Volumes:    volumes,
+		},
+	}
+
+	if workload.Global {
+		result.daemonSet = &appsv1.DaemonSet{
+			ObjectMeta: meta,
+			Spec: appsv1.DaemonSetSpec{
+				Selector: &metav1.LabelSelector{MatchLabels: provider.CopyLabels(labels)},
+				Template: template,
+			},
+		}
+
+		return result
+	}
+
+	replicas := int32(1)
+	result.deployment = &appsv1.Deployment{
+		ObjectMeta: meta,
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: provider.CopyLabels(labels)},
+			Strategy: appsv1.DeploymentStrategy{Type: appsv1.RecreateDeploymentStrategyType},
+			Template: template,
+		},
+	}
+
+	if len(workload.Ports) > 0 {
+		result.service = &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: provider.CopyLabels(labels)},
+			Spec: corev1.ServiceSpec{
+				Type:     corev1.ServiceTypeClusterIP,
+				Selector: provider.CopyLabels(labels),
+				Ports:    toServicePorts(workload.Ports),
+			},
+		}
+	}
+
+	return result
+}
+
+// toSecurityContext runs the container as the user of the workload, kubernetes only supports numeric users
+// e.g. '65534:65534', apart from 'root' other user names are left to the image.
+func toSecurityContext(user string) *corev1.SecurityContext {
+	if user == "root" {
+		user = "0"
+	}
+
+	var uid, gid int64
+	if n, _ := fmt.Sscanf(user, "%d:%d", &uid, &gid); n == 0 {
+		return nil
+	} else if n == 1 {
+		return &corev1.SecurityContext{RunAsUser: &uid}
+	}
+
+	return &corev1.SecurityContext{RunAsUser: &uid, RunAsGroup: &gid}
+}
+
+// workloadName returns the name of the resources running the workload of a feature, names are DNS labels.
+func workloadName(feat string, workload string) string {
+	name := fmt.Sprintf("gco-%s", feat)
+	if workload != "" {
+		name = fmt.Sprintf("%s-%s", name, workload)
+	}
+
+	return strings.ReplaceAll(name, ".", "-")
+}
+
+// configMapName returns the name of the config map containing the configuration files of the workload.
+func configMapName(workload string) string {
+	return fmt.Sprintf("%s-config", workload)
+}
@@ -0,0 +1,20 @@
+package kubernetes
+
+import (
+	"strings"
+
+	"github.com/mbaitar/gco/agent/internal/provider"
+)
+
+// Kubernetes restricts the values of labels, which is why the encoded values of the labels of the agent are kept as
+// annotations. Only the labels identifying the resources are kept as labels.
+
+// selector returns the label selector matching all labels.
+func selector(labels ...provider.Label) string {
+	parts := make([]string, 0, len(labels))
+	for _, l := range labels {
+		parts = append(parts, l.String())
+	}
+
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,432 @@
+package kubernetes
+
+import (
+	"context"
+	"errors"
+
+	"github.com/mbaitar/gco/agent/internal/config"
+	"github.com/mbaitar/gco/agent/internal/log"
+	"github.com/mbaitar/gco/agent/internal/provider"
+	"github.com/mbaitar/gco/agent/internal/state"
+	"github.com/mbaitar/gco/agent/pkg/feature"
+	"github.com/mbaitar/gco/agent/pkg/resource"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// ErrPidsLimitUnsupported is returned for applications limiting the number of processes, which can only be configured per node.
+var ErrPidsLimitUnsupported = errors.New("pids limit is not supported by the kubernetes provider")
+
+// Provider defines a kubernetes provider which manages the resources within a single namespace of a cluster.
+// Applications are deployments exposed by a service, features are daemon sets or deployments.
+type Provider struct {
+	// client represents the kubernetes clientset
+	client    kubernetes.Interface
+	namespace string
+}
+
+func NewKubernetesProvider(conf config.KubernetesProvider) *Provider {
+	return &Provider{
+		client:    newKubernetesClient(conf.Kubeconfig),
+		namespace: conf.Namespace,
+	}
+}
+
+func (p *Provider) CreateApplication(app *resource.Application) error {
+	if err := validateApplication(app); err != nil {
+		return err
+	}
+
+	resources, err := fromApplicationResource(app, p.namespace)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	if resources.secret != nil {
+		if err = p.applySecret(ctx, app.Name, resources.secret); err != nil {
+			return err
+		}
+	}
+
+	_, err = p.client.AppsV1().Deployments(p.namespace).Create(ctx, resources.deployment, metav1.CreateOptions{})
+	if err != nil {
+		if resources.secret != nil {
+			p.warnOnError(app.Name, p.client.CoreV1().Secrets(p.namespace).Delete(ctx, resources.secret.Name, metav1.DeleteOptions{}))
+		}
+
+		return err
+	}
+
+	if resources.service != nil {
+		if _, err = p.client.CoreV1().Services(p.namespace).Create(ctx, resources.service, metav1.CreateOptions{}); err != nil {
+			// remove the partially created application, otherwise it would be reported as existing
+			p.warnOnError(app.Name, p.removeApplicationResources(app.Name))
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (p *Provider) UpdateApplication(app *resource.Application) error {
+	if err := validateApplication(app); err != nil {
+		return err
+	}
+
+	existing, err := p.getApplicationDeployment(app.Name)
+	if err != nil {
+		return err
+	}
+
+	if existing == nil {
+		return provider.ErrAppNotFound
+	}
+
+	resources, err := fromApplicationResource(app, p.namespace)
+	if err != nil {
+		return err
+	}
+
+	service, err := p.getService(app.Name)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	if err = p.applySecret(ctx, app.Name, resources.secret); err != nil {
+		return err
+	}
+
+	existing.Labels = resources.deployment.Labels
+	existing.Annotations = resources.deployment.Annotations
+	existing.Spec = resources.deployment.Spec
+	if _, err = p.client.AppsV1().Deployments(p.namespace).Update(ctx, existing, metav1.UpdateOptions{}); err != nil {
+		return err
+	}
+
+	return p.applyService(ctx, service, resources.service)
+}
+
+func (p *Provider) RemoveApplication(app *resource.Application) error {
+	existing, err := p.getApplicationDeployment(app.Name)
+	if err != nil {
+		return err
+	}
+
+	if existing == nil {
+		return provider.ErrAppNotFound
+	}
+
+	return p.removeApplicationResources(app.Name)
+}
+
+// validateApplication verifies that the application can be translated to the resources of the cluster.
+func validateApplication(app *resource.Application) error {
+	if err := provider.ValidateLabels(app); err != nil {
+		return err
+	}
+
+	if app.Resources != nil && app.Resources.PidsLimit > 0 {
+		return ErrPidsLimitUnsupported
+	}
+
+	return nil
+}
+
+// getReplicas returns the number of requested replicas of the deployment.
+func getReplicas(deployment *appsv1.Deployment) int {
+	if deployment.Spec.Replicas == nil {
+		return 1
+	}
+
+	return int(*deployment.Spec.Replicas)
+}
+
+func (p *Provider) CreateFeature(feat feature.Feature) error {
+	workloads, err := createFeatureWorkloads(feat, p.namespace)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	for _, workload := range workloads {
+		if err = p.createFeatureWorkload(ctx, workload); err != nil {
+			// a single remaining workload marks the feature as enabled in the actual state
+			p.warnOnError(feat.Name(), p.removeFeatureResources(feat.Name()))
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (p *Provider) UpdateFeature(feat feature.Feature) error {
+	if err := p.RemoveFeature(feat); err != nil {
+		return err
+	}
+
+	return p.CreateFeature(feat)
+}
+
+func (p *Provider) RemoveFeature(feat feature.Feature) error {
+	ctx := context.Background()
+	opts := metav1.ListOptions{LabelSelector: selector(provider.ManagedByLabel(), provider.FeatureLabel(feat.Name()))}
+
+	deployments, err := p.client.AppsV1().Deployments(p.namespace).List(ctx, opts)
+	if err != nil {
+		return err
+	}
+
+	daemonSets, err := p.client.AppsV1().DaemonSets(p.namespace).List(ctx, opts)
+	if err != nil {
+		return err
+	}
+
+	if len(deployments.Items) == 0 && len(daemonSets.Items) == 0 {
+		return provider.ErrFeatureNotFound
+	}
+
+	return p.removeFeatureResources(feat.Name())
+}
+
+func (p *Provider) ActualState() (*state.Spec, error) {
+	ctx := context.Background()
+	opts := metav1.ListOptions{LabelSelector: selector(provider.ManagedByLabel())}
+
+	deployments, err := p.client.AppsV1().Deployments(p.namespace).List(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	daemonSets, err := p.client.AppsV1().DaemonSets(p.namespace).List(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	services, err := p.client.CoreV1().Services(p.namespace).List(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	servicesByName := make(map[string]*corev1.Service, len(services.Items))
+	for idx := range services.Items {
+		servicesByName[services.Items[idx].Name] = &services.Items[idx]
+	}
+
+	applications := make([]resource.Application, 0)
+	features := state.Feature{}
+	for _, deployment := range deployments.Items {
+		switch resource.Kind(deployment.Labels[provider.KindLabelTag.String()]) {
+		case resource.ApplicationKind:
+			applications = append(applications, toApplicationResource(deployment, servicesByName[deployment.Name]))
+		case resource.FeatureKind:
+			addFeature(features, deployment.ObjectMeta)
+		}
+	}
+
+	for _, daemonSet := range daemonSets.Items {
+		addFeature(features, daemonSet.ObjectMeta)
+	}
+
+	spec := &state.Spec{
+		Applications: applications,
+		Feature:      features,
+	}
+
+	return spec, nil
+}
+
+// addFeature adds the feature of the workload to the features, using the configuration of its annotation.
+func addFeature(features state.Feature, meta metav1.ObjectMeta) {
+	featureName := meta.Labels[provider.FeatureLabelTag.String()]
+	def, found := feature.Lookup(featureName)
+	if !found {
+		log.Warnf("Ignoring workload of unknown feature=%s", featureName)
+		return
+	}
+
+	features[def.Name] = feature.DecodeFeature(meta.Annotations[provider.ConfigLabelTag.String()], def.New())
+}
+
+// getApplicationDeployment returns the deployment of the application, or nil when it does not exist.
+func (p *Provider) getApplicationDeployment(name string) (*appsv1.Deployment, error) {
+	deployment, err := p.client.AppsV1().Deployments(p.namespace).Get(context.Background(), name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	// a deployment with the same name which is not managed by the agent is not an application
+	if deployment.Labels[provider.ManagedByLabelTag.String()] != provider.ManagedByLabel().Value ||
+		deployment.Labels[provider.KindLabelTag.String()] != string(resource.ApplicationKind) {
+		return nil, nil
+	}
+
+	return deployment, nil
+}
+
+// getService returns the service with the name, or nil when it does not exist.
+func (p *Provider) getService(name string) (*corev1.Service, error) {
+	service, err := p.client.CoreV1().Services(p.namespace).Get(context.Background(), name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+
+	return service, err
+}
+
+// applySecret creates, updates or removes the secret of the application.
+func (p *Provider) applySecret(ctx context.Context, app string, secret *corev1.Secret) error {
+	secrets := p.client.CoreV1().Secrets(p.namespace)
+	if secret == nil {
+		return ignoreNotFound(secrets.Delete(ctx, secretName(app), metav1.DeleteOptions{}))
+	}
+
+	_, err := secrets.Update(ctx, secret, metav1.UpdateOptions{})
+	if apierrors.IsNotFound(err) {
+		_, err = secrets.Create(ctx, secret, metav1.CreateOptions{})
+	}
+
+	return err
+}
+
+// applyService creates, updates or removes the service of the application, the cluster IP of an existing service is kept.
+func (p *Provider) applyService(ctx context.Context, existing *corev1.Service, service *corev1.Service) error {
+	services := p.client.CoreV1().Services(p.namespace)
+	switch {
+	case existing == nil && service == nil:
+		return nil
+	case existing == nil:
+		_, err := services.Create(ctx, service, metav1.CreateOptions{})
+		return err
+	case service == nil:
+		return ignoreNotFound(services.Delete(ctx, existing.Name, metav1.DeleteOptions{}))
+	}
+
+	existing.Labels = service.Labels
+	existing.Spec.Selector = service.Spec.Selector
+	existing.Spec.Ports = service.Spec.Ports
+	_, err := services.Update(ctx, existing, metav1.UpdateOptions{})
+	return err
+}
+
+// removeApplicationResources removes the deployment, service and secret of the application.
+func (p *Provider) removeApplicationResources(name string) error {
+	ctx := context.Background()
+	err := ignoreNotFound(p.client.AppsV1().Deployments(p.namespace).Delete(ctx, name, deleteOptions()))
+	if err != nil {
+		return err
+	}
+
+	if err = ignoreNotFound(p.client.CoreV1().Services(p.namespace).Delete(ctx, name, metav1.DeleteOptions{})); err != nil {
+		return err
+	}
+
+	return ignoreNotFound(p.client.CoreV1().Secrets(p.namespace).Delete(ctx, secretName(name), metav1.DeleteOptions{}))
+}
+
+func (p *Provider) createFeatureWorkload(ctx context.Context, workload featureWorkload) error {
+	if workload.configMap != nil {
+		if _, err := p.client.CoreV1().ConfigMaps(p.namespace).Create(ctx, workload.configMap, metav1.CreateOptions{}); err != nil {
+			return err
+		}
+	}
+
+	if workload.daemonSet != nil {
+		if _, err := p.client.AppsV1().DaemonSets(p.namespace).Create(ctx, workload.daemonSet, metav1.CreateOptions{}); err != nil {
+			return err
+		}
+	}
+
+	if workload.deployment != nil {
+		if _, err := p.client.AppsV1().Deployments(p.namespace).Create(ctx, workload.deployment, metav1.CreateOptions{}); err != nil {
+			return err
+		}
+	}
+
+	if workload.service != nil {
+		if _, err := p.client.CoreV1().Services(p.namespace).Create(ctx, workload.service, metav1.CreateOptions{}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// removeFeatureResources removes the daemon sets, deployments, services and config maps of the feature.
+func (p *Provider) removeFeatureResources(name string) error {
+	ctx := context.Background()
+	opts := metav1.ListOptions{LabelSelector: selector(provider.ManagedByLabel(), provider.FeatureLabel(name))}
+
+	daemonSets, err := p.client.AppsV1().DaemonSets(p.namespace).List(ctx, opts)
+	if err != nil {
+		return err
+	}
+
+	for _, daemonSet := range daemonSets.Items {
+		if err = ignoreNotFound(p.client.AppsV1().DaemonSets(p.namespace).Delete(ctx, daemonSet.Name, deleteOptions())); err != nil {
+			return err
+		}
+	}
+
+	deployments, err := p.client.AppsV1().Deployments(p.namespace).List(ctx, opts)
+	if err != nil {
+		return err
+	}
+
+	for _, deployment := range deployments.Items {
+		if err = ignoreNotFound(p.client.AppsV1().Deployments(p.namespace).Delete(ctx, deployment.Name, deleteOptions())); err != nil {
+			return err
+		}
+	}
+
+	services, err := p.client.CoreV1().Services(p.namespace).List(ctx, opts)
+	if err != nil {
+		return err
+	}
+
+	for _, service := range services.Items {
+		if err = ignoreNotFound(p.client.CoreV1().Services(p.namespace).Delete(ctx, service.Name, metav1.DeleteOptions{})); err != nil {
+			return err
+		}
+	}
+
+	configMaps, err := p.client.CoreV1().ConfigMaps(p.namespace).List(ctx, opts)
+	if err != nil {
+		return err
+	}
+
+	for _, configMap := range configMaps.Items {
+		if err = ignoreNotFound(p.client.CoreV1().ConfigMaps(p.namespace).Delete(ctx, configMap.Name, metav1.DeleteOptions{})); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// deleteOptions removes the pods of a deployment or daemon set in the background.
+func deleteOptions() metav1.DeleteOptions {
+	propagation := metav1.DeletePropagationBackground
+	return metav1.DeleteOptions{PropagationPolicy: &propagation}
+}
+
+// warnOnError logs the error of removing partially created resources.
+func (p *Provider) warnOnError(name string, err error) {
+	if err != nil {
+		log.Warnf("Unable to remove partially created resources of %s: %v", name, err)
+	}
+}
+
+func ignoreNotFound(err error) error {
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+
+	return err
+}
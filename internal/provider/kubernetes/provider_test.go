@@ -0,0 +1,266 @@
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	providers "github.com/mbaitar/gco/agent/internal/provider"
+	"github.com/mbaitar/gco/agent/pkg/feature"
+	"github.com/mbaitar/gco/agent/pkg/resource"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func exampleApplication() *resource.Application {
+	return &resource.Application{
+		Name:      "nginx",
+		Instances: 2,
+		Image: resource.Image{
+			Name: "nginx",
+			Tag:  "latest",
+		},
+		Ports: []resource.Port{
+			{HostPort: 8080, ContainerPort: 80, Protocol: "tcp"},
+		},
+	}
+}
+
+func TestProvider_CreateApplication(t *testing.T) {
+	provider, client := NewTestProvider()
+
+	err := provider.CreateApplication(exampleApplication())
+	assert.Nil(t, err, "should not have thrown an error")
+
+	deployment := getDeployment(t, client, "nginx")
+	assert.Equal(t, 2, getReplicas(deployment))
+	assert.Equal(t, "gco", deployment.Labels[providers.ManagedByLabelTag.String()])
+	assert.Equal(t, "nginx:latest", deployment.Spec.Template.Spec.Containers[0].Image)
+
+	service := getService(t, client, "nginx")
+	if assert.Equal(t, 1, len(service.Spec.Ports)) {
+		assert.Equal(t, int32(8080), service.Spec.Ports[0].Port)
+		assert.Equal(t, 80, service.Spec.Ports[0].TargetPort.IntValue())
+	}
+	assert.Equal(t, deployment.Spec.Selector.MatchLabels, service.Spec.Selector)
+}
+
+func TestProvider_CreateApplication_withoutPorts(t *testing.T) {
+	provider, client := NewTestProvider()
+
+	app := exampleApplication()
+	app.Ports = nil
+	assert.Nil(t, provider.CreateApplication(app))
+
+	assert.Equal(t, 1, countObjects(t, client, "deployments"))
+	assert.Equal(t, 0, countObjects(t, client, "services"), "should not have created a service")
+}
+
+func TestProvider_CreateApplication_serviceError(t *testing.T) {
+	provider, client := NewTestProvider()
+	client.PrependReactor("create", "services", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.New("test error")
+	})
+
+	err := provider.CreateApplication(exampleApplication())
+	assert.NotNil(t, err, "should have thrown an error")
+	assert.Equal(t, 0, countObjects(t, client, "deployments"), "should have removed the partially created application")
+}
+
+func TestProvider_CreateApplication_unsupported(t *testing.T) {
+	provider, client := NewTestProvider()
+
+	app := exampleApplication()
+	app.Labels = map[string]string{"gco.io/name": "other"}
+	assert.ErrorIs(t, provider.CreateApplication(app), providers.ErrReservedLabel)
+
+	app = exampleApplication()
+	app.Resources = &resource.Resources{PidsLimit: 100}
+	assert.ErrorIs(t, provider.CreateApplication(app), ErrPidsLimitUnsupported)
+
+	assert.Equal(t, 0, countObjects(t, client, "deployments"))
+}
+
+func TestProvider_UpdateApplication(t *testing.T) {
+	provider, client := NewTestProvider()
+	assert.Nil(t, provider.CreateApplication(exampleApplication()))
+
+	app := exampleApplication()
+	app.Image.Tag = "1.23"
+	app.Ports = append(app.Ports, resource.Port{HostPort: 8443, ContainerPort: 443, Protocol: "tcp"})
+	err := provider.UpdateApplication(app)
+	assert.Nil(t, err, "should not have thrown an error")
+
+	deployment := getDeployment(t, client, "nginx")
+	assert.Equal(t, "nginx:1.23", deployment.Spec.Template.Spec.Containers[0].Image)
+	assert.Equal(t, 2, len(getService(t, client, "nginx").Spec.Ports))
+
+	// removing the ports removes the service
+	app.Ports = nil
+	assert.Nil(t, provider.UpdateApplication(app))
+	assert.Equal(t, 0, countObjects(t, client, "services"))
+}
+
+func TestProvider_UpdateApplication_unchanged(t *testing.T) {
+	provider, client := NewTestProvider()
+	assert.Nil(t, provider.CreateApplication(exampleApplication()))
+	template := getDeployment(t, client, "nginx").Spec.Template
+
+	// pods which are not ready yet are left to the deployment controller
+	assert.Nil(t, provider.UpdateApplication(exampleApplication()))
+	assert.Equal(t, template, getDeployment(t, client, "nginx").Spec.Template, "should not have redeployed the pods")
+
+	// scaling keeps the pods
+	app := exampleApplication()
+	app.Instances = 3
+	assert.Nil(t, provider.UpdateApplication(app))
+
+	deployment := getDeployment(t, client, "nginx")
+	assert.Equal(t, 3, getReplicas(deployment))
+	assert.Equal(t, template, deployment.Spec.Template)
+}
+
+func TestProvider_UpdateApplication_notFound(t *testing.T) {
+	// a deployment which is not managed by the agent is not updated
+	provider, _ := NewTestProvider(&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "nginx", Namespace: testNamespace}})
+
+	err := provider.UpdateApplication(exampleApplication())
+	assert.ErrorIs(t, err, providers.ErrAppNotFound)
+}
+
+func TestProvider_RemoveApplication(t *testing.T) {
+	provider, client := NewTestProvider()
+	assert.Nil(t, provider.CreateApplication(exampleApplication()))
+
+	err := provider.RemoveApplication(exampleApplication())
+	assert.Nil(t, err, "should not have thrown an error")
+	assert.Equal(t, 0, countObjects(t, client, "deployments"))
+	assert.Equal(t, 0, countObjects(t, client, "services"))
+
+	err = provider.RemoveApplication(exampleApplication())
+	assert.ErrorIs(t, err, providers.ErrAppNotFound)
+}
+
+func TestProvider_ActualState(t *testing.T) {
+	// resources which are not managed by the agent are ignored
+	provider, client := NewTestProvider(&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: testNamespace}})
+	assert.Nil(t, provider.CreateApplication(exampleApplication()))
+	assert.Nil(t, provider.CreateFeature(&feature.FluentBit{LogLevel: "info", Version: "2.0.0"}))
+
+	// pods which are not ready are reported as instances, the deployment controller replaces them
+	setReadyReplicas(t, client, "nginx", 0)
+
+	spec, err := provider.ActualState()
+	assert.Nil(t, err, "should not have thrown an error")
+	if assert.Equal(t, 1, len(spec.Applications), "should only contain the application") {
+		app := spec.Applications[0]
+		assert.Equal(t, "nginx", app.Name)
+		assert.Equal(t, 2, app.Instances, "should report the requested replicas as instances")
+		assert.Equal(t, exampleApplication().CalculateHash(), app.CalculateHash())
+	}
+
+	if assert.Equal(t, 1, len(spec.Feature)) {
+		fluentBit, ok := spec.Feature[feature.NameFluentBit].(*feature.FluentBit)
+		if assert.True(t, ok, "should have decoded the feature") {
+			assert.Equal(t, "2.0.0", fluentBit.Version)
+		}
+	}
+}
+
+func TestProvider_CreateFeature(t *testing.T) {
+	provider, client := NewTestProvider()
+	fluentBit := &feature.FluentBit{LogLevel: "info", Version: "2.0.0"}
+
+	err := provider.CreateFeature(fluentBit)
+	assert.Nil(t, err, "should not have thrown an error")
+
+	daemonSet, err := client.AppsV1().DaemonSets(testNamespace).Get(context.Background(), "gco-fluent-bit", metav1.GetOptions{})
+	if assert.Nil(t, err, "should run fluent-bit on every node") {
+		container := daemonSet.Spec.Template.Spec.Containers[0]
+		if assert.Equal(t, 1, len(container.Ports)) {
+			assert.Equal(t, int32(24224), container.Ports[0].HostPort, "should bind the port on the node")
+		}
+
+		mount := container.VolumeMounts[len(container.VolumeMounts)-1]
+		assert.Equal(t, "/fluent-bit/etc/fluent-bit.conf", mount.MountPath)
+		assert.Equal(t, "fluent-bit.conf", mount.SubPath)
+		assert.True(t, mount.ReadOnly)
+	}
+
+	configMap, err := client.CoreV1().ConfigMaps(testNamespace).Get(context.Background(), "gco-fluent-bit-config", metav1.GetOptions{})
+	if assert.Nil(t, err, "should have stored the configuration in a config map") {
+		assert.Equal(t, fluentBit.CreateConfig(), configMap.Data["fluent-bit.conf"])
+	}
+}
+
+func TestProvider_CreateFeature_replicated(t *testing.T) {
+	provider, client := NewTestProvider()
+
+	err := provider.CreateFeature(&TestFeature{Version: "1.0"})
+	assert.Nil(t, err, "should not have thrown an error")
+	assert.Equal(t, 1, countObjects(t, client, "daemonsets"), "should run the agent on every node")
+
+	server := getDeployment(t, client, "gco-test-feature-server")
+	assert.Equal(t, 1, getReplicas(server), "should run a single replica")
+	if container := server.Spec.Template.Spec.Containers[0]; assert.NotNil(t, container.SecurityContext) {
+		assert.Equal(t, int64(0), *container.SecurityContext.RunAsUser)
+	}
+	assert.Equal(t, 9090, int(getService(t, client, "gco-test-feature-server").Spec.Ports[0].Port))
+}
+
+func TestProvider_CreateFeature_rollback(t *testing.T) {
+	provider, client := NewTestProvider()
+	client.PrependReactor("create", "deployments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.New("test error")
+	})
+
+	err := provider.CreateFeature(&TestFeature{Version: "1.0"})
+	assert.NotNil(t, err, "should have thrown an error")
+	assert.Equal(t, 0, countObjects(t, client, "daemonsets"), "should have removed the partially created feature")
+	assert.Equal(t, 0, countObjects(t, client, "configmaps"))
+}
+
+func TestProvider_CreateFeature_unsupported(t *testing.T) {
+	provider, client := NewTestProvider()
+
+	// the nodes do not necessarily provide a docker socket
+	assert.ErrorIs(t, provider.CreateFeature(&feature.Ingress{}), providers.ErrFeatureNotSupported)
+	assert.ErrorIs(t, provider.CreateFeature(&feature.Monitoring{}), providers.ErrFeatureNotSupported)
+	assert.Equal(t, 0, countObjects(t, client, "daemonsets"))
+	assert.Equal(t, 0, countObjects(t, client, "deployments"))
+	assert.False(t, provider.SupportsFeature(feature.NameIngress))
+	assert.False(t, provider.SupportsFeature(feature.NameMonitoring))
+	assert.True(t, provider.SupportsFeature(feature.NameFluentBit))
+}
+
+func TestProvider_RemoveFeature(t *testing.T) {
+	provider, client := NewTestProvider()
+	testFeature := &TestFeature{Version: "1.0"}
+	assert.Nil(t, provider.CreateFeature(testFeature))
+
+	err := provider.RemoveFeature(testFeature)
+	assert.Nil(t, err, "should not have thrown an error")
+	for _, resource := range []string{"deployments", "daemonsets", "services", "configmaps"} {
+		assert.Equal(t, 0, countObjects(t, client, resource), "should have removed the %s", resource)
+	}
+
+	err = provider.RemoveFeature(testFeature)
+	assert.ErrorIs(t, err, providers.ErrFeatureNotFound)
+}
+
+func TestProvider_UpdateFeature(t *testing.T) {
+	provider, client := NewTestProvider()
+	assert.Nil(t, provider.CreateFeature(&feature.FluentBit{LogLevel: "info", Version: "2.0.0"}))
+
+	fluentBit := &feature.FluentBit{LogLevel: "debug", Version: "2.0.0"}
+	err := provider.UpdateFeature(fluentBit)
+	assert.Nil(t, err, "should not have thrown an error")
+
+	configMap, err := client.CoreV1().ConfigMaps(testNamespace).Get(context.Background(), "gco-fluent-bit-config", metav1.GetOptions{})
+	if assert.Nil(t, err) {
+		assert.Equal(t, fluentBit.CreateConfig(), configMap.Data["fluent-bit.conf"], "should have replaced the configuration")
+	}
+}
@@ -0,0 +1,524 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/mbaitar/gco/agent/internal/files"
+	"github.com/mbaitar/gco/agent/internal/provider"
+	"github.com/mbaitar/gco/agent/pkg/resource"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiresource "k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// applicationResources contains the kubernetes resources of an application, the service and secret are nil
+// when the application does not define any ports or secret references.
+type applicationResources struct {
+	deployment *appsv1.Deployment
+	service    *corev1.Service
+	secret     *corev1.Secret
+}
+
+// fromApplicationResource creates the resources of the application. The instances of the application are the
+// replicas of the deployment, the ports are exposed by a service and referenced secrets are kept in a secret.
+func fromApplicationResource(app *resource.Application, namespace string) (applicationResources, error) {
+	labels := make(map[string]string)
+	provider.AddLabel(labels, provider.ManagedByLabel())
+	provider.AddLabel(labels, provider.KindLabel(resource.ApplicationKind))
+	provider.AddLabel(labels, provider.NameLabel(app.Name))
+	selectorLabels := provider.CopyLabels(labels)
+
+	annotations := make(map[string]string)
+	env, secret, err := toEnv(app, namespace, labels)
+	if err != nil {
+		return applicationResources{}, err
+	}
+
+	// keep track of the managed environment variables and secret references
+	if len(app.Env) > 0 {
+		keys := make([]string, 0, len(app.Env))
+		for key, value := range app.Env {
+			keys = append(keys, key)
+			if resource.IsSecretReference(value) {
+				provider.AddLabel(annotations, provider.SecretLabel(key, value))
+			}
+		}
+
+		sort.Strings(keys)
+		provider.AddLabel(annotations, provider.EnvLabel(keys))
+	}
+
+	// the additional labels are kept as annotations of the deployment and its pods, as their values are not restricted
+	// to the label syntax of kubernetes. Labels using the platform prefix are rejected by the validation.
+	podAnnotations := make(map[string]string, len(app.Labels))
+	if len(app.Labels) > 0 {
+		keys := make([]string, 0, len(app.Labels))
+		for key, value := range app.Labels {
+			if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+				return applicationResources{}, fmt.Errorf("invalid label key '%s': %s", key, strings.Join(errs, ", "))
+			}
+
+			annotations[key] = value
+			podAnnotations[key] = value
+			keys = append(keys, key)
+		}
+
+		sort.Strings(keys)
+		provider.AddLabel(annotations, provider.LabelsLabel(keys))
+	}
+
+	if app.Ingress != nil {
+		encoded, _ := json.Marshal(app.Ingress)
+		provider.AddLabel(annotations, provider.IngressLabel(string(encoded)))
+	}
+
+	if app.Metrics != nil {
+		encoded, _ := json.Marshal(app.Metrics)
+		provider.AddLabel(annotations, provider.MetricsLabel(string(encoded)))
+	}
+
+	if app.HealthCheck != nil {
+		encoded, _ := json.Marshal(app.HealthCheck)
+		provider.AddLabel(annotations, provider.HealthLabel(string(encoded)))
+	}
+
+	// the restart policy is kept in an annotation as the pods of a deployment are always restarted
+	if !app.RestartPolicy.IsDefault() {
+		encoded, _ := json.Marshal(app.RestartPolicy)
+		provider.AddLabel(annotations, provider.RestartLabel(string(encoded)))
+	}
+
+	volumes, mounts := toVolumes(app.Mounts)
+	probe := toProbe(app.HealthCheck)
+	replicas := int32(app.GetInstances())
+
+	resources := applicationResources{
+		deployment: &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: app.Name, Namespace: namespace, Labels: labels, Annotations: annotations},
+			Spec: appsv1.DeploymentSpec{
+				Replicas: &replicas,
+				Selector: &metav1.LabelSelector{MatchLabels: selectorLabels},
+				Strategy: toDeploymentStrategy(app.GetUpdateStrategy()),
+				Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{Labels: provider.CopyLabels(labels), Annotations: podAnnotations},
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{{
+							Name:           app.Name,
+							Image:          fmt.Sprintf("%s:%s", app.Image.Name, app.Image.Tag),
+							Env:            env,
+							Ports:          toContainerPorts(app.Ports, false),
+							VolumeMounts:   mounts,
+							Resources:      toResourceRequirements(app.Resources),
+							LivenessProbe:  probe,
+							ReadinessProbe: probe,
+						}},
+						Volumes: volumes,
+					},
+				},
+			},
+		},
+		secret: secret,
+	}
+
+	if len(app.Ports) > 0 {
+		resources.service = &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: app.Name, Namespace: namespace, Labels: provider.CopyLabels(selectorLabels)},
+			Spec: corev1.ServiceSpec{
+				Type:     corev1.ServiceTypeClusterIP,
+				Selector: provider.CopyLabels(selectorLabels),
+				Ports:    toServicePorts(app.Ports),
+			},
+		}
+	}
+
+	return resources, nil
+}
+
+// toApplicationResource creates the application from the deployment and its service. The requested replicas are the
+// instances, the deployment controller replaces pods which are missing or not ready.
+func toApplicationResource(deployment appsv1.Deployment, service *corev1.Service) resource.Application {
+	annotations := deployment.Annotations
+	app := resource.Application{
+		Name:      deployment.Labels[provider.NameLabelTag.String()],
+		Instances: getReplicas(&deployment),
+		Labels:    getLabelResources(deployment.Labels, annotations),
+		Ports:     make([]resource.Port, 0),
+	}
+
+	podSpec := deployment.Spec.Template.Spec
+	if len(podSpec.Containers) > 0 {
+		container := podSpec.Containers[0]
+		app.Image = toImageResource(container.Image)
+		app.Mounts = toMountResources(podSpec.Volumes, container.VolumeMounts)
+		app.Env = getEnvResources(annotations, container.Env)
+		app.Resources = toResources(container.Resources)
+	}
+
+	if service != nil {
+		app.Ports = toPortResources(service.Spec.Ports)
+	}
+
+	// the health check definition is kept in an annotation as the probes can not be translated back
+	if encoded := annotations[provider.HealthLabelTag.String()]; encoded != "" {
+		health := &resource.HealthCheck{}
+		if err := json.Unmarshal([]byte(encoded), health); err == nil {
+			app.HealthCheck = health
+		}
+	}
+
+	if encoded := annotations[provider.IngressLabelTag.String()]; encoded != "" {
+		ingress := &resource.Ingress{}
+		if err := json.Unmarshal([]byte(encoded), ingress); err == nil {
+			app.Ingress = ingress
+		}
+	}
+
+	if encoded := annotations[provider.MetricsLabelTag.String()]; encoded != "" {
+		metrics := &resource.Metrics{}
+		if err := json.Unmarshal([]byte(encoded), metrics); err == nil {
+			app.Metrics = metrics
+		}
+	}
+
+	if encoded := annotations[provider.RestartLabelTag.String()]; encoded != "" {
+		restart := &resource.RestartPolicy{}
+		if err := json.Unmarshal([]byte(encoded), restart); err == nil {
+			app.RestartPolicy = restart
+		}
+	}
+
+	return app
+}
+
+// toImageResource parses the image reference, the tag follows the last colon unless it is part of the registry address.
+func toImageResource(image string) resource.Image {
+	if idx := strings.Index(image, "@"); idx >= 0 {
+		image = image[:idx]
+	}
+
+	idx := strings.LastIndex(image, ":")
+	if idx < 0 || strings.Contains(image[idx:], "/") {
+		return resource.Image{Name: image, Tag: "latest"}
+	}
+
+	return resource.Image{Name: image[:idx], Tag: image[idx+1:]}
+}
+
+// toEnv returns the environment variables of the container, secret references are resolved into the secret of
+// the application which is referenced by the environment variable. The secret is nil when nothing is referenced.
+func toEnv(app *resource.Application, namespace string, labels map[string]string) ([]corev1.EnvVar, *corev1.Secret, error) {
+	keys := make([]string, 0, len(app.Env))
+	for key := range app.Env {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var secret *corev1.Secret
+	env := make([]corev1.EnvVar, 0, len(keys))
+	for _, key := range keys {
+		value := app.Env[key]
+		if !resource.IsSecretReference(value) {
+			env = append(env, corev1.EnvVar{Name: key, Value: value})
+			continue
+		}
+
+		resolved, err := files.ReadSecret(resource.SecretName(value))
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if secret == nil {
+			secret = &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: secretName(app.Name), Namespace: namespace, Labels: provider.CopyLabels(labels)},
+				Type:       corev1.SecretTypeOpaque,
+				Data:       make(map[string][]byte),
+			}
+		}
+
+		secret.Data[key] = []byte(resolved)
+		env = append(env, corev1.EnvVar{Name: key, ValueFrom: &corev1.EnvVarSource{
+			SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: secretName(app.Name)},
+				Key:                  key,
+			},
+		}})
+	}
+
+	return env, secret, nil
+}
+
+// secretName returns the name of the secret containing the resolved secret references of the application.
+func secretName(app string) string {
+	return fmt.Sprintf("%s-secrets", app)
+}
+
+// getEnvResources returns the environment variables managed by the agent, secrets are replaced by their reference.
+func getEnvResources(annotations map[string]string, env []corev1.EnvVar) map[string]string {
+	managed := annotations[provider.EnvLabelTag.String()]
+	if managed == "" {
+		return nil
+	}
+
+	values := make(map[string]string, len(env))
+	for _, entry := range env {
+		values[entry.Name] = entry.Value
+	}
+
+	resources := make(map[string]string)
+	for _, key := range strings.Split(managed, ",") {
+		if reference := annotations[provider.SecretLabelTag(key).String()]; reference != "" {
+			resources[key] = reference
+		} else {
+			resources[key] = values[key]
+		}
+	}
+
+	return resources
+}
+
+// getLabelResources returns the additional labels of the application, which are kept as annotations. Deployments
+// created by previous versions of the agent keep them as labels.
+func getLabelResources(labels map[string]string, annotations map[string]string) map[string]string {
+	managed := annotations[provider.LabelsLabelTag.String()]
+	if managed == "" {
+		return nil
+	}
+
+	resources := make(map[string]string)
+	for _, key := range strings.Split(managed, ",") {
+		if value, found := annotations[key]; found {
+			resources[key] = value
+		} else {
+			resources[key] = labels[key]
+		}
+	}
+
+	return resources
+}
+
+// toContainerPorts returns the ports of the container, the host ports are only bound by the
+// workloads of features which run on every node.
+func toContainerPorts(ports []resource.Port, bindHost bool) []corev1.ContainerPort {
+	if len(ports) == 0 {
+		return nil
+	}
+
+	converted := make([]corev1.ContainerPort, 0, len(ports))
+	for _, port := range ports {
+		containerPort := corev1.ContainerPort{
+			ContainerPort: int32(port.ContainerPort),
+			Protocol:      toProtocol(port.Protocol),
+		}
+
+		if bindHost {
+			containerPort.HostPort = int32(port.HostPort)
+		}
+
+		converted = append(converted, containerPort)
+	}
+
+	return converted
+}
+
+// toServicePorts exposes the container ports using the host port as port of the service.
+func toServicePorts(ports []resource.Port) []corev1.ServicePort {
+	converted := make([]corev1.ServicePort, 0, len(ports))
+	for _, port := range ports {
+		protocol := toProtocol(port.Protocol)
+		converted = append(converted, corev1.ServicePort{
+			Name:       fmt.Sprintf("%s-%d", strings.ToLower(string(protocol)), port.HostPort),
+			Protocol:   protocol,
+			Port:       int32(port.HostPort),
+			TargetPort: intstr.FromInt(int(port.ContainerPort)),
+		})
+	}
+
+	return converted
+}
+
+func toPortResources(ports []corev1.ServicePort) []resource.Port {
+	resources := make([]resource.Port, 0, len(ports))
+	for _, port := range ports {
+		resources = append(resources, resource.Port{
+			ContainerPort: uint16(port.TargetPort.IntValue()),
+			HostPort:      uint16(port.Port),
+			Protocol:      resource.Protocol(strings.ToLower(string(port.Protocol))),
+		})
+	}
+
+	return resources
+}
+
+func toProtocol(protocol resource.Protocol) corev1.Protocol {
+	if protocol == resource.UdpProtocol {
+		return corev1.ProtocolUDP
+	}
+
+	return corev1.ProtocolTCP
+}
+
+// toVolumes translates the mounts, volumes are persistent volume claims which have to exist within the namespace,
+// binds are host paths and tmpfs mounts are empty directories kept in memory.
+func toVolumes(mounts []resource.Mount) ([]corev1.Volume, []corev1.VolumeMount) {
+	if len(mounts) == 0 {
+		return nil, nil
+	}
+
+	volumes := make([]corev1.Volume, 0, len(mounts))
+	volumeMounts := make([]corev1.VolumeMount, 0, len(mounts))
+	for idx, m := range mounts {
+		volume := corev1.Volume{Name: fmt.Sprintf("mount-%d", idx)}
+		switch m.Type {
+		case resource.VolumeMountType:
+			volume.PersistentVolumeClaim = &corev1.PersistentVolumeClaimVolumeSource{ClaimName: m.Source, ReadOnly: m.ReadOnly}
+		case resource.BindMountType:
+			volume.HostPath = &corev1.HostPathVolumeSource{Path: m.Source}
+		case resource.TmpfsMountType:
+			volume.EmptyDir = &corev1.EmptyDirVolumeSource{Medium: corev1.StorageMediumMemory}
+		default:
+			continue
+		}
+
+		volumes = append(volumes, volume)
+		volumeMounts = append(volumeMounts, corev1.VolumeMount{Name: volume.Name, MountPath: m.Target, ReadOnly: m.ReadOnly})
+	}
+
+	return volumes, volumeMounts
+}
+
+func toMountResources(volumes []corev1.Volume, volumeMounts []corev1.VolumeMount) []resource.Mount {
+	if len(volumeMounts) == 0 {
+		return nil
+	}
+
+	byName := make(map[string]corev1.Volume, len(volumes))
+	for _, volume := range volumes {
+		byName[volume.Name] = volume
+	}
+
+	converted := make([]resource.Mount, 0, len(volumeMounts))
+	for _, volumeMount := range volumeMounts {
+		volume, found := byName[volumeMount.Name]
+		if !found {
+			continue
+		}
+
+		m := resource.Mount{Target: volumeMount.MountPath, ReadOnly: volumeMount.ReadOnly}
+		switch {
+		case volume.PersistentVolumeClaim != nil:
+			m.Type = resource.VolumeMountType
+			m.Source = volume.PersistentVolumeClaim.ClaimName
+		case volume.HostPath != nil:
+			m.Type = resource.BindMountType
+			m.Source = volume.HostPath.Path
+		case volume.EmptyDir != nil:
+			m.Type = resource.TmpfsMountType
+		default:
+			continue
+		}
+
+		converted = append(converted, m)
+	}
+
+	return converted
+}
+
+// toResourceRequirements translates the limits, the memory reservation is the requested memory.
+func toResourceRequirements(resources *resource.Resources) corev1.ResourceRequirements {
+	requirements := corev1.ResourceRequirements{}
+	if resources.IsEmpty() {
+		return requirements
+	}
+
+	limits := corev1.ResourceList{}
+	if resources.CPULimit > 0 {
+		limits[corev1.ResourceCPU] = *apiresource.NewMilliQuantity(int64(math.Round(resources.CPULimit*1000)), apiresource.DecimalSI)
+	}
+
+	if resources.MemoryLimit > 0 {
+		limits[corev1.ResourceMemory] = *apiresource.NewQuantity(resources.MemoryLimit, apiresource.BinarySI)
+	}
+
+	if len(limits) > 0 {
+		requirements.Limits = limits
+	}
+
+	if resources.MemoryReservation > 0 {
+		requirements.Requests = corev1.ResourceList{
+			corev1.ResourceMemory: *apiresource.NewQuantity(resources.MemoryReservation, apiresource.BinarySI),
+		}
+	}
+
+	return requirements
+}
+
+func toResources(requirements corev1.ResourceRequirements) *resource.Resources {
+	resources := &resource.Resources{}
+	if cpu, found := requirements.Limits[corev1.ResourceCPU]; found {
+		resources.CPULimit = float64(cpu.MilliValue()) / 1000
+	}
+
+	if memory, found := requirements.Limits[corev1.ResourceMemory]; found {
+		resources.MemoryLimit = memory.Value()
+	}
+
+	if memory, found := requirements.Requests[corev1.ResourceMemory]; found {
+		resources.MemoryReservation = memory.Value()
+	}
+
+	if resources.IsEmpty() {
+		return nil
+	}
+
+	return resources
+}
+
+// toProbe translates the health check to a probe, which is used to restart unhealthy pods and to
+// only count ready pods as instances. Unlike the docker health check the probes are executed by the kubelet.
+func toProbe(check *resource.HealthCheck) *corev1.Probe {
+	if check == nil {
+		return nil
+	}
+
+	probe := &corev1.Probe{
+		PeriodSeconds:       int32(check.Interval),
+		TimeoutSeconds:      int32(check.Timeout),
+		FailureThreshold:    int32(check.Retries),
+		InitialDelaySeconds: int32(check.StartPeriod),
+	}
+
+	switch check.Type {
+	case resource.CommandHealthCheck:
+		probe.Exec = &corev1.ExecAction{Command: check.Command}
+	case resource.HttpHealthCheck:
+		probe.HTTPGet = &corev1.HTTPGetAction{Path: check.Path, Port: intstr.FromInt(int(check.Port))}
+	case resource.TcpHealthCheck:
+		probe.TCPSocket = &corev1.TCPSocketAction{Port: intstr.FromInt(int(check.Port))}
+	default:
+		return nil
+	}
+
+	return probe
+}
+
+// toDeploymentStrategy translates the update strategy, a rolling update replaces one pod at a time
+// starting the new pod first, a recreate stops all pods before starting the new ones.
+func toDeploymentStrategy(strategy resource.UpdateStrategy) appsv1.DeploymentStrategy {
+	if strategy == resource.RollingUpdateStrategy {
+		maxSurge := intstr.FromInt(1)
+		maxUnavailable := intstr.FromInt(0)
+		return appsv1.DeploymentStrategy{
+			Type:          appsv1.RollingUpdateDeploymentStrategyType,
+			RollingUpdate: &appsv1.RollingUpdateDeployment{MaxSurge: &maxSurge, MaxUnavailable: &maxUnavailable},
+		}
+	}
+
+	return appsv1.DeploymentStrategy{Type: appsv1.RecreateDeploymentStrategyType}
+}
@@ -0,0 +1,111 @@
+package kubernetes
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/mbaitar/gco/agent/internal/files"
+	"github.com/mbaitar/gco/agent/pkg/resource"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestFromApplicationResource(t *testing.T) {
+	files.SetDirectory(t.TempDir())
+	dir, _ := files.GetDirectory()
+	_ = os.MkdirAll(path.Join(dir, "secrets"), 0700)
+	_ = os.WriteFile(path.Join(dir, "secrets", "postgres-password"), []byte("plaintext\n"), 0600)
+
+	application := &resource.Application{
+		Name:      "postgres",
+		Instances: 2,
+		Image:     resource.Image{Name: "registry.example.com:5000/postgres", Tag: "15"},
+		Ports:     []resource.Port{{HostPort: 5432, ContainerPort: 5432, Protocol: "tcp"}},
+		Env: map[string]string{
+			"POSTGRES_USER":     "admin",
+			"POSTGRES_PASSWORD": "secret:postgres-password",
+		},
+		Mounts: []resource.Mount{
+			{Type: resource.VolumeMountType, Source: "postgres-data", Target: "/var/lib/postgresql/data"},
+			{Type: resource.BindMountType, Source: "/etc/postgres", Target: "/etc/postgresql", ReadOnly: true},
+			{Type: resource.TmpfsMountType, Target: "/tmp"},
+		},
+		Resources:     &resource.Resources{CPULimit: 0.5, MemoryLimit: 256 * 1024 * 1024, MemoryReservation: 128 * 1024 * 1024},
+		HealthCheck:   &resource.HealthCheck{Type: resource.TcpHealthCheck, Port: 5432, Interval: 10, Timeout: 2, Retries: 3},
+		RestartPolicy: &resource.RestartPolicy{Name: resource.OnFailureRestartPolicy, MaxRetries: 5},
+		Ingress:       &resource.Ingress{Hosts: []string{"example.com"}, Port: 5432},
+		Labels:        map[string]string{"team": "storage", "traefik.http.routers.postgres.rule": "Host(`example.com`)"},
+	}
+
+	resources, err := fromApplicationResource(application, testNamespace)
+	if !assert.Nil(t, err, "should not have thrown an error") {
+		return
+	}
+
+	deployment := resources.deployment
+	container := deployment.Spec.Template.Spec.Containers[0]
+	assert.Equal(t, "storage", deployment.Spec.Template.Annotations["team"], "should have added the label to the pods")
+	assert.NotContains(t, deployment.Spec.Template.Labels, "traefik.http.routers.postgres.rule", "should not use free-form values as labels")
+	assert.NotContains(t, deployment.Labels, "team", "should keep the additional labels as annotations")
+	assert.NotContains(t, deployment.Spec.Selector.MatchLabels, "team", "should not select pods using additional labels")
+	assert.Equal(t, int32(10), container.LivenessProbe.PeriodSeconds)
+	assert.NotNil(t, container.ReadinessProbe.TCPSocket)
+
+	// the secret is not part of the deployment
+	if assert.NotNil(t, resources.secret) {
+		assert.Equal(t, []byte("plaintext"), resources.secret.Data["POSTGRES_PASSWORD"])
+	}
+	assert.Equal(t, []corev1.EnvVar{
+		{Name: "POSTGRES_PASSWORD", ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+			LocalObjectReference: corev1.LocalObjectReference{Name: "postgres-secrets"}, Key: "POSTGRES_PASSWORD",
+		}}},
+		{Name: "POSTGRES_USER", Value: "admin"},
+	}, container.Env)
+
+	// read the application back
+	parsed := toApplicationResource(*deployment, resources.service)
+	assert.Equal(t, application.Env, parsed.Env, "should only include managed env and secret references")
+	assert.Equal(t, application.Mounts, parsed.Mounts)
+	assert.Equal(t, application.Resources, parsed.Resources)
+	assert.Equal(t, application.Ports, parsed.Ports)
+	assert.Equal(t, application.Labels, parsed.Labels)
+	assert.Equal(t, application.GetInstances(), parsed.Instances)
+	assert.Equal(t, application.CalculateHash(), parsed.CalculateHash())
+
+	application.Labels = map[string]string{"team/": "storage"}
+	_, err = fromApplicationResource(application, testNamespace)
+	assert.NotNil(t, err, "should have rejected an invalid label key")
+}
+
+func TestToApplicationResource_drift(t *testing.T) {
+	application := &resource.Application{
+		Name:      "nginx",
+		Image:     resource.Image{Name: "nginx", Tag: "latest"},
+		Resources: &resource.Resources{MemoryLimit: 256 * 1024 * 1024},
+	}
+
+	resources, err := fromApplicationResource(application, testNamespace)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	// changes made within the cluster should be detected as drift
+	deployment := *resources.deployment
+	deployment.Spec.Template.Spec.Containers[0].Image = "nginx:1.23"
+	parsed := toApplicationResource(deployment, nil)
+	assert.Equal(t, resource.Image{Name: "nginx", Tag: "1.23"}, parsed.Image)
+	assert.NotEqual(t, application.CalculateHash(), parsed.CalculateHash())
+}
+
+func TestToDeploymentStrategy(t *testing.T) {
+	assert.Equal(t, appsv1.RollingUpdateDeploymentStrategyType, toDeploymentStrategy(resource.RollingUpdateStrategy).Type)
+	assert.Equal(t, appsv1.RecreateDeploymentStrategyType, toDeploymentStrategy(resource.RecreateUpdateStrategy).Type)
+}
+
+func TestToImageResource(t *testing.T) {
+	assert.Equal(t, resource.Image{Name: "nginx", Tag: "latest"}, toImageResource("nginx"))
+	assert.Equal(t, resource.Image{Name: "nginx", Tag: "1.23"}, toImageResource("nginx:1.23@sha256:abc"))
+	assert.Equal(t, resource.Image{Name: "localhost:5000/nginx", Tag: "latest"}, toImageResource("localhost:5000/nginx"))
+}
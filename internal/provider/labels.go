@@ -0,0 +1,136 @@
+package provider
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/mbaitar/gco/agent/pkg/resource"
+)
+
+// LabelPrefix is the prefix used for the labels associated with GCO, which is shared by every provider.
+const LabelPrefix = "gco.io"
+
+// LabelTag is the key of a label.
+type LabelTag string
+
+func (t LabelTag) String() string {
+	return string(t)
+}
+
+// PlatformLabelTag returns the tag of the key within the label prefix reserved for the agent.
+func PlatformLabelTag(key string) LabelTag {
+	return LabelTag(fmt.Sprintf("%s/%s", LabelPrefix, key))
+}
+
+// Label is a label attached to the resources managed by a provider.
+type Label struct {
+	Tag   LabelTag
+	Value string
+}
+
+func (l Label) String() string {
+	return fmt.Sprintf("%s=%s", l.Tag.String(), l.Value)
+}
+
+var (
+	ManagedByLabelTag = PlatformLabelTag("managed-by")
+	NameLabelTag      = PlatformLabelTag("name")
+	KindLabelTag      = PlatformLabelTag("kind")
+	FeatureLabelTag   = PlatformLabelTag("feature")
+	ConfigLabelTag    = PlatformLabelTag("config")
+	InstanceLabelTag  = PlatformLabelTag("instance")
+	EnvLabelTag       = PlatformLabelTag("env")
+	HealthLabelTag    = PlatformLabelTag("health-check")
+	IngressLabelTag   = PlatformLabelTag("ingress")
+	MetricsLabelTag   = PlatformLabelTag("metrics")
+	LabelsLabelTag    = PlatformLabelTag("labels")
+	RestartLabelTag   = PlatformLabelTag("restart-policy")
+)
+
+func ManagedByLabel() Label {
+	return Label{Tag: ManagedByLabelTag, Value: "gco"}
+}
+
+func NameLabel(name string) Label {
+	return Label{Tag: NameLabelTag, Value: name}
+}
+
+func KindLabel(kind resource.Kind) Label {
+	return Label{Tag: KindLabelTag, Value: string(kind)}
+}
+
+func FeatureLabel(name string) Label {
+	return Label{Tag: FeatureLabelTag, Value: name}
+}
+
+func ConfigLabel(value string) Label {
+	return Label{Tag: ConfigLabelTag, Value: value}
+}
+
+func InstanceLabel(ordinal int) Label {
+	return Label{Tag: InstanceLabelTag, Value: strconv.Itoa(ordinal)}
+}
+
+func EnvLabel(keys []string) Label {
+	return Label{Tag: EnvLabelTag, Value: strings.Join(keys, ",")}
+}
+
+func SecretLabelTag(key string) LabelTag {
+	return PlatformLabelTag(fmt.Sprintf("secret.%s", key))
+}
+
+func SecretLabel(key string, reference string) Label {
+	return Label{Tag: SecretLabelTag(key), Value: reference}
+}
+
+func HealthLabel(value string) Label {
+	return Label{Tag: HealthLabelTag, Value: value}
+}
+
+func IngressLabel(value string) Label {
+	return Label{Tag: IngressLabelTag, Value: value}
+}
+
+func MetricsLabel(value string) Label {
+	return Label{Tag: MetricsLabelTag, Value: value}
+}
+
+func LabelsLabel(keys []string) Label {
+	return Label{Tag: LabelsLabelTag, Value: strings.Join(keys, ",")}
+}
+
+func RestartLabel(value string) Label {
+	return Label{Tag: RestartLabelTag, Value: value}
+}
+
+// IsPlatformLabel returns true when the key uses the label prefix reserved for the agent.
+func IsPlatformLabel(key string) bool {
+	return strings.HasPrefix(key, LabelPrefix+"/")
+}
+
+// ValidateLabels verifies that the additional labels of the application do not use the reserved label prefix.
+func ValidateLabels(app *resource.Application) error {
+	for key := range app.Labels {
+		if IsPlatformLabel(key) {
+			return fmt.Errorf("%w: %s", ErrReservedLabel, key)
+		}
+	}
+
+	return nil
+}
+
+// AddLabel adds the label to the labels.
+func AddLabel(labels map[string]string, label Label) {
+	labels[label.Tag.String()] = label.Value
+}
+
+// CopyLabels returns a copy of the labels, e.g. to attach the same labels to multiple resources.
+func CopyLabels(labels map[string]string) map[string]string {
+	copied := make(map[string]string, len(labels))
+	for key, value := range labels {
+		copied[key] = value
+	}
+
+	return copied
+}
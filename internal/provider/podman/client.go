@@ -0,0 +1,396 @@
+package podman
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/network"
+	"github.com/mbaitar/gco/agent/internal/log"
+	"github.com/mbaitar/gco/agent/internal/provider"
+	v1 "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// apiPath is the versioned path of the libpod API.
+const apiPath = "/v4.0.0/libpod"
+
+// podName is the name of the pod grouping the managed containers.
+const podName = "gco"
+
+// ErrPrivilegedPort is returned when a rootless podman is requested to publish a privileged host port.
+var ErrPrivilegedPort = errors.New("rootless podman can not publish privileged host ports")
+
+// libpodClient implements the docker client calls of the docker provider using the libpod API.
+type libpodClient struct {
+	http    *http.Client
+	baseURL string
+	// pod is the name of the pod the containers are created in, containers are not grouped when empty.
+	pod string
+	// rootless is true when podman runs without root privileges.
+	rootless bool
+	// unprivilegedPortStart is the first host port which can be published by a rootless podman.
+	unprivilegedPortStart int
+}
+
+// newLibpodClient creates a client using the unix socket, the default socket of the current user is used when empty.
+func newLibpodClient(socket string) *libpodClient {
+	if socket == "" {
+		socket = defaultSocket()
+	}
+
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			dialer := net.Dialer{}
+			return dialer.DialContext(ctx, "unix", socket)
+		},
+	}
+
+	return &libpodClient{
+		http:    &http.Client{Transport: transport},
+		baseURL: "http://podman" + apiPath,
+	}
+}
+
+// defaultSocket returns the socket from CONTAINER_HOST, the rootless socket of the user or the system socket for root.
+func defaultSocket() string {
+	if host := os.Getenv("CONTAINER_HOST"); strings.HasPrefix(host, "unix://") {
+		return strings.TrimPrefix(host, "unix://")
+	}
+
+	if os.Geteuid() == 0 {
+		return "/run/podman/podman.sock"
+	}
+
+	runtimeDir := os.Getenv("XDG_RUNTIME_DIR")
+	if runtimeDir == "" {
+		runtimeDir = fmt.Sprintf("/run/user/%d", os.Geteuid())
+	}
+
+	return filepath.Join(runtimeDir, "podman", "podman.sock")
+}
+
+// apiError is an error response of the libpod API.
+type apiError struct {
+	StatusCode int    `json:"response"`
+	Message    string `json:"message"`
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("podman: %s (status %d)", e.Message, e.StatusCode)
+}
+
+// notFoundError is a not found response of the libpod API, marked as not found for the docker errdefs.
+type notFoundError struct {
+	*apiError
+}
+
+func (e notFoundError) NotFound() {}
+
+// do sends the request, the body is encoded and the response decoded as JSON when not nil.
+func (c *libpodClient) do(ctx context.Context, method string, path string, query url.Values, body interface{}, result interface{}) error {
+	response, err := c.request(ctx, method, path, query, body)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if result == nil {
+		return nil
+	}
+
+	return json.NewDecoder(response.Body).Decode(result)
+}
+
+// request sends the request and returns the response, the caller has to close the body of successful responses.
+func (c *libpodClient) request(ctx context.Context, method string, path string, query url.Values, body interface{}) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+
+		reader = bytes.NewReader(encoded)
+	}
+
+	target := c.baseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	request, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return nil, err
+	}
+
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+
+	response, err := c.http.Do(request)
+	if err != nil {
+		return nil, err
+	}
+
+	if response.StatusCode >= http.StatusBadRequest {
+		defer response.Body.Close()
+
+		apiErr := &apiError{}
+		if err := json.NewDecoder(response.Body).Decode(apiErr); err != nil || apiErr.Message == "" {
+			apiErr.Message = http.StatusText(response.StatusCode)
+		}
+
+		apiErr.StatusCode = response.StatusCode
+		if apiErr.StatusCode == http.StatusNotFound {
+			return nil, notFoundError{apiErr}
+		}
+
+		return nil, apiErr
+	}
+
+	return response, nil
+}
+
+// isRootless returns true when podman runs without root privileges.
+func (c *libpodClient) isRootless(ctx context.Context) (bool, error) {
+	result := &info{}
+	if err := c.do(ctx, http.MethodGet, "/info", nil, nil, result); err != nil {
+		return false, err
+	}
+
+	return result.Host.Security.Rootless, nil
+}
+
+// ensurePod creates the pod grouping the containers if it does not exist. The pod is created without an infra
+// container, the containers keep their own network namespace and port mappings.
+func (c *libpodClient) ensurePod(ctx context.Context) error {
+	err := c.do(ctx, http.MethodGet, "/pods/"+url.PathEscape(c.pod)+"/exists", nil, nil, nil)
+	if err == nil || !isNotFound(err) {
+		return err
+	}
+
+	log.Debugf("Creating pod=%s", c.pod)
+	// the pod is marked with the same label as the managed containers
+	spec := podSpec{Name: c.pod, NoInfra: true, Labels: make(map[string]string)}
+	provider.AddLabel(spec.Labels, provider.ManagedByLabel())
+
+	return c.do(ctx, http.MethodPost, "/pods/create", nil, spec, nil)
+}
+
+// validateHostPort returns ErrPrivilegedPort when a rootless podman is unable to publish the host port.
+func (c *libpodClient) validateHostPort(port uint16) error {
+	if c.rootless && port != 0 && int(port) < c.unprivilegedPortStart {
+		return fmt.Errorf("%w: host port %d is below %d", ErrPrivilegedPort, port, c.unprivilegedPortStart)
+	}
+
+	return nil
+}
+
+func (c *libpodClient) ContainerCreate(ctx context.Context, config *container.Config, hostConfig *container.HostConfig, _ *network.NetworkingConfig, _ *v1.Platform, containerName string) (container.ContainerCreateCreatedBody, error) {
+	spec, err := toCreateSpec(containerName, config, hostConfig)
+	if err != nil {
+		return container.ContainerCreateCreatedBody{}, err
+	}
+
+	for _, mapping := range spec.PortMappings {
+		if err := c.validateHostPort(mapping.HostPort); err != nil {
+			return container.ContainerCreateCreatedBody{}, err
+		}
+	}
+
+	// podman does not offer the fluentd log driver, the default log driver of podman is used instead
+	if hostConfig.LogConfig.Type != "" {
+		log.Warnf("Log driver '%s' is not supported by podman, using the default log driver for container=%s", hostConfig.LogConfig.Type, containerName)
+	}
+
+	if c.pod != "" {
+		if err := c.ensurePod(ctx); err != nil {
+			return container.ContainerCreateCreatedBody{}, err
+		}
+
+		spec.Pod = c.pod
+	}
+
+	response := createResponse{}
+	if err := c.do(ctx, http.MethodPost, "/containers/create", nil, spec, &response); err != nil {
+		return container.ContainerCreateCreatedBody{}, err
+	}
+
+	return container.ContainerCreateCreatedBody{ID: response.ID, Warnings: response.Warnings}, nil
+}
+
+func (c *libpodClient) ContainerStart(ctx context.Context, id string, _ types.ContainerStartOptions) error {
+	return c.do(ctx, http.MethodPost, "/containers/"+url.PathEscape(id)+"/start", nil, nil, nil)
+}
+
+func (c *libpodClient) ContainerRemove(ctx context.Context, id string, options types.ContainerRemoveOptions) error {
+	query := url.Values{}
+	query.Set("force", strconv.FormatBool(options.Force))
+	query.Set("v", strconv.FormatBool(options.RemoveVolumes))
+
+	return c.do(ctx, http.MethodDelete, "/containers/"+url.PathEscape(id), query, nil, nil)
+}
+
+func (c *libpodClient) ContainerRename(ctx context.Context, id string, name string) error {
+	query := url.Values{}
+	query.Set("name", name)
+
+	return c.do(ctx, http.MethodPost, "/containers/"+url.PathEscape(id)+"/rename", query, nil, nil)
+}
+
+func (c *libpodClient) ContainerList(ctx context.Context, options types.ContainerListOptions) ([]types.Container, error) {
+	query := url.Values{}
+	query.Set("all", strconv.FormatBool(options.All))
+	if options.Filters.Len() > 0 {
+		encoded, err := encodeFilters(options.Filters)
+		if err != nil {
+			return nil, err
+		}
+
+		query.Set("filters", encoded)
+	}
+
+	listed := make([]listContainer, 0)
+	if err := c.do(ctx, http.MethodGet, "/containers/json", query, nil, &listed); err != nil {
+		return nil, err
+	}
+
+	containers := make([]types.Container, len(listed))
+	for idx, listedContainer := range listed {
+		containers[idx] = toContainer(listedContainer)
+	}
+
+	return containers, nil
+}
+
+func (c *libpodClient) ContainerInspect(ctx context.Context, id string) (types.ContainerJSON, error) {
+	inspected := inspectContainer{}
+	if err := c.do(ctx, http.MethodGet, "/containers/"+url.PathEscape(id)+"/json", nil, nil, &inspected); err != nil {
+		return types.ContainerJSON{}, err
+	}
+
+	return toContainerJSON(inspected), nil
+}
+
+func (c *libpodClient) ImageList(ctx context.Context, options types.ImageListOptions) ([]types.ImageSummary, error) {
+	query := url.Values{}
+	if options.Filters.Len() > 0 {
+		encoded, err := encodeFilters(options.Filters)
+		if err != nil {
+			return nil, err
+		}
+
+		query.Set("filters", encoded)
+	}
+
+	images := make([]imageSummary, 0)
+	if err := c.do(ctx, http.MethodGet, "/images/json", query, nil, &images); err != nil {
+		return nil, err
+	}
+
+	return toImageSummaries(images), nil
+}
+
+// ImagePull pulls the image and waits for the pull to complete, as libpod reports failed pulls within the stream.
+func (c *libpodClient) ImagePull(ctx context.Context, ref string, _ types.ImagePullOptions) (io.ReadCloser, error) {
+	query := url.Values{}
+	query.Set("reference", ref)
+
+	response, err := c.request(ctx, http.MethodPost, "/images/pull", query, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	decoder := json.NewDecoder(response.Body)
+	for {
+		report := pullReport{}
+		if err := decoder.Decode(&report); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		if report.Error != "" {
+			return nil, fmt.Errorf("unable to pull image '%s': %s", ref, report.Error)
+		}
+	}
+
+	return io.NopCloser(bytes.NewReader(nil)), nil
+}
+
+// Events streams the events of the libpod API, which reports them in the format of the docker events. The stream
+// ends with an error on the error channel, like the docker client it reports io.EOF when podman closed the stream.
+func (c *libpodClient) Events(ctx context.Context, options types.EventsOptions) (<-chan events.Message, <-chan error) {
+	messages := make(chan events.Message)
+	errs := make(chan error, 1)
+
+	query := url.Values{}
+	query.Set("stream", "true")
+	if options.Filters.Len() > 0 {
+		encoded, err := encodeFilters(options.Filters)
+		if err != nil {
+			errs <- err
+			return messages, errs
+		}
+
+		query.Set("filters", encoded)
+	}
+
+	go func() {
+		response, err := c.request(ctx, http.MethodGet, "/events", query, nil)
+		if err != nil {
+			errs <- err
+			return
+		}
+		defer response.Body.Close()
+
+		decoder := json.NewDecoder(response.Body)
+		for {
+			message := events.Message{}
+			if err := decoder.Decode(&message); err != nil {
+				errs <- err
+				return
+			}
+
+			select {
+			case messages <- message:
+			case <-ctx.Done():
+				errs <- ctx.Err()
+				return
+			}
+		}
+	}()
+
+	return messages, errs
+}
+
+// encodeFilters encodes the filters in the libpod format, which maps each filter to a list of values.
+func encodeFilters(args filters.Args) (string, error) {
+	values := make(map[string][]string)
+	for _, key := range args.Keys() {
+		values[key] = args.Get(key)
+	}
+
+	encoded, err := json.Marshal(values)
+	return string(encoded), err
+}
+
+// isNotFound returns true when the API responded with not found.
+func isNotFound(err error) bool {
+	return errors.As(err, &notFoundError{})
+}
@@ -0,0 +1,537 @@
+package podman
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/errdefs"
+	"github.com/docker/go-connections/nat"
+	"github.com/mbaitar/gco/agent/internal/files"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestServer mimics the libpod endpoints used by the client, containers are kept in memory. Like podman it
+// normalizes image names, assigns random host ports and reports the named volumes after the other mounts.
+type TestServer struct {
+	*httptest.Server
+
+	mutex      sync.Mutex
+	rootless   bool
+	containers map[string]*inspectContainer
+	specs      map[string]createSpec
+	pods       map[string]podSpec
+	images     map[string]bool
+	pulls      []string
+	nextID     int
+	nextPort   int
+
+	// pullErr is reported within the pull stream of every pull.
+	pullErr string
+	// events are streamed to every subscriber before closing the stream.
+	events []events.Message
+	// eventFilters are the filters of the last subscription.
+	eventFilters map[string][]string
+}
+
+func NewTestServer(t *testing.T) *TestServer {
+	server := &TestServer{
+		containers: make(map[string]*inspectContainer),
+		specs:      make(map[string]createSpec),
+		pods:       make(map[string]podSpec),
+		images:     make(map[string]bool),
+		nextPort:   40000,
+	}
+
+	server.Server = httptest.NewServer(http.StripPrefix(apiPath, http.HandlerFunc(server.handle)))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// NewTestProvider creates a provider using a rootless podman stand-in which groups the containers in the pod.
+func NewTestProvider(t *testing.T) (*Provider, *TestServer) {
+	files.SetDirectory(t.TempDir())
+
+	server := NewTestServer(t)
+	server.rootless = true
+
+	client := server.newClient()
+	client.pod = podName
+	client.rootless = true
+	return newProvider(client), server
+}
+
+func (s *TestServer) newClient() *libpodClient {
+	return &libpodClient{
+		http:                  s.Client(),
+		baseURL:               s.URL + apiPath,
+		unprivilegedPortStart: 1024,
+	}
+}
+
+func (s *TestServer) handle(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	path := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/info":
+		result := info{}
+		result.Host.Security.Rootless = s.rootless
+		writeJSON(w, http.StatusOK, result)
+	case r.Method == http.MethodGet && len(path) == 3 && path[0] == "pods" && path[2] == "exists":
+		if _, found := s.pods[path[1]]; !found {
+			writeError(w, http.StatusNotFound, "no such pod")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPost && r.URL.Path == "/pods/create":
+		spec := podSpec{}
+		_ = json.NewDecoder(r.Body).Decode(&spec)
+		s.pods[spec.Name] = spec
+		writeJSON(w, http.StatusCreated, createResponse{ID: spec.Name})
+	case r.Method == http.MethodPost && r.URL.Path == "/containers/create":
+		s.createContainer(w, r)
+	case r.Method == http.MethodGet && r.URL.Path == "/containers/json":
+		s.listContainers(w, r)
+	case r.Method == http.MethodGet && r.URL.Path == "/images/json":
+		s.listImages(w, r)
+	case r.Method == http.MethodPost && r.URL.Path == "/images/pull":
+		s.pullImage(w, r)
+	case r.Method == http.MethodGet && r.URL.Path == "/events":
+		s.streamEvents(w, r)
+	case len(path) >= 2 && path[0] == "containers":
+		c := s.getContainer(path[1])
+		if c == nil {
+			writeError(w, http.StatusNotFound, fmt.Sprintf("no container with name or ID %s found", path[1]))
+			return
+		}
+
+		s.handleContainer(w, r, c, path[2:])
+	default:
+		writeError(w, http.StatusNotFound, "unknown endpoint")
+	}
+}
+
+func (s *TestServer) handleContainer(w http.ResponseWriter, r *http.Request, c *inspectContainer, action []string) {
+	switch {
+	case r.Method == http.MethodGet && len(action) == 1 && action[0] == "json":
+		writeJSON(w, http.StatusOK, c)
+	case r.Method == http.MethodPost && len(action) == 1 && action[0] == "start":
+		c.State.Status = "running"
+		c.State.Running = true
+		if s.specs[c.ID].HealthConfig != nil {
+			c.State.Health = &healthResult{Status: types.Healthy}
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPost && len(action) == 1 && action[0] == "rename":
+		c.Name = r.URL.Query().Get("name")
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodDelete && len(action) == 0:
+		delete(s.containers, c.ID)
+		delete(s.specs, c.ID)
+		writeJSON(w, http.StatusOK, []interface{}{})
+	default:
+		writeError(w, http.StatusNotFound, "unknown endpoint")
+	}
+}
+
+func (s *TestServer) createContainer(w http.ResponseWriter, r *http.Request) {
+	spec := createSpec{}
+	if err := json.NewDecoder(r.Body).Decode(&spec); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if s.getContainer(spec.Name) != nil {
+		writeError(w, http.StatusConflict, fmt.Sprintf("the container name \"%s\" is already in use", spec.Name))
+		return
+	}
+
+	if _, found := s.pods[spec.Pod]; spec.Pod != "" && !found {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("no pod with name or ID %s found", spec.Pod))
+		return
+	}
+
+	s.nextID++
+	c := &inspectContainer{
+		ID:    fmt.Sprintf("%064d", s.nextID),
+		Name:  spec.Name,
+		Pod:   spec.Pod,
+		State: inspectState{Status: "created"},
+		Config: inspectConfig{
+			Image:  "docker.io/library/" + spec.Image,
+			User:   spec.User,
+			Cmd:    spec.Command,
+			Labels: spec.Labels,
+		},
+		HostConfig: inspectHostConfig{PortBindings: make(map[string][]inspectHostPort)},
+	}
+
+	for key, value := range spec.Env {
+		c.Config.Env = append(c.Config.Env, fmt.Sprintf("%s=%s", key, value))
+	}
+	sort.Strings(c.Config.Env)
+
+	for _, mapping := range spec.PortMappings {
+		if mapping.HostPort == 0 {
+			s.nextPort++
+			mapping.HostPort = uint16(s.nextPort)
+		}
+
+		port := fmt.Sprintf("%d/%s", mapping.ContainerPort, mapping.Protocol)
+		c.HostConfig.PortBindings[port] = append(c.HostConfig.PortBindings[port], inspectHostPort{
+			HostIP:   mapping.HostIP,
+			HostPort: fmt.Sprintf("%d", mapping.HostPort),
+		})
+	}
+
+	for _, m := range spec.Mounts {
+		c.Mounts = append(c.Mounts, inspectMount{Type: m.Type, Source: m.Source, Destination: m.Destination, RW: !isReadOnly(m.Options)})
+	}
+
+	for _, v := range spec.Volumes {
+		c.Mounts = append(c.Mounts, inspectMount{Type: "volume", Name: v.Name, Destination: v.Dest, RW: !isReadOnly(v.Options)})
+	}
+
+	if spec.RestartPolicy != "" {
+		c.HostConfig.RestartPolicy = &inspectRestartPolicy{Name: spec.RestartPolicy}
+		if spec.RestartTries != nil {
+			c.HostConfig.RestartPolicy.MaximumRetryCount = *spec.RestartTries
+		}
+	}
+
+	if limits := spec.ResourceLimits; limits != nil {
+		if limits.Memory != nil {
+			c.HostConfig.Memory = limits.Memory.Limit
+			c.HostConfig.MemoryReservation = limits.Memory.Reservation
+		}
+
+		if limits.CPU != nil {
+			c.HostConfig.CpuQuota = limits.CPU.Quota
+			c.HostConfig.CpuPeriod = limits.CPU.Period
+		}
+
+		if limits.Pids != nil {
+			c.HostConfig.PidsLimit = limits.Pids.Limit
+		}
+	}
+
+	s.containers[c.ID] = c
+	s.specs[c.ID] = spec
+	writeJSON(w, http.StatusCreated, createResponse{ID: c.ID})
+}
+
+func (s *TestServer) listContainers(w http.ResponseWriter, r *http.Request) {
+	values := make(map[string][]string)
+	if encoded := r.URL.Query().Get("filters"); encoded != "" {
+		if err := json.Unmarshal([]byte(encoded), &values); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	listed := make([]listContainer, 0)
+	for _, c := range s.sortedContainers() {
+		if !matchesLabels(c.Config.Labels, values["label"]) {
+			continue
+		}
+
+		listed = append(listed, listContainer{
+			ID:      c.ID,
+			Names:   []string{c.Name},
+			Image:   c.Config.Image,
+			Labels:  c.Config.Labels,
+			State:   c.State.Status,
+			PodName: c.Pod,
+		})
+	}
+
+	writeJSON(w, http.StatusOK, listed)
+}
+
+func (s *TestServer) listImages(w http.ResponseWriter, r *http.Request) {
+	values := make(map[string][]string)
+	if encoded := r.URL.Query().Get("filters"); encoded != "" {
+		_ = json.Unmarshal([]byte(encoded), &values)
+	}
+
+	images := make([]imageSummary, 0)
+	for _, reference := range values["reference"] {
+		if s.images[reference] {
+			images = append(images, imageSummary{ID: reference, RepoTags: []string{"docker.io/library/" + reference}})
+		}
+	}
+
+	writeJSON(w, http.StatusOK, images)
+}
+
+// pullImage responds with a stream of reports, failures are reported within the stream.
+func (s *TestServer) pullImage(w http.ResponseWriter, r *http.Request) {
+	reference := r.URL.Query().Get("reference")
+	s.pulls = append(s.pulls, reference)
+
+	w.WriteHeader(http.StatusOK)
+	encoder := json.NewEncoder(w)
+	_ = encoder.Encode(pullReport{Stream: fmt.Sprintf("Trying to pull docker.io/library/%s...\n", reference)})
+	if s.pullErr != "" {
+		_ = encoder.Encode(pullReport{Error: s.pullErr})
+		return
+	}
+
+	s.images[reference] = true
+	_ = encoder.Encode(pullReport{ID: reference})
+}
+
+// streamEvents responds with a stream of the events, the stream is closed afterwards.
+func (s *TestServer) streamEvents(w http.ResponseWriter, r *http.Request) {
+	s.eventFilters = make(map[string][]string)
+	_ = json.Unmarshal([]byte(r.URL.Query().Get("filters")), &s.eventFilters)
+
+	w.WriteHeader(http.StatusOK)
+	encoder := json.NewEncoder(w)
+	for _, message := range s.events {
+		_ = encoder.Encode(message)
+	}
+}
+
+// getContainer returns the container with the id or name, like podman both are accepted.
+func (s *TestServer) getContainer(ref string) *inspectContainer {
+	for _, c := range s.containers {
+		if c.ID == ref || c.Name == ref {
+			return c
+		}
+	}
+
+	return nil
+}
+
+func (s *TestServer) sortedContainers() []*inspectContainer {
+	containers := make([]*inspectContainer, 0, len(s.containers))
+	for _, c := range s.containers {
+		containers = append(containers, c)
+	}
+
+	sort.Slice(containers, func(a, b int) bool {
+		return containers[a].ID < containers[b].ID
+	})
+
+	return containers
+}
+
+// getSpec returns the spec used to create the container with the name.
+func (s *TestServer) getSpec(name string) (createSpec, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	c := s.getContainer(name)
+	if c == nil {
+		return createSpec{}, false
+	}
+
+	return s.specs[c.ID], true
+}
+
+func (s *TestServer) countContainers() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return len(s.containers)
+}
+
+func matchesLabels(labels map[string]string, selectors []string) bool {
+	for _, selector := range selectors {
+		parts := strings.SplitN(selector, "=", 2)
+		value, found := labels[parts[0]]
+		if !found || (len(parts) == 2 && value != parts[1]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func isReadOnly(options []string) bool {
+	for _, option := range options {
+		if option == "ro" {
+			return true
+		}
+	}
+
+	return false
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]interface{}{"cause": message, "message": message, "response": status})
+}
+
+func TestLibpodClient_ContainerCreate(t *testing.T) {
+	server := NewTestServer(t)
+	client := server.newClient()
+	client.pod = podName
+
+	pids := int64(100)
+	config := &container.Config{
+		Image:  "nginx:latest",
+		Env:    []string{"MODE=production"},
+		Labels: map[string]string{"gco.io/name": "nginx"},
+	}
+	hostConfig := &container.HostConfig{
+		PortBindings: nat.PortMap{
+			"80/tcp":  {{HostIP: "0.0.0.0", HostPort: "8080"}},
+			"443/tcp": {{HostIP: "0.0.0.0", HostPort: ""}},
+		},
+		Binds: []string{"/etc/gco/nginx.conf:/etc/nginx/nginx.conf:ro"},
+		Mounts: []mount.Mount{
+			{Type: mount.TypeVolume, Source: "data", Target: "/data"},
+			{Type: mount.TypeTmpfs, Target: "/tmp"},
+		},
+		Resources:     container.Resources{NanoCPUs: 5e8, Memory: 64 << 20, PidsLimit: &pids},
+		RestartPolicy: container.RestartPolicy{Name: "on-failure", MaximumRetryCount: 3},
+	}
+
+	body, err := client.ContainerCreate(context.Background(), config, hostConfig, nil, nil, "nginx-0")
+	assert.Nil(t, err)
+	assert.NotEmpty(t, body.ID)
+	assert.Contains(t, server.pods, podName, "should have created the pod")
+	assert.True(t, server.pods[podName].NoInfra, "should not share the namespaces of an infra container")
+
+	spec, _ := server.getSpec("nginx-0")
+	assert.Equal(t, podName, spec.Pod)
+	assert.Equal(t, map[string]string{"MODE": "production"}, spec.Env)
+	assert.Equal(t, []portMapping{
+		{HostIP: "0.0.0.0", ContainerPort: 80, HostPort: 8080, Protocol: "tcp"},
+		{HostIP: "0.0.0.0", ContainerPort: 443, Protocol: "tcp"},
+	}, spec.PortMappings)
+	assert.Equal(t, []specMount{
+		{Destination: "/etc/nginx/nginx.conf", Type: "bind", Source: "/etc/gco/nginx.conf", Options: []string{"ro"}},
+		{Destination: "/tmp", Type: "tmpfs", Source: "tmpfs"},
+	}, spec.Mounts)
+	assert.Equal(t, []namedVolume{{Name: "data", Dest: "/data"}}, spec.Volumes)
+	assert.Equal(t, "on-failure", spec.RestartPolicy)
+	assert.Equal(t, uint(3), *spec.RestartTries)
+	assert.Equal(t, &resourceLimits{
+		Memory: &memoryLimits{Limit: 64 << 20},
+		CPU:    &cpuLimits{Quota: 50000, Period: cpuPeriod},
+		Pids:   &pidsLimits{Limit: 100},
+	}, spec.ResourceLimits)
+
+	inspected, err := client.ContainerInspect(context.Background(), "nginx-0")
+	assert.Nil(t, err)
+	assert.Equal(t, "/nginx-0", inspected.Name)
+	assert.Equal(t, "nginx:latest", inspected.Config.Image, "should report the requested image")
+	assert.Equal(t, hostConfig.PortBindings, inspected.HostConfig.PortBindings, "should report the requested host ports")
+	assert.Equal(t, hostConfig.Binds, inspected.HostConfig.Binds)
+	assert.Equal(t, hostConfig.Mounts, inspected.HostConfig.Mounts, "should keep the order of the mounts")
+	assert.Equal(t, hostConfig.Resources.NanoCPUs, inspected.HostConfig.NanoCPUs)
+	assert.Equal(t, pids, *inspected.HostConfig.PidsLimit)
+	assert.Equal(t, hostConfig.RestartPolicy, inspected.HostConfig.RestartPolicy)
+}
+
+func TestLibpodClient_ContainerCreate_privilegedPort(t *testing.T) {
+	server := NewTestServer(t)
+	client := server.newClient()
+	client.rootless = true
+
+	hostConfig := &container.HostConfig{PortBindings: nat.PortMap{"80/tcp": {{HostPort: "80"}}}}
+	_, err := client.ContainerCreate(context.Background(), &container.Config{Image: "nginx:latest"}, hostConfig, nil, nil, "nginx-0")
+	assert.ErrorIs(t, err, ErrPrivilegedPort)
+	assert.Equal(t, 0, server.countContainers())
+
+	client.rootless = false
+	_, err = client.ContainerCreate(context.Background(), &container.Config{Image: "nginx:latest"}, hostConfig, nil, nil, "nginx-0")
+	assert.Nil(t, err, "should publish privileged ports with a rootful podman")
+	assert.Empty(t, server.pods, "should not create a pod without pod grouping")
+}
+
+func TestLibpodClient_ContainerInspect_notFound(t *testing.T) {
+	client := NewTestServer(t).newClient()
+
+	_, err := client.ContainerInspect(context.Background(), "unknown")
+	assert.True(t, errdefs.IsNotFound(err), "should report not found like the docker client")
+	assert.Contains(t, err.Error(), "no container with name or ID unknown found")
+}
+
+func TestLibpodClient_ContainerList(t *testing.T) {
+	server := NewTestServer(t)
+	client := server.newClient()
+
+	for _, name := range []string{"nginx", "postgres"} {
+		config := &container.Config{Image: name + ":latest", Labels: map[string]string{"gco.io/managed-by": "gco", "gco.io/name": name}}
+		_, err := client.ContainerCreate(context.Background(), config, &container.HostConfig{}, nil, nil, name)
+		assert.Nil(t, err)
+	}
+
+	opts := types.ContainerListOptions{All: true, Filters: filters.NewArgs()}
+	opts.Filters.Add("label", "gco.io/managed-by=gco")
+	opts.Filters.Add("label", "gco.io/name=postgres")
+
+	containers, err := client.ContainerList(context.Background(), opts)
+	assert.Nil(t, err)
+	if assert.Len(t, containers, 1) {
+		assert.Equal(t, []string{"/postgres"}, containers[0].Names)
+		assert.Equal(t, "created", containers[0].State)
+	}
+}
+
+func TestLibpodClient_ImagePull(t *testing.T) {
+	server := NewTestServer(t)
+	client := server.newClient()
+
+	reader, err := client.ImagePull(context.Background(), "nginx:latest", types.ImagePullOptions{})
+	if assert.Nil(t, err) {
+		_ = reader.Close()
+	}
+
+	opts := types.ImageListOptions{Filters: filters.NewArgs()}
+	opts.Filters.Add("reference", "nginx:latest")
+	images, err := client.ImageList(context.Background(), opts)
+	assert.Nil(t, err)
+	assert.Len(t, images, 1)
+
+	server.pullErr = "manifest unknown"
+	_, err = client.ImagePull(context.Background(), "nginx:unknown", types.ImagePullOptions{})
+	if assert.NotNil(t, err, "should report the error within the stream") {
+		assert.Contains(t, err.Error(), "manifest unknown")
+	}
+}
+
+func TestLibpodClient_Events(t *testing.T) {
+	server := NewTestServer(t)
+	server.events = []events.Message{
+		{Type: events.ContainerEventType, Action: "start", Actor: events.Actor{ID: "nginx-0", Attributes: map[string]string{"gco.io/name": "nginx"}}},
+		{Type: events.ContainerEventType, Action: "die", Actor: events.Actor{ID: "nginx-0"}},
+	}
+	client := server.newClient()
+
+	opts := types.EventsOptions{Filters: filters.NewArgs()}
+	opts.Filters.Add("type", events.ContainerEventType)
+	messages, errs := client.Events(context.Background(), opts)
+
+	for _, expected := range server.events {
+		message := <-messages
+		assert.Equal(t, expected.Action, message.Action)
+		assert.Equal(t, expected.Actor, message.Actor)
+	}
+
+	assert.ErrorIs(t, <-errs, io.EOF, "should report the closed stream")
+	assert.Equal(t, []string{events.ContainerEventType}, server.eventFilters["type"])
+}
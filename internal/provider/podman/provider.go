@@ -0,0 +1,76 @@
+package podman
+
+import (
+	"context"
+	"os"
+
+	"github.com/mbaitar/gco/agent/internal/config"
+	"github.com/mbaitar/gco/agent/internal/log"
+	"github.com/mbaitar/gco/agent/internal/provider/docker"
+	"github.com/mbaitar/gco/agent/pkg/resource"
+)
+
+// providerName is the name used to look up podman specific feature materializers. Features materialized for docker
+// only, e.g. features mounting the docker socket, are not supported.
+const providerName = "podman"
+
+// Provider defines a podman provider which communicates with the libpod API of the podman socket. The containers
+// are managed the same way as by the docker provider, grouped within the 'gco' pod instead of a compose project.
+type Provider struct {
+	*docker.Provider
+
+	client *libpodClient
+}
+
+func NewPodmanProvider(conf config.PodmanProvider) *Provider {
+	client := newLibpodClient(conf.Socket)
+	client.unprivilegedPortStart = conf.UnprivilegedPortStart
+	if conf.UsePodGrouping {
+		client.pod = podName
+	}
+
+	rootless, err := client.isRootless(context.Background())
+	if err != nil {
+		// the socket of a rootless podman is owned by the user running it
+		rootless = os.Geteuid() != 0
+		log.Warnf("Unable to determine whether podman runs rootless, assuming rootless=%t: %v", rootless, err)
+	}
+
+	client.rootless = rootless
+	return newProvider(client)
+}
+
+func newProvider(client *libpodClient) *Provider {
+	return &Provider{
+		Provider: docker.NewDockerProviderWithClient(client).WithMaterializerName(providerName),
+		client:   client,
+	}
+}
+
+func (p *Provider) CreateApplication(app *resource.Application) error {
+	if err := p.validatePorts(app); err != nil {
+		return err
+	}
+
+	return p.Provider.CreateApplication(app)
+}
+
+// UpdateApplication validates the ports before updating, the current containers are kept when the update would fail.
+func (p *Provider) UpdateApplication(app *resource.Application) error {
+	if err := p.validatePorts(app); err != nil {
+		return err
+	}
+
+	return p.Provider.UpdateApplication(app)
+}
+
+// validatePorts returns ErrPrivilegedPort when a host port of the application can not be published.
+func (p *Provider) validatePorts(app *resource.Application) error {
+	for _, port := range app.Ports {
+		if err := p.client.validateHostPort(port.HostPort); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
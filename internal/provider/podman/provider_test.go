@@ -0,0 +1,175 @@
+package podman
+
+import (
+	"testing"
+
+	providerErrors "github.com/mbaitar/gco/agent/internal/provider"
+	"github.com/mbaitar/gco/agent/pkg/feature"
+	"github.com/mbaitar/gco/agent/pkg/resource"
+	"github.com/stretchr/testify/assert"
+)
+
+func exampleApplication() *resource.Application {
+	return &resource.Application{
+		Name:      "nginx",
+		Instances: 1,
+		Image: resource.Image{
+			Name: "nginx",
+			Tag:  "latest",
+		},
+		Ports: []resource.Port{
+			{HostPort: 8080, ContainerPort: 80, Protocol: "tcp"},
+			{ContainerPort: 443, Protocol: "tcp"},
+		},
+		Mounts: []resource.Mount{
+			{Type: resource.VolumeMountType, Source: "data", Target: "/data"},
+			{Type: resource.TmpfsMountType, Target: "/tmp"},
+		},
+		Resources: &resource.Resources{
+			CPULimit:    0.5,
+			MemoryLimit: 64 << 20,
+		},
+		RestartPolicy: &resource.RestartPolicy{Name: resource.OnFailureRestartPolicy, MaxRetries: 3},
+	}
+}
+
+func TestProvider_CreateApplication(t *testing.T) {
+	provider, server := NewTestProvider(t)
+
+	err := provider.CreateApplication(exampleApplication())
+	assert.Nil(t, err, "should not have thrown an error")
+	assert.Equal(t, 1, server.countContainers())
+	assert.Equal(t, []string{"nginx:latest"}, server.pulls)
+
+	spec, found := server.getSpec("nginx-0")
+	if assert.True(t, found, "should have created the first instance") {
+		assert.Equal(t, podName, spec.Pod, "should have grouped the container in the pod")
+		assert.Equal(t, "nginx", spec.Labels["gco.io/name"])
+		assert.NotContains(t, spec.Labels, "com.docker.compose.project")
+	}
+}
+
+func TestProvider_CreateApplication_privilegedPort(t *testing.T) {
+	provider, server := NewTestProvider(t)
+
+	app := exampleApplication()
+	app.Ports = []resource.Port{{HostPort: 80, ContainerPort: 80, Protocol: "tcp"}}
+
+	err := provider.CreateApplication(app)
+	assert.ErrorIs(t, err, ErrPrivilegedPort)
+	assert.Equal(t, 0, server.countContainers())
+	assert.Empty(t, server.pulls, "should not have pulled the image")
+}
+
+func TestProvider_CreateApplication_pullError(t *testing.T) {
+	provider, server := NewTestProvider(t)
+	server.pullErr = "manifest unknown"
+
+	err := provider.CreateApplication(exampleApplication())
+	assert.NotNil(t, err, "should have thrown an error")
+	assert.Equal(t, 0, server.countContainers())
+}
+
+func TestProvider_CreateApplication_reservedLabel(t *testing.T) {
+	provider, server := NewTestProvider(t)
+
+	app := exampleApplication()
+	app.Labels = map[string]string{"gco.io/name": "other"}
+
+	err := provider.CreateApplication(app)
+	assert.ErrorIs(t, err, providerErrors.ErrReservedLabel)
+	assert.Equal(t, 0, server.countContainers())
+}
+
+func TestProvider_UpdateApplication(t *testing.T) {
+	provider, server := NewTestProvider(t)
+	assert.Nil(t, provider.CreateApplication(exampleApplication()))
+
+	app := exampleApplication()
+	app.Image.Tag = "1.23"
+	err := provider.UpdateApplication(app)
+	assert.Nil(t, err, "should not have thrown an error")
+	assert.Equal(t, 1, server.countContainers())
+
+	spec, found := server.getSpec("nginx-0")
+	if assert.True(t, found) {
+		assert.Equal(t, "nginx:1.23", spec.Image)
+	}
+}
+
+func TestProvider_UpdateApplication_privilegedPort(t *testing.T) {
+	provider, server := NewTestProvider(t)
+	assert.Nil(t, provider.CreateApplication(exampleApplication()))
+
+	app := exampleApplication()
+	app.Ports = []resource.Port{{HostPort: 443, ContainerPort: 443, Protocol: "tcp"}}
+
+	err := provider.UpdateApplication(app)
+	assert.ErrorIs(t, err, ErrPrivilegedPort)
+	assert.Equal(t, 1, server.countContainers(), "should have kept the current container")
+}
+
+func TestProvider_UpdateApplication_notFound(t *testing.T) {
+	provider, _ := NewTestProvider(t)
+
+	err := provider.UpdateApplication(exampleApplication())
+	assert.ErrorIs(t, err, providerErrors.ErrAppNotFound)
+}
+
+func TestProvider_RemoveApplication(t *testing.T) {
+	provider, server := NewTestProvider(t)
+	assert.Nil(t, provider.CreateApplication(exampleApplication()))
+
+	err := provider.RemoveApplication(exampleApplication())
+	assert.Nil(t, err, "should not have thrown an error")
+	assert.Equal(t, 0, server.countContainers())
+
+	err = provider.RemoveApplication(exampleApplication())
+	assert.ErrorIs(t, err, providerErrors.ErrAppNotFound)
+}
+
+func TestProvider_ActualState(t *testing.T) {
+	provider, _ := NewTestProvider(t)
+	assert.Nil(t, provider.CreateApplication(exampleApplication()))
+	assert.Nil(t, provider.CreateFeature(&feature.FluentBit{LogLevel: "info", Version: "2.0.0"}))
+
+	spec, err := provider.ActualState()
+	assert.Nil(t, err, "should not have thrown an error")
+	if assert.Equal(t, 1, len(spec.Applications), "should only contain the application") {
+		app := spec.Applications[0]
+		assert.Equal(t, "nginx", app.Name)
+		assert.Equal(t, 1, app.Instances)
+		assert.Equal(t, exampleApplication().CalculateHash(), app.CalculateHash(), "should report the application as created")
+	}
+
+	if assert.Equal(t, 1, len(spec.Feature)) {
+		fluentBit, ok := spec.Feature[feature.NameFluentBit].(*feature.FluentBit)
+		if assert.True(t, ok, "should have decoded the feature") {
+			assert.Equal(t, "2.0.0", fluentBit.Version)
+		}
+	}
+}
+
+func TestProvider_RemoveFeature(t *testing.T) {
+	provider, server := NewTestProvider(t)
+	assert.Nil(t, provider.CreateFeature(&feature.FluentBit{LogLevel: "info", Version: "2.0.0"}))
+	assert.Equal(t, 1, server.countContainers())
+
+	err := provider.RemoveFeature(&feature.FluentBit{})
+	assert.Nil(t, err, "should not have thrown an error")
+	assert.Equal(t, 0, server.countContainers())
+}
+
+func TestProvider_CreateFeature_unsupported(t *testing.T) {
+	provider, server := NewTestProvider(t)
+
+	err := provider.CreateFeature(&feature.Ingress{})
+	assert.ErrorIs(t, err, providerErrors.ErrFeatureNotSupported, "should not mount the docker socket")
+
+	err = provider.CreateFeature(&feature.Monitoring{Prometheus: true})
+	assert.ErrorIs(t, err, providerErrors.ErrFeatureNotSupported, "should not mount the docker socket")
+	assert.Equal(t, 0, server.countContainers())
+	assert.False(t, provider.SupportsFeature(feature.NameIngress))
+	assert.False(t, provider.SupportsFeature(feature.NameMonitoring))
+	assert.True(t, provider.SupportsFeature(feature.NameFluentBit))
+}
@@ -0,0 +1,468 @@
+package podman
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/go-connections/nat"
+	"github.com/mbaitar/gco/agent/internal/provider"
+)
+
+// specLabelTag contains the recorded creation spec of a container.
+var specLabelTag = provider.PlatformLabelTag("podman-spec")
+
+// cpuPeriod is the CFS period used to translate the cpu limit into a quota.
+const cpuPeriod = 100000
+
+// recordedSpec contains the values of the container creation which podman does not report as requested. Podman
+// normalizes image names, assigns the random host ports and groups the mounts by type. The values can not change
+// without recreating the container, as such the recorded values are reported instead.
+type recordedSpec struct {
+	Image        string        `json:"image"`
+	PortBindings nat.PortMap   `json:"ports,omitempty"`
+	Binds        []string      `json:"binds,omitempty"`
+	Mounts       []mount.Mount `json:"mounts,omitempty"`
+}
+
+// createSpec is the subset of the libpod spec generator used to create a container.
+type createSpec struct {
+	Name           string                  `json:"name"`
+	Image          string                  `json:"image"`
+	Pod            string                  `json:"pod,omitempty"`
+	Command        []string                `json:"command,omitempty"`
+	User           string                  `json:"user,omitempty"`
+	Env            map[string]string       `json:"env,omitempty"`
+	Labels         map[string]string       `json:"labels,omitempty"`
+	PortMappings   []portMapping           `json:"portmappings,omitempty"`
+	Mounts         []specMount             `json:"mounts,omitempty"`
+	Volumes        []namedVolume           `json:"volumes,omitempty"`
+	RestartPolicy  string                  `json:"restart_policy,omitempty"`
+	RestartTries   *uint                   `json:"restart_tries,omitempty"`
+	ResourceLimits *resourceLimits         `json:"resource_limits,omitempty"`
+	HealthConfig   *container.HealthConfig `json:"healthconfig,omitempty"`
+}
+
+type portMapping struct {
+	HostIP        string `json:"host_ip,omitempty"`
+	ContainerPort uint16 `json:"container_port"`
+	HostPort      uint16 `json:"host_port"`
+	Protocol      string `json:"protocol,omitempty"`
+}
+
+type specMount struct {
+	Destination string   `json:"destination"`
+	Type        string   `json:"type"`
+	Source      string   `json:"source,omitempty"`
+	Options     []string `json:"options,omitempty"`
+}
+
+type namedVolume struct {
+	Name    string   `json:"Name"`
+	Dest    string   `json:"Dest"`
+	Options []string `json:"Options,omitempty"`
+}
+
+type resourceLimits struct {
+	Memory *memoryLimits `json:"memory,omitempty"`
+	CPU    *cpuLimits    `json:"cpu,omitempty"`
+	Pids   *pidsLimits   `json:"pids,omitempty"`
+}
+
+type memoryLimits struct {
+	Limit       int64 `json:"limit,omitempty"`
+	Reservation int64 `json:"reservation,omitempty"`
+}
+
+type cpuLimits struct {
+	Quota  int64  `json:"quota,omitempty"`
+	Period uint64 `json:"period,omitempty"`
+}
+
+type pidsLimits struct {
+	Limit int64 `json:"limit"`
+}
+
+// createResponse is the response of the libpod container and pod create endpoints.
+type createResponse struct {
+	ID       string   `json:"Id"`
+	Warnings []string `json:"Warnings"`
+}
+
+// podSpec is the subset of the libpod pod spec generator used to create the pod of the managed containers.
+type podSpec struct {
+	Name    string            `json:"name"`
+	NoInfra bool              `json:"no_infra"`
+	Labels  map[string]string `json:"labels,omitempty"`
+}
+
+// listContainer is a container returned by the libpod list endpoint.
+type listContainer struct {
+	ID      string            `json:"Id"`
+	Names   []string          `json:"Names"`
+	Image   string            `json:"Image"`
+	Labels  map[string]string `json:"Labels"`
+	State   string            `json:"State"`
+	PodName string            `json:"PodName"`
+}
+
+// inspectContainer is the subset of the libpod container inspect response.
+type inspectContainer struct {
+	ID         string            `json:"Id"`
+	Name       string            `json:"Name"`
+	Pod        string            `json:"Pod"`
+	State      inspectState      `json:"State"`
+	Config     inspectConfig     `json:"Config"`
+	HostConfig inspectHostConfig `json:"HostConfig"`
+	Mounts     []inspectMount    `json:"Mounts"`
+}
+
+type inspectState struct {
+	Status  string        `json:"Status"`
+	Running bool          `json:"Running"`
+	Health  *healthResult `json:"Health,omitempty"`
+}
+
+type healthResult struct {
+	Status string `json:"Status"`
+}
+
+type inspectConfig struct {
+	Image  string            `json:"Image"`
+	User   string            `json:"User"`
+	Env    []string          `json:"Env"`
+	Cmd    []string          `json:"Cmd"`
+	Labels map[string]string `json:"Labels"`
+}
+
+type inspectHostConfig struct {
+	PortBindings      map[string][]inspectHostPort `json:"PortBindings"`
+	RestartPolicy     *inspectRestartPolicy        `json:"RestartPolicy"`
+	NanoCpus          int64                        `json:"NanoCpus"`
+	CpuPeriod         uint64                       `json:"CpuPeriod"`
+	CpuQuota          int64                        `json:"CpuQuota"`
+	Memory            int64                        `json:"Memory"`
+	MemoryReservation int64                        `json:"MemoryReservation"`
+	PidsLimit         int64                        `json:"PidsLimit"`
+}
+
+type inspectHostPort struct {
+	HostIP   string `json:"HostIp"`
+	HostPort string `json:"HostPort"`
+}
+
+type inspectRestartPolicy struct {
+	Name              string `json:"Name"`
+	MaximumRetryCount uint   `json:"MaximumRetryCount"`
+}
+
+type inspectMount struct {
+	Type        string `json:"Type"`
+	Name        string `json:"Name"`
+	Source      string `json:"Source"`
+	Destination string `json:"Destination"`
+	RW          bool   `json:"RW"`
+}
+
+// imageSummary is an image returned by the libpod image list endpoint.
+type imageSummary struct {
+	ID       string   `json:"Id"`
+	RepoTags []string `json:"RepoTags"`
+}
+
+// pullReport is a single message of the libpod image pull stream.
+type pullReport struct {
+	Stream string `json:"stream,omitempty"`
+	Error  string `json:"error,omitempty"`
+	ID     string `json:"id,omitempty"`
+}
+
+// info is the subset of the libpod system info response.
+type info struct {
+	Host struct {
+		Security struct {
+			Rootless bool `json:"rootless"`
+		} `json:"security"`
+	} `json:"host"`
+}
+
+// toCreateSpec translates the docker container configuration into the libpod spec generator.
+func toCreateSpec(name string, config *container.Config, hostConfig *container.HostConfig) (*createSpec, error) {
+	spec := &createSpec{
+		Name:    name,
+		Image:   config.Image,
+		Command: config.Cmd,
+		User:    config.User,
+		Env:     toEnv(config.Env),
+		Labels:  make(map[string]string, len(config.Labels)+1),
+	}
+
+	for key, value := range config.Labels {
+		spec.Labels[key] = value
+	}
+
+	recorded, err := json.Marshal(recordedSpec{
+		Image:        config.Image,
+		PortBindings: hostConfig.PortBindings,
+		Binds:        hostConfig.Binds,
+		Mounts:       hostConfig.Mounts,
+	})
+	if err != nil {
+		return nil, err
+	}
+	spec.Labels[specLabelTag.String()] = string(recorded)
+
+	spec.PortMappings, err = toPortMappings(hostConfig.PortBindings)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, bind := range hostConfig.Binds {
+		spec.addBind(bind)
+	}
+
+	for _, m := range hostConfig.Mounts {
+		spec.addMount(m)
+	}
+
+	if hostConfig.RestartPolicy.IsAlways() || hostConfig.RestartPolicy.IsOnFailure() || hostConfig.RestartPolicy.IsUnlessStopped() {
+		spec.RestartPolicy = hostConfig.RestartPolicy.Name
+		if hostConfig.RestartPolicy.IsOnFailure() && hostConfig.RestartPolicy.MaximumRetryCount > 0 {
+			tries := uint(hostConfig.RestartPolicy.MaximumRetryCount)
+			spec.RestartTries = &tries
+		}
+	}
+
+	spec.ResourceLimits = toResourceLimits(hostConfig.Resources)
+	spec.HealthConfig = config.Healthcheck
+	return spec, nil
+}
+
+// toEnv parses the environment variables from the docker format.
+func toEnv(env []string) map[string]string {
+	if len(env) == 0 {
+		return nil
+	}
+
+	values := make(map[string]string, len(env))
+	for _, entry := range env {
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) == 2 {
+			values[parts[0]] = parts[1]
+		} else {
+			values[parts[0]] = ""
+		}
+	}
+
+	return values
+}
+
+// toPortMappings translates the port bindings, sorted by container port for a stable result. An empty host port
+// lets podman assign a random host port.
+func toPortMappings(bindings nat.PortMap) ([]portMapping, error) {
+	ports := make([]nat.Port, 0, len(bindings))
+	for port := range bindings {
+		ports = append(ports, port)
+	}
+
+	nat.Sort(ports, func(a, b nat.Port) bool {
+		if a.Int() == b.Int() {
+			return a.Proto() < b.Proto()
+		}
+
+		return a.Int() < b.Int()
+	})
+
+	mappings := make([]portMapping, 0, len(ports))
+	for _, port := range ports {
+		for _, binding := range bindings[port] {
+			mapping := portMapping{
+				HostIP:        binding.HostIP,
+				ContainerPort: uint16(port.Int()),
+				Protocol:      port.Proto(),
+			}
+
+			if binding.HostPort != "" {
+				hostPort, err := strconv.ParseUint(binding.HostPort, 10, 16)
+				if err != nil {
+					return nil, fmt.Errorf("invalid host port '%s' for port %s", binding.HostPort, port)
+				}
+
+				mapping.HostPort = uint16(hostPort)
+			}
+
+			mappings = append(mappings, mapping)
+		}
+	}
+
+	return mappings, nil
+}
+
+// addBind adds the docker bind 'source:destination[:ro]', absolute sources are bind mounts, others named volumes.
+func (s *createSpec) addBind(bind string) {
+	parts := strings.Split(bind, ":")
+	if len(parts) < 2 {
+		return
+	}
+
+	var options []string
+	if len(parts) > 2 && parts[2] == "ro" {
+		options = []string{"ro"}
+	}
+
+	if filepath.IsAbs(parts[0]) {
+		s.Mounts = append(s.Mounts, specMount{Destination: parts[1], Type: "bind", Source: parts[0], Options: options})
+	} else {
+		s.Volumes = append(s.Volumes, namedVolume{Name: parts[0], Dest: parts[1], Options: options})
+	}
+}
+
+// addMount adds the docker mount using the matching libpod mount type.
+func (s *createSpec) addMount(m mount.Mount) {
+	var options []string
+	if m.ReadOnly {
+		options = []string{"ro"}
+	}
+
+	switch m.Type {
+	case mount.TypeVolume:
+		s.Volumes = append(s.Volumes, namedVolume{Name: m.Source, Dest: m.Target, Options: options})
+	case mount.TypeTmpfs:
+		s.Mounts = append(s.Mounts, specMount{Destination: m.Target, Type: "tmpfs", Source: "tmpfs", Options: options})
+	default:
+		s.Mounts = append(s.Mounts, specMount{Destination: m.Target, Type: "bind", Source: m.Source, Options: options})
+	}
+}
+
+// toResourceLimits translates the docker resources, the cpu limit is translated into a CFS quota.
+func toResourceLimits(resources container.Resources) *resourceLimits {
+	limits := &resourceLimits{}
+	if resources.Memory > 0 || resources.MemoryReservation > 0 {
+		limits.Memory = &memoryLimits{Limit: resources.Memory, Reservation: resources.MemoryReservation}
+	}
+
+	if resources.NanoCPUs > 0 {
+		limits.CPU = &cpuLimits{Quota: resources.NanoCPUs * cpuPeriod / 1e9, Period: cpuPeriod}
+	}
+
+	if resources.PidsLimit != nil && *resources.PidsLimit > 0 {
+		limits.Pids = &pidsLimits{Limit: *resources.PidsLimit}
+	}
+
+	if limits.Memory == nil && limits.CPU == nil && limits.Pids == nil {
+		return nil
+	}
+
+	return limits
+}
+
+// toContainer translates the listed libpod container.
+func toContainer(c listContainer) types.Container {
+	names := make([]string, len(c.Names))
+	for idx, name := range c.Names {
+		names[idx] = "/" + name
+	}
+
+	return types.Container{
+		ID:     c.ID,
+		Names:  names,
+		Image:  c.Image,
+		Labels: c.Labels,
+		State:  c.State,
+	}
+}
+
+// toContainerJSON translates the inspected libpod container into the docker format, the recorded values of the
+// creation spec take precedence over the inspected values.
+func toContainerJSON(c inspectContainer) types.ContainerJSON {
+	hostConfig := &container.HostConfig{
+		PortBindings: make(nat.PortMap, len(c.HostConfig.PortBindings)),
+		Resources: container.Resources{
+			NanoCPUs:          c.HostConfig.NanoCpus,
+			Memory:            c.HostConfig.Memory,
+			MemoryReservation: c.HostConfig.MemoryReservation,
+		},
+	}
+
+	// older podman versions only report the CFS quota
+	if hostConfig.NanoCPUs == 0 && c.HostConfig.CpuQuota > 0 && c.HostConfig.CpuPeriod > 0 {
+		hostConfig.NanoCPUs = c.HostConfig.CpuQuota * 1e9 / int64(c.HostConfig.CpuPeriod)
+	}
+
+	if c.HostConfig.PidsLimit > 0 {
+		limit := c.HostConfig.PidsLimit
+		hostConfig.PidsLimit = &limit
+	}
+
+	if policy := c.HostConfig.RestartPolicy; policy != nil {
+		hostConfig.RestartPolicy = container.RestartPolicy{Name: policy.Name, MaximumRetryCount: int(policy.MaximumRetryCount)}
+	}
+
+	for port, bindings := range c.HostConfig.PortBindings {
+		for _, binding := range bindings {
+			hostConfig.PortBindings[nat.Port(port)] = append(hostConfig.PortBindings[nat.Port(port)], nat.PortBinding{
+				HostIP:   binding.HostIP,
+				HostPort: binding.HostPort,
+			})
+		}
+	}
+
+	for _, m := range c.Mounts {
+		switch m.Type {
+		case "volume":
+			hostConfig.Mounts = append(hostConfig.Mounts, mount.Mount{Type: mount.TypeVolume, Source: m.Name, Target: m.Destination, ReadOnly: !m.RW})
+		case "tmpfs":
+			hostConfig.Mounts = append(hostConfig.Mounts, mount.Mount{Type: mount.TypeTmpfs, Target: m.Destination, ReadOnly: !m.RW})
+		default:
+			hostConfig.Mounts = append(hostConfig.Mounts, mount.Mount{Type: mount.TypeBind, Source: m.Source, Target: m.Destination, ReadOnly: !m.RW})
+		}
+	}
+
+	config := &container.Config{
+		Image:  c.Config.Image,
+		User:   c.Config.User,
+		Env:    c.Config.Env,
+		Cmd:    c.Config.Cmd,
+		Labels: c.Config.Labels,
+	}
+
+	if encoded, found := c.Config.Labels[specLabelTag.String()]; found {
+		recorded := recordedSpec{}
+		if err := json.Unmarshal([]byte(encoded), &recorded); err == nil {
+			config.Image = recorded.Image
+			hostConfig.PortBindings = recorded.PortBindings
+			hostConfig.Binds = recorded.Binds
+			hostConfig.Mounts = recorded.Mounts
+		}
+	}
+
+	state := &types.ContainerState{Status: c.State.Status, Running: c.State.Running}
+	if c.State.Health != nil && c.State.Health.Status != "" {
+		state.Health = &types.Health{Status: c.State.Health.Status}
+	}
+
+	return types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{
+			ID:         c.ID,
+			Name:       "/" + c.Name,
+			State:      state,
+			HostConfig: hostConfig,
+		},
+		Config: config,
+	}
+}
+
+// toImageSummaries translates the listed libpod images.
+func toImageSummaries(images []imageSummary) []types.ImageSummary {
+	summaries := make([]types.ImageSummary, len(images))
+	for idx, image := range images {
+		summaries[idx] = types.ImageSummary{ID: image.ID, RepoTags: image.RepoTags}
+	}
+
+	return summaries
+}
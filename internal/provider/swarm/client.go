@@ -0,0 +1,24 @@
+package swarm
+
+import (
+	"os"
+
+	docker "github.com/docker/docker/client"
+	"github.com/mbaitar/gco/agent/internal/log"
+)
+
+// swarmClient defines the parts of the docker API used by the swarm provider.
+type swarmClient interface {
+	docker.ServiceAPIClient
+	docker.ConfigAPIClient
+}
+
+func newSwarmClient() swarmClient {
+	cli, err := docker.NewClientWithOpts(docker.FromEnv, docker.WithAPIVersionNegotiation())
+	if err != nil {
+		log.Errorf("Unable to create new docker client: %v", err)
+		os.Exit(1)
+	}
+
+	return cli
+}
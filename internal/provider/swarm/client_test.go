@@ -0,0 +1,171 @@
+package swarm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/swarm"
+)
+
+// TestClient is an in-memory stand-in for the services, tasks and configs of a swarm.
+type TestClient struct {
+	swarmClient
+
+	services map[string]swarm.Service
+	tasks    []swarm.Task
+	configs  map[string]swarm.Config
+	nextId   int
+
+	serviceCreateErr error
+	serviceCreated   int
+	serviceUpdates   []swarm.ServiceSpec
+	configRemoveErr  error
+}
+
+func NewTestClient() *TestClient {
+	return &TestClient{
+		services:       make(map[string]swarm.Service),
+		tasks:          make([]swarm.Task, 0),
+		configs:        make(map[string]swarm.Config),
+		serviceUpdates: make([]swarm.ServiceSpec, 0),
+	}
+}
+
+func NewTestProvider(client *TestClient) *Provider {
+	return &Provider{client: client}
+}
+
+func (t *TestClient) newId(kind string) string {
+	t.nextId++
+	return fmt.Sprintf("%s-%d", kind, t.nextId)
+}
+
+func (t *TestClient) ServiceCreate(ctx context.Context, service swarm.ServiceSpec, options types.ServiceCreateOptions) (types.ServiceCreateResponse, error) {
+	if t.serviceCreateErr != nil && t.serviceCreated > 0 {
+		return types.ServiceCreateResponse{}, t.serviceCreateErr
+	}
+
+	for _, existing := range t.services {
+		if existing.Spec.Name == service.Name {
+			return types.ServiceCreateResponse{}, fmt.Errorf("service %s already exists", service.Name)
+		}
+	}
+
+	t.serviceCreated++
+	id := t.newId("service")
+	t.services[id] = swarm.Service{ID: id, Meta: swarm.Meta{Version: swarm.Version{Index: 1}}, Spec: service}
+	return types.ServiceCreateResponse{ID: id}, nil
+}
+
+func (t *TestClient) ServiceUpdate(ctx context.Context, serviceID string, version swarm.Version, service swarm.ServiceSpec, options types.ServiceUpdateOptions) (types.ServiceUpdateResponse, error) {
+	existing, found := t.services[serviceID]
+	if !found {
+		return types.ServiceUpdateResponse{}, errors.New("service not found")
+	}
+
+	if existing.Version != version {
+		return types.ServiceUpdateResponse{}, errors.New("update out of sequence")
+	}
+
+	existing.Spec = service
+	existing.Version.Index++
+	t.services[serviceID] = existing
+	t.serviceUpdates = append(t.serviceUpdates, service)
+	return types.ServiceUpdateResponse{}, nil
+}
+
+func (t *TestClient) ServiceRemove(ctx context.Context, serviceID string) error {
+	if _, found := t.services[serviceID]; !found {
+		return errors.New("service not found")
+	}
+
+	delete(t.services, serviceID)
+	return nil
+}
+
+func (t *TestClient) ServiceList(ctx context.Context, options types.ServiceListOptions) ([]swarm.Service, error) {
+	services := make([]swarm.Service, 0)
+	for _, service := range t.services {
+		if matchesLabels(options.Filters, service.Spec.Labels) {
+			services = append(services, service)
+		}
+	}
+
+	return services, nil
+}
+
+func (t *TestClient) TaskList(ctx context.Context, options types.TaskListOptions) ([]swarm.Task, error) {
+	return t.tasks, nil
+}
+
+func (t *TestClient) ConfigList(ctx context.Context, options types.ConfigListOptions) ([]swarm.Config, error) {
+	configs := make([]swarm.Config, 0)
+	for _, config := range t.configs {
+		names := options.Filters.Get("name")
+		if len(names) > 0 && !strings.HasPrefix(config.Spec.Name, names[0]) {
+			continue
+		}
+
+		if matchesLabels(options.Filters, config.Spec.Labels) {
+			configs = append(configs, config)
+		}
+	}
+
+	return configs, nil
+}
+
+func (t *TestClient) ConfigCreate(ctx context.Context, config swarm.ConfigSpec) (types.ConfigCreateResponse, error) {
+	id := t.newId("config")
+	t.configs[id] = swarm.Config{ID: id, Spec: config}
+	return types.ConfigCreateResponse{ID: id}, nil
+}
+
+func (t *TestClient) ConfigRemove(ctx context.Context, id string) error {
+	if t.configRemoveErr != nil {
+		return t.configRemoveErr
+	}
+
+	delete(t.configs, id)
+	return nil
+}
+
+// addTasks adds tasks in the given state for the service with the given name.
+func (t *TestClient) addTasks(name string, state swarm.TaskState, count int) {
+	for id, service := range t.services {
+		if service.Spec.Name != name {
+			continue
+		}
+
+		for i := 0; i < count; i++ {
+			task := swarm.Task{ID: t.newId("task"), ServiceID: id, DesiredState: swarm.TaskStateRunning, Status: swarm.TaskStatus{State: state}}
+			t.tasks = append(t.tasks, task)
+		}
+	}
+}
+
+// getService returns the service with the given name.
+func (t *TestClient) getService(name string) *swarm.Service {
+	for _, service := range t.services {
+		if service.Spec.Name == name {
+			return &service
+		}
+	}
+
+	return nil
+}
+
+// matchesLabels returns true when the labels contain every label filter.
+func matchesLabels(args filters.Args, labels map[string]string) bool {
+	for _, filter := range args.Get("label") {
+		parts := strings.SplitN(filter, "=", 2)
+		if value, found := labels[parts[0]]; !found || (len(parts) == 2 && value != parts[1]) {
+			return false
+		}
+	}
+
+	return true
+}
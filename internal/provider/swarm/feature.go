@@ -0,0 +1,133 @@
+package swarm
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/mbaitar/gco/agent/internal/hash"
+	"github.com/mbaitar/gco/agent/internal/provider"
+	"github.com/mbaitar/gco/agent/pkg/feature"
+	"github.com/mbaitar/gco/agent/pkg/resource"
+)
+
+// providerName is the name used to look up swarm specific feature materializers.
+const providerName = "swarm"
+
+// featureService describes the service of a feature workload together with the configs it references.
+type featureService struct {
+	spec    swarm.ServiceSpec
+	configs []swarm.ConfigSpec
+}
+
+// SupportsFeature returns true when a materializer of the feature has been registered for the provider.
+func (p *Provider) SupportsFeature(name string) bool {
+	return feature.SupportedBy(name, providerName)
+}
+
+// createFeatureServices creates the service specifications using the workloads of the registered feature.
+func createFeatureServices(feat feature.Feature) ([]featureService, error) {
+	def, found := feature.Lookup(feat.Name())
+	if !found {
+		return nil, provider.ErrFeatureNotSupported
+	}
+
+	materialize := def.MaterializerFor(providerName)
+	if materialize == nil {
+		return nil, provider.ErrFeatureNotSupported
+	}
+
+	workloads, err := materialize(feat)
+	if err != nil {
+		return nil, err
+	}
+
+	services := make([]featureService, 0, len(workloads))
+	for _, workload := range workloads {
+		services = append(services, createWorkloadService(feat, workload))
+	}
+
+	return services, nil
+}
+
+// createWorkloadService creates the service specification for a single workload of the feature. Global workloads
+// run on every node and publish their ports on the node itself, other workloads run a single replica.
+// The generated configuration files are stored as swarm configs, which are available on every node.
+func createWorkloadService(feat feature.Feature, workload feature.Workload) featureService {
+	name := serviceName(feat.Name(), workload.Name)
+
+	labels := make(map[string]string)
+	provider.AddLabel(labels, provider.ManagedByLabel())
+	provider.AddLabel(labels, provider.KindLabel(resource.FeatureKind))
+	provider.AddLabel(labels, provider.NameLabel(name))
+	provider.AddLabel(labels, provider.FeatureLabel(feat.Name()))
+	provider.AddLabel(labels, provider.ConfigLabel(feature.EncodeFeature(feat)))
+
+	keys := make([]string, 0, len(workload.Env))
+	for key := range workload.Env {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	env := make([]string, 0, len(keys))
+	for _, key := range keys {
+		env = append(env, fmt.Sprintf("%s=%s", key, workload.Env[key]))
+	}
+
+	service := featureService{
+		spec: swarm.ServiceSpec{
+			Annotations: swarm.Annotations{Name: name, Labels: labels},
+			TaskTemplate: swarm.TaskSpec{
+				ContainerSpec: &swarm.ContainerSpec{
+					Image:  workload.Image,
+					Labels: provider.CopyLabels(labels),
+					Args:   workload.Command,
+					User:   workload.User,
+					Env:    env,
+					Mounts: toMounts(workload.Mounts),
+				},
+				RestartPolicy: &swarm.RestartPolicy{Condition: swarm.RestartPolicyConditionAny},
+			},
+			EndpointSpec: toEndpointSpec(workload.Ports),
+		},
+	}
+
+	if workload.Global {
+		service.spec.Mode = swarm.ServiceMode{Global: &swarm.GlobalService{}}
+		if service.spec.EndpointSpec != nil {
+			for idx := range service.spec.EndpointSpec.Ports {
+				service.spec.EndpointSpec.Ports[idx].PublishMode = swarm.PortConfigPublishModeHost
+			}
+		}
+	} else {
+		replicas := uint64(1)
+		service.spec.Mode = swarm.ServiceMode{Replicated: &swarm.ReplicatedService{Replicas: &replicas}}
+	}
+
+	for _, configFile := range workload.ConfigFiles {
+		// configs can not be modified, the content hash results in a new config when the content changes
+		configName := fmt.Sprintf("%s-%s-%s", name, configFile.Name, hash.ShortHash(hash.CalculateHashFromString(configFile.Content)))
+		service.configs = append(service.configs, swarm.ConfigSpec{
+			Annotations: swarm.Annotations{Name: configName, Labels: provider.CopyLabels(labels)},
+			Data:        []byte(configFile.Content),
+		})
+
+		service.spec.TaskTemplate.ContainerSpec.Configs = append(service.spec.TaskTemplate.ContainerSpec.Configs, &swarm.ConfigReference{
+			File:       &swarm.ConfigReferenceFileTarget{Name: configFile.Target, UID: "0", GID: "0", Mode: 0444},
+			ConfigName: configName,
+		})
+	}
+
+	return service
+}
+
+// serviceName returns the name of the service running the workload of a feature, service names are DNS labels.
+func serviceName(feat string, workload string) string {
+	name := fmt.Sprintf("gco-%s", feat)
+	if workload != "" {
+		name = fmt.Sprintf("%s-%s", name, workload)
+	}
+
+	return strings.ReplaceAll(name, ".", "-")
+}
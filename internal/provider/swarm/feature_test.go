@@ -0,0 +1,50 @@
+package swarm
+
+import (
+	"github.com/mbaitar/gco/agent/internal/hash"
+	"github.com/mbaitar/gco/agent/pkg/feature"
+	"github.com/mbaitar/gco/agent/pkg/resource"
+)
+
+// nameTestFeature is the name of a feature running a global agent and a single server, like a monitoring stack.
+const nameTestFeature = "test-feature"
+
+func init() {
+	feature.MustRegister(feature.Definition{
+		Name:        nameTestFeature,
+		New:         func() feature.Feature { return &TestFeature{} },
+		Materialize: materializeTestFeature,
+	})
+}
+
+type TestFeature struct {
+	Version string `json:"version"`
+}
+
+func (f *TestFeature) ConfigHash() string {
+	return hash.CalculateHash(f)
+}
+
+func (f *TestFeature) Name() string {
+	return nameTestFeature
+}
+
+func materializeTestFeature(feat feature.Feature) ([]feature.Workload, error) {
+	return []feature.Workload{
+		{
+			Name:   "agent",
+			Image:  "agent:latest",
+			Ports:  []resource.Port{{HostPort: 8080, ContainerPort: 8080, Protocol: resource.TcpProtocol}},
+			Global: true,
+		},
+		{
+			Name:  "server",
+			Image: "server:latest",
+			User:  "0",
+			Ports: []resource.Port{{HostPort: 9090, ContainerPort: 9090, Protocol: resource.TcpProtocol}},
+			ConfigFiles: []feature.ConfigFile{
+				{Name: "server.yml", Target: "/etc/server/server.yml", Content: "scrape: []\n"},
+			},
+		},
+	}, nil
+}
@@ -0,0 +1,301 @@
+package swarm
+
+import (
+	"context"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/mbaitar/gco/agent/internal/log"
+	"github.com/mbaitar/gco/agent/internal/provider"
+	"github.com/mbaitar/gco/agent/internal/state"
+	"github.com/mbaitar/gco/agent/pkg/feature"
+	"github.com/mbaitar/gco/agent/pkg/resource"
+)
+
+// Provider defines a swarm provider which manages the services of a docker swarm using the docker socket
+// of a manager node. Applications are replicated services, features are global or replicated services.
+type Provider struct {
+	// client represents the Docker SDK client
+	client swarmClient
+}
+
+func NewSwarmProvider() *Provider {
+	return &Provider{
+		client: newSwarmClient(),
+	}
+}
+
+func (p *Provider) CreateApplication(app *resource.Application) error {
+	if err := provider.ValidateLabels(app); err != nil {
+		return err
+	}
+
+	spec, err := fromApplicationResource(app)
+	if err != nil {
+		return err
+	}
+
+	resp, err := p.client.ServiceCreate(context.Background(), spec, types.ServiceCreateOptions{})
+	if err != nil {
+		return err
+	}
+
+	logWarnings(app.Name, resp.Warnings)
+	return nil
+}
+
+func (p *Provider) UpdateApplication(app *resource.Application) error {
+	if err := provider.ValidateLabels(app); err != nil {
+		return err
+	}
+
+	service, err := p.getApplicationService(app.Name)
+	if err != nil {
+		return err
+	}
+
+	if service == nil {
+		return provider.ErrAppNotFound
+	}
+
+	spec, err := fromApplicationResource(app)
+	if err != nil {
+		return err
+	}
+
+	// keep the counter of the service, a different value forces swarm to redeploy all tasks
+	spec.TaskTemplate.ForceUpdate = service.Spec.TaskTemplate.ForceUpdate
+
+	resp, err := p.client.ServiceUpdate(context.Background(), service.ID, service.Version, spec, types.ServiceUpdateOptions{})
+	if err != nil {
+		return err
+	}
+
+	logWarnings(app.Name, resp.Warnings)
+	return nil
+}
+
+func (p *Provider) RemoveApplication(app *resource.Application) error {
+	service, err := p.getApplicationService(app.Name)
+	if err != nil {
+		return err
+	}
+
+	if service == nil {
+		return provider.ErrAppNotFound
+	}
+
+	return p.client.ServiceRemove(context.Background(), service.ID)
+}
+
+// getReplicas returns the number of requested replicas of a replicated service.
+func getReplicas(spec swarm.ServiceSpec) int {
+	if spec.Mode.Replicated == nil || spec.Mode.Replicated.Replicas == nil {
+		return 0
+	}
+
+	return int(*spec.Mode.Replicated.Replicas)
+}
+
+func (p *Provider) CreateFeature(feat feature.Feature) error {
+	services, err := createFeatureServices(feat)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	created := make([]string, 0, len(services))
+	for _, service := range services {
+		err = p.createConfigs(service)
+		if err == nil {
+			var resp types.ServiceCreateResponse
+			resp, err = p.client.ServiceCreate(ctx, service.spec, types.ServiceCreateOptions{})
+			if err == nil {
+				created = append(created, resp.ID)
+				logWarnings(service.spec.Name, resp.Warnings)
+			}
+		}
+
+		if err != nil {
+			// the services and configs created so far are removed again, the feature is created as a whole or not at all
+			for _, id := range created {
+				if removeErr := p.client.ServiceRemove(ctx, id); removeErr != nil {
+					log.Warnf("Unable to remove service of partially created feature=%s: %v", feat.Name(), removeErr)
+				}
+			}
+
+			p.removeConfigs(feat.Name())
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (p *Provider) UpdateFeature(feat feature.Feature) error {
+	if err := p.RemoveFeature(feat); err != nil {
+		return err
+	}
+
+	return p.CreateFeature(feat)
+}
+
+func (p *Provider) RemoveFeature(feat feature.Feature) error {
+	services, err := p.listServices(provider.FeatureLabel(feat.Name()))
+	if err != nil {
+		return err
+	}
+
+	if len(services) == 0 {
+		return provider.ErrFeatureNotFound
+	}
+
+	for _, service := range services {
+		if err = p.client.ServiceRemove(context.Background(), service.ID); err != nil {
+			return err
+		}
+	}
+
+	p.removeConfigs(feat.Name())
+	return nil
+}
+
+func (p *Provider) ActualState() (*state.Spec, error) {
+	services, err := p.listServices(provider.ManagedByLabel())
+	if err != nil {
+		return nil, err
+	}
+
+	running, err := p.countRunningTasks()
+	if err != nil {
+		return nil, err
+	}
+
+	applications := make([]resource.Application, 0)
+	features := state.Feature{}
+	for _, service := range services {
+		switch resource.Kind(service.Spec.Labels[provider.KindLabelTag.String()]) {
+		case resource.ApplicationKind:
+			applications = append(applications, toApplicationResource(service.Spec, running[service.ID]))
+		case resource.FeatureKind:
+			featureName := service.Spec.Labels[provider.FeatureLabelTag.String()]
+			def, found := feature.Lookup(featureName)
+			if !found {
+				log.Warnf("Ignoring service of unknown feature=%s", featureName)
+				continue
+			}
+
+			features[def.Name] = feature.DecodeFeature(service.Spec.Labels[provider.ConfigLabelTag.String()], def.New())
+		}
+	}
+
+	spec := &state.Spec{
+		Applications: applications,
+		Feature:      features,
+	}
+
+	return spec, nil
+}
+
+// getApplicationService returns the service of the application, or nil when it does not exist.
+func (p *Provider) getApplicationService(name string) (*swarm.Service, error) {
+	services, err := p.listServices(provider.KindLabel(resource.ApplicationKind), provider.NameLabel(name))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(services) == 0 {
+		return nil, nil
+	}
+
+	return &services[0], nil
+}
+
+// listServices returns the services managed by the agent matching all labels.
+func (p *Provider) listServices(labels ...provider.Label) ([]swarm.Service, error) {
+	args := filters.NewArgs(filters.Arg("label", provider.ManagedByLabel().String()))
+	for _, l := range labels {
+		args.Add("label", l.String())
+	}
+
+	return p.client.ServiceList(context.Background(), types.ServiceListOptions{Filters: args})
+}
+
+// countRunningTasks returns the number of running tasks keyed by the id of their service, tasks which are
+// still starting or have been replaced are not counted.
+func (p *Provider) countRunningTasks() (map[string]int, error) {
+	args := filters.NewArgs(filters.Arg("desired-state", string(swarm.TaskStateRunning)))
+	tasks, err := p.client.TaskList(context.Background(), types.TaskListOptions{Filters: args})
+	if err != nil {
+		return nil, err
+	}
+
+	running := make(map[string]int)
+	for _, task := range tasks {
+		if task.DesiredState == swarm.TaskStateRunning && task.Status.State == swarm.TaskStateRunning {
+			running[task.ServiceID]++
+		}
+	}
+
+	return running, nil
+}
+
+// createConfigs creates the configs referenced by the service, existing configs with the same name are reused
+// as their name contains the hash of the content.
+func (p *Provider) createConfigs(service featureService) error {
+	ctx := context.Background()
+	for _, config := range service.configs {
+		existing, err := p.client.ConfigList(ctx, types.ConfigListOptions{Filters: filters.NewArgs(filters.Arg("name", config.Name))})
+		if err != nil {
+			return err
+		}
+
+		id := ""
+		for _, c := range existing {
+			if c.Spec.Name == config.Name {
+				id = c.ID
+			}
+		}
+
+		if id == "" {
+			resp, err := p.client.ConfigCreate(ctx, config)
+			if err != nil {
+				return err
+			}
+
+			id = resp.ID
+		}
+
+		for _, reference := range service.spec.TaskTemplate.ContainerSpec.Configs {
+			if reference.ConfigName == config.Name {
+				reference.ConfigID = id
+			}
+		}
+	}
+
+	return nil
+}
+
+// removeConfigs removes the configs of the feature, configs which are still in use are kept.
+func (p *Provider) removeConfigs(name string) {
+	ctx := context.Background()
+	args := filters.NewArgs(filters.Arg("label", provider.ManagedByLabel().String()), filters.Arg("label", provider.FeatureLabel(name).String()))
+	configs, err := p.client.ConfigList(ctx, types.ConfigListOptions{Filters: args})
+	if err != nil {
+		log.Warnf("Unable to list configs of feature=%s: %v", name, err)
+		return
+	}
+
+	for _, config := range configs {
+		if err = p.client.ConfigRemove(ctx, config.ID); err != nil {
+			log.Warnf("Unable to remove config=%s of feature=%s: %v", config.Spec.Name, name, err)
+		}
+	}
+}
+
+func logWarnings(name string, warnings []string) {
+	for _, warning := range warnings {
+		log.Warnf("Swarm reported a warning for service=%s: %s", name, warning)
+	}
+}
@@ -0,0 +1,257 @@
+package swarm
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/swarm"
+	providers "github.com/mbaitar/gco/agent/internal/provider"
+	"github.com/mbaitar/gco/agent/pkg/feature"
+	"github.com/mbaitar/gco/agent/pkg/resource"
+	"github.com/stretchr/testify/assert"
+)
+
+func exampleApplication() *resource.Application {
+	return &resource.Application{
+		Name:      "nginx",
+		Instances: 2,
+		Image: resource.Image{
+			Name: "nginx",
+			Tag:  "latest",
+		},
+		Ports: []resource.Port{
+			{HostPort: 8080, ContainerPort: 80, Protocol: "tcp"},
+		},
+	}
+}
+
+func TestProvider_CreateApplication(t *testing.T) {
+	client := NewTestClient()
+	provider := NewTestProvider(client)
+
+	err := provider.CreateApplication(exampleApplication())
+	assert.Nil(t, err, "should not have thrown an error")
+
+	service := client.getService("nginx")
+	if assert.NotNil(t, service, "should have created the service") {
+		assert.Equal(t, 2, getReplicas(service.Spec))
+		assert.Equal(t, "nginx:latest", service.Spec.TaskTemplate.ContainerSpec.Image)
+		assert.Equal(t, string(resource.ApplicationKind), service.Spec.Labels[providers.KindLabelTag.String()])
+	}
+}
+
+func TestProvider_CreateApplication_reservedLabel(t *testing.T) {
+	client := NewTestClient()
+	provider := NewTestProvider(client)
+
+	app := exampleApplication()
+	app.Labels = map[string]string{"gco.io/name": "other"}
+
+	err := provider.CreateApplication(app)
+	assert.ErrorIs(t, err, providers.ErrReservedLabel)
+	assert.Equal(t, 0, len(client.services), "should not have created a service")
+}
+
+func TestProvider_UpdateApplication(t *testing.T) {
+	client := NewTestClient()
+	provider := NewTestProvider(client)
+	assert.Nil(t, provider.CreateApplication(exampleApplication()))
+
+	app := exampleApplication()
+	app.Image.Tag = "1.23"
+	err := provider.UpdateApplication(app)
+	assert.Nil(t, err, "should not have thrown an error")
+
+	if assert.Equal(t, 1, len(client.serviceUpdates)) {
+		spec := client.serviceUpdates[0]
+		assert.Equal(t, "nginx:1.23", spec.TaskTemplate.ContainerSpec.Image)
+		assert.Equal(t, uint64(0), spec.TaskTemplate.ForceUpdate, "should not have forced a redeployment of a changed application")
+	}
+}
+
+func TestProvider_UpdateApplication_scale(t *testing.T) {
+	client := NewTestClient()
+	provider := NewTestProvider(client)
+	assert.Nil(t, provider.CreateApplication(exampleApplication()))
+
+	app := exampleApplication()
+	app.Instances = 3
+	assert.Nil(t, provider.UpdateApplication(app))
+
+	if assert.Equal(t, 1, len(client.serviceUpdates)) {
+		spec := client.serviceUpdates[0]
+		assert.Equal(t, 3, getReplicas(spec))
+		assert.Equal(t, uint64(0), spec.TaskTemplate.ForceUpdate, "should not have forced a redeployment when scaling")
+	}
+}
+
+func TestProvider_UpdateApplication_unchanged(t *testing.T) {
+	client := NewTestClient()
+	provider := NewTestProvider(client)
+	assert.Nil(t, provider.CreateApplication(exampleApplication()))
+
+	// the tasks have been redeployed by an operator
+	for id, service := range client.services {
+		service.Spec.TaskTemplate.ForceUpdate = 3
+		client.services[id] = service
+	}
+
+	// tasks which are not running yet are left to swarm
+	assert.Nil(t, provider.UpdateApplication(exampleApplication()))
+
+	if assert.Equal(t, 1, len(client.serviceUpdates)) {
+		assert.Equal(t, uint64(3), client.serviceUpdates[0].TaskTemplate.ForceUpdate, "should not have redeployed the tasks")
+	}
+}
+
+func TestProvider_UpdateApplication_notFound(t *testing.T) {
+	provider := NewTestProvider(NewTestClient())
+
+	err := provider.UpdateApplication(exampleApplication())
+	assert.ErrorIs(t, err, providers.ErrAppNotFound)
+}
+
+func TestProvider_RemoveApplication(t *testing.T) {
+	client := NewTestClient()
+	provider := NewTestProvider(client)
+	assert.Nil(t, provider.CreateApplication(exampleApplication()))
+
+	err := provider.RemoveApplication(exampleApplication())
+	assert.Nil(t, err, "should not have thrown an error")
+	assert.Equal(t, 0, len(client.services), "should have removed the service")
+
+	err = provider.RemoveApplication(exampleApplication())
+	assert.ErrorIs(t, err, providers.ErrAppNotFound)
+}
+
+func TestProvider_ActualState(t *testing.T) {
+	client := NewTestClient()
+	provider := NewTestProvider(client)
+	assert.Nil(t, provider.CreateApplication(exampleApplication()))
+	assert.Nil(t, provider.CreateFeature(&feature.FluentBit{LogLevel: "info", Version: "2.0.0"}))
+
+	// a service not managed by the agent is ignored
+	_, err := client.ServiceCreate(context.Background(), swarm.ServiceSpec{Annotations: swarm.Annotations{Name: "other"}}, types.ServiceCreateOptions{})
+	assert.Nil(t, err)
+
+	// only one of the replicas is running yet
+	client.addTasks("nginx", swarm.TaskStateRunning, 1)
+	client.addTasks("nginx", swarm.TaskStateStarting, 1)
+
+	// a task which is being replaced is not counted
+	replaced := swarm.Task{ID: "task-replaced", ServiceID: client.getService("nginx").ID, DesiredState: swarm.TaskStateShutdown, Status: swarm.TaskStatus{State: swarm.TaskStateRunning}}
+	client.tasks = append(client.tasks, replaced)
+
+	spec, err := provider.ActualState()
+	assert.Nil(t, err, "should not have thrown an error")
+	if assert.Equal(t, 1, len(spec.Applications), "should only contain the application") {
+		app := spec.Applications[0]
+		assert.Equal(t, "nginx", app.Name)
+		assert.Equal(t, 1, app.Instances, "should count the running tasks as instances")
+		assert.Equal(t, exampleApplication().CalculateHash(), app.CalculateHash())
+	}
+
+	if assert.Equal(t, 1, len(spec.Feature)) {
+		fluentBit, ok := spec.Feature[feature.NameFluentBit].(*feature.FluentBit)
+		if assert.True(t, ok, "should have decoded the feature") {
+			assert.Equal(t, "2.0.0", fluentBit.Version)
+		}
+	}
+}
+
+func TestProvider_CreateFeature(t *testing.T) {
+	client := NewTestClient()
+	provider := NewTestProvider(client)
+
+	err := provider.CreateFeature(&feature.FluentBit{LogLevel: "info", Version: "2.0.0"})
+	assert.Nil(t, err, "should not have thrown an error")
+
+	service := client.getService("gco-fluent-bit")
+	if assert.NotNil(t, service, "should have created the service") {
+		assert.NotNil(t, service.Spec.Mode.Global, "should run on every node")
+		if assert.Equal(t, 1, len(service.Spec.EndpointSpec.Ports)) {
+			assert.Equal(t, swarm.PortConfigPublishModeHost, service.Spec.EndpointSpec.Ports[0].PublishMode)
+		}
+
+		references := service.Spec.TaskTemplate.ContainerSpec.Configs
+		if assert.Equal(t, 1, len(references), "should reference the config file") && assert.Equal(t, 1, len(client.configs)) {
+			config, found := client.configs[references[0].ConfigID]
+			if assert.True(t, found, "should reference the created config") {
+				assert.Equal(t, config.Spec.Name, references[0].ConfigName)
+				assert.Equal(t, "/fluent-bit/etc/fluent-bit.conf", references[0].File.Name)
+			}
+		}
+	}
+}
+
+func TestProvider_CreateFeature_replicated(t *testing.T) {
+	client := NewTestClient()
+	provider := NewTestProvider(client)
+
+	err := provider.CreateFeature(&TestFeature{Version: "1.0"})
+	assert.Nil(t, err, "should not have thrown an error")
+	assert.Equal(t, 2, len(client.services))
+
+	server := client.getService("gco-test-feature-server")
+	if assert.NotNil(t, server) {
+		assert.Nil(t, server.Spec.Mode.Global)
+		assert.Equal(t, 1, getReplicas(server.Spec), "should run a single replica")
+	}
+
+	agent := client.getService("gco-test-feature-agent")
+	if assert.NotNil(t, agent) {
+		assert.NotNil(t, agent.Spec.Mode.Global)
+	}
+}
+
+func TestProvider_CreateFeature_rollback(t *testing.T) {
+	client := NewTestClient()
+	provider := NewTestProvider(client)
+	client.serviceCreateErr = errors.New("test error")
+
+	err := provider.CreateFeature(&TestFeature{Version: "1.0"})
+	assert.NotNil(t, err, "should have thrown an error")
+	assert.Equal(t, 0, len(client.services), "should have removed the partially created services")
+	assert.Equal(t, 0, len(client.configs), "should have removed the configs")
+}
+
+func TestProvider_CreateFeature_unsupported(t *testing.T) {
+	client := NewTestClient()
+	provider := NewTestProvider(client)
+
+	// the docker socket only provides the containers of the local node
+	assert.ErrorIs(t, provider.CreateFeature(&feature.Ingress{}), providers.ErrFeatureNotSupported)
+	assert.ErrorIs(t, provider.CreateFeature(&feature.Monitoring{}), providers.ErrFeatureNotSupported)
+	assert.Equal(t, 0, len(client.services))
+	assert.False(t, provider.SupportsFeature(feature.NameIngress))
+	assert.False(t, provider.SupportsFeature(feature.NameMonitoring))
+	assert.True(t, provider.SupportsFeature(feature.NameFluentBit))
+}
+
+func TestProvider_RemoveFeature(t *testing.T) {
+	client := NewTestClient()
+	provider := NewTestProvider(client)
+	fluentBit := &feature.FluentBit{LogLevel: "info", Version: "2.0.0"}
+	assert.Nil(t, provider.CreateFeature(fluentBit))
+
+	err := provider.RemoveFeature(fluentBit)
+	assert.Nil(t, err, "should not have thrown an error")
+	assert.Equal(t, 0, len(client.services))
+	assert.Equal(t, 0, len(client.configs))
+
+	err = provider.RemoveFeature(fluentBit)
+	assert.ErrorIs(t, err, providers.ErrFeatureNotFound)
+}
+
+func TestProvider_UpdateFeature(t *testing.T) {
+	client := NewTestClient()
+	provider := NewTestProvider(client)
+	assert.Nil(t, provider.CreateFeature(&feature.FluentBit{LogLevel: "info", Version: "2.0.0"}))
+
+	err := provider.UpdateFeature(&feature.FluentBit{LogLevel: "debug", Version: "2.0.0"})
+	assert.Nil(t, err, "should not have thrown an error")
+	assert.Equal(t, 1, len(client.services))
+	assert.Equal(t, 1, len(client.configs), "should have replaced the config")
+}
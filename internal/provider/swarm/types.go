@@ -0,0 +1,419 @@
+package swarm
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/mbaitar/gco/agent/internal/files"
+	"github.com/mbaitar/gco/agent/internal/provider"
+	"github.com/mbaitar/gco/agent/pkg/resource"
+)
+
+// fromApplicationResource creates the service specification of the application. The instances of the application
+// are the replicas of the service, the labels of the agent are attached to the service and its containers.
+func fromApplicationResource(app *resource.Application) (swarm.ServiceSpec, error) {
+	labels := make(map[string]string)
+	provider.AddLabel(labels, provider.ManagedByLabel())
+	provider.AddLabel(labels, provider.KindLabel(resource.ApplicationKind))
+	provider.AddLabel(labels, provider.NameLabel(app.Name))
+
+	env, err := resolveEnv(app.Env)
+	if err != nil {
+		return swarm.ServiceSpec{}, err
+	}
+
+	// keep track of the managed environment variables and secret references
+	if len(app.Env) > 0 {
+		keys := make([]string, 0, len(app.Env))
+		for key, value := range app.Env {
+			keys = append(keys, key)
+			if resource.IsSecretReference(value) {
+				provider.AddLabel(labels, provider.SecretLabel(key, value))
+			}
+		}
+
+		sort.Strings(keys)
+		provider.AddLabel(labels, provider.EnvLabel(keys))
+	}
+
+	// keep track of the additional labels, labels using the platform prefix are rejected by provider.ValidateLabels
+	if len(app.Labels) > 0 {
+		keys := make([]string, 0, len(app.Labels))
+		for key, value := range app.Labels {
+			labels[key] = value
+			keys = append(keys, key)
+		}
+
+		sort.Strings(keys)
+		provider.AddLabel(labels, provider.LabelsLabel(keys))
+	}
+
+	if app.Ingress != nil {
+		encoded, _ := json.Marshal(app.Ingress)
+		provider.AddLabel(labels, provider.IngressLabel(string(encoded)))
+	}
+
+	if app.Metrics != nil {
+		encoded, _ := json.Marshal(app.Metrics)
+		provider.AddLabel(labels, provider.MetricsLabel(string(encoded)))
+	}
+
+	if app.HealthCheck != nil {
+		encoded, _ := json.Marshal(app.HealthCheck)
+		provider.AddLabel(labels, provider.HealthLabel(string(encoded)))
+	}
+
+	// the restart policy is kept in a label as swarm restarts the tasks of applications without a policy as well
+	if !app.RestartPolicy.IsDefault() {
+		encoded, _ := json.Marshal(app.RestartPolicy)
+		provider.AddLabel(labels, provider.RestartLabel(string(encoded)))
+	}
+
+	replicas := uint64(app.GetInstances())
+	spec := swarm.ServiceSpec{
+		Annotations: swarm.Annotations{Name: app.Name, Labels: labels},
+		TaskTemplate: swarm.TaskSpec{
+			ContainerSpec: &swarm.ContainerSpec{
+				Image:       fmt.Sprintf("%s:%s", app.Image.Name, app.Image.Tag),
+				Labels:      provider.CopyLabels(labels),
+				Env:         env,
+				Mounts:      toMounts(app.Mounts),
+				Healthcheck: healthConfig(app.HealthCheck),
+			},
+			Resources:     toResourceRequirements(app.Resources),
+			RestartPolicy: toRestartPolicy(app.RestartPolicy),
+			LogDriver:     toLogDriver(app.LogConfig),
+		},
+		Mode:         swarm.ServiceMode{Replicated: &swarm.ReplicatedService{Replicas: &replicas}},
+		UpdateConfig: toUpdateConfig(app.GetUpdateStrategy()),
+		EndpointSpec: toEndpointSpec(app.Ports),
+	}
+
+	return spec, nil
+}
+
+// toApplicationResource creates the application from the service specification, the running tasks are the instances.
+func toApplicationResource(spec swarm.ServiceSpec, running int) resource.Application {
+	labels := spec.Labels
+	app := resource.Application{
+		Name:      labels[provider.NameLabelTag.String()],
+		Instances: running,
+		Labels:    getLabelResources(labels),
+	}
+
+	if spec.TaskTemplate.ContainerSpec != nil {
+		containerSpec := spec.TaskTemplate.ContainerSpec
+		app.Image = toImageResource(containerSpec.Image)
+		app.Mounts = toMountResources(containerSpec.Mounts)
+		app.Env = getEnvResources(labels, containerSpec.Env)
+	}
+
+	if spec.EndpointSpec != nil {
+		app.Ports = toPortResources(spec.EndpointSpec.Ports)
+	} else {
+		app.Ports = make([]resource.Port, 0)
+	}
+
+	// the health check definition is kept in a label as the docker health check can not be translated back
+	if encoded := labels[provider.HealthLabelTag.String()]; encoded != "" {
+		health := &resource.HealthCheck{}
+		if err := json.Unmarshal([]byte(encoded), health); err == nil {
+			app.HealthCheck = health
+		}
+	}
+
+	if encoded := labels[provider.IngressLabelTag.String()]; encoded != "" {
+		ingress := &resource.Ingress{}
+		if err := json.Unmarshal([]byte(encoded), ingress); err == nil {
+			app.Ingress = ingress
+		}
+	}
+
+	if encoded := labels[provider.MetricsLabelTag.String()]; encoded != "" {
+		metrics := &resource.Metrics{}
+		if err := json.Unmarshal([]byte(encoded), metrics); err == nil {
+			app.Metrics = metrics
+		}
+	}
+
+	if encoded := labels[provider.RestartLabelTag.String()]; encoded != "" {
+		restart := &resource.RestartPolicy{}
+		if err := json.Unmarshal([]byte(encoded), restart); err == nil {
+			app.RestartPolicy = restart
+		}
+	}
+
+	app.Resources = toResources(spec.TaskTemplate.Resources)
+	return app
+}
+
+// toImageResource parses the image reference, swarm pins the image by appending its digest.
+func toImageResource(image string) resource.Image {
+	if idx := strings.Index(image, "@"); idx >= 0 {
+		image = image[:idx]
+	}
+
+	// the tag follows the last colon unless it is part of the registry address
+	idx := strings.LastIndex(image, ":")
+	if idx < 0 || strings.Contains(image[idx:], "/") {
+		return resource.Image{Name: image, Tag: "latest"}
+	}
+
+	return resource.Image{Name: image[:idx], Tag: image[idx+1:]}
+}
+
+func toEndpointSpec(ports []resource.Port) *swarm.EndpointSpec {
+	if len(ports) == 0 {
+		return nil
+	}
+
+	spec := &swarm.EndpointSpec{Mode: swarm.ResolutionModeVIP}
+	for _, port := range ports {
+		spec.Ports = append(spec.Ports, swarm.PortConfig{
+			Protocol:      swarm.PortConfigProtocol(port.Protocol),
+			TargetPort:    uint32(port.ContainerPort),
+			PublishedPort: uint32(port.HostPort),
+			PublishMode:   swarm.PortConfigPublishModeIngress,
+		})
+	}
+
+	return spec
+}
+
+func toPortResources(ports []swarm.PortConfig) []resource.Port {
+	resources := make([]resource.Port, 0, len(ports))
+	for _, port := range ports {
+		resources = append(resources, resource.Port{
+			ContainerPort: uint16(port.TargetPort),
+			HostPort:      uint16(port.PublishedPort),
+			Protocol:      resource.Protocol(port.Protocol),
+		})
+	}
+
+	return resources
+}
+
+func toMounts(mounts []resource.Mount) []mount.Mount {
+	if len(mounts) == 0 {
+		return nil
+	}
+
+	converted := make([]mount.Mount, len(mounts))
+	for idx, m := range mounts {
+		converted[idx] = mount.Mount{
+			Type:     mount.Type(m.Type),
+			Source:   m.Source,
+			Target:   m.Target,
+			ReadOnly: m.ReadOnly,
+		}
+	}
+
+	return converted
+}
+
+func toMountResources(mounts []mount.Mount) []resource.Mount {
+	if len(mounts) == 0 {
+		return nil
+	}
+
+	converted := make([]resource.Mount, len(mounts))
+	for idx, m := range mounts {
+		converted[idx] = resource.Mount{
+			Type:     resource.MountType(m.Type),
+			Source:   m.Source,
+			Target:   m.Target,
+			ReadOnly: m.ReadOnly,
+		}
+	}
+
+	return converted
+}
+
+func toResourceRequirements(resources *resource.Resources) *swarm.ResourceRequirements {
+	if resources.IsEmpty() {
+		return nil
+	}
+
+	requirements := &swarm.ResourceRequirements{
+		Limits: &swarm.Limit{
+			NanoCPUs:    int64(math.Round(resources.CPULimit * 1e9)),
+			MemoryBytes: resources.MemoryLimit,
+			Pids:        resources.PidsLimit,
+		},
+	}
+
+	if resources.MemoryReservation > 0 {
+		requirements.Reservations = &swarm.Resources{MemoryBytes: resources.MemoryReservation}
+	}
+
+	return requirements
+}
+
+func toResources(requirements *swarm.ResourceRequirements) *resource.Resources {
+	if requirements == nil {
+		return nil
+	}
+
+	resources := &resource.Resources{}
+	if requirements.Limits != nil {
+		resources.CPULimit = float64(requirements.Limits.NanoCPUs) / 1e9
+		resources.MemoryLimit = requirements.Limits.MemoryBytes
+		resources.PidsLimit = requirements.Limits.Pids
+	}
+
+	if requirements.Reservations != nil {
+		resources.MemoryReservation = requirements.Reservations.MemoryBytes
+	}
+
+	if resources.IsEmpty() {
+		return nil
+	}
+
+	return resources
+}
+
+// toRestartPolicy translates the restart policy. Swarm keeps the replicas running by restarting failed tasks,
+// which is why the tasks of applications without a policy are restarted as well.
+func toRestartPolicy(policy *resource.RestartPolicy) *swarm.RestartPolicy {
+	if policy.IsDefault() || policy.Name != resource.OnFailureRestartPolicy {
+		return &swarm.RestartPolicy{Condition: swarm.RestartPolicyConditionAny}
+	}
+
+	restart := &swarm.RestartPolicy{Condition: swarm.RestartPolicyConditionOnFailure}
+	if policy.MaxRetries > 0 {
+		attempts := uint64(policy.MaxRetries)
+		restart.MaxAttempts = &attempts
+	}
+
+	return restart
+}
+
+// toUpdateConfig translates the update strategy, a rolling update replaces one task at a time
+// starting the new task first, a recreate stops all tasks before starting the new ones.
+func toUpdateConfig(strategy resource.UpdateStrategy) *swarm.UpdateConfig {
+	if strategy == resource.RollingUpdateStrategy {
+		return &swarm.UpdateConfig{Parallelism: 1, Order: swarm.UpdateOrderStartFirst, FailureAction: swarm.UpdateFailureActionRollback}
+	}
+
+	return &swarm.UpdateConfig{Parallelism: 0, Order: swarm.UpdateOrderStopFirst}
+}
+
+func toLogDriver(logConfig *resource.LogConfig) *swarm.Driver {
+	if logConfig == nil || logConfig.Driver != resource.FluentdLogDriver {
+		return nil
+	}
+
+	return &swarm.Driver{
+		Name: "fluentd",
+		Options: map[string]string{
+			"labels":          strings.Join([]string{provider.KindLabelTag.String(), provider.ManagedByLabelTag.String(), provider.NameLabelTag.String()}, ","),
+			"fluentd-async":   "true",
+			"fluentd-address": logConfig.Config["address"],
+		},
+	}
+}
+
+// healthConfig translates the health check of the application to the docker health check.
+// The HTTP and TCP checks are executed within the container and require wget or nc to be available in the image.
+func healthConfig(check *resource.HealthCheck) *container.HealthConfig {
+	if check == nil {
+		return nil
+	}
+
+	config := &container.HealthConfig{
+		Interval:    check.IntervalDuration(),
+		Timeout:     check.TimeoutDuration(),
+		StartPeriod: check.StartPeriodDuration(),
+		Retries:     int(check.Retries),
+	}
+
+	switch check.Type {
+	case resource.CommandHealthCheck:
+		config.Test = append([]string{"CMD"}, check.Command...)
+	case resource.HttpHealthCheck:
+		url := fmt.Sprintf("http://localhost:%d%s", check.Port, check.Path)
+		config.Test = []string{"CMD-SHELL", fmt.Sprintf("wget -q -O /dev/null %s || exit 1", url)}
+	case resource.TcpHealthCheck:
+		config.Test = []string{"CMD-SHELL", fmt.Sprintf("nc -z localhost %d || exit 1", check.Port)}
+	default:
+		return nil
+	}
+
+	return config
+}
+
+// resolveEnv returns the environment variables in the docker format with the referenced secrets resolved.
+func resolveEnv(values map[string]string) ([]string, error) {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	env := make([]string, 0, len(keys))
+	for _, key := range keys {
+		value := values[key]
+		if resource.IsSecretReference(value) {
+			secret, err := files.ReadSecret(resource.SecretName(value))
+			if err != nil {
+				return nil, err
+			}
+
+			value = secret
+		}
+
+		env = append(env, fmt.Sprintf("%s=%s", key, value))
+	}
+
+	return env, nil
+}
+
+// getEnvResources returns the environment variables managed by the agent, secrets are replaced by their reference.
+func getEnvResources(labels map[string]string, env []string) map[string]string {
+	managed := labels[provider.EnvLabelTag.String()]
+	if managed == "" {
+		return nil
+	}
+
+	values := make(map[string]string, len(env))
+	for _, entry := range env {
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) == 2 {
+			values[parts[0]] = parts[1]
+		} else {
+			values[parts[0]] = ""
+		}
+	}
+
+	resources := make(map[string]string)
+	for _, key := range strings.Split(managed, ",") {
+		if reference := labels[provider.SecretLabelTag(key).String()]; reference != "" {
+			resources[key] = reference
+		} else {
+			resources[key] = values[key]
+		}
+	}
+
+	return resources
+}
+
+// getLabelResources returns the additional labels of the application.
+func getLabelResources(labels map[string]string) map[string]string {
+	managed := labels[provider.LabelsLabelTag.String()]
+	if managed == "" {
+		return nil
+	}
+
+	resources := make(map[string]string)
+	for _, key := range strings.Split(managed, ",") {
+		resources[key] = labels[key]
+	}
+
+	return resources
+}
@@ -0,0 +1,93 @@
+package swarm
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/mbaitar/gco/agent/internal/files"
+	"github.com/mbaitar/gco/agent/pkg/resource"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFromApplicationResource(t *testing.T) {
+	files.SetDirectory(t.TempDir())
+	dir, _ := files.GetDirectory()
+	_ = os.MkdirAll(path.Join(dir, "secrets"), 0700)
+	_ = os.WriteFile(path.Join(dir, "secrets", "postgres-password"), []byte("plaintext\n"), 0600)
+
+	application := &resource.Application{
+		Name:      "postgres",
+		Instances: 2,
+		Image:     resource.Image{Name: "registry.example.com:5000/postgres", Tag: "15"},
+		Ports:     []resource.Port{{HostPort: 5432, ContainerPort: 5432, Protocol: "tcp"}},
+		Env: map[string]string{
+			"POSTGRES_USER":     "admin",
+			"POSTGRES_PASSWORD": "secret:postgres-password",
+		},
+		Mounts: []resource.Mount{
+			{Type: resource.VolumeMountType, Source: "postgres-data", Target: "/var/lib/postgresql/data"},
+		},
+		Resources:   &resource.Resources{CPULimit: 0.5, MemoryLimit: 256 * 1024 * 1024},
+		HealthCheck: &resource.HealthCheck{Type: resource.TcpHealthCheck, Port: 5432, Interval: 10, Timeout: 2, Retries: 3},
+		Ingress:     &resource.Ingress{Hosts: []string{"example.com"}, Port: 5432},
+		Labels:      map[string]string{"traefik.enable": "true"},
+	}
+
+	spec, err := fromApplicationResource(application)
+	if !assert.Nil(t, err, "should not have thrown an error") {
+		return
+	}
+
+	assert.Equal(t, "postgres", spec.Name)
+	assert.Equal(t, 2, getReplicas(spec))
+	assert.Equal(t, "true", spec.Labels["traefik.enable"], "should have added the label to the service")
+	assert.Equal(t, spec.Labels, spec.TaskTemplate.ContainerSpec.Labels, "should have added the labels to the containers")
+	assert.Equal(t, []string{"POSTGRES_PASSWORD=plaintext", "POSTGRES_USER=admin"}, spec.TaskTemplate.ContainerSpec.Env)
+	assert.Equal(t, swarm.PortConfigPublishModeIngress, spec.EndpointSpec.Ports[0].PublishMode)
+
+	// swarm pins the image by its digest and returns the resolved secret
+	spec.TaskTemplate.ContainerSpec.Image = "registry.example.com:5000/postgres:15@sha256:abc"
+	parsed := toApplicationResource(spec, 1)
+	assert.Equal(t, 1, parsed.Instances, "should report the running tasks as instances")
+	assert.Equal(t, application.Image, parsed.Image)
+	assert.Equal(t, application.Env, parsed.Env, "should only include managed env and secret references")
+	assert.Equal(t, application.Resources, parsed.Resources)
+	assert.Equal(t, application.CalculateHash(), parsed.CalculateHash())
+}
+
+func TestToImageResource(t *testing.T) {
+	assert.Equal(t, resource.Image{Name: "nginx", Tag: "latest"}, toImageResource("nginx"))
+	assert.Equal(t, resource.Image{Name: "nginx", Tag: "1.23"}, toImageResource("nginx:1.23@sha256:abc"))
+	assert.Equal(t, resource.Image{Name: "localhost:5000/nginx", Tag: "latest"}, toImageResource("localhost:5000/nginx"))
+}
+
+func TestToRestartPolicy(t *testing.T) {
+	assert.Equal(t, swarm.RestartPolicyConditionAny, toRestartPolicy(nil).Condition, "should keep the replicas running by default")
+
+	policy := toRestartPolicy(&resource.RestartPolicy{Name: resource.OnFailureRestartPolicy, MaxRetries: 5})
+	assert.Equal(t, swarm.RestartPolicyConditionOnFailure, policy.Condition)
+	if assert.NotNil(t, policy.MaxAttempts) {
+		assert.Equal(t, uint64(5), *policy.MaxAttempts)
+	}
+
+	// the policy is kept in a label, as the default policy of swarm restarts failed tasks as well
+	application := &resource.Application{
+		Name:          "nginx",
+		Image:         resource.Image{Name: "nginx", Tag: "latest"},
+		RestartPolicy: &resource.RestartPolicy{Name: resource.OnFailureRestartPolicy, MaxRetries: 5},
+	}
+
+	spec, err := fromApplicationResource(application)
+	if assert.Nil(t, err) {
+		parsed := toApplicationResource(spec, 1)
+		assert.Equal(t, application.RestartPolicy, parsed.RestartPolicy)
+		assert.Equal(t, application.CalculateHash(), parsed.CalculateHash())
+	}
+}
+
+func TestToUpdateConfig(t *testing.T) {
+	assert.Equal(t, swarm.UpdateOrderStartFirst, toUpdateConfig(resource.RollingUpdateStrategy).Order)
+	assert.Equal(t, swarm.UpdateOrderStopFirst, toUpdateConfig(resource.RecreateUpdateStrategy).Order)
+}
@@ -2,16 +2,33 @@ package application
 
 import (
 	"context"
+	"errors"
 
 	applicationv1 "github.com/mbaitar/gco/agent/gen/proto/application/v1"
+	"github.com/mbaitar/gco/agent/internal/state"
+	"github.com/mbaitar/gco/agent/internal/state/diff"
+	"github.com/mbaitar/gco/agent/internal/state/persistence"
 	"github.com/mbaitar/gco/agent/pkg/control"
 	"github.com/mbaitar/gco/agent/pkg/resource"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
+// stateController contains the methods of the control.StateController used by the server.
+type stateController interface {
+	GetCurrentState() *state.Spec
+	GetActualState() state.Spec
+	GetBackoff(name string) (diff.Backoff, bool)
+	CreateApplication(application resource.Application) (*state.Spec, error)
+	UpdateApplication(application resource.Application) (*state.Spec, error)
+	DeleteApplication(name string) (*state.Spec, error)
+	RegisterHandler(handler control.StateUpdateHandler) string
+	RemoveHandler(signature string)
+}
+
 type Server struct {
-	state *control.StateController
+	state stateController
 
 	applicationv1.UnimplementedApplicationServiceServer
 }
@@ -34,7 +51,7 @@ func (s *Server) GetApplication(ctx context.Context, req *applicationv1.GetAppli
 	}
 
 	return &applicationv1.GetApplicationResponse{
-		Application: app.ToApplicationV1(),
+		Application: s.toApplicationV1(app),
 	}, nil
 }
 
@@ -44,9 +61,13 @@ func (s *Server) CreateApplication(ctx context.Context, req *applicationv1.Creat
 		return nil, status.Error(codes.InvalidArgument, "requires application argument")
 	}
 
+	if err := app.Validate(); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
 	_, err := s.state.CreateApplication(*app)
 	if err != nil {
-		return nil, status.Error(codes.AlreadyExists, err.Error())
+		return nil, toStatusError(err)
 	}
 
 	return &applicationv1.CreateApplicationResponse{}, nil
@@ -58,9 +79,13 @@ func (s *Server) UpdateApplication(ctx context.Context, req *applicationv1.Updat
 		return nil, status.Error(codes.InvalidArgument, "requires application argument")
 	}
 
+	if err := app.Validate(); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
 	_, err := s.state.UpdateApplication(*app)
 	if err != nil {
-		return nil, status.Error(codes.NotFound, err.Error())
+		return nil, toStatusError(err)
 	}
 
 	return &applicationv1.UpdateApplicationResponse{}, nil
@@ -73,7 +98,7 @@ func (s *Server) DeleteApplication(ctx context.Context, req *applicationv1.Delet
 
 	_, err := s.state.DeleteApplication(req.Name)
 	if err != nil {
-		return nil, status.Errorf(codes.NotFound, "application '%s' not found", req.Name)
+		return nil, toStatusError(err)
 	}
 
 	return &applicationv1.DeleteApplicationResponse{}, nil
@@ -84,10 +109,39 @@ func (s *Server) ListApplications(ctx context.Context, req *applicationv1.ListAp
 	apps := make([]*applicationv1.Application, 0)
 
 	for _, app := range state.Applications {
-		apps = append(apps, app.ToApplicationV1())
+		apps = append(apps, s.toApplicationV1(&app))
 	}
 
 	return &applicationv1.ListApplicationsResponse{
 		Applications: apps,
 	}, nil
 }
+
+// toApplicationV1 converts the application and includes the backoff when the application keeps failing.
+func (s *Server) toApplicationV1(app *resource.Application) *applicationv1.Application {
+	v1 := app.ToApplicationV1()
+
+	if backoff, found := s.state.GetBackoff(app.Name); found {
+		v1.Backoff = &applicationv1.ApplicationBackoff{
+			Failures:    uint32(backoff.Failures),
+			LastError:   backoff.LastError,
+			NextAttempt: timestamppb.New(backoff.NextAttempt),
+		}
+	}
+
+	return v1
+}
+
+// toStatusError converts the error of the state controller to a gRPC status error.
+func toStatusError(err error) error {
+	switch {
+	case errors.Is(err, state.ErrApplicationExists):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, state.ErrApplicationNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, persistence.ErrConflict):
+		return status.Error(codes.Aborted, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
@@ -0,0 +1,85 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	applicationv1 "github.com/mbaitar/gco/agent/gen/proto/application/v1"
+	"github.com/mbaitar/gco/agent/internal/state"
+	"github.com/mbaitar/gco/agent/internal/state/diff"
+	"github.com/mbaitar/gco/agent/internal/state/persistence"
+	"github.com/mbaitar/gco/agent/pkg/control"
+	"github.com/mbaitar/gco/agent/pkg/resource"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// FakeStateController returns the configured error for every change of the state.
+type FakeStateController struct {
+	err error
+}
+
+func (f *FakeStateController) GetCurrentState() *state.Spec {
+	return state.EmptySpec()
+}
+
+func (f *FakeStateController) GetActualState() state.Spec {
+	return *state.EmptySpec()
+}
+
+func (f *FakeStateController) GetBackoff(_ string) (diff.Backoff, bool) {
+	return diff.Backoff{}, false
+}
+
+func (f *FakeStateController) CreateApplication(_ resource.Application) (*state.Spec, error) {
+	return nil, f.err
+}
+
+func (f *FakeStateController) UpdateApplication(_ resource.Application) (*state.Spec, error) {
+	return nil, f.err
+}
+
+func (f *FakeStateController) DeleteApplication(_ string) (*state.Spec, error) {
+	return nil, f.err
+}
+
+func (f *FakeStateController) RegisterHandler(_ control.StateUpdateHandler) string {
+	return ""
+}
+
+func (f *FakeStateController) RemoveHandler(_ string) {}
+
+func TestServer_conflict(t *testing.T) {
+	server := &Server{state: &FakeStateController{err: persistence.ErrConflict}}
+	app := &applicationv1.Application{Name: "nginx", Image: &applicationv1.Image{Name: "nginx", Tag: "latest"}}
+
+	_, err := server.CreateApplication(context.Background(), &applicationv1.CreateApplicationRequest{Application: app})
+	assert.Equal(t, codes.Aborted, status.Code(err))
+
+	_, err = server.UpdateApplication(context.Background(), &applicationv1.UpdateApplicationRequest{Application: app})
+	assert.Equal(t, codes.Aborted, status.Code(err))
+
+	_, err = server.DeleteApplication(context.Background(), &applicationv1.DeleteApplicationRequest{Name: "nginx"})
+	assert.Equal(t, codes.Aborted, status.Code(err))
+}
+
+func TestServer_invalidUpdateStrategy(t *testing.T) {
+	server := &Server{state: &FakeStateController{}}
+	app := &applicationv1.Application{Name: "nginx", Image: &applicationv1.Image{Name: "nginx", Tag: "latest"}, UpdateStrategy: "blue-green"}
+
+	_, err := server.CreateApplication(context.Background(), &applicationv1.CreateApplicationRequest{Application: app})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = server.UpdateApplication(context.Background(), &applicationv1.UpdateApplicationRequest{Application: app})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func Test_toStatusError(t *testing.T) {
+	assert.Equal(t, codes.AlreadyExists, status.Code(toStatusError(fmt.Errorf("%w: 'nginx'", state.ErrApplicationExists))))
+	assert.Equal(t, codes.NotFound, status.Code(toStatusError(fmt.Errorf("%w: 'nginx'", state.ErrApplicationNotFound))))
+	assert.Equal(t, codes.Aborted, status.Code(toStatusError(persistence.ErrConflict)))
+	assert.Equal(t, codes.Internal, status.Code(toStatusError(errors.New("disk full"))))
+}
@@ -0,0 +1,93 @@
+package application
+
+import (
+	"context"
+
+	applicationv1 "github.com/mbaitar/gco/agent/gen/proto/application/v1"
+	"github.com/mbaitar/gco/agent/internal/log"
+	"github.com/mbaitar/gco/agent/internal/state"
+	"github.com/mbaitar/gco/agent/pkg/resource"
+)
+
+func (s *Server) WatchApplications(req *applicationv1.WatchApplicationsRequest, stream applicationv1.ApplicationService_WatchApplicationsServer) error {
+	return s.Watch(stream.Context(), stream.Send)
+}
+
+// Watch sends the current applications of the actual state as added events, followed by an event for every
+// application which has been added, changed or removed after a reconciliation.
+// This method will block until the context has been cancelled or sending an event failed.
+func (s *Server) Watch(ctx context.Context, send func(event *applicationv1.WatchApplicationsResponse) error) error {
+	// only the latest actual state is relevant, older updates can be replaced
+	updates := make(chan state.Spec, 1)
+	signature := s.state.RegisterHandler(func(spec state.Spec) {
+		select {
+		case updates <- spec:
+		default:
+			select {
+			case <-updates:
+			default:
+			}
+			updates <- spec
+		}
+	})
+	defer s.state.RemoveHandler(signature)
+
+	log.Debugf("Started watching applications (handler=%s)", signature)
+	previous := *state.EmptySpec()
+	current := s.state.GetActualState()
+
+	for {
+		for _, event := range applicationEvents(&previous, &current) {
+			if err := send(event); err != nil {
+				log.Debugf("Stopped watching applications (handler=%s): %v", signature, err)
+				return err
+			}
+		}
+
+		previous = current
+
+		select {
+		case <-ctx.Done():
+			log.Debugf("Stopped watching applications (handler=%s)", signature)
+			return nil
+		case current = <-updates:
+		}
+	}
+}
+
+// applicationEvents compares two actual states and returns an event for every added, changed or removed application.
+func applicationEvents(previous *state.Spec, current *state.Spec) []*applicationv1.WatchApplicationsResponse {
+	events := make([]*applicationv1.WatchApplicationsResponse, 0)
+
+	known := make(map[string]resource.Application)
+	for _, app := range previous.Applications {
+		known[app.Name] = app
+	}
+
+	for _, app := range current.Applications {
+		match, found := known[app.Name]
+		delete(known, app.Name)
+
+		if !found {
+			events = append(events, newApplicationEvent(applicationv1.ApplicationEventType_APPLICATION_EVENT_TYPE_ADDED, app))
+		} else if match.CalculateHash() != app.CalculateHash() || match.Instances != app.Instances {
+			events = append(events, newApplicationEvent(applicationv1.ApplicationEventType_APPLICATION_EVENT_TYPE_CHANGED, app))
+		}
+	}
+
+	// remaining applications are no longer part of the current state
+	for _, app := range previous.Applications {
+		if _, removed := known[app.Name]; removed {
+			events = append(events, newApplicationEvent(applicationv1.ApplicationEventType_APPLICATION_EVENT_TYPE_REMOVED, app))
+		}
+	}
+
+	return events
+}
+
+func newApplicationEvent(eventType applicationv1.ApplicationEventType, app resource.Application) *applicationv1.WatchApplicationsResponse {
+	return &applicationv1.WatchApplicationsResponse{
+		Type:        eventType,
+		Application: app.ToApplicationV1(),
+	}
+}
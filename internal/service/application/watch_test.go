@@ -0,0 +1,61 @@
+package application
+
+import (
+	"testing"
+
+	applicationv1 "github.com/mbaitar/gco/agent/gen/proto/application/v1"
+	"github.com/mbaitar/gco/agent/internal/state"
+	"github.com/mbaitar/gco/agent/pkg/resource"
+	"github.com/stretchr/testify/assert"
+)
+
+func sampleApp(name string, tag string) resource.Application {
+	return resource.Application{
+		Name:      name,
+		Image:     resource.Image{Name: "nginx", Tag: tag},
+		Instances: 1,
+	}
+}
+
+func Test_applicationEvents(t *testing.T) {
+	previous := &state.Spec{
+		Applications: []resource.Application{
+			sampleApp("unchanged", "latest"),
+			sampleApp("changed", "latest"),
+			sampleApp("removed", "latest"),
+		},
+	}
+
+	current := &state.Spec{
+		Applications: []resource.Application{
+			sampleApp("unchanged", "latest"),
+			sampleApp("changed", "v1.0.0"),
+			sampleApp("added", "latest"),
+		},
+	}
+
+	events := applicationEvents(previous, current)
+	if assert.Equal(t, 3, len(events), "should have found three events") {
+		types := make(map[string]applicationv1.ApplicationEventType)
+		for _, event := range events {
+			types[event.Application.Name] = event.Type
+		}
+
+		assert.Equal(t, applicationv1.ApplicationEventType_APPLICATION_EVENT_TYPE_CHANGED, types["changed"])
+		assert.Equal(t, applicationv1.ApplicationEventType_APPLICATION_EVENT_TYPE_ADDED, types["added"])
+		assert.Equal(t, applicationv1.ApplicationEventType_APPLICATION_EVENT_TYPE_REMOVED, types["removed"])
+	}
+}
+
+func Test_applicationEvents_instancesChanged(t *testing.T) {
+	app := sampleApp("app-1", "latest")
+	previous := &state.Spec{Applications: []resource.Application{app}}
+
+	app.Instances = 0
+	current := &state.Spec{Applications: []resource.Application{app}}
+
+	events := applicationEvents(previous, current)
+	if assert.Equal(t, 1, len(events)) {
+		assert.Equal(t, applicationv1.ApplicationEventType_APPLICATION_EVENT_TYPE_CHANGED, events[0].Type)
+	}
+}
@@ -0,0 +1,169 @@
+package feature
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	featurev1 "github.com/mbaitar/gco/agent/gen/proto/feature/v1"
+	"github.com/mbaitar/gco/agent/internal/provider"
+	"github.com/mbaitar/gco/agent/internal/state"
+	"github.com/mbaitar/gco/agent/internal/state/persistence"
+	"github.com/mbaitar/gco/agent/pkg/control"
+	"github.com/mbaitar/gco/agent/pkg/feature"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// stateController contains the methods of the control.StateController used by the server.
+type stateController interface {
+	GetCurrentState() *state.Spec
+	EnableFeature(feat feature.Feature) (*state.Spec, error)
+	UpdateFeature(feat feature.Feature) (*state.Spec, error)
+	DisableFeature(name string) (*state.Spec, error)
+}
+
+type Server struct {
+	state stateController
+
+	featurev1.UnimplementedFeatureServiceServer
+}
+
+func NewServer(state *control.StateController) *Server {
+	return &Server{
+		state: state,
+	}
+}
+
+func (s *Server) ListFeatures(ctx context.Context, req *featurev1.ListFeaturesRequest) (*featurev1.ListFeaturesResponse, error) {
+	state := s.state.GetCurrentState()
+	features := make([]*featurev1.Feature, 0)
+
+	for _, feat := range state.ListFeatures() {
+		v1, err := toFeatureV1(feat)
+		if err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+
+		features = append(features, v1)
+	}
+
+	return &featurev1.ListFeaturesResponse{
+		Features: features,
+	}, nil
+}
+
+func (s *Server) GetFeature(ctx context.Context, req *featurev1.GetFeatureRequest) (*featurev1.GetFeatureResponse, error) {
+	if req.Name == "" {
+		return nil, status.Error(codes.InvalidArgument, "name required")
+	}
+
+	state := s.state.GetCurrentState()
+	feat := state.GetFeature(req.Name)
+	if feat == nil {
+		return nil, status.Errorf(codes.NotFound, "feature '%s' not enabled", req.Name)
+	}
+
+	v1, err := toFeatureV1(feat)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &featurev1.GetFeatureResponse{
+		Feature: v1,
+	}, nil
+}
+
+func (s *Server) EnableFeature(ctx context.Context, req *featurev1.EnableFeatureRequest) (*featurev1.EnableFeatureResponse, error) {
+	feat, err := fromFeatureV1(req.Feature)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.state.EnableFeature(feat)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	return &featurev1.EnableFeatureResponse{}, nil
+}
+
+func (s *Server) UpdateFeature(ctx context.Context, req *featurev1.UpdateFeatureRequest) (*featurev1.UpdateFeatureResponse, error) {
+	feat, err := fromFeatureV1(req.Feature)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.state.UpdateFeature(feat)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	return &featurev1.UpdateFeatureResponse{}, nil
+}
+
+func (s *Server) DisableFeature(ctx context.Context, req *featurev1.DisableFeatureRequest) (*featurev1.DisableFeatureResponse, error) {
+	if req.Name == "" {
+		return nil, status.Error(codes.InvalidArgument, "name required")
+	}
+
+	_, err := s.state.DisableFeature(req.Name)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	return &featurev1.DisableFeatureResponse{}, nil
+}
+
+// toFeatureV1 converts the feature with its configuration encoded as JSON.
+func toFeatureV1(feat feature.Feature) (*featurev1.Feature, error) {
+	config, err := json.Marshal(feat)
+	if err != nil {
+		return nil, err
+	}
+
+	return &featurev1.Feature{
+		Name:       feat.Name(),
+		Config:     string(config),
+		ConfigHash: feat.ConfigHash(),
+	}, nil
+}
+
+// fromFeatureV1 creates the feature matching the name using the JSON encoded configuration.
+// The returned error is a gRPC status error.
+func fromFeatureV1(v1 *featurev1.Feature) (feature.Feature, error) {
+	if v1 == nil {
+		return nil, status.Error(codes.InvalidArgument, "requires feature argument")
+	}
+
+	feat, err := feature.New(v1.Name)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "feature '%s' is not supported", v1.Name)
+	}
+
+	if v1.Config != "" {
+		if err = json.Unmarshal([]byte(v1.Config), feat); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid configuration for feature '%s': %v", v1.Name, err)
+		}
+	}
+
+	return feat, nil
+}
+
+// toStatusError converts the error of the state controller to a gRPC status error.
+func toStatusError(err error) error {
+	switch {
+	case errors.Is(err, state.ErrFeatureEnabled):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, state.ErrFeatureNotEnabled):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, feature.ErrUnknownFeature):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, provider.ErrFeatureNotSupported):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, persistence.ErrConflict):
+		return status.Error(codes.Aborted, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
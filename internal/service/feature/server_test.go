@@ -0,0 +1,92 @@
+package feature
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	featurev1 "github.com/mbaitar/gco/agent/gen/proto/feature/v1"
+	"github.com/mbaitar/gco/agent/internal/provider"
+	"github.com/mbaitar/gco/agent/internal/state"
+	"github.com/mbaitar/gco/agent/internal/state/persistence"
+	"github.com/mbaitar/gco/agent/pkg/feature"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// FakeStateController returns the configured error for every change of the state.
+type FakeStateController struct {
+	err error
+}
+
+func (f *FakeStateController) GetCurrentState() *state.Spec {
+	return state.EmptySpec()
+}
+
+func (f *FakeStateController) EnableFeature(_ feature.Feature) (*state.Spec, error) {
+	return nil, f.err
+}
+
+func (f *FakeStateController) UpdateFeature(_ feature.Feature) (*state.Spec, error) {
+	return nil, f.err
+}
+
+func (f *FakeStateController) DisableFeature(_ string) (*state.Spec, error) {
+	return nil, f.err
+}
+
+func TestServer_conflict(t *testing.T) {
+	server := &Server{state: &FakeStateController{err: persistence.ErrConflict}}
+	feat := &featurev1.Feature{Name: feature.NameFluentBit}
+
+	_, err := server.EnableFeature(context.Background(), &featurev1.EnableFeatureRequest{Feature: feat})
+	assert.Equal(t, codes.Aborted, status.Code(err))
+
+	_, err = server.UpdateFeature(context.Background(), &featurev1.UpdateFeatureRequest{Feature: feat})
+	assert.Equal(t, codes.Aborted, status.Code(err))
+
+	_, err = server.DisableFeature(context.Background(), &featurev1.DisableFeatureRequest{Name: feature.NameFluentBit})
+	assert.Equal(t, codes.Aborted, status.Code(err))
+}
+
+func Test_toStatusError(t *testing.T) {
+	assert.Equal(t, codes.AlreadyExists, status.Code(toStatusError(fmt.Errorf("%w: 'ingress'", state.ErrFeatureEnabled))))
+	assert.Equal(t, codes.NotFound, status.Code(toStatusError(fmt.Errorf("%w: 'ingress'", state.ErrFeatureNotEnabled))))
+	assert.Equal(t, codes.FailedPrecondition, status.Code(toStatusError(fmt.Errorf("%w: ingress", provider.ErrFeatureNotSupported))))
+	assert.Equal(t, codes.Aborted, status.Code(toStatusError(persistence.ErrConflict)))
+	assert.Equal(t, codes.Internal, status.Code(toStatusError(errors.New("disk full"))))
+}
+
+func Test_toFeatureV1(t *testing.T) {
+	fb := &feature.FluentBit{LogLevel: "info"}
+
+	v1, err := toFeatureV1(fb)
+	if assert.Nil(t, err) {
+		assert.Equal(t, feature.NameFluentBit, v1.Name)
+		assert.Equal(t, fb.ConfigHash(), v1.ConfigHash)
+		assert.Contains(t, v1.Config, `"logLevel":"info"`)
+	}
+}
+
+func Test_fromFeatureV1(t *testing.T) {
+	feat, err := fromFeatureV1(&featurev1.Feature{Name: feature.NameFluentBit, Config: `{"logLevel":"debug"}`})
+	if assert.Nil(t, err) {
+		fb, ok := feat.(*feature.FluentBit)
+		if assert.True(t, ok, "should have created the fluent-bit feature") {
+			assert.Equal(t, "debug", fb.LogLevel)
+		}
+	}
+}
+
+func Test_fromFeatureV1_invalid(t *testing.T) {
+	_, err := fromFeatureV1(nil)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = fromFeatureV1(&featurev1.Feature{Name: "unknown"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err), "should reject unknown features")
+
+	_, err = fromFeatureV1(&featurev1.Feature{Name: feature.NameFluentBit, Config: "{"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err), "should reject invalid configuration")
+}
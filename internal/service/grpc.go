@@ -5,9 +5,13 @@ import (
 	"os"
 
 	applicationv1 "github.com/mbaitar/gco/agent/gen/proto/application/v1"
+	featurev1 "github.com/mbaitar/gco/agent/gen/proto/feature/v1"
+	statev1 "github.com/mbaitar/gco/agent/gen/proto/state/v1"
 	"github.com/mbaitar/gco/agent/internal/config"
 	"github.com/mbaitar/gco/agent/internal/log"
 	"github.com/mbaitar/gco/agent/internal/service/application"
+	"github.com/mbaitar/gco/agent/internal/service/feature"
+	"github.com/mbaitar/gco/agent/internal/service/state"
 	"github.com/mbaitar/gco/agent/pkg/control"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/reflection"
@@ -28,8 +32,13 @@ func StartGRPC(conf config.Grpc, controller *control.StateController) {
 	}
 
 	// create gRPC server
-	server := grpc.NewServer()
+	server := grpc.NewServer(
+		grpc.UnaryInterceptor(unaryMetricsInterceptor),
+		grpc.StreamInterceptor(streamMetricsInterceptor),
+	)
 	applicationv1.RegisterApplicationServiceServer(server, application.NewServer(controller))
+	featurev1.RegisterFeatureServiceServer(server, feature.NewServer(controller))
+	statev1.RegisterStateServiceServer(server, state.NewServer(controller))
 
 	if conf.EnableReflection {
 		log.Debug("gRPC reflection mode has been enabled")
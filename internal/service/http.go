@@ -11,14 +11,19 @@ import (
 	"os"
 
 	applicationv1 "github.com/mbaitar/gco/agent/gen/proto/application/v1"
+	featurev1 "github.com/mbaitar/gco/agent/gen/proto/feature/v1"
+	statev1 "github.com/mbaitar/gco/agent/gen/proto/state/v1"
 	"github.com/mbaitar/gco/agent/internal/config"
 	"github.com/mbaitar/gco/agent/internal/log"
 	"github.com/mbaitar/gco/agent/internal/service/application"
+	"github.com/mbaitar/gco/agent/internal/service/feature"
+	"github.com/mbaitar/gco/agent/internal/service/state"
 	"github.com/mbaitar/gco/agent/pkg/control"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
 	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 type httpServer struct {
@@ -40,6 +45,8 @@ func StartHTTP(conf config.Http, controller *control.StateController) {
 
 	// create services
 	appServer := application.NewServer(controller)
+	featServer := feature.NewServer(controller)
+	stateServer := state.NewServer(controller)
 
 	// register routes
 	router := mux.NewRouter().StrictSlash(true)
@@ -48,6 +55,17 @@ func StartHTTP(conf config.Http, controller *control.StateController) {
 	router.HandleFunc("/api/v1/applications.get", serviceWrapper(&applicationv1.GetApplicationRequest{}, appServer.GetApplication)).Methods(http.MethodPost)
 	router.HandleFunc("/api/v1/applications.update", serviceWrapper(&applicationv1.UpdateApplicationRequest{}, appServer.UpdateApplication)).Methods(http.MethodPost)
 	router.HandleFunc("/api/v1/applications.delete", serviceWrapper(&applicationv1.DeleteApplicationRequest{}, appServer.DeleteApplication)).Methods(http.MethodPost)
+	router.HandleFunc("/api/v1/applications.watch", eventStreamWrapper(appServer.Watch)).Methods(http.MethodGet)
+	router.HandleFunc("/api/v1/features.list", serviceWrapper(&featurev1.ListFeaturesRequest{}, featServer.ListFeatures)).Methods(http.MethodPost)
+	router.HandleFunc("/api/v1/features.get", serviceWrapper(&featurev1.GetFeatureRequest{}, featServer.GetFeature)).Methods(http.MethodPost)
+	router.HandleFunc("/api/v1/features.enable", serviceWrapper(&featurev1.EnableFeatureRequest{}, featServer.EnableFeature)).Methods(http.MethodPost)
+	router.HandleFunc("/api/v1/features.update", serviceWrapper(&featurev1.UpdateFeatureRequest{}, featServer.UpdateFeature)).Methods(http.MethodPost)
+	router.HandleFunc("/api/v1/features.disable", serviceWrapper(&featurev1.DisableFeatureRequest{}, featServer.DisableFeature)).Methods(http.MethodPost)
+	router.HandleFunc("/api/v1/revisions.list", serviceWrapper(&statev1.ListRevisionsRequest{}, stateServer.ListRevisions)).Methods(http.MethodPost)
+	router.HandleFunc("/api/v1/revisions.get", serviceWrapper(&statev1.GetRevisionRequest{}, stateServer.GetRevision)).Methods(http.MethodPost)
+	router.HandleFunc("/api/v1/revisions.rollback", serviceWrapper(&statev1.RollbackToRevisionRequest{}, stateServer.RollbackToRevision)).Methods(http.MethodPost)
+	router.Handle("/metrics", promhttp.Handler()).Methods(http.MethodGet)
+	router.Use(httpMetricsMiddleware)
 
 	// start listening for HTTP connections
 	log.Infof("Started listening for HTTP connections on '%s'", conf.GetNetworkAddress())
@@ -99,6 +117,41 @@ func serviceWrapper[ServiceRequest any, ServiceResponse any](sReq ServiceRequest
 	}
 }
 
+// eventStreamWrapper streams the events sent by the handler to the client using Server-Sent Events.
+func eventStreamWrapper[Event any](handler func(ctx context.Context, send func(event Event) error) error) func(res http.ResponseWriter, req *http.Request) {
+	return func(res http.ResponseWriter, req *http.Request) {
+		flusher, ok := res.(http.Flusher)
+		if !ok {
+			writeHttpError(res, errors.New("streaming is not supported"))
+			return
+		}
+
+		res.Header().Set("Content-Type", "text/event-stream")
+		res.Header().Set("Cache-Control", "no-cache")
+		res.Header().Set("Connection", "keep-alive")
+		res.WriteHeader(http.StatusOK)
+		flusher.Flush()
+
+		err := handler(req.Context(), func(event Event) error {
+			body, err := json.Marshal(event)
+			if err != nil {
+				return err
+			}
+
+			if _, err = fmt.Fprintf(res, "data: %s\n\n", body); err != nil {
+				return err
+			}
+
+			flusher.Flush()
+			return nil
+		})
+
+		if err != nil {
+			log.Debugf("Closed event stream for '%s': %v", req.URL.Path, err)
+		}
+	}
+}
+
 func writeHttpError(res http.ResponseWriter, error error) {
 	message := error.Error()
 	httpStatus := http.StatusInternalServerError
@@ -107,10 +160,12 @@ func writeHttpError(res http.ResponseWriter, error error) {
 
 		// extract status code
 		switch s.Code() {
-		case codes.InvalidArgument, codes.AlreadyExists:
+		case codes.InvalidArgument, codes.AlreadyExists, codes.FailedPrecondition:
 			httpStatus = http.StatusBadRequest
 		case codes.NotFound:
 			httpStatus = http.StatusNotFound
+		case codes.Aborted:
+			httpStatus = http.StatusConflict
 		}
 
 		// update message
@@ -0,0 +1,62 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/mbaitar/gco/agent/internal/metrics"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// unaryMetricsInterceptor counts the unary gRPC requests by method and status code.
+func unaryMetricsInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	res, err := handler(ctx, req)
+	metrics.ApiRequests.WithLabelValues("grpc", info.FullMethod, status.Code(err).String()).Inc()
+	return res, err
+}
+
+// streamMetricsInterceptor counts the streaming gRPC requests by method and status code.
+func streamMetricsInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	err := handler(srv, ss)
+	metrics.ApiRequests.WithLabelValues("grpc", info.FullMethod, status.Code(err).String()).Inc()
+	return err
+}
+
+// statusRecorder keeps track of the status code written to the response.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Flush allows event streams to flush through the recorder.
+func (r *statusRecorder) Flush() {
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// httpMetricsMiddleware counts the HTTP requests by route and status code.
+func httpMetricsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		recorder := &statusRecorder{ResponseWriter: res, status: http.StatusOK}
+		next.ServeHTTP(recorder, req)
+
+		// use the route template to keep the number of label values bounded
+		method := req.URL.Path
+		if route := mux.CurrentRoute(req); route != nil {
+			if template, err := route.GetPathTemplate(); err == nil {
+				method = template
+			}
+		}
+
+		metrics.ApiRequests.WithLabelValues("http", method, strconv.Itoa(recorder.status)).Inc()
+	})
+}
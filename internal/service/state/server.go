@@ -0,0 +1,119 @@
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	statev1 "github.com/mbaitar/gco/agent/gen/proto/state/v1"
+	"github.com/mbaitar/gco/agent/internal/state/persistence"
+	"github.com/mbaitar/gco/agent/pkg/control"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type Server struct {
+	state *control.StateController
+
+	statev1.UnimplementedStateServiceServer
+}
+
+func NewServer(state *control.StateController) *Server {
+	return &Server{
+		state: state,
+	}
+}
+
+func (s *Server) ListRevisions(ctx context.Context, req *statev1.ListRevisionsRequest) (*statev1.ListRevisionsResponse, error) {
+	revisions, err := s.state.ListRevisions(int(req.Limit))
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	v1 := make([]*statev1.Revision, 0, len(revisions))
+	for _, revision := range revisions {
+		converted, err := toRevisionV1(&revision)
+		if err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+
+		v1 = append(v1, converted)
+	}
+
+	return &statev1.ListRevisionsResponse{
+		Revisions: v1,
+	}, nil
+}
+
+func (s *Server) GetRevision(ctx context.Context, req *statev1.GetRevisionRequest) (*statev1.GetRevisionResponse, error) {
+	if req.Number == 0 {
+		return nil, status.Error(codes.InvalidArgument, "number required")
+	}
+
+	revision, err := s.state.GetRevision(int64(req.Number))
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	v1, err := toRevisionV1(revision)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &statev1.GetRevisionResponse{
+		Revision: v1,
+	}, nil
+}
+
+func (s *Server) RollbackToRevision(ctx context.Context, req *statev1.RollbackToRevisionRequest) (*statev1.RollbackToRevisionResponse, error) {
+	if req.Number == 0 {
+		return nil, status.Error(codes.InvalidArgument, "number required")
+	}
+
+	revision, err := s.state.RollbackToRevision(int64(req.Number))
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	v1, err := toRevisionV1(revision)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &statev1.RollbackToRevisionResponse{
+		Revision: v1,
+	}, nil
+}
+
+// toRevisionV1 converts the revision with its state specification encoded as JSON, when it has been included.
+func toRevisionV1(revision *persistence.Revision) (*statev1.Revision, error) {
+	v1 := &statev1.Revision{
+		Number:    uint64(revision.Number),
+		CreatedAt: timestamppb.New(revision.CreatedAt),
+		Origin:    revision.Origin,
+	}
+
+	if revision.Spec != nil {
+		spec, err := json.Marshal(revision.Spec)
+		if err != nil {
+			return nil, err
+		}
+
+		v1.Spec = string(spec)
+	}
+
+	return v1, nil
+}
+
+// toStatusError converts the error of the state controller to a gRPC status error.
+func toStatusError(err error) error {
+	switch {
+	case errors.Is(err, control.ErrRevisionsUnsupported):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, persistence.ErrRevisionNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
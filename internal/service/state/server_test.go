@@ -0,0 +1,40 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/mbaitar/gco/agent/internal/state"
+	"github.com/mbaitar/gco/agent/internal/state/persistence"
+	"github.com/mbaitar/gco/agent/pkg/control"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func Test_toRevisionV1(t *testing.T) {
+	createdAt := time.UnixMilli(1700000000000)
+	revision := &persistence.Revision{Number: 3, CreatedAt: createdAt, Origin: "UpdateApplication"}
+
+	v1, err := toRevisionV1(revision)
+	if assert.Nil(t, err) {
+		assert.Equal(t, uint64(3), v1.Number)
+		assert.Equal(t, createdAt, v1.CreatedAt.AsTime().Local())
+		assert.Equal(t, "UpdateApplication", v1.Origin)
+		assert.Empty(t, v1.Spec, "should not include a missing state specification")
+	}
+
+	revision.Spec = state.EmptySpec()
+	v1, err = toRevisionV1(revision)
+	if assert.Nil(t, err) {
+		assert.Contains(t, v1.Spec, `"applications":[]`)
+	}
+}
+
+func Test_toStatusError(t *testing.T) {
+	assert.Equal(t, codes.FailedPrecondition, status.Code(toStatusError(control.ErrRevisionsUnsupported)))
+	assert.Equal(t, codes.NotFound, status.Code(toStatusError(fmt.Errorf("%w: 42", persistence.ErrRevisionNotFound))))
+	assert.Equal(t, codes.Internal, status.Code(toStatusError(errors.New("database is locked"))))
+}
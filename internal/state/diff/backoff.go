@@ -0,0 +1,169 @@
+package diff
+
+import (
+	"errors"
+	"time"
+
+	"github.com/mbaitar/gco/agent/pkg/resource"
+)
+
+const (
+	// defaultBackoffInitial is the delay after the first failed attempt to create or update an application.
+	defaultBackoffInitial = 5 * time.Second
+	// defaultBackoffMax is the maximum delay between two attempts to create or update an application.
+	defaultBackoffMax = 5 * time.Minute
+	// crashLoopWindow is the time after creating or updating an application in which exited or unhealthy instances
+	// are considered a crash loop instead of a drift.
+	crashLoopWindow = time.Minute
+)
+
+// ErrCrashLoop is recorded as the error of applications whose instances exited shortly after being started.
+var ErrCrashLoop = errors.New("instances exited shortly after being started")
+
+// Backoff describes an application which repeatedly failed to be created or updated.
+type Backoff struct {
+	// Failures is the number of consecutive failed attempts.
+	Failures int
+	// LastError is the error returned by the provider on the last attempt.
+	LastError string
+	// NextAttempt is the time after which the application will be retried.
+	NextAttempt time.Time
+
+	// hash is the hash of the application which failed, a changed application is retried immediately.
+	hash string
+	// crashLoop is true when the instances exited after the application has been started successfully.
+	crashLoop bool
+}
+
+// started describes an application which has been created or updated successfully.
+type started struct {
+	hash      string
+	instances int
+	at        time.Time
+	// crashed is true when the crash loop has been recorded, the application is started again after the backoff.
+	crashed bool
+}
+
+// WithBackoff sets the initial and maximum delay between attempts for applications which keep failing.
+func (r *Reconciler) WithBackoff(initial time.Duration, max time.Duration) *Reconciler {
+	r.backoffInitial = initial
+	r.backoffMax = max
+	return r
+}
+
+// Backoff returns a copy of the backoff state of all applications which are currently failing.
+func (r *Reconciler) Backoff() map[string]Backoff {
+	backoff := make(map[string]Backoff, len(r.backoff))
+	for name, entry := range r.backoff {
+		backoff[name] = entry
+	}
+
+	return backoff
+}
+
+// inBackoff returns true when the application failed before and should not be retried yet.
+func (r *Reconciler) inBackoff(app *resource.Application) bool {
+	entry, found := r.backoff[app.Name]
+	if !found {
+		return false
+	}
+
+	if entry.hash != app.CalculateHash() {
+		delete(r.backoff, app.Name)
+		return false
+	}
+
+	return r.now().Before(entry.NextAttempt)
+}
+
+// recordFailure increases the backoff of the application, doubling the delay up to the maximum.
+func (r *Reconciler) recordFailure(app *resource.Application, err error) Backoff {
+	entry := r.backoff[app.Name]
+	if entry.hash != app.CalculateHash() {
+		entry = Backoff{hash: app.CalculateHash()}
+	}
+
+	delay := r.backoffInitial
+	for i := 0; i < entry.Failures && delay < r.backoffMax; i++ {
+		delay *= 2
+	}
+
+	if delay > r.backoffMax {
+		delay = r.backoffMax
+	}
+
+	entry.Failures++
+	entry.LastError = err.Error()
+	entry.NextAttempt = r.now().Add(delay)
+
+	r.backoff[app.Name] = entry
+	return entry
+}
+
+// recordSuccess resets the backoff of the application. The backoff of a crash loop is kept until the instances
+// keep running, as these are started successfully every time.
+func (r *Reconciler) recordSuccess(app *resource.Application) {
+	r.started[app.Name] = started{hash: app.CalculateHash(), instances: app.GetInstances(), at: r.now()}
+	if !r.backoff[app.Name].crashLoop {
+		delete(r.backoff, app.Name)
+	}
+}
+
+// isCrashLooping returns true when instances of the application exited or are unhealthy shortly after it has been
+// created or updated, while the application did not change. Instances which are missing because their health check
+// is still starting are not considered a crash loop.
+func (r *Reconciler) isCrashLooping(app *resource.Application) bool {
+	entry, found := r.started[app.Name]
+	if !found || entry.crashed || entry.hash != app.CalculateHash() || entry.instances != app.GetInstances() {
+		return false
+	}
+
+	if r.now().Sub(entry.at) >= crashLoopWindow {
+		return false
+	}
+
+	if r.actual == nil {
+		return false
+	}
+
+	actual := r.actual.GetApplication(app.Name)
+	return actual != nil && (actual.ExitedInstances > 0 || actual.UnhealthyInstances > 0)
+}
+
+// recordCrashLoop increases the backoff of the application, it is started again once the backoff passed.
+func (r *Reconciler) recordCrashLoop(app *resource.Application) Backoff {
+	if start, found := r.started[app.Name]; found {
+		start.crashed = true
+		r.started[app.Name] = start
+	}
+
+	entry := r.recordFailure(app, ErrCrashLoop)
+	entry.crashLoop = true
+	r.backoff[app.Name] = entry
+	return entry
+}
+
+// recordRunning resets the backoff of the application once the instances kept running for the crash loop window.
+func (r *Reconciler) recordRunning(app *resource.Application) {
+	if entry, found := r.started[app.Name]; found && r.now().Sub(entry.at) < crashLoopWindow {
+		return
+	}
+
+	delete(r.started, app.Name)
+	delete(r.backoff, app.Name)
+}
+
+// pruneBackoff removes the backoff of applications which are no longer part of the desired state.
+func (r *Reconciler) pruneBackoff() {
+	for name := range r.backoff {
+		if r.desired == nil || r.desired.GetApplication(name) == nil {
+			delete(r.backoff, name)
+		}
+	}
+
+	for name := range r.started {
+		if r.desired == nil || r.desired.GetApplication(name) == nil {
+			delete(r.started, name)
+		}
+	}
+}
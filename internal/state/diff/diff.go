@@ -3,6 +3,7 @@ package diff
 import (
 	"github.com/mbaitar/gco/agent/internal/flag"
 	"github.com/mbaitar/gco/agent/internal/log"
+	"github.com/mbaitar/gco/agent/internal/metrics"
 	"github.com/mbaitar/gco/agent/internal/state"
 	"github.com/mbaitar/gco/agent/pkg/feature"
 	"github.com/mbaitar/gco/agent/pkg/resource"
@@ -40,9 +41,8 @@ func newSpecMap(spec *state.Spec) *specMap {
 		appLookup[name] = app
 	}
 
-	if spec.Feature.FluentBit != nil {
-		name := spec.Feature.FluentBit.Name()
-		featureLookup[name] = spec.Feature.FluentBit
+	for name, feat := range spec.Feature {
+		featureLookup[name] = feat
 	}
 
 	return &specMap{
@@ -79,7 +79,8 @@ func (sm *specMap) RemoveFeature(name string) {
 }
 
 // compare defines a function which will calculate the state changes between the actual and desired system.
-func compare(desired *state.Spec, actual *state.Spec) *changes {
+// Features which are not supported by the provider are neither evaluated nor created.
+func compare(desired *state.Spec, actual *state.Spec, supports func(name string) bool) *changes {
 	output := &changes{}
 
 	if desired == nil {
@@ -90,10 +91,18 @@ func compare(desired *state.Spec, actual *state.Spec) *changes {
 		actual = state.EmptySpec()
 	}
 
-	// evaluate both state specifications before comparing
-	log.Debug("Evaluating state specifications before comparing")
+	// evaluate a copy of the desired state specification before comparing, the actual state is read
+	// from the external system and already contains the configuration of the enabled features
+	log.Debug("Evaluating desired state specification before comparing")
+	desired = desired.Copy()
+	for _, name := range desired.Feature.Names() {
+		if !supports(name) {
+			log.Debugf("Ignoring feature=%s which is not supported by the provider", name)
+			_ = desired.DisableFeature(name)
+		}
+	}
+
 	desired.Evaluate()
-	actual.Evaluate()
 
 	desiredMap := newSpecMap(desired)
 	actualMap := newSpecMap(actual)
@@ -125,10 +134,11 @@ func compare(desired *state.Spec, actual *state.Spec) *changes {
 			desiredHash := app.CalculateHash()
 
 			hashMismatch := actualHash != desiredHash
-			instanceMismatch := app.Instances != match.Instances
-			log.Debugf("Difference calculation for app '%s' (hash=%v, instance=%v)", app.Name, hashMismatch, instanceMismatch)
+			instanceMismatch := app.GetInstances() != match.Instances
+			unhealthy := match.UnhealthyInstances > 0
+			log.Debugf("Difference calculation for app '%s' (hash=%v, instance=%v, unhealthy=%v)", app.Name, hashMismatch, instanceMismatch, unhealthy)
 
-			if hashMismatch || instanceMismatch {
+			if hashMismatch || instanceMismatch || unhealthy {
 				output.apps.changed = append(output.apps.changed, app)
 			} else {
 				output.apps.unchanged = append(output.apps.unchanged, app)
@@ -173,5 +183,16 @@ func compare(desired *state.Spec, actual *state.Spec) *changes {
 		output.features.removed = append(output.features.removed, feat)
 	}
 
+	output.recordDrift()
 	return output
 }
+
+// recordDrift counts the differences between the desired and actual state in the drift metric.
+func (c *changes) recordDrift() {
+	metrics.DriftDetected.WithLabelValues("application", "added").Add(float64(len(c.apps.added)))
+	metrics.DriftDetected.WithLabelValues("application", "changed").Add(float64(len(c.apps.changed)))
+	metrics.DriftDetected.WithLabelValues("application", "removed").Add(float64(len(c.apps.removed)))
+	metrics.DriftDetected.WithLabelValues("feature", "added").Add(float64(len(c.features.added)))
+	metrics.DriftDetected.WithLabelValues("feature", "changed").Add(float64(len(c.features.changed)))
+	metrics.DriftDetected.WithLabelValues("feature", "removed").Add(float64(len(c.features.removed)))
+}
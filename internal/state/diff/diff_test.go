@@ -23,6 +23,11 @@ func SampleApp(name string) *resource.Application {
 	}
 }
 
+// supportsAll mimics a provider which supports every feature.
+func supportsAll(string) bool {
+	return true
+}
+
 func Test_changes_onlyAddedResources(t *testing.T) {
 	actual := state.EmptySpec()
 	desired := &state.Spec{
@@ -33,7 +38,7 @@ func Test_changes_onlyAddedResources(t *testing.T) {
 		},
 	}
 
-	c := compare(desired, actual)
+	c := compare(desired, actual, supportsAll)
 	if assert.NotNil(t, c, "should not return nil") {
 		assert.Equal(t, 3, len(c.apps.added), "should have found three new resources")
 
@@ -67,7 +72,7 @@ func Test_changes_unchangedApplication(t *testing.T) {
 		},
 	}
 
-	c := compare(desired, actual)
+	c := compare(desired, actual, supportsAll)
 	if assert.NotNil(t, c, "should not return nil") {
 		assert.Equal(t, 1, len(c.apps.unchanged), "should have found one unchanged resource")
 
@@ -99,7 +104,7 @@ func Test_changes_changedApplication(t *testing.T) {
 		},
 	}
 
-	c := compare(desired, actual)
+	c := compare(desired, actual, supportsAll)
 	if assert.NotNil(t, c, "should not return nil") {
 		assert.Equal(t, 1, len(c.apps.changed), "should have found one changed resource")
 
@@ -122,7 +127,7 @@ func Test_changes_removedApplication(t *testing.T) {
 	}
 	desired := state.EmptySpec()
 
-	c := compare(desired, actual)
+	c := compare(desired, actual, supportsAll)
 	if assert.NotNil(t, c, "should not return nil") {
 		assert.Equal(t, 1, len(c.apps.removed), "should have found one removed resource")
 
@@ -144,7 +149,7 @@ func Test_changes_nilDesiredState(t *testing.T) {
 		},
 	}
 
-	c := compare(desired, nil)
+	c := compare(desired, nil, supportsAll)
 	if assert.NotNil(t, c, "should not return nil") {
 		assert.Equal(t, 1, len(c.apps.added), "should have found one added resource")
 	}
@@ -157,7 +162,7 @@ func Test_changes_nilActualState(t *testing.T) {
 		},
 	}
 
-	c := compare(nil, actual)
+	c := compare(nil, actual, supportsAll)
 	if assert.NotNil(t, c, "should not return nil") {
 		assert.Equal(t, 1, len(c.apps.removed), "should have found one removed resource")
 	}
@@ -167,13 +172,13 @@ func Test_changes_newFeature(t *testing.T) {
 	desired := &state.Spec{
 		Applications: make([]resource.Application, 0),
 		Feature: state.Feature{
-			FluentBit: &feature.FluentBit{
+			feature.NameFluentBit: &feature.FluentBit{
 				Labels: "agent=fluent-bit",
 			},
 		},
 	}
 
-	c := compare(desired, nil)
+	c := compare(desired, nil, supportsAll)
 	assert.Equal(t, 0, len(c.features.changed))
 	assert.Equal(t, 0, len(c.features.unchanged))
 	assert.Equal(t, 0, len(c.features.removed))
@@ -190,13 +195,13 @@ func Test_changes_removedFeature(t *testing.T) {
 	actual := &state.Spec{
 		Applications: make([]resource.Application, 0),
 		Feature: state.Feature{
-			FluentBit: &feature.FluentBit{
+			feature.NameFluentBit: &feature.FluentBit{
 				Labels: "agent=fluent-bit",
 			},
 		},
 	}
 
-	c := compare(nil, actual)
+	c := compare(nil, actual, supportsAll)
 	assert.Equal(t, 0, len(c.features.changed))
 	assert.Equal(t, 0, len(c.features.unchanged))
 	assert.Equal(t, 0, len(c.features.added))
@@ -213,7 +218,7 @@ func Test_changes_changedFeature(t *testing.T) {
 	desired := &state.Spec{
 		Applications: make([]resource.Application, 0),
 		Feature: state.Feature{
-			FluentBit: &feature.FluentBit{
+			feature.NameFluentBit: &feature.FluentBit{
 				Labels: "agent=fluent-bit",
 			},
 		},
@@ -222,13 +227,13 @@ func Test_changes_changedFeature(t *testing.T) {
 	actual := &state.Spec{
 		Applications: make([]resource.Application, 0),
 		Feature: state.Feature{
-			FluentBit: &feature.FluentBit{
+			feature.NameFluentBit: &feature.FluentBit{
 				Labels: "another=label",
 			},
 		},
 	}
 
-	c := compare(desired, actual)
+	c := compare(desired, actual, supportsAll)
 	assert.Equal(t, 0, len(c.features.added))
 	assert.Equal(t, 0, len(c.features.unchanged))
 	assert.Equal(t, 0, len(c.features.removed))
@@ -244,7 +249,7 @@ func Test_changes_unchangedFeature(t *testing.T) {
 	desired := &state.Spec{
 		Applications: make([]resource.Application, 0),
 		Feature: state.Feature{
-			FluentBit: &feature.FluentBit{
+			feature.NameFluentBit: &feature.FluentBit{
 				Labels: "agent=fluent-bit",
 			},
 		},
@@ -253,13 +258,13 @@ func Test_changes_unchangedFeature(t *testing.T) {
 	actual := &state.Spec{
 		Applications: make([]resource.Application, 0),
 		Feature: state.Feature{
-			FluentBit: &feature.FluentBit{
+			feature.NameFluentBit: &feature.FluentBit{
 				Labels: "agent=fluent-bit",
 			},
 		},
 	}
 
-	c := compare(desired, actual)
+	c := compare(desired, actual, supportsAll)
 	assert.Equal(t, 0, len(c.features.changed))
 	assert.Equal(t, 0, len(c.features.added))
 	assert.Equal(t, 0, len(c.features.removed))
@@ -270,3 +275,102 @@ func Test_changes_unchangedFeature(t *testing.T) {
 		}
 	}
 }
+
+func Test_changes_instances(t *testing.T) {
+	actualApp := SampleApp("app-1")
+	desiredApp := SampleApp("app-1")
+	desiredApp.Instances = 0 // not specified, should default to a single instance
+
+	actual := &state.Spec{Applications: []resource.Application{*actualApp}}
+	desired := &state.Spec{Applications: []resource.Application{*desiredApp}}
+
+	c := compare(desired, actual, supportsAll)
+	assert.Equal(t, 1, len(c.apps.unchanged), "should have used the default instances")
+
+	desired.Applications[0].Instances = 3
+	c = compare(desired, actual, supportsAll)
+	assert.Equal(t, 1, len(c.apps.changed), "should have detected the instance difference")
+}
+
+func Test_changes_unhealthyInstances(t *testing.T) {
+	actualApp := SampleApp("app-1")
+	actualApp.UnhealthyInstances = 1
+	desiredApp := SampleApp("app-1")
+
+	actual := &state.Spec{Applications: []resource.Application{*actualApp}}
+	desired := &state.Spec{Applications: []resource.Application{*desiredApp}}
+
+	c := compare(desired, actual, supportsAll)
+	assert.Equal(t, 1, len(c.apps.changed), "should have recreated the unhealthy application")
+}
+
+func Test_changes_ingressLabels(t *testing.T) {
+	desiredApp := SampleApp("app-1")
+	desiredApp.Ingress = &resource.Ingress{Hosts: []string{"example.com"}, Port: 80}
+	desired := &state.Spec{
+		Applications: []resource.Application{*desiredApp},
+		Feature:      state.Feature{feature.NameIngress: &feature.Ingress{}},
+	}
+
+	// the deployed application does not have the routing labels yet
+	actualApp := SampleApp("app-1")
+	actualApp.Ingress = &resource.Ingress{Hosts: []string{"example.com"}, Port: 80}
+	actual := &state.Spec{
+		Applications: []resource.Application{*actualApp},
+		Feature:      state.Feature{feature.NameIngress: &feature.Ingress{}},
+	}
+
+	c := compare(desired, actual, supportsAll)
+	if assert.Equal(t, 1, len(c.apps.changed), "should have redeployed the application with the routing labels") {
+		assert.Equal(t, "true", c.apps.changed[0].Labels["traefik.enable"])
+	}
+
+	assert.Nil(t, desired.Applications[0].Labels, "should not have modified the desired state")
+}
+
+func Test_changes_unsupportedFeature(t *testing.T) {
+	desiredApp := SampleApp("app-1")
+	desiredApp.Ingress = &resource.Ingress{Hosts: []string{"example.com"}, Port: 80}
+	desired := &state.Spec{
+		Applications: []resource.Application{*desiredApp},
+		Feature:      state.Feature{feature.NameIngress: &feature.Ingress{}},
+	}
+
+	actualApp := SampleApp("app-1")
+	actualApp.Ingress = &resource.Ingress{Hosts: []string{"example.com"}, Port: 80}
+	actual := &state.Spec{Applications: []resource.Application{*actualApp}}
+
+	c := compare(desired, actual, func(name string) bool { return name != feature.NameIngress })
+	assert.Equal(t, 0, len(c.features.added), "should not have created the unsupported feature")
+	assert.Equal(t, 1, len(c.apps.unchanged), "should not have attached the routing labels of the unsupported feature")
+	assert.Equal(t, 1, len(desired.Feature), "should not have modified the desired state")
+}
+
+func Test_changes_monitoringTargets(t *testing.T) {
+	desiredApp := SampleApp("app-1")
+	desiredApp.Metrics = &resource.Metrics{Port: 9000}
+	desired := &state.Spec{
+		Applications: []resource.Application{*desiredApp},
+		Feature:      state.Feature{feature.NameMonitoring: &feature.Monitoring{Prometheus: true}},
+	}
+
+	// the deployed scrape config does not contain the application yet
+	actual := &state.Spec{
+		Applications: []resource.Application{*desiredApp},
+		Feature:      state.Feature{feature.NameMonitoring: &feature.Monitoring{Prometheus: true}},
+	}
+
+	c := compare(desired, actual, supportsAll)
+	if assert.Equal(t, 1, len(c.features.changed), "should have updated the scrape config") {
+		monitoring := c.features.changed[0].(*feature.Monitoring)
+		assert.Equal(t, 1, len(monitoring.Targets))
+
+		// the deployed feature is read back from the encoded configuration
+		actual.Feature = state.Feature{
+			feature.NameMonitoring: feature.DecodeFeature(feature.EncodeFeature(monitoring), &feature.Monitoring{}),
+		}
+	}
+
+	c = compare(desired, actual, supportsAll)
+	assert.Equal(t, 1, len(c.features.unchanged), "should not update the scrape config again")
+}
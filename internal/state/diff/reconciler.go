@@ -1,7 +1,10 @@
 package diff
 
 import (
+	"time"
+
 	"github.com/mbaitar/gco/agent/internal/log"
+	"github.com/mbaitar/gco/agent/internal/metrics"
 	"github.com/mbaitar/gco/agent/internal/provider"
 	"github.com/mbaitar/gco/agent/internal/state"
 )
@@ -15,6 +18,13 @@ type Reconciler struct {
 	desired *state.Spec
 	// actual defines the actual state in which the system currently is
 	actual *state.Spec
+
+	// backoff keeps track of the applications which failed to be created or updated
+	backoff        map[string]Backoff
+	started        map[string]started
+	backoffInitial time.Duration
+	backoffMax     time.Duration
+	now            func() time.Time
 }
 
 // InitReconciler initialises a new reconciler with the associated external container provider.
@@ -23,6 +33,12 @@ func InitReconciler(provider provider.Provider) *Reconciler {
 		provider: provider,
 		desired:  state.EmptySpec(),
 		actual:   state.EmptySpec(),
+
+		backoff:        make(map[string]Backoff),
+		started:        make(map[string]started),
+		backoffInitial: defaultBackoffInitial,
+		backoffMax:     defaultBackoffMax,
+		now:            time.Now,
 	}
 }
 
@@ -32,6 +48,11 @@ func (r *Reconciler) WithInitialActualState(actual *state.Spec) *Reconciler {
 	return r
 }
 
+// Actual returns the last known actual state of the external system.
+func (r *Reconciler) Actual() *state.Spec {
+	return r.actual
+}
+
 func (r *Reconciler) Apply(desired *state.Spec) {
 	r.desired = desired
 	r.update(true)
@@ -43,12 +64,26 @@ func (r *Reconciler) Observe(actual *state.Spec) {
 }
 
 func (r *Reconciler) update(triggerFetch bool) {
+	if triggerFetch {
+		metrics.ReconcilePasses.WithLabelValues("apply").Inc()
+	} else {
+		metrics.ReconcilePasses.WithLabelValues("observe").Inc()
+	}
+
 	modified := false
-	result := compare(r.desired, r.actual)
+	result := compare(r.desired, r.actual, func(name string) bool {
+		return provider.SupportsFeature(r.provider, name)
+	})
+	r.pruneBackoff()
+
+	for _, app := range result.apps.unchanged {
+		r.recordRunning(&app)
+	}
 
 	// adding features (before applications), supporting infrastructure -> some applications might rely on it
 	for _, feat := range result.features.added {
 		err := r.provider.CreateFeature(feat)
+		recordAction("create_feature", err)
 		if err != nil {
 			log.Errorf("Error while creating feature=%s: %v", feat.Name(), err)
 		} else {
@@ -60,8 +95,9 @@ func (r *Reconciler) update(triggerFetch bool) {
 	// updating features (before applications)
 	for _, feat := range result.features.changed {
 		err := r.provider.UpdateFeature(feat)
+		recordAction("update_feature", err)
 		if err != nil {
-			log.Errorf("Error while creating feature=%s: %v", feat.Name(), err)
+			log.Errorf("Error while updating feature=%s: %v", feat.Name(), err)
 		} else {
 			log.Debugf("Updated feature=%s to hash=%s", feat.Name(), feat.ConfigHash())
 			modified = true
@@ -71,6 +107,7 @@ func (r *Reconciler) update(triggerFetch bool) {
 	// remove applications -> first
 	for _, app := range result.apps.removed {
 		err := r.provider.RemoveApplication(&app)
+		recordAction("remove_application", err)
 		if err != nil {
 			log.Errorf("Error while removing application=%s: %v", app.Name, err)
 		} else {
@@ -81,22 +118,44 @@ func (r *Reconciler) update(triggerFetch bool) {
 
 	// update applications -> second
 	for _, app := range result.apps.changed {
+		if r.inBackoff(&app) {
+			log.Debugf("Skipping update of application=%s, backing off after previous failures", app.Name)
+			continue
+		}
+
+		if r.isCrashLooping(&app) {
+			backoff := r.recordCrashLoop(&app)
+			log.Warnf("Instances of application=%s exited shortly after being started, retrying after %s", app.Name, backoff.NextAttempt.Format(time.RFC3339))
+			continue
+		}
+
 		err := r.provider.UpdateApplication(&app)
+		recordAction("update_application", err)
 		if err != nil {
-			log.Errorf("Error while updating application=%s: %v", app.Name, err)
+			backoff := r.recordFailure(&app, err)
+			log.Errorf("Error while updating application=%s, retrying after %s: %v", app.Name, backoff.NextAttempt.Format(time.RFC3339), err)
 		} else {
 			log.Debugf("Updated application=%s to hash=%s", app.Name, app.CalculateHash())
+			r.recordSuccess(&app)
 			modified = true
 		}
 	}
 
 	// create new applications -> last
 	for _, app := range result.apps.added {
+		if r.inBackoff(&app) {
+			log.Debugf("Skipping creation of application=%s, backing off after previous failures", app.Name)
+			continue
+		}
+
 		err := r.provider.CreateApplication(&app)
+		recordAction("create_application", err)
 		if err != nil {
-			log.Errorf("Error while creating application=%s: %v", app.Name, err)
+			backoff := r.recordFailure(&app, err)
+			log.Errorf("Error while creating application=%s, retrying after %s: %v", app.Name, backoff.NextAttempt.Format(time.RFC3339), err)
 		} else {
 			log.Debugf("Created application=%s with hash=%s", app.Name, app.CalculateHash())
+			r.recordSuccess(&app)
 			modified = true
 		}
 	}
@@ -104,6 +163,7 @@ func (r *Reconciler) update(triggerFetch bool) {
 	// remove features (after applications)
 	for _, feat := range result.features.removed {
 		err := r.provider.RemoveFeature(feat)
+		recordAction("remove_feature", err)
 		if err != nil {
 			log.Errorf("Error while removing feature=%s: %v", feat.Name(), err)
 		} else {
@@ -122,3 +182,8 @@ func (r *Reconciler) update(triggerFetch bool) {
 		}
 	}
 }
+
+// recordAction counts the outcome of an action performed against the provider.
+func recordAction(action string, err error) {
+	metrics.ReconcileActions.WithLabelValues(action, metrics.Outcome(err)).Inc()
+}
@@ -3,10 +3,13 @@ package diff
 import (
 	"errors"
 	"testing"
+	"time"
 
+	"github.com/mbaitar/gco/agent/internal/metrics"
 	"github.com/mbaitar/gco/agent/internal/state"
 	"github.com/mbaitar/gco/agent/pkg/feature"
 	"github.com/mbaitar/gco/agent/pkg/resource"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -167,7 +170,7 @@ func TestReconciler_Apply_creatingFeature(t *testing.T) {
 	desired := &state.Spec{
 		Applications: make([]resource.Application, 0),
 		Feature: state.Feature{
-			FluentBit: &feature.FluentBit{LogLevel: "debug"},
+			feature.NameFluentBit: &feature.FluentBit{LogLevel: "debug"},
 		},
 	}
 
@@ -186,7 +189,7 @@ func TestReconciler_Apply_creatingFeatureWithError(t *testing.T) {
 	desired := &state.Spec{
 		Applications: make([]resource.Application, 0),
 		Feature: state.Feature{
-			FluentBit: &feature.FluentBit{LogLevel: "debug"},
+			feature.NameFluentBit: &feature.FluentBit{LogLevel: "debug"},
 		},
 	}
 
@@ -206,13 +209,13 @@ func TestReconciler_Apply_updateFeature(t *testing.T) {
 	actual := &state.Spec{
 		Applications: make([]resource.Application, 0),
 		Feature: state.Feature{
-			FluentBit: &feature.FluentBit{LogLevel: "info"},
+			feature.NameFluentBit: &feature.FluentBit{LogLevel: "info"},
 		},
 	}
 	desired := &state.Spec{
 		Applications: make([]resource.Application, 0),
 		Feature: state.Feature{
-			FluentBit: &feature.FluentBit{LogLevel: "debug"},
+			feature.NameFluentBit: &feature.FluentBit{LogLevel: "debug"},
 		},
 	}
 
@@ -230,13 +233,13 @@ func TestReconciler_Apply_updateFeatureWithError(t *testing.T) {
 	actual := &state.Spec{
 		Applications: make([]resource.Application, 0),
 		Feature: state.Feature{
-			FluentBit: &feature.FluentBit{LogLevel: "info"},
+			feature.NameFluentBit: &feature.FluentBit{LogLevel: "info"},
 		},
 	}
 	desired := &state.Spec{
 		Applications: make([]resource.Application, 0),
 		Feature: state.Feature{
-			FluentBit: &feature.FluentBit{LogLevel: "debug"},
+			feature.NameFluentBit: &feature.FluentBit{LogLevel: "debug"},
 		},
 	}
 
@@ -256,7 +259,7 @@ func TestReconciler_Apply_removeFeature(t *testing.T) {
 	actual := &state.Spec{
 		Applications: make([]resource.Application, 0),
 		Feature: state.Feature{
-			FluentBit: &feature.FluentBit{LogLevel: "info"},
+			feature.NameFluentBit: &feature.FluentBit{LogLevel: "info"},
 		},
 	}
 	desired := state.EmptySpec()
@@ -275,7 +278,7 @@ func TestReconciler_Apply_removeFeatureWithError(t *testing.T) {
 	actual := &state.Spec{
 		Applications: make([]resource.Application, 0),
 		Feature: state.Feature{
-			FluentBit: &feature.FluentBit{LogLevel: "info"},
+			feature.NameFluentBit: &feature.FluentBit{LogLevel: "info"},
 		},
 	}
 	desired := state.EmptySpec()
@@ -288,3 +291,183 @@ func TestReconciler_Apply_removeFeatureWithError(t *testing.T) {
 	assert.Equal(t, 1, len(provider.removeFeatCalls), "should have called #RemoveFeature()")
 	assert.Equal(t, 0, provider.actualCalls, "should not have called #ActualState()")
 }
+
+func TestReconciler_Apply_backoff(t *testing.T) {
+	provider := &TestProvider{}
+	reconciler := InitReconciler(provider).WithBackoff(time.Second, 4*time.Second)
+
+	now := time.Now()
+	reconciler.now = func() time.Time { return now }
+
+	desired := &state.Spec{
+		Applications: []resource.Application{
+			*SampleApp("app-1"),
+		},
+	}
+
+	provider.createErr = errors.New("test error")
+	reconciler.Apply(desired)
+	assert.Equal(t, 1, len(provider.createCalls), "should have tried to create the application")
+
+	backoff, found := reconciler.Backoff()["app-1"]
+	if assert.True(t, found, "should have backed off the application") {
+		assert.Equal(t, 1, backoff.Failures)
+		assert.Equal(t, "test error", backoff.LastError)
+		assert.Equal(t, now.Add(time.Second), backoff.NextAttempt)
+	}
+
+	// retrying before the next attempt should not call the provider
+	reconciler.Observe(state.EmptySpec())
+	assert.Equal(t, 1, len(provider.createCalls), "should not have retried during the backoff")
+
+	// the delay doubles after each failure
+	now = now.Add(time.Second)
+	reconciler.Observe(state.EmptySpec())
+	assert.Equal(t, 2, len(provider.createCalls), "should have retried after the backoff")
+	assert.Equal(t, now.Add(2*time.Second), reconciler.Backoff()["app-1"].NextAttempt)
+
+	// the delay is limited to the maximum
+	now = now.Add(2 * time.Second)
+	reconciler.Observe(state.EmptySpec())
+	now = now.Add(4 * time.Second)
+	reconciler.Observe(state.EmptySpec())
+	assert.Equal(t, 4, len(provider.createCalls))
+	assert.Equal(t, now.Add(4*time.Second), reconciler.Backoff()["app-1"].NextAttempt)
+
+	// a changed application is retried immediately
+	changed := SampleApp("app-1")
+	changed.Image.Tag = "v1.0.0"
+	provider.createErr = nil
+	reconciler.Apply(&state.Spec{Applications: []resource.Application{*changed}})
+	assert.Equal(t, 5, len(provider.createCalls), "should have retried the changed application")
+	assert.Equal(t, 0, len(reconciler.Backoff()), "should have reset the backoff after a success")
+}
+
+func TestReconciler_Observe_crashLoop(t *testing.T) {
+	provider := &TestProvider{}
+	reconciler := InitReconciler(provider).WithBackoff(time.Second, 4*time.Second)
+
+	now := time.Now()
+	reconciler.now = func() time.Time { return now }
+
+	stoppedApp := SampleApp("app-1")
+	stoppedApp.Instances = 0
+	stoppedApp.ExitedInstances = 1
+	running := &state.Spec{Applications: []resource.Application{*SampleApp("app-1")}}
+	stopped := &state.Spec{Applications: []resource.Application{*stoppedApp}}
+
+	provider.actualReturn = running
+	reconciler.Apply(&state.Spec{Applications: []resource.Application{*SampleApp("app-1")}})
+	assert.Equal(t, 1, len(provider.createCalls))
+
+	// the instance stopped right after being started
+	now = now.Add(10 * time.Second)
+	reconciler.Observe(stopped)
+	assert.Equal(t, 0, len(provider.updateCalls), "should not have restarted the crashing application")
+
+	backoff, found := reconciler.Backoff()["app-1"]
+	if assert.True(t, found, "should have backed off the application") {
+		assert.Equal(t, 1, backoff.Failures)
+		assert.Equal(t, ErrCrashLoop.Error(), backoff.LastError)
+	}
+
+	// the application is started again after the backoff, the delay doubles when it keeps crashing
+	now = now.Add(time.Second)
+	reconciler.Observe(stopped)
+	assert.Equal(t, 1, len(provider.updateCalls), "should have restarted the application after the backoff")
+
+	reconciler.Observe(stopped)
+	assert.Equal(t, 1, len(provider.updateCalls))
+	assert.Equal(t, 2, reconciler.Backoff()["app-1"].Failures)
+	assert.Equal(t, now.Add(2*time.Second), reconciler.Backoff()["app-1"].NextAttempt)
+
+	// the backoff is reset once the instance keeps running
+	now = now.Add(2 * time.Second)
+	reconciler.Observe(stopped)
+	assert.Equal(t, 2, len(provider.updateCalls))
+
+	reconciler.Observe(running)
+	assert.Equal(t, 2, reconciler.Backoff()["app-1"].Failures, "should keep the backoff while the instance just started")
+
+	now = now.Add(crashLoopWindow)
+	reconciler.Observe(running)
+	assert.Equal(t, 0, len(reconciler.Backoff()), "should have reset the backoff")
+
+	// an instance which stops later on is restarted immediately
+	reconciler.Observe(stopped)
+	assert.Equal(t, 3, len(provider.updateCalls))
+}
+
+func TestReconciler_Observe_startingHealthCheck(t *testing.T) {
+	provider := &TestProvider{}
+	reconciler := InitReconciler(provider).WithBackoff(time.Second, 4*time.Second)
+
+	now := time.Now()
+	reconciler.now = func() time.Time { return now }
+
+	// the instance is not reported while its health check is starting
+	startingApp := SampleApp("app-1")
+	startingApp.Instances = 0
+	exitedApp := SampleApp("app-1")
+	exitedApp.Instances = 0
+	exitedApp.ExitedInstances = 1
+	starting := &state.Spec{Applications: []resource.Application{*startingApp}}
+	exited := &state.Spec{Applications: []resource.Application{*exitedApp}}
+	healthy := &state.Spec{Applications: []resource.Application{*SampleApp("app-1")}}
+
+	provider.actualReturn = healthy
+	reconciler.Apply(&state.Spec{Applications: []resource.Application{*SampleApp("app-1")}})
+
+	now = now.Add(10 * time.Second)
+	reconciler.Observe(exited)
+	assert.Equal(t, 1, reconciler.Backoff()["app-1"].Failures, "should have backed off the exited instance")
+
+	// the application is started again after the backoff
+	now = now.Add(time.Second)
+	reconciler.Observe(starting)
+	assert.Equal(t, 1, len(provider.updateCalls))
+
+	reconciler.Observe(starting)
+	assert.Equal(t, 1, reconciler.Backoff()["app-1"].Failures, "should not consider a starting instance a crash loop")
+
+	reconciler.Observe(healthy)
+	assert.Equal(t, 1, len(reconciler.Backoff()), "should keep the backoff while the instance just started")
+
+	now = now.Add(crashLoopWindow)
+	reconciler.Observe(healthy)
+	assert.Equal(t, 0, len(reconciler.Backoff()), "should have reset the backoff once the instance kept running")
+}
+
+func TestReconciler_Apply_backoffRemovedApplication(t *testing.T) {
+	provider := &TestProvider{}
+	reconciler := InitReconciler(provider)
+
+	provider.createErr = errors.New("test error")
+	reconciler.Apply(&state.Spec{Applications: []resource.Application{*SampleApp("app-1")}})
+	assert.Equal(t, 1, len(reconciler.Backoff()))
+
+	reconciler.Apply(state.EmptySpec())
+	assert.Equal(t, 0, len(reconciler.Backoff()), "should have removed the backoff of the removed application")
+}
+
+func TestReconciler_Apply_metrics(t *testing.T) {
+	metrics.ReconcileActions.Reset()
+	metrics.DriftDetected.Reset()
+
+	provider := &TestProvider{}
+	reconciler := InitReconciler(provider)
+
+	desired := &state.Spec{
+		Applications: []resource.Application{
+			*SampleApp("app-1"),
+			*SampleApp("app-2"),
+		},
+	}
+
+	provider.createErr = errors.New("test error")
+	reconciler.Apply(desired)
+
+	assert.Equal(t, float64(2), testutil.ToFloat64(metrics.DriftDetected.WithLabelValues("application", "added")), "should have counted the added applications")
+	assert.Equal(t, float64(2), testutil.ToFloat64(metrics.ReconcileActions.WithLabelValues("create_application", metrics.OutcomeFailure)), "should have counted the failed creations")
+	assert.Equal(t, float64(0), testutil.ToFloat64(metrics.ReconcileActions.WithLabelValues("create_application", metrics.OutcomeSuccess)))
+}
@@ -4,18 +4,34 @@ import (
 	"github.com/mbaitar/gco/agent/pkg/feature"
 )
 
-// Evaluate updates the current application specifications based on the enabled features.
+// Evaluate updates the current application specifications based on the enabled features,
+// features depending on the applications are replaced with the configuration discovered from them.
 func (s *Spec) Evaluate() {
-	isFluentBitEnabled := s.IsFeatureEnabled(feature.NameFluentBit)
+	evaluators := make([]feature.Evaluator, 0)
+	for _, feat := range s.ListFeatures() {
+		if evaluator, ok := feat.(feature.Evaluator); ok {
+			evaluators = append(evaluators, evaluator)
+		}
+	}
 
 	for i := range s.Applications {
 		app := &s.Applications[i]
-		hasLogConfig := app.LogConfig != nil
 
 		// evaluate each app based on the enabled features
-		if !hasLogConfig && isFluentBitEnabled {
-			app.LogConfig = s.Feature.FluentBit.DefaultLogConfig()
+		for _, evaluator := range evaluators {
+			evaluator.Evaluate(app)
+		}
+	}
+
+	// the feature map might be shared with the original specification, replace it instead of modifying it
+	discovered := make(Feature, len(s.Feature))
+	for name, feat := range s.Feature {
+		if discoverer, ok := feat.(feature.Discoverer); ok {
+			feat = discoverer.Discover(s.Applications)
 		}
 
+		discovered[name] = feat
 	}
+
+	s.Feature = discovered
 }
@@ -15,7 +15,7 @@ func TestSpec_Evaluate_shouldAppendFluentBitConfig(t *testing.T) {
 			{Name: "app-1", Image: resource.Image{Name: "nginx", Tag: "latest"}},
 		},
 		Feature: Feature{
-			FluentBit: &feature.FluentBit{
+			feature.NameFluentBit: &feature.FluentBit{
 				LogLevel: "info",
 			},
 		},
@@ -47,7 +47,7 @@ func TestSpec_Evaluate_shouldNotOverwriteExistingConfig(t *testing.T) {
 			},
 		},
 		Feature: Feature{
-			FluentBit: &feature.FluentBit{
+			feature.NameFluentBit: &feature.FluentBit{
 				LogLevel: "info",
 			},
 		},
@@ -64,3 +64,28 @@ func TestSpec_Evaluate_shouldNotOverwriteExistingConfig(t *testing.T) {
 		}
 	}
 }
+
+func TestSpec_Evaluate_shouldDiscoverMetricsTargets(t *testing.T) {
+	monitoring := &feature.Monitoring{Prometheus: true}
+	spec := &Spec{
+		Applications: []resource.Application{
+			{Name: "app-2", Metrics: &resource.Metrics{Port: 9000}},
+			{Name: "app-1", Metrics: &resource.Metrics{Port: 8080, Path: "/stats"}},
+			{Name: "app-3"},
+		},
+		Feature: Feature{feature.NameMonitoring: monitoring},
+	}
+
+	evaluated := spec.Copy()
+	evaluated.Evaluate()
+
+	discovered, ok := evaluated.Feature[feature.NameMonitoring].(*feature.Monitoring)
+	if assert.True(t, ok, "should have kept the monitoring feature") {
+		assert.Equal(t, []feature.ScrapeTarget{
+			{Application: "app-1", Port: 8080, Path: "/stats"},
+			{Application: "app-2", Port: 9000, Path: "/metrics"},
+		}, discovered.Targets)
+	}
+
+	assert.Nil(t, monitoring.Targets, "should not have modified the original specification")
+}
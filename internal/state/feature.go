@@ -1,8 +1,165 @@
 package state
 
-import "github.com/mbaitar/gco/agent/pkg/feature"
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
 
-type Feature struct {
-	// FluentBit specifies the fluent-bit feature configuration for the agent.
-	FluentBit *feature.FluentBit `json:"fluentBit,omitempty"`
+	"github.com/mbaitar/gco/agent/internal/log"
+	"github.com/mbaitar/gco/agent/pkg/feature"
+)
+
+var (
+	// ErrFeatureEnabled is returned when enabling a feature which has already been enabled.
+	ErrFeatureEnabled = errors.New("feature has already been enabled")
+	// ErrFeatureNotEnabled is returned when updating or disabling a feature which has not been enabled.
+	ErrFeatureNotEnabled = errors.New("feature not enabled")
+)
+
+// Feature contains the enabled features keyed by their name, see feature.Register for the available features.
+type Feature map[string]feature.Feature
+
+// unknownFeature keeps the configuration of a feature which has not been registered, e.g. written by a newer agent,
+// so it is written back unchanged when persisting the state specification. It is neither listed nor reconciled.
+type unknownFeature struct {
+	name   string
+	config json.RawMessage
+}
+
+func (u *unknownFeature) ConfigHash() string {
+	return ""
+}
+
+func (u *unknownFeature) Name() string {
+	return u.name
+}
+
+func (u *unknownFeature) MarshalJSON() ([]byte, error) {
+	return u.config, nil
+}
+
+// MarshalJSON encodes the configuration of every feature keyed by name, no features results in an empty object.
+func (f Feature) MarshalJSON() ([]byte, error) {
+	if f == nil {
+		return []byte("{}"), nil
+	}
+
+	return json.Marshal(map[string]feature.Feature(f))
+}
+
+// UnmarshalJSON decodes the configuration of every feature using the registered feature definition.
+// Unknown features, e.g. written by a newer agent, are kept as is so the remaining specification can still be loaded.
+func (f *Feature) UnmarshalJSON(data []byte) error {
+	raw := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	features := make(Feature, len(raw))
+	for name, config := range raw {
+		def, found := feature.Lookup(name)
+		if !found {
+			log.Warnf("Keeping unknown feature '%s' of the state specification without applying it", name)
+			features[name] = &unknownFeature{name: name, config: config}
+			continue
+		}
+
+		// features which have been disabled using 'null' are skipped
+		if string(config) == "null" {
+			continue
+		}
+
+		feat := def.New()
+		if err := json.Unmarshal(config, feat); err != nil {
+			return fmt.Errorf("invalid configuration for feature '%s': %w", name, err)
+		}
+
+		features[def.Name] = feat
+	}
+
+	*f = features
+	return nil
+}
+
+// Names returns the sorted names of the enabled features, unknown features are not included.
+func (f Feature) Names() []string {
+	names := make([]string, 0, len(f))
+	for name, feat := range f {
+		if _, unknown := feat.(*unknownFeature); !unknown {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// ListFeatures returns the enabled features of the current state specification sorted by name.
+func (s *Spec) ListFeatures() []feature.Feature {
+	features := make([]feature.Feature, 0, len(s.Feature))
+	for _, name := range s.Feature.Names() {
+		features = append(features, s.Feature[name])
+	}
+
+	return features
+}
+
+// GetFeature returns the enabled feature matching the name or nil when it has not been enabled.
+func (s *Spec) GetFeature(name string) feature.Feature {
+	feat := s.Feature[name]
+	if _, unknown := feat.(*unknownFeature); unknown {
+		return nil
+	}
+
+	return feat
+}
+
+// EnableFeature adds the feature to the state if it has not been enabled yet.
+func (s *Spec) EnableFeature(feat feature.Feature) error {
+	if s.IsFeatureEnabled(feat.Name()) {
+		return fmt.Errorf("%w: '%s'", ErrFeatureEnabled, feat.Name())
+	}
+
+	return s.setFeature(feat.Name(), feat)
+}
+
+// UpdateFeature replaces the configuration of an enabled feature.
+func (s *Spec) UpdateFeature(feat feature.Feature) error {
+	if !s.IsFeatureEnabled(feat.Name()) {
+		return fmt.Errorf("%w: '%s'", ErrFeatureNotEnabled, feat.Name())
+	}
+
+	return s.setFeature(feat.Name(), feat)
+}
+
+// DisableFeature removes the feature matching the name from the state.
+func (s *Spec) DisableFeature(name string) error {
+	if !s.IsFeatureEnabled(name) {
+		return fmt.Errorf("%w: '%s'", ErrFeatureNotEnabled, name)
+	}
+
+	return s.setFeature(name, nil)
+}
+
+// setFeature sets the configuration of the feature matching the name, a nil feature disables it.
+// The features are copied as the map might be shared with a state which is being reconciled.
+func (s *Spec) setFeature(name string, feat feature.Feature) error {
+	if _, found := feature.Lookup(name); !found {
+		return feature.ErrUnknownFeature
+	}
+
+	features := make(Feature, len(s.Feature)+1)
+	for key, value := range s.Feature {
+		features[key] = value
+	}
+
+	if feat == nil {
+		delete(features, name)
+	} else {
+		features[name] = feat
+	}
+
+	s.Feature = features
+	return nil
 }
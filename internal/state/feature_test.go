@@ -0,0 +1,108 @@
+package state
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/mbaitar/gco/agent/pkg/feature"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSpec_EnableFeature(t *testing.T) {
+	spec := EmptySpec()
+
+	err := spec.EnableFeature(&feature.FluentBit{LogLevel: "info"})
+	assert.Nil(t, err, "should not have thrown")
+	assert.True(t, spec.IsFeatureEnabled(feature.NameFluentBit), "should have enabled the feature")
+	assert.Equal(t, 1, len(spec.ListFeatures()))
+
+	err = spec.EnableFeature(&feature.FluentBit{LogLevel: "debug"})
+	assert.ErrorIs(t, err, ErrFeatureEnabled, "should have thrown as the feature has already been enabled")
+	assert.Equal(t, "info", spec.GetFeature(feature.NameFluentBit).(*feature.FluentBit).LogLevel)
+}
+
+func TestSpec_UpdateFeature(t *testing.T) {
+	spec := EmptySpec()
+
+	err := spec.UpdateFeature(&feature.FluentBit{LogLevel: "debug"})
+	assert.ErrorIs(t, err, ErrFeatureNotEnabled, "should have thrown as the feature has not been enabled")
+
+	spec.Feature = Feature{feature.NameFluentBit: &feature.FluentBit{LogLevel: "info"}}
+	err = spec.UpdateFeature(&feature.FluentBit{LogLevel: "debug"})
+	assert.Nil(t, err, "should not have thrown")
+
+	feat := spec.GetFeature(feature.NameFluentBit)
+	if assert.NotNil(t, feat) {
+		assert.Equal(t, "debug", feat.(*feature.FluentBit).LogLevel)
+	}
+}
+
+func TestSpec_DisableFeature(t *testing.T) {
+	spec := EmptySpec()
+
+	err := spec.DisableFeature(feature.NameFluentBit)
+	assert.ErrorIs(t, err, ErrFeatureNotEnabled, "should have thrown as the feature has not been enabled")
+
+	spec.Feature = Feature{feature.NameFluentBit: &feature.FluentBit{LogLevel: "info"}}
+	err = spec.DisableFeature(feature.NameFluentBit)
+	assert.Nil(t, err, "should not have thrown")
+	assert.False(t, spec.IsFeatureEnabled(feature.NameFluentBit))
+	assert.Nil(t, spec.GetFeature(feature.NameFluentBit))
+}
+
+func TestFeature_UnmarshalJSON(t *testing.T) {
+	features := Feature{}
+	err := json.Unmarshal([]byte(`{"fluent-bit": {"logLevel": "debug"}}`), &features)
+	if assert.Nil(t, err) {
+		fb, ok := features[feature.NameFluentBit].(*feature.FluentBit)
+		if assert.True(t, ok, "should have decoded the fluent-bit feature") {
+			assert.Equal(t, "debug", fb.LogLevel)
+		}
+	}
+
+	// older state specifications use the field name of the feature
+	features = Feature{}
+	err = json.Unmarshal([]byte(`{"fluentBit": {"logLevel": "info"}}`), &features)
+	if assert.Nil(t, err) {
+		assert.NotNil(t, features[feature.NameFluentBit], "should have decoded the feature using the alias")
+	}
+
+	features = Feature{}
+	err = json.Unmarshal([]byte(`{"unknown": {}, "fluent-bit": {"logLevel": "info"}}`), &features)
+	if assert.Nil(t, err, "should not reject the specification because of an unknown feature") {
+		assert.Equal(t, []string{feature.NameFluentBit}, features.Names(), "should have skipped the unknown feature")
+	}
+}
+
+func TestFeature_unknownRoundTrip(t *testing.T) {
+	spec := EmptySpec()
+	err := json.Unmarshal([]byte(`{"applications": [], "feature": {"unknown": {"value": 42}, "fluent-bit": {"logLevel": "info"}}}`), spec)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	assert.False(t, spec.IsFeatureEnabled("unknown"), "should not report the unknown feature as enabled")
+	assert.Equal(t, 1, len(spec.ListFeatures()))
+
+	// modifying the known features keeps the unknown feature
+	assert.Nil(t, spec.UpdateFeature(&feature.FluentBit{LogLevel: "debug"}))
+
+	content, err := json.Marshal(spec.Feature)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	raw := make(map[string]json.RawMessage)
+	if assert.Nil(t, json.Unmarshal(content, &raw)) {
+		assert.JSONEq(t, `{"value": 42}`, string(raw["unknown"]), "should have written the unknown feature unchanged")
+		assert.Contains(t, string(raw[feature.NameFluentBit]), `"logLevel":"debug"`)
+	}
+}
+
+func TestFeature_MarshalJSON(t *testing.T) {
+	var features Feature
+	content, err := json.Marshal(features)
+	if assert.Nil(t, err) {
+		assert.Equal(t, "{}", string(content))
+	}
+}
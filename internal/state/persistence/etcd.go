@@ -0,0 +1,231 @@
+package persistence
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mbaitar/gco/agent/internal/config"
+	"github.com/mbaitar/gco/agent/internal/log"
+	"github.com/mbaitar/gco/agent/internal/state"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// etcdRequestTimeout is the maximum duration of a single request to the etcd cluster.
+const etcdRequestTimeout = 5 * time.Second
+
+// ErrConflict is returned when the persisted state has been modified since it has last been read or persisted.
+var ErrConflict = errors.New("state has been modified concurrently")
+
+// EtcdController persists the state to a key within an etcd cluster. Changes of the key by other agents or
+// operators are observed using a watch, the agent's own writes are recognized by their revision.
+type EtcdController struct {
+	channel chan state.Spec
+	client  *clientv3.Client
+	key     string
+
+	// revision is the modification revision of the key as it has last been read, persisted or observed,
+	// 0 when the key does not exist. Persisting only succeeds when the key has not been modified since.
+	revision     int64
+	revisionLock sync.Mutex
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewEtcdController connects to the etcd cluster and starts watching the key for changes.
+func NewEtcdController(conf config.EtcdPersistence) (*EtcdController, error) {
+	client, err := clientv3.New(clientv3.Config{
+		Endpoints:   conf.Endpoints,
+		DialTimeout: conf.DialTimeout,
+		Username:    conf.Username,
+		Password:    conf.Password,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to connect to etcd: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	controller := &EtcdController{
+		channel: make(chan state.Spec),
+		client:  client,
+		key:     conf.Key,
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+
+	// the client connects lazily, the initial request verifies the cluster is reachable
+	requestCtx, requestCancel := context.WithTimeout(ctx, conf.DialTimeout)
+	defer requestCancel()
+	resp, err := client.Get(requestCtx, conf.Key, clientv3.WithKeysOnly())
+	if err != nil {
+		controller.Close()
+		return nil, fmt.Errorf("unable to connect to etcd: %w", err)
+	}
+
+	go controller.watch(resp.Header.Revision + 1)
+
+	log.Debugf("Etcd state controller initialized (endpoints=%v, key=%s)", conf.Endpoints, conf.Key)
+	return controller, nil
+}
+
+func (e *EtcdController) GetChangeChannel() ChangeChannel {
+	return e.channel
+}
+
+// Persist writes the state to the key unless it has been modified since it has last been read, persisted or
+// observed, in which case ErrConflict is returned and the modification is passed on using the change channel.
+func (e *EtcdController) Persist(spec *state.Spec) error {
+	e.revisionLock.Lock()
+	defer e.revisionLock.Unlock()
+
+	revision, err := e.compareAndSwap(spec, e.revision)
+	if errors.Is(err, ErrConflict) {
+		// pass the modification on without waiting for the watch, which ignores it once it has been handled
+		go e.resync()
+		return err
+	} else if err != nil {
+		return err
+	}
+
+	e.revision = revision
+	return nil
+}
+
+// Read reads the persisted state, it returns nil when no state has been persisted yet.
+func (e *EtcdController) Read() (*state.Spec, error) {
+	ctx, cancel := context.WithTimeout(e.ctx, etcdRequestTimeout)
+	defer cancel()
+
+	resp, err := e.client.Get(ctx, e.key)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read state from etcd: %w", err)
+	}
+
+	e.revisionLock.Lock()
+	defer e.revisionLock.Unlock()
+
+	if len(resp.Kvs) == 0 {
+		e.revision = 0
+		return nil, nil
+	}
+
+	spec, err := parseState(resp.Kvs[0].Value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: key '%s'", err, e.key)
+	}
+
+	e.revision = resp.Kvs[0].ModRevision
+	return spec, nil
+}
+
+// Close stops watching the key and closes the connection to the cluster.
+func (e *EtcdController) Close() error {
+	e.cancel()
+	return e.client.Close()
+}
+
+// compareAndSwap writes the state when the modification revision of the key equals the expected revision
+// and returns the revision of the write. The modification revision of a missing key is 0.
+func (e *EtcdController) compareAndSwap(spec *state.Spec, expected int64) (int64, error) {
+	var buffer bytes.Buffer
+	if err := WriteJson(&buffer, spec); err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := context.WithTimeout(e.ctx, etcdRequestTimeout)
+	defer cancel()
+
+	resp, err := e.client.Txn(ctx).
+		If(clientv3.Compare(clientv3.ModRevision(e.key), "=", expected)).
+		Then(clientv3.OpPut(e.key, buffer.String())).
+		Commit()
+	if err != nil {
+		return 0, fmt.Errorf("unable to persist state to etcd: %w", err)
+	}
+
+	if !resp.Succeeded {
+		return 0, fmt.Errorf("%w: key '%s' has been modified since revision %d", ErrConflict, e.key, expected)
+	}
+
+	return resp.Header.Revision, nil
+}
+
+// watch passes the modifications of the key, starting at the revision, on to the change channel until the
+// controller has been closed. The watch is restarted when it fails, e.g. after the revision has been compacted.
+func (e *EtcdController) watch(revision int64) {
+	for e.ctx.Err() == nil {
+		watchCtx := clientv3.WithRequireLeader(e.ctx)
+		for resp := range e.client.Watch(watchCtx, e.key, clientv3.WithRev(revision)) {
+			if err := resp.Err(); err != nil {
+				log.Warnf("Error occurred while watching etcd key '%s': %v", e.key, err)
+				break
+			}
+
+			for _, event := range resp.Events {
+				e.handleEvent(event)
+			}
+
+			revision = resp.Header.Revision + 1
+		}
+
+		if e.ctx.Err() != nil {
+			return
+		}
+
+		// continue from the latest revision, passing on a modification missed in the meantime
+		revision = e.resync()
+		time.Sleep(time.Second)
+	}
+}
+
+// handleEvent passes a modification of the key on unless it originates from the controller itself.
+func (e *EtcdController) handleEvent(event *clientv3.Event) {
+	e.revisionLock.Lock()
+	if event.Kv.ModRevision <= e.revision {
+		e.revisionLock.Unlock()
+		log.Debugf("Ignoring known revision %d of etcd key '%s'", event.Kv.ModRevision, e.key)
+		return
+	}
+
+	if event.Type == clientv3.EventTypeDelete {
+		e.revision = 0
+		e.revisionLock.Unlock()
+		log.Warnf("Etcd key '%s' has been deleted, the state will be persisted again on the next change", e.key)
+		return
+	}
+
+	spec, err := parseState(event.Kv.Value)
+	if err != nil {
+		e.revisionLock.Unlock()
+		log.Warnf("Ignoring revision %d of etcd key '%s': %v", event.Kv.ModRevision, e.key, err)
+		return
+	}
+
+	e.revision = event.Kv.ModRevision
+	e.revisionLock.Unlock()
+
+	log.Debugf("Received a state change from etcd key '%s' (revision=%d)", e.key, event.Kv.ModRevision)
+	e.channel <- *spec
+}
+
+// resync reads the key after the watch failed or a write conflicted and returns the revision to continue watching at.
+func (e *EtcdController) resync() int64 {
+	ctx, cancel := context.WithTimeout(e.ctx, etcdRequestTimeout)
+	defer cancel()
+
+	resp, err := e.client.Get(ctx, e.key)
+	if err != nil {
+		log.Warnf("Unable to read etcd key '%s': %v", e.key, err)
+		return 0
+	}
+
+	if len(resp.Kvs) > 0 {
+		e.handleEvent(&clientv3.Event{Type: clientv3.EventTypePut, Kv: resp.Kvs[0]})
+	}
+
+	return resp.Header.Revision + 1
+}
@@ -0,0 +1,177 @@
+package persistence
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/mbaitar/gco/agent/internal/config"
+	"github.com/stretchr/testify/assert"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/server/v3/embed"
+)
+
+// startTestEtcd starts an embedded etcd server and returns its client URL.
+func startTestEtcd(t *testing.T) string {
+	local, _ := url.Parse("http://127.0.0.1:0")
+
+	conf := embed.NewConfig()
+	conf.Dir = t.TempDir()
+	conf.LogLevel = "error"
+	conf.LCUrls = []url.URL{*local}
+	conf.LPUrls = []url.URL{*local}
+
+	server, err := embed.StartEtcd(conf)
+	if err != nil {
+		t.Fatalf("unable to start etcd: %v", err)
+	}
+	t.Cleanup(server.Close)
+
+	select {
+	case <-server.Server.ReadyNotify():
+	case <-time.After(10 * time.Second):
+		t.Fatalf("etcd did not become ready")
+	}
+
+	return "http://" + server.Clients[0].Addr().String()
+}
+
+func NewTestEtcdController(t *testing.T, endpoint string) *EtcdController {
+	c, err := NewEtcdController(config.EtcdPersistence{
+		Endpoints:   []string{endpoint},
+		Key:         "/gco/state",
+		DialTimeout: time.Second,
+	})
+	if err != nil {
+		t.Fatalf("unable to create etcd controller: %v", err)
+	}
+	t.Cleanup(func() { c.Close() })
+
+	return c
+}
+
+func TestEtcdController_Persist(t *testing.T) {
+	c := NewTestEtcdController(t, startTestEtcd(t))
+
+	spec, err := c.Read()
+	assert.Nil(t, err)
+	assert.Nil(t, spec, "should not have read a state before anything has been persisted")
+
+	assert.Nil(t, c.Persist(testSpec("nginx")))
+	assert.Nil(t, c.Persist(testSpec("apache")), "should be able to persist again using its own revision")
+
+	spec, err = c.Read()
+	if assert.Nil(t, err) && assert.NotNil(t, spec) {
+		assert.Equal(t, "apache", spec.Applications[0].Name)
+	}
+}
+
+func TestEtcdController_Persist_conflict(t *testing.T) {
+	c := NewTestEtcdController(t, startTestEtcd(t))
+
+	assert.Nil(t, c.Persist(testSpec("nginx")))
+
+	// a write based on a stale revision has to be rejected
+	_, err := c.compareAndSwap(testSpec("apache"), 0)
+	assert.ErrorIs(t, err, ErrConflict)
+
+	spec, err := c.Read()
+	if assert.Nil(t, err) && assert.NotNil(t, spec) {
+		assert.Equal(t, "nginx", spec.Applications[0].Name)
+	}
+}
+
+func TestEtcdController_Persist_conflictEmitsChange(t *testing.T) {
+	endpoint := startTestEtcd(t)
+	c := NewTestEtcdController(t, endpoint)
+	other := NewTestEtcdController(t, endpoint)
+	assert.Nil(t, c.Persist(testSpec("nginx")))
+
+	// the watch blocks on passing on the first modification, as such the second one is not known yet
+	_, err := other.client.Put(context.Background(), c.key, `{"applications":[{"name":"apache","image":{"name":"httpd","tag":"latest"}}],"feature":{}}`)
+	assert.Nil(t, err)
+	_, err = other.client.Put(context.Background(), c.key, `{"applications":[{"name":"redis","image":{"name":"redis","tag":"latest"}}],"feature":{}}`)
+	assert.Nil(t, err)
+
+	assert.ErrorIs(t, c.Persist(testSpec("mysql")), ErrConflict)
+
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case s := <-c.GetChangeChannel():
+			if s.Applications[0].Name == "redis" {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("should have passed on the conflicting modification")
+		}
+	}
+}
+
+func TestEtcdController_Read_corrupt(t *testing.T) {
+	endpoint := startTestEtcd(t)
+	c := NewTestEtcdController(t, endpoint)
+
+	_, err := c.client.Put(context.Background(), c.key, "{no json")
+	assert.Nil(t, err)
+
+	_, err = c.Read()
+	assert.ErrorIs(t, err, ErrCorruptState)
+}
+
+func TestEtcdController_EmitChangeChannel(t *testing.T) {
+	endpoint := startTestEtcd(t)
+	first := NewTestEtcdController(t, endpoint)
+	second := NewTestEtcdController(t, endpoint)
+
+	assert.Nil(t, first.Persist(testSpec("nginx")))
+
+	// the write is passed on to the other agent sharing the key, but not to the agent itself
+	select {
+	case s := <-second.GetChangeChannel():
+		assert.Equal(t, "nginx", s.Applications[0].Name)
+	case <-time.After(time.Second):
+		t.Fatalf("timeout reached")
+	}
+
+	select {
+	case <-first.GetChangeChannel():
+		t.Fatalf("should not have emitted its own write")
+	case <-time.After(time.Millisecond * 300):
+	}
+
+	// the other agent continues from the observed revision
+	assert.Nil(t, second.Persist(testSpec("apache")))
+	select {
+	case s := <-first.GetChangeChannel():
+		assert.Equal(t, "apache", s.Applications[0].Name)
+	case <-time.After(time.Second):
+		t.Fatalf("timeout reached")
+	}
+}
+
+func TestEtcdController_EmitChangeChannel_deleted(t *testing.T) {
+	c := NewTestEtcdController(t, startTestEtcd(t))
+	assert.Nil(t, c.Persist(testSpec("nginx")))
+
+	_, err := c.client.Delete(context.Background(), c.key, clientv3.WithPrefix())
+	assert.Nil(t, err)
+
+	select {
+	case <-c.GetChangeChannel():
+		t.Fatalf("should not have emitted the deletion")
+	case <-time.After(time.Millisecond * 300):
+	}
+
+	assert.Nil(t, c.Persist(testSpec("apache")), "should recreate the deleted key")
+}
+
+func TestNewEtcdController_unavailable(t *testing.T) {
+	_, err := NewEtcdController(config.EtcdPersistence{
+		Endpoints:   []string{"http://127.0.0.1:1"},
+		Key:         "/gco/state",
+		DialTimeout: time.Millisecond * 100,
+	})
+	assert.NotNil(t, err)
+}
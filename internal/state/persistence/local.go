@@ -1,22 +1,47 @@
 package persistence
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
 	"os"
+	"path"
+	"sync"
 
+	"github.com/mbaitar/gco/agent/internal/hash"
 	"github.com/mbaitar/gco/agent/internal/log"
 	"github.com/mbaitar/gco/agent/internal/state"
 )
 
+// defaultBackups is the number of previous states kept next to the state file.
+const defaultBackups = 3
+
+// ErrCorruptState is returned when neither the state file nor any of its backups contain a valid state.
+var ErrCorruptState = errors.New("state file is corrupt")
+
+// ErrEmptyState is returned when the state file is empty and none of its backups contain a valid state, e.g. after
+// it has been truncated or when it has been created by a previous version of the agent before anything was persisted.
+var ErrEmptyState = errors.New("state file is empty")
+
 type LocalController struct {
 	channel       chan state.Spec
 	stateLocation string
 	watcher       *Watcher
+
+	// backups is the number of previous states kept as '<state file>.<n>', where '.1' is the most recent one.
+	backups int
+
+	// known is the hash of the state which has last been persisted or read, changes of the state file
+	// matching this hash originate from the controller itself and are not passed on.
+	known     string
+	knownLock sync.Mutex
 }
 
 func NewLocalController(stateFile string) *LocalController {
 	controller := &LocalController{
 		channel:       make(chan state.Spec),
 		stateLocation: stateFile,
+		backups:       defaultBackups,
 	}
 
 	// create watcher
@@ -24,7 +49,7 @@ func NewLocalController(stateFile string) *LocalController {
 	controller.watcher = watcher
 
 	// prepare local controller
-	_, err := controller.getStateFile()
+	err := os.MkdirAll(path.Dir(stateFile), 0744)
 	if err != nil {
 		log.Errorf("Unable to initialize local persistence controller: %v", err)
 		os.Exit(1)
@@ -44,57 +69,198 @@ func NewLocalController(stateFile string) *LocalController {
 	return controller
 }
 
+// WithBackups sets the number of previous states which are kept when persisting a new state.
+func (l *LocalController) WithBackups(backups int) *LocalController {
+	l.backups = backups
+	return l
+}
+
 func (l *LocalController) GetChangeChannel() ChangeChannel {
 	return l.channel
 }
 
+// Persist writes the state to a temporary file which replaces the state file once it has been synced to disk,
+// a crash while persisting leaves either the previous or the new state behind. The previous state is kept as backup.
 func (l *LocalController) Persist(spec *state.Spec) error {
-	file, err := l.getStateFile()
-	if err != nil {
+	var buffer bytes.Buffer
+	if err := WriteJson(&buffer, spec); err != nil {
 		return err
 	}
 
-	// truncate before writing
-	err = file.Truncate(0)
-	if err != nil {
-		return err
+	if err := l.rotateBackups(); err != nil {
+		return fmt.Errorf("unable to back up state file: %w", err)
 	}
 
-	err = WriteJson(file, spec)
-	if err != nil {
+	// remember the state before writing, as the watcher might observe the write before it returns
+	previous := l.setKnown(spec)
+	if err := writeFileAtomic(l.stateLocation, buffer.Bytes()); err != nil {
+		l.knownLock.Lock()
+		l.known = previous
+		l.knownLock.Unlock()
 		return err
 	}
 
 	return nil
 }
 
+// Read reads the persisted state, it returns nil when no state has been persisted yet. When the state file is corrupt
+// or empty the most recent valid backup is used instead, ErrCorruptState or ErrEmptyState is returned when no valid
+// backup exists. Starting without a state would remove every managed application.
 func (l *LocalController) Read() (*state.Spec, error) {
-	file, err := l.getStateFile()
-	if err != nil {
+	spec, err := readStateFile(l.stateLocation)
+	if err == nil {
+		l.setKnown(spec)
+		return spec, nil
+	}
+
+	if !errors.Is(err, ErrEmptyState) && !errors.Is(err, ErrCorruptState) {
 		return nil, err
 	}
 
-	spec := ReadJson(file)
-	if spec == nil {
-		return nil, nil
+	log.Warnf("Unable to read state file '%s': %v", l.stateLocation, err)
+
+	for i := 1; i <= l.backups; i++ {
+		backup := l.backupLocation(i)
+		spec, backupErr := readStateFile(backup)
+		if backupErr != nil || spec == nil {
+			continue
+		}
+
+		log.Warnf("Falling back to backup '%s' of the state file", backup)
+		l.setKnown(spec)
+		return spec, nil
 	}
 
-	return spec, nil
+	return nil, fmt.Errorf("%w: no valid backup of '%s' available, restore or remove the file to continue", err, l.stateLocation)
 }
 
-func (l *LocalController) getStateFile() (*os.File, error) {
-	if _, err := os.Stat(l.stateLocation); err != nil {
-		if os.IsNotExist(err) {
-			return os.Create(l.stateLocation)
-		} else {
-			return nil, err
+// rotateBackups shifts the existing backups and copies the current state file to the most recent backup.
+// A corrupt state file is not kept, as it would replace a valid backup.
+func (l *LocalController) rotateBackups() error {
+	if l.backups <= 0 {
+		return nil
+	}
+
+	content, err := os.ReadFile(l.stateLocation)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if _, err = parseState(content); err != nil {
+		log.Warnf("Not backing up corrupt state file '%s': %v", l.stateLocation, err)
+		return nil
+	}
+
+	for i := l.backups - 1; i >= 1; i-- {
+		err = os.Rename(l.backupLocation(i), l.backupLocation(i+1))
+		if err != nil && !os.IsNotExist(err) {
+			return err
 		}
-	} else {
-		return os.OpenFile(l.stateLocation, os.O_RDWR, 0644)
 	}
+
+	return writeFileAtomic(l.backupLocation(1), content)
+}
+
+// backupLocation returns the location of the n-th most recent backup.
+func (l *LocalController) backupLocation(n int) string {
+	return fmt.Sprintf("%s.%d", l.stateLocation, n)
 }
 
+// handleStateChange passes the state read from the modified state file on, unless it equals the known state.
 func (l *LocalController) handleStateChange(s *state.Spec) {
+	l.knownLock.Lock()
+	changed := l.known != hashState(s)
+	l.knownLock.Unlock()
+
+	if !changed {
+		log.Debugf("Ignoring unchanged state from '%s'", l.stateLocation)
+		return
+	}
+
 	log.Debugf("Received a state change from state '%s'", l.stateLocation)
+	l.setKnown(s)
 	l.channel <- *s
 }
+
+// setKnown remembers the hash of the state and returns the previously known hash.
+func (l *LocalController) setKnown(s *state.Spec) string {
+	l.knownLock.Lock()
+	defer l.knownLock.Unlock()
+
+	previous := l.known
+	l.known = hashState(s)
+	return previous
+}
+
+// hashState calculates the hash of the state, independent of the formatting of the state file.
+func hashState(s *state.Spec) string {
+	if s == nil {
+		return ""
+	}
+
+	return hash.CalculateHash(s)
+}
+
+// readStateFile reads the state from the file, it returns nil when the file does not exist and ErrEmptyState when
+// the file is empty.
+func readStateFile(location string) (*state.Spec, error) {
+	content, err := os.ReadFile(location)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if len(bytes.TrimSpace(content)) == 0 {
+		return nil, ErrEmptyState
+	}
+
+	return parseState(content)
+}
+
+// writeFileAtomic writes the content to a temporary file within the same directory, syncs it to disk
+// and renames it to the location, which atomically replaces the existing file.
+func writeFileAtomic(location string, content []byte) error {
+	dir := path.Dir(location)
+	temp, err := os.CreateTemp(dir, fmt.Sprintf(".%s-*.tmp", path.Base(location)))
+	if err != nil {
+		return err
+	}
+
+	// clean up the temporary file when anything fails, this is a no-op after the rename
+	defer os.Remove(temp.Name())
+
+	if _, err = temp.Write(content); err != nil {
+		temp.Close()
+		return err
+	}
+
+	if err = temp.Sync(); err != nil {
+		temp.Close()
+		return err
+	}
+
+	if err = temp.Close(); err != nil {
+		return err
+	}
+
+	if err = os.Chmod(temp.Name(), 0644); err != nil {
+		return err
+	}
+
+	if err = os.Rename(temp.Name(), location); err != nil {
+		return err
+	}
+
+	// sync the directory to persist the rename
+	if d, err := os.Open(dir); err == nil {
+		if err = d.Sync(); err != nil {
+			log.Debugf("Unable to sync directory '%s': %v", dir, err)
+		}
+		d.Close()
+	}
+
+	return nil
+}
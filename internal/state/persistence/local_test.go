@@ -1,6 +1,7 @@
 package persistence
 
 import (
+	"fmt"
 	"os"
 	"path"
 	"testing"
@@ -26,47 +27,121 @@ func TestLocalController_GetChangeChannel(t *testing.T) {
 }
 
 func TestLocalController_EmitChangeChannel(t *testing.T) {
+	c := NewTestLocalController(t)
+	channel := c.GetChangeChannel()
+
+	err := writeExternal(c.stateLocation, testSpec("nginx"))
+	if err != nil {
+		t.Fatalf("unable to write state file: %v", err)
+	}
+
+	select {
+	case s := <-channel:
+		assert.Equal(t, "nginx", s.Applications[0].Name)
+	case <-time.After(time.Millisecond * 500):
+		t.Fatalf("timeout reached")
+	}
+}
 
+func TestLocalController_EmitChangeChannel_ignoresOwnWrites(t *testing.T) {
 	c := NewTestLocalController(t)
 	channel := c.GetChangeChannel()
 
-	timeout := make(chan interface{})
+	err := c.Persist(testSpec("nginx"))
+	assert.Nil(t, err)
 
-	spec := &state.Spec{
+	select {
+	case <-channel:
+		t.Fatalf("should not have emitted the persisted state")
+	case <-time.After(time.Millisecond * 300):
+	}
+}
+
+func TestLocalController_EmitChangeChannel_ignoresUnchanged(t *testing.T) {
+	c := NewTestLocalController(t)
+	channel := c.GetChangeChannel()
+
+	err := c.Persist(testSpec("nginx"))
+	assert.Nil(t, err)
+
+	// reformatting the file does not change the state
+	err = os.WriteFile(c.stateLocation, []byte(`{"applications":[{"name":"nginx","image":{"name":"nginx","tag":"latest"},"instances":0}],"feature":{}}`), 0644)
+	assert.Nil(t, err)
+
+	select {
+	case <-channel:
+		t.Fatalf("should not have emitted the unchanged state")
+	case <-time.After(time.Millisecond * 300):
+	}
+}
+
+func TestLocalController_EmitChangeChannel_debounced(t *testing.T) {
+	c := NewTestLocalController(t)
+	channel := c.GetChangeChannel()
+
+	// a burst of writes results in a single change
+	for _, name := range []string{"first", "second", "third"} {
+		err := writeExternal(c.stateLocation, testSpec(name))
+		assert.Nil(t, err)
+	}
+
+	select {
+	case s := <-channel:
+		assert.Equal(t, "third", s.Applications[0].Name)
+	case <-time.After(time.Millisecond * 500):
+		t.Fatalf("timeout reached")
+	}
+
+	select {
+	case <-channel:
+		t.Fatalf("should have emitted the burst only once")
+	case <-time.After(time.Millisecond * 300):
+	}
+}
+
+func TestLocalController_EmitChangeChannel_renamed(t *testing.T) {
+	c := NewTestLocalController(t)
+	channel := c.GetChangeChannel()
+
+	err := c.Persist(testSpec("nginx"))
+	assert.Nil(t, err)
+
+	// editors like vim write a new file and rename it to the original file
+	temp := path.Join(path.Dir(c.stateLocation), "gco.state.swp")
+	err = writeExternal(temp, testSpec("apache"))
+	assert.Nil(t, err)
+	err = os.Rename(temp, c.stateLocation)
+	assert.Nil(t, err)
+
+	select {
+	case s := <-channel:
+		assert.Equal(t, "apache", s.Applications[0].Name)
+	case <-time.After(time.Millisecond * 500):
+		t.Fatalf("timeout reached")
+	}
+}
+
+// testSpec creates a state with a single application.
+func testSpec(name string) *state.Spec {
+	return &state.Spec{
 		Applications: []resource.Application{
 			{
-				Name:  "nginx",
+				Name:  name,
 				Image: resource.Image{Name: "nginx", Tag: "latest"},
 			},
 		},
 	}
+}
 
-	err := c.Persist(spec)
+// writeExternal writes the state in place, as done by an external editor.
+func writeExternal(location string, spec *state.Spec) error {
+	file, err := os.Create(location)
 	if err != nil {
-		t.Errorf("should not have thrown an error upon persisting")
-		t.FailNow()
-	}
-
-	go func() {
-		// timeout after 500ms
-		time.Sleep(time.Millisecond * 500)
-		timeout <- nil
-	}()
-
-	// wait for read channel
-	select {
-	case <-timeout:
-		{
-			t.Errorf("timeout reached")
-			t.FailNow()
-		}
-	case <-channel:
-		{
-			t.Logf("successfully received event from read channel")
-			return
-		}
+		return err
 	}
+	defer file.Close()
 
+	return WriteJson(file, spec)
 }
 
 func TestLocalController_Persist(t *testing.T) {
@@ -126,3 +201,94 @@ func TestLocalController_Read(t *testing.T) {
 		assert.Equal(t, expectedHash, actualHash)
 	}
 }
+
+func TestLocalController_Persist_backups(t *testing.T) {
+	c := NewTestLocalController(t).WithBackups(2)
+
+	for i := 1; i <= 3; i++ {
+		spec := &state.Spec{
+			Applications: []resource.Application{
+				{Name: fmt.Sprintf("app-%d", i), Image: resource.Image{Name: "nginx", Tag: "latest"}},
+			},
+		}
+
+		err := c.Persist(spec)
+		assert.Nil(t, err, "should not have thrown an error")
+	}
+
+	current, _ := readStateFile(c.stateLocation)
+	backup1, _ := readStateFile(c.stateLocation + ".1")
+	backup2, _ := readStateFile(c.stateLocation + ".2")
+	assert.Equal(t, "app-3", current.Applications[0].Name)
+	assert.Equal(t, "app-2", backup1.Applications[0].Name, "should have kept the previous state")
+	assert.Equal(t, "app-1", backup2.Applications[0].Name, "should have kept the state before the previous state")
+
+	_, err := os.Stat(c.stateLocation + ".3")
+	assert.True(t, os.IsNotExist(err), "should not keep more than the configured backups")
+
+	// no temporary files should be left behind
+	entries, _ := os.ReadDir(path.Dir(c.stateLocation))
+	assert.Equal(t, 3, len(entries))
+}
+
+func TestLocalController_Read_empty(t *testing.T) {
+	c := NewTestLocalController(t)
+
+	read, err := c.Read()
+	assert.Nil(t, err, "should not have thrown when no state has been persisted")
+	assert.Nil(t, read)
+
+	// an empty file without backup is rejected instead of starting without a state
+	os.WriteFile(c.stateLocation, []byte{}, 0644)
+	read, err = c.Read()
+	assert.ErrorIs(t, err, ErrEmptyState)
+	assert.Nil(t, read)
+}
+
+func TestLocalController_Read_emptyFallsBackToBackup(t *testing.T) {
+	c := NewTestLocalController(t)
+
+	err := c.Persist(testState)
+	assert.Nil(t, err)
+	err = c.Persist(state.EmptySpec())
+	assert.Nil(t, err)
+
+	os.WriteFile(c.stateLocation, []byte{}, 0644)
+
+	read, err := c.Read()
+	if assert.Nil(t, err, "should have fallen back to the backup") && assert.NotNil(t, read) {
+		assert.Equal(t, 1, len(read.Applications), "should have read the most recent backup")
+	}
+}
+
+func TestLocalController_Read_corruptFallsBackToBackup(t *testing.T) {
+	c := NewTestLocalController(t)
+
+	err := c.Persist(testState)
+	assert.Nil(t, err)
+	err = c.Persist(state.EmptySpec())
+	assert.Nil(t, err)
+
+	// simulate a half written state file
+	os.WriteFile(c.stateLocation, []byte(`{"applications": [{"name": "ngi`), 0644)
+
+	read, err := c.Read()
+	if assert.Nil(t, err, "should have fallen back to the backup") && assert.NotNil(t, read) {
+		assert.Equal(t, 1, len(read.Applications), "should have read the most recent backup")
+	}
+
+	// the corrupt file should not replace the valid backup
+	err = c.Persist(state.EmptySpec())
+	assert.Nil(t, err)
+	backup, _ := readStateFile(c.stateLocation + ".1")
+	assert.Equal(t, 1, len(backup.Applications))
+}
+
+func TestLocalController_Read_corruptWithoutBackup(t *testing.T) {
+	c := NewTestLocalController(t)
+	os.WriteFile(c.stateLocation, []byte("blah=blah"), 0644)
+
+	read, err := c.Read()
+	assert.ErrorIs(t, err, ErrCorruptState, "should refuse to continue with a corrupt state")
+	assert.Nil(t, read)
+}
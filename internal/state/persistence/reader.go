@@ -2,6 +2,7 @@ package persistence
 
 import (
 	"encoding/json"
+	"fmt"
 	"io"
 
 	"github.com/mbaitar/gco/agent/internal/log"
@@ -10,15 +11,13 @@ import (
 
 // ReadJson reads the state from the io.ReadCloser.
 func ReadJson(reader io.ReadCloser) *state.Spec {
-
 	value, err := io.ReadAll(reader)
 	if err != nil {
 		log.Errorf("failed to read config: %v", err)
 		return nil
 	}
 
-	config := &state.Spec{}
-	err = json.Unmarshal(value, config)
+	config, err := parseState(value)
 	if err != nil {
 		log.Errorf("failed to unmarshal data: %v", err)
 		return nil
@@ -26,3 +25,13 @@ func ReadJson(reader io.ReadCloser) *state.Spec {
 
 	return config
 }
+
+// parseState parses the JSON encoded state, it returns ErrCorruptState when the content is not a valid state.
+func parseState(content []byte) (*state.Spec, error) {
+	config := &state.Spec{}
+	if err := json.Unmarshal(content, config); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorruptState, err)
+	}
+
+	return config, nil
+}
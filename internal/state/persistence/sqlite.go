@@ -0,0 +1,207 @@
+package persistence
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"time"
+
+	"github.com/mbaitar/gco/agent/internal/log"
+	"github.com/mbaitar/gco/agent/internal/state"
+
+	// registers the pure Go 'sqlite' database driver
+	_ "modernc.org/sqlite"
+)
+
+// defaultRevisions is the number of previous revisions kept next to the most recent revision.
+const defaultRevisions = 3
+
+// ErrRevisionNotFound is returned when the requested revision does not exist.
+var ErrRevisionNotFound = errors.New("revision not found")
+
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS revisions (
+	number     INTEGER PRIMARY KEY AUTOINCREMENT,
+	created_at INTEGER NOT NULL,
+	origin     TEXT NOT NULL,
+	spec       TEXT NOT NULL
+)`
+
+// SqliteController persists every state to a SQLite database as a new revision, the most recent revision
+// is the persisted state. Previous revisions are kept to be able to roll back to them.
+type SqliteController struct {
+	channel chan state.Spec
+	db      *sql.DB
+
+	// revisions is the number of previous revisions kept when persisting a new revision, older revisions are removed.
+	revisions int
+}
+
+// NewSqliteController opens the database at the location, creating it when it does not exist yet.
+func NewSqliteController(location string) (*SqliteController, error) {
+	err := os.MkdirAll(path.Dir(location), 0744)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := sql.Open("sqlite", fmt.Sprintf("file:%s?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)", location))
+	if err != nil {
+		return nil, fmt.Errorf("unable to open database '%s': %w", location, err)
+	}
+
+	// a single connection serializes the writes of the agent
+	db.SetMaxOpenConns(1)
+
+	if _, err = db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("unable to create schema of database '%s': %w", location, err)
+	}
+
+	log.Debugf("Sqlite state controller initialized (location=%s)", location)
+	return &SqliteController{
+		channel:   make(chan state.Spec),
+		db:        db,
+		revisions: defaultRevisions,
+	}, nil
+}
+
+// WithRevisions sets the number of previous revisions which are kept when persisting a new revision.
+func (s *SqliteController) WithRevisions(revisions int) *SqliteController {
+	s.revisions = revisions
+	return s
+}
+
+// GetChangeChannel returns the change channel, the database is only modified by the agent itself
+// which is why no changes are emitted.
+func (s *SqliteController) GetChangeChannel() ChangeChannel {
+	return s.channel
+}
+
+func (s *SqliteController) Persist(spec *state.Spec) error {
+	_, err := s.PersistRevision(spec, "")
+	return err
+}
+
+func (s *SqliteController) PersistRevision(spec *state.Spec, origin string) (*Revision, error) {
+	var buffer bytes.Buffer
+	if err := WriteJson(&buffer, spec); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("unable to persist revision: %w", err)
+	}
+	defer tx.Rollback()
+
+	createdAt := time.Now()
+	result, err := tx.Exec("INSERT INTO revisions (created_at, origin, spec) VALUES (?, ?, ?)",
+		createdAt.UnixMilli(), origin, buffer.String())
+	if err != nil {
+		return nil, fmt.Errorf("unable to persist revision: %w", err)
+	}
+
+	number, err := result.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("unable to persist revision: %w", err)
+	}
+
+	// the outdated revisions are removed within the same transaction, so a failure keeps the previous revisions
+	_, err = tx.Exec("DELETE FROM revisions WHERE number NOT IN (SELECT number FROM revisions ORDER BY number DESC LIMIT ?)",
+		s.revisions+1)
+	if err != nil {
+		return nil, fmt.Errorf("unable to prune revisions: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("unable to persist revision: %w", err)
+	}
+
+	log.Debugf("Persisted revision %d (origin=%s)", number, origin)
+	return &Revision{
+		Number:    number,
+		CreatedAt: time.UnixMilli(createdAt.UnixMilli()),
+		Origin:    origin,
+		Spec:      spec,
+	}, nil
+}
+
+// Read returns the state of the most recent revision, it returns nil when no state has been persisted yet.
+func (s *SqliteController) Read() (*state.Spec, error) {
+	row := s.db.QueryRow("SELECT number, created_at, origin, spec FROM revisions ORDER BY number DESC LIMIT 1")
+	revision, err := scanRevision(row)
+	if errors.Is(err, ErrRevisionNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return revision.Spec, nil
+}
+
+func (s *SqliteController) ListRevisions(limit int) ([]Revision, error) {
+	// a negative limit returns all rows
+	if limit <= 0 {
+		limit = -1
+	}
+
+	rows, err := s.db.Query("SELECT number, created_at, origin FROM revisions ORDER BY number DESC LIMIT ?", limit)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list revisions: %w", err)
+	}
+	defer rows.Close()
+
+	revisions := make([]Revision, 0)
+	for rows.Next() {
+		var revision Revision
+		var createdAt int64
+		if err = rows.Scan(&revision.Number, &createdAt, &revision.Origin); err != nil {
+			return nil, fmt.Errorf("unable to list revisions: %w", err)
+		}
+
+		revision.CreatedAt = time.UnixMilli(createdAt)
+		revisions = append(revisions, revision)
+	}
+
+	return revisions, rows.Err()
+}
+
+func (s *SqliteController) GetRevision(number int64) (*Revision, error) {
+	row := s.db.QueryRow("SELECT number, created_at, origin, spec FROM revisions WHERE number = ?", number)
+	revision, err := scanRevision(row)
+	if errors.Is(err, ErrRevisionNotFound) {
+		return nil, fmt.Errorf("%w: %d", ErrRevisionNotFound, number)
+	}
+
+	return revision, err
+}
+
+// Close closes the database.
+func (s *SqliteController) Close() error {
+	return s.db.Close()
+}
+
+// scanRevision reads a revision including its state specification, it returns ErrRevisionNotFound when the row is empty.
+func scanRevision(row *sql.Row) (*Revision, error) {
+	var revision Revision
+	var createdAt int64
+	var spec string
+
+	err := row.Scan(&revision.Number, &createdAt, &revision.Origin, &spec)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrRevisionNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("unable to read revision: %w", err)
+	}
+
+	revision.CreatedAt = time.UnixMilli(createdAt)
+	revision.Spec, err = parseState([]byte(spec))
+	if err != nil {
+		return nil, fmt.Errorf("%w: revision %d", err, revision.Number)
+	}
+
+	return &revision, nil
+}
@@ -0,0 +1,109 @@
+package persistence
+
+import (
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func NewTestSqliteController(t *testing.T) *SqliteController {
+	c, err := NewSqliteController(path.Join(t.TempDir(), "gco.db"))
+	if err != nil {
+		t.Fatalf("unable to create sqlite controller: %v", err)
+	}
+	t.Cleanup(func() { c.Close() })
+
+	return c
+}
+
+func TestSqliteController_Read_empty(t *testing.T) {
+	c := NewTestSqliteController(t)
+
+	spec, err := c.Read()
+	assert.Nil(t, err)
+	assert.Nil(t, spec, "should not have read a state before anything has been persisted")
+}
+
+func TestSqliteController_PersistRevision(t *testing.T) {
+	c := NewTestSqliteController(t)
+
+	first, err := c.PersistRevision(testSpec("nginx"), "CreateApplication")
+	assert.Nil(t, err)
+	second, err := c.PersistRevision(testSpec("apache"), "UpdateApplication")
+	assert.Nil(t, err)
+	assert.Greater(t, second.Number, first.Number)
+
+	spec, err := c.Read()
+	if assert.Nil(t, err) && assert.NotNil(t, spec) {
+		assert.Equal(t, "apache", spec.Applications[0].Name, "should read the most recent revision")
+	}
+
+	revision, err := c.GetRevision(first.Number)
+	if assert.Nil(t, err) {
+		assert.Equal(t, "CreateApplication", revision.Origin)
+		assert.Equal(t, first.CreatedAt, revision.CreatedAt)
+		assert.Equal(t, "nginx", revision.Spec.Applications[0].Name)
+	}
+
+	_, err = c.GetRevision(42)
+	assert.ErrorIs(t, err, ErrRevisionNotFound)
+}
+
+func TestSqliteController_ListRevisions(t *testing.T) {
+	c := NewTestSqliteController(t)
+	for _, name := range []string{"first", "second", "third"} {
+		assert.Nil(t, c.Persist(testSpec(name)))
+	}
+
+	revisions, err := c.ListRevisions(0)
+	if assert.Nil(t, err) && assert.Len(t, revisions, 3) {
+		assert.Equal(t, int64(3), revisions[0].Number, "should start with the most recent revision")
+		assert.Nil(t, revisions[0].Spec, "should not include the state specification")
+	}
+
+	revisions, err = c.ListRevisions(2)
+	if assert.Nil(t, err) && assert.Len(t, revisions, 2) {
+		assert.Equal(t, int64(2), revisions[1].Number)
+	}
+}
+
+func TestSqliteController_PersistRevision_prunes(t *testing.T) {
+	c := NewTestSqliteController(t).WithRevisions(2)
+	for _, name := range []string{"first", "second", "third", "fourth", "fifth"} {
+		assert.Nil(t, c.Persist(testSpec(name)))
+	}
+
+	revisions, err := c.ListRevisions(0)
+	if assert.Nil(t, err) && assert.Len(t, revisions, 3, "should keep the most recent and two previous revisions") {
+		assert.Equal(t, int64(5), revisions[0].Number)
+		assert.Equal(t, int64(3), revisions[2].Number)
+	}
+
+	_, err = c.GetRevision(2)
+	assert.ErrorIs(t, err, ErrRevisionNotFound, "should have removed the outdated revision")
+
+	spec, err := c.Read()
+	if assert.Nil(t, err) && assert.NotNil(t, spec) {
+		assert.Equal(t, "fifth", spec.Applications[0].Name)
+	}
+}
+
+func TestSqliteController_reopen(t *testing.T) {
+	location := path.Join(t.TempDir(), "gco.db")
+	c, err := NewSqliteController(location)
+	if assert.Nil(t, err) {
+		assert.Nil(t, c.Persist(testSpec("nginx")))
+		assert.Nil(t, c.Close())
+	}
+
+	c, err = NewSqliteController(location)
+	if assert.Nil(t, err) {
+		defer c.Close()
+
+		spec, err := c.Read()
+		if assert.Nil(t, err) && assert.NotNil(t, spec) {
+			assert.Equal(t, "nginx", spec.Applications[0].Name, "should keep the state after a restart")
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package persistence
 
 import (
+	"time"
+
 	"github.com/mbaitar/gco/agent/internal/state"
 )
 
@@ -20,3 +22,30 @@ type Controller interface {
 	// Read defines a function which will try to read the current persisted state from the controller.
 	Read() (*state.Spec, error)
 }
+
+// Revision is a persisted state specification as it has been kept by a RevisionController.
+type Revision struct {
+	// Number identifies the revision, later revisions have a higher number.
+	Number int64
+	// CreatedAt is the time the revision has been persisted.
+	CreatedAt time.Time
+	// Origin is the API call which persisted the revision, e.g. 'UpdateApplication'.
+	Origin string
+	// Spec is the persisted state specification.
+	Spec *state.Spec
+}
+
+// RevisionController defines a Controller which keeps every persisted state as a numbered revision.
+type RevisionController interface {
+	Controller
+
+	// PersistRevision persists the state specification as a new revision, recording the API call it originates from.
+	PersistRevision(spec *state.Spec, origin string) (*Revision, error)
+
+	// ListRevisions returns the most recent revisions without their state specification, starting with the
+	// most recent one. A limit of 0 returns all revisions.
+	ListRevisions(limit int) ([]Revision, error)
+
+	// GetRevision returns the revision including its state specification, or ErrRevisionNotFound.
+	GetRevision(number int64) (*Revision, error)
+}
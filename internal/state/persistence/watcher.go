@@ -2,18 +2,25 @@ package persistence
 
 import (
 	"os"
+	"path"
+	"time"
 
 	"github.com/fsnotify/fsnotify"
 	"github.com/mbaitar/gco/agent/internal/log"
 	"github.com/mbaitar/gco/agent/internal/state"
 )
 
+// defaultWatchDebounce is the time the file has to be left unmodified before reading it,
+// editors and atomic writers often modify a file using multiple operations.
+const defaultWatchDebounce = 100 * time.Millisecond
+
 type ChangeHandler func(s *state.Spec)
 
 type Watcher struct {
-	handler ChangeHandler
-	file    string
-	watcher *fsnotify.Watcher
+	handler  ChangeHandler
+	file     string
+	watcher  *fsnotify.Watcher
+	debounce time.Duration
 }
 
 func NewWatcher(file string, handler ChangeHandler) *Watcher {
@@ -24,9 +31,10 @@ func NewWatcher(file string, handler ChangeHandler) *Watcher {
 	}
 
 	return &Watcher{
-		handler: handler,
-		file:    file,
-		watcher: watcher,
+		handler:  handler,
+		file:     file,
+		watcher:  watcher,
+		debounce: defaultWatchDebounce,
 	}
 }
 
@@ -35,12 +43,14 @@ func (w *Watcher) read() (*state.Spec, error) {
 	if err != nil {
 		return nil, err
 	}
+	defer file.Close()
 
 	return ReadJson(file), nil
 }
 
+// Init starts watching the directory of the file, as watching the file itself stops once it has been replaced.
 func (w *Watcher) Init() error {
-	err := w.watcher.Add(w.file)
+	err := w.watcher.Add(path.Dir(w.file))
 	if err != nil {
 		log.Warnf("Unable to watch file: %v", err)
 		return err
@@ -49,9 +59,19 @@ func (w *Watcher) Init() error {
 	return nil
 }
 
+// Watch handles the file system events until the watcher has been closed. Bursts of modifications are
+// debounced into a single read once the file has not been modified for the debounce interval, files replaced by renaming another file (e.g. vim or atomic
+// writers) are reported as created within the watched directory.
 func (w *Watcher) Watch() {
 	defer w.watcher.Close()
 
+	// the timer only runs once a modification has been detected
+	debounce := time.NewTimer(w.debounce)
+	if !debounce.Stop() {
+		<-debounce.C
+	}
+	defer debounce.Stop()
+
 	for {
 		select {
 		case err, ok := <-w.watcher.Errors:
@@ -67,17 +87,35 @@ func (w *Watcher) Watch() {
 				return
 			}
 
-			if event.Has(fsnotify.Write) {
-				log.Debug("File modification detected")
-				config, err := w.read()
-				if err != nil {
-					log.Warnf("Unable to read config from changed file: %v", err)
-				} else if config != nil {
-					w.handler(config)
-				} else {
-					log.Warn("File change detected but unable to read config")
+			if path.Clean(event.Name) != path.Clean(w.file) {
+				continue
+			}
+
+			if event.Has(fsnotify.Write) || event.Has(fsnotify.Create) {
+				log.Debugf("File modification detected (%s)", event.Op)
+				if !debounce.Stop() {
+					// drain the channel in case the timer fired but has not been received yet
+					select {
+					case <-debounce.C:
+					default:
+					}
 				}
+				debounce.Reset(w.debounce)
 			}
+		case <-debounce.C:
+			w.handleChange()
 		}
 	}
 }
+
+// handleChange reads the modified file and passes the state to the handler.
+func (w *Watcher) handleChange() {
+	config, err := w.read()
+	if err != nil {
+		log.Warnf("Unable to read config from changed file: %v", err)
+	} else if config != nil {
+		w.handler(config)
+	} else {
+		log.Warn("File change detected but unable to read config")
+	}
+}
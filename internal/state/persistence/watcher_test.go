@@ -4,6 +4,7 @@ import (
 	"errors"
 	"os"
 	"path"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -41,32 +42,37 @@ func TestWatcher_Watch_JSON(t *testing.T) {
 		t.Fatalf("unable to create test file: %v", err)
 	}
 
-	called := 0
+	var called atomic.Int32
 	handler := func(c *state.Spec) {
-		called += 1
+		called.Add(1)
 	}
 	watcher := NewWatcher(location, handler)
+	watcher.debounce = time.Millisecond
 
 	go func() {
 		watcher.watcher.Errors <- errors.New("test error")
 		watcher.watcher.Errors <- errors.New("test error")
-		assert.Equal(t, 0, called, "should not have called handler")
+		assert.Equal(t, int32(0), called.Load(), "should not have called handler")
 
 		// trigger read
-		watcher.watcher.Events <- fsnotify.Event{Op: fsnotify.Write, Name: "test"}
-		time.Sleep(time.Millisecond * 10) // give event some time to propagate
-		assert.Equal(t, 1, called, "should have triggered the handler func")
+		watcher.watcher.Events <- fsnotify.Event{Op: fsnotify.Write, Name: location}
+		assert.Eventually(t, func() bool { return called.Load() == 1 }, time.Second, time.Millisecond, "should have triggered the handler func")
+
+		// trigger write of another file within the directory
+		watcher.watcher.Events <- fsnotify.Event{Op: fsnotify.Write, Name: path.Join(dir, "other.json")}
+		time.Sleep(time.Millisecond * 10)
+		assert.Equal(t, int32(1), called.Load(), "should have ignored the other file")
 
 		// trigger removal
-		watcher.watcher.Events <- fsnotify.Event{Op: fsnotify.Remove, Name: "test"}
+		watcher.watcher.Events <- fsnotify.Event{Op: fsnotify.Remove, Name: location}
 		time.Sleep(time.Millisecond * 10)
-		assert.Equal(t, 1, called, "should not have triggered the handler func again")
+		assert.Equal(t, int32(1), called.Load(), "should not have triggered the handler func again")
 
-		// trigger read of incorrect file
-		watcher.file = "/does/not/exist"
-		watcher.watcher.Events <- fsnotify.Event{Op: fsnotify.Write, Name: "test"}
+		// trigger read of a file which no longer exists
+		_ = os.Remove(location)
+		watcher.watcher.Events <- fsnotify.Event{Op: fsnotify.Write, Name: location}
 		time.Sleep(time.Millisecond * 10)
-		assert.Equal(t, 1, called, "should have resulted in a read error")
+		assert.Equal(t, int32(1), called.Load(), "should have resulted in a read error")
 
 		// close watcher
 		watcher.watcher.Close()
@@ -74,3 +80,66 @@ func TestWatcher_Watch_JSON(t *testing.T) {
 
 	watcher.Watch()
 }
+
+func TestWatcher_Watch_debounce(t *testing.T) {
+	dir := t.TempDir()
+	location := path.Join(dir, "config.json")
+	err := os.WriteFile(location, []byte(testJson), os.ModePerm)
+	if err != nil {
+		t.Fatalf("unable to create test file: %v", err)
+	}
+
+	var called atomic.Int32
+	handler := func(c *state.Spec) {
+		called.Add(1)
+	}
+	watcher := NewWatcher(location, handler)
+	watcher.debounce = time.Millisecond * 20
+
+	go func() {
+		// editors modify the file using multiple operations
+		watcher.watcher.Events <- fsnotify.Event{Op: fsnotify.Create, Name: location}
+		watcher.watcher.Events <- fsnotify.Event{Op: fsnotify.Write, Name: location}
+		watcher.watcher.Events <- fsnotify.Event{Op: fsnotify.Write, Name: location}
+		assert.Equal(t, int32(0), called.Load(), "should not have called handler before the debounce")
+
+		assert.Eventually(t, func() bool { return called.Load() == 1 }, time.Second, time.Millisecond, "should have triggered the handler func")
+		time.Sleep(time.Millisecond * 50)
+		assert.Equal(t, int32(1), called.Load(), "should have triggered the handler func once")
+
+		watcher.watcher.Close()
+	}()
+
+	watcher.Watch()
+}
+
+func TestWatcher_Watch_debounceUntilQuiet(t *testing.T) {
+	dir := t.TempDir()
+	location := path.Join(dir, "config.json")
+	err := os.WriteFile(location, []byte(testJson), os.ModePerm)
+	if err != nil {
+		t.Fatalf("unable to create test file: %v", err)
+	}
+
+	var called atomic.Int32
+	handler := func(c *state.Spec) {
+		called.Add(1)
+	}
+	watcher := NewWatcher(location, handler)
+	watcher.debounce = time.Millisecond * 100
+
+	go func() {
+		// every modification within the debounce interval postpones the read
+		for i := 0; i < 4; i++ {
+			watcher.watcher.Events <- fsnotify.Event{Op: fsnotify.Write, Name: location}
+			time.Sleep(time.Millisecond * 70)
+		}
+		assert.Equal(t, int32(0), called.Load(), "should not have called handler while the file is modified")
+
+		assert.Eventually(t, func() bool { return called.Load() == 1 }, time.Second, time.Millisecond, "should have triggered the handler func once the file is quiet")
+
+		watcher.watcher.Close()
+	}()
+
+	watcher.Watch()
+}
@@ -4,17 +4,23 @@ import (
 	"errors"
 	"fmt"
 
-	"github.com/mbaitar/gco/agent/pkg/feature"
 	"github.com/mbaitar/gco/agent/pkg/resource"
 )
 
+var (
+	// ErrApplicationExists is returned when adding an application whose name is already in use.
+	ErrApplicationExists = errors.New("application already exists")
+	// ErrApplicationNotFound is returned when no application matches the name.
+	ErrApplicationNotFound = errors.New("application not found")
+)
+
 // Spec describes the application specification in an 'as is' or 'should be' state.
 type Spec struct {
 	// Applications lists the available applications in the current state specification.
 	Applications []resource.Application `json:"applications"`
 
 	// Feature contains all the enabled features for the agent.
-	Feature Feature `json:"feature,omitempty"`
+	Feature Feature `json:"feature"`
 }
 
 // EmptySpec returns a new empty state specification
@@ -24,6 +30,17 @@ func EmptySpec() *Spec {
 	}
 }
 
+// Copy returns a copy of the state specification which can be modified without changing the original applications.
+func (s *Spec) Copy() *Spec {
+	applications := make([]resource.Application, len(s.Applications))
+	copy(applications, s.Applications)
+
+	return &Spec{
+		Applications: applications,
+		Feature:      s.Feature,
+	}
+}
+
 // GetApplication tries to find the application matching the given name.
 func (s *Spec) GetApplication(name string) *resource.Application {
 	for _, app := range s.Applications {
@@ -39,7 +56,7 @@ func (s *Spec) GetApplication(name string) *resource.Application {
 func (s *Spec) AddApplication(app resource.Application) error {
 	match := s.GetApplication(app.Name)
 	if match != nil {
-		return errors.New("application already exists")
+		return fmt.Errorf("%w: '%s'", ErrApplicationExists, app.Name)
 	}
 
 	s.Applications = append(s.Applications, app)
@@ -55,7 +72,7 @@ func (s *Spec) UpdateApplication(update resource.Application) error {
 		}
 	}
 
-	return fmt.Errorf("no application found to update with name '%s'", update.Name)
+	return fmt.Errorf("%w: '%s'", ErrApplicationNotFound, update.Name)
 }
 
 // RemoveApplication tries to find the matching application by name and removes it from the current spec.
@@ -70,7 +87,7 @@ func (s *Spec) RemoveApplication(name string) error {
 	}
 
 	if idx < 0 {
-		return fmt.Errorf("no application found to remove with name '%s'", name)
+		return fmt.Errorf("%w: '%s'", ErrApplicationNotFound, name)
 	}
 
 	s.Applications = append(s.Applications[:idx], s.Applications[idx+1:]...)
@@ -79,9 +96,5 @@ func (s *Spec) RemoveApplication(name string) error {
 
 // IsFeatureEnabled returns true if the specified name of the feature can be found in the state specification.
 func (s *Spec) IsFeatureEnabled(name string) bool {
-	if feature.NameFluentBit == name {
-		return s.Feature.FluentBit != nil
-	}
-
-	return false
+	return s.GetFeature(name) != nil
 }
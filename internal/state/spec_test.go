@@ -37,7 +37,7 @@ func TestSpec_AddApplication(t *testing.T) {
 	}
 
 	err = spec.AddApplication(app)
-	assert.NotNil(t, err, "should have thrown as application already exists")
+	assert.ErrorIs(t, err, ErrApplicationExists, "should have thrown as application already exists")
 }
 
 func TestSpec_UpdateApplication(t *testing.T) {
@@ -66,7 +66,7 @@ func TestSpec_UpdateApplication(t *testing.T) {
 
 	app.Name = "blah"
 	err = spec.UpdateApplication(app)
-	assert.NotNil(t, err, "should not have found a matching application")
+	assert.ErrorIs(t, err, ErrApplicationNotFound, "should not have found a matching application")
 }
 
 func TestSpec_RemoveApplication(t *testing.T) {
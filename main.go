@@ -1,19 +1,44 @@
 package main
 
 import (
+	"errors"
+	"fmt"
 	"os"
 
 	"github.com/mbaitar/gco/agent/internal/config"
+	"github.com/mbaitar/gco/agent/internal/files"
 	"github.com/mbaitar/gco/agent/internal/log"
+	"github.com/mbaitar/gco/agent/internal/metrics"
 	"github.com/mbaitar/gco/agent/internal/provider"
+	"github.com/mbaitar/gco/agent/internal/provider/containerd"
 	"github.com/mbaitar/gco/agent/internal/provider/docker"
+	"github.com/mbaitar/gco/agent/internal/provider/kubernetes"
+	"github.com/mbaitar/gco/agent/internal/provider/podman"
+	"github.com/mbaitar/gco/agent/internal/provider/swarm"
 	"github.com/mbaitar/gco/agent/internal/service"
+	"github.com/mbaitar/gco/agent/internal/state/persistence"
 	"github.com/mbaitar/gco/agent/pkg/control"
 )
 
 func createProvider(conf *config.Config) provider.Provider {
 	if conf.Docker.Enabled {
-		return docker.NewDockerProvider().WithConfig(conf.Docker)
+		return metrics.InstrumentProvider(docker.NewDockerProvider().WithConfig(conf.Docker))
+	}
+
+	if conf.Swarm.Enabled {
+		return metrics.InstrumentProvider(swarm.NewSwarmProvider())
+	}
+
+	if conf.Kubernetes.Enabled {
+		return metrics.InstrumentProvider(kubernetes.NewKubernetesProvider(conf.Kubernetes))
+	}
+
+	if conf.Podman.Enabled {
+		return metrics.InstrumentProvider(podman.NewPodmanProvider(conf.Podman))
+	}
+
+	if conf.Containerd.Enabled {
+		return metrics.InstrumentProvider(containerd.NewContainerdProvider(conf.Containerd))
 	}
 
 	log.Errorf("No provider has been enabled, please check your configuration")
@@ -21,29 +46,84 @@ func createProvider(conf *config.Config) provider.Provider {
 	return nil // should not be reached
 }
 
-func createStateController(p provider.Provider) *control.StateController {
+func createPersistence(conf *config.Config) persistence.Controller {
+	switch conf.Persistence.Backend {
+	case config.LocalBackend:
+		return persistence.NewLocalController(conf.GetStateFile()).WithBackups(conf.Persistence.Backups)
+	case config.SqliteBackend:
+		controller, err := persistence.NewSqliteController(conf.GetDatabase())
+		if err != nil {
+			log.Errorf("Unable to initialize sqlite persistence controller: %v", err)
+			os.Exit(1)
+		}
+
+		return controller.WithRevisions(conf.Persistence.Revisions)
+	case config.EtcdBackend:
+		controller, err := persistence.NewEtcdController(conf.Persistence.Etcd)
+		if err != nil {
+			log.Errorf("Unable to initialize etcd persistence controller: %v", err)
+			os.Exit(1)
+		}
+
+		return controller
+	}
+
+	log.Errorf("Unsupported persistence backend '%s', please check your configuration", conf.Persistence.Backend)
+	os.Exit(1)
+	return nil // should not be reached
+}
+
+func createStateController(conf *config.Config, p provider.Provider, persisted persistence.Controller) *control.StateController {
 	ctrl, err := control.InitControl(p)
 	if err != nil {
 		log.Errorf("failed to initialize control: %v", err)
 		os.Exit(1)
 	} else {
-		log.Info("Successfully initialized control loop using docker provider")
+		log.Info("Successfully initialized control loop")
 	}
 
+	ctrl.WithResyncInterval(conf.General.ResyncInterval)
+
 	go ctrl.Start()
 
-	state := control.NewStateController(ctrl)
+	state := control.NewStateController(ctrl, persisted)
 	return state
 }
 
 func main() {
-	// load config
-	conf := config.DefaultConfig()
+	// load config (defaults < config file < GCO_* environment variables < command-line flags)
+	conf, opts, err := config.Load(os.Args[1:], os.LookupEnv)
+	if errors.Is(err, config.ErrHelp) {
+		fmt.Print(config.Usage())
+		return
+	} else if err != nil {
+		log.Errorf("Failed to load configuration: %v", err)
+		os.Exit(2)
+	}
+
+	if opts.PrintConfig {
+		fmt.Print(conf.String())
+		return
+	}
+
+	if opts.ConfigFile != "" {
+		log.Infof("Loaded configuration file '%s'", opts.ConfigFile)
+	}
+
 	conf.SetFlags()
 
+	// prepare data directory, containing the state and generated configuration files
+	if err = os.MkdirAll(conf.General.DataDirectory, 0744); err != nil {
+		log.Errorf("Unable to create data directory '%s': %v", conf.General.DataDirectory, err)
+		os.Exit(1)
+	}
+	files.SetDirectory(conf.General.DataDirectory)
+	files.WarnOnLegacySecrets(conf.General.DataDirectory)
+
 	// setup application
 	prov := createProvider(conf)
-	controller := createStateController(prov)
+	persisted := createPersistence(conf)
+	controller := createStateController(conf, prov, persisted)
 
 	// start gRPC server
 	go service.StartGRPC(conf.Grpc, controller)
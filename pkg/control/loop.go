@@ -3,6 +3,7 @@ package control
 import (
 	"context"
 	"os"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/mbaitar/gco/agent/internal/log"
@@ -29,6 +30,10 @@ type Control struct {
 
 	sem      *semaphore.Weighted
 	handlers map[string]StateUpdateHandler
+	actual   state.Spec
+	backoff  map[string]diff.Backoff
+
+	resyncInterval time.Duration
 }
 
 // InitControl will initialize the control structure used for keeping the system in the correct state.
@@ -52,6 +57,8 @@ func InitControl(p provider.Provider) (*Control, error) {
 
 		sem:      semaphore.NewWeighted(1),
 		handlers: make(map[string]StateUpdateHandler),
+		actual:   *actual,
+		backoff:  make(map[string]diff.Backoff),
 	}, nil
 }
 
@@ -60,14 +67,26 @@ func InitControl(p provider.Provider) (*Control, error) {
 func (c *Control) Start() {
 	log.Info("Resource control loop has been started")
 
+	// observe external changes if supported by the provider
+	if observer, ok := c.provider.(provider.Observer); ok {
+		go observer.Watch(c.exit, c.Observe)
+	}
+
+	// periodically resync the actual state as a safety net
+	if c.resyncInterval > 0 {
+		go c.resync()
+	}
+
 	for {
 		select {
 		case desired := <-c.apply:
 			log.Infof("Received signal from 'apply' channel (applications=%d)", len(desired.Applications))
 			c.reconciler.Apply(&desired)
+			c.notifyHandlers()
 		case actual := <-c.observe:
 			log.Infof("Received signal from 'observe' channel (applications=%d)", len(actual.Applications))
 			c.reconciler.Observe(&actual)
+			c.notifyHandlers()
 		case <-c.exit:
 			log.Debug("Received signal from 'exit' channel")
 			return
@@ -88,16 +107,56 @@ func (c *Control) Apply(spec state.Spec) {
 
 // Observe will observe a change from the external system and propagate it to the reconciler to decide what needs to happen.
 func (c *Control) Observe(spec state.Spec) {
-	c.observe <- spec
+	select {
+	case c.observe <- spec:
+	case <-c.exit:
+		log.Debug("Control loop has been stopped, ignoring observed state")
+	}
+}
+
+// ActualState returns the actual state as it was known after the last reconciliation.
+func (c *Control) ActualState() state.Spec {
+	c.acquireHandlerLock()
+	defer c.sem.Release(1)
+
+	return c.actual
+}
+
+// SupportsFeature returns true when the provider is able to create the feature matching the name.
+func (c *Control) SupportsFeature(name string) bool {
+	return provider.SupportsFeature(c.provider, name)
+}
+
+// Backoff returns the backoff of the applications which failed to be created or updated during the last reconciliation.
+func (c *Control) Backoff() map[string]diff.Backoff {
+	c.acquireHandlerLock()
+	defer c.sem.Release(1)
+
+	return c.backoff
+}
+
+// notifyHandlers calls every registered handler with the actual state after a reconciliation.
+// Handlers are called from within the control loop and should therefore not block.
+func (c *Control) notifyHandlers() {
+	c.acquireHandlerLock()
+	c.actual = *c.reconciler.Actual()
+	c.backoff = c.reconciler.Backoff()
+	actual := c.actual
+
+	handlers := make([]StateUpdateHandler, 0, len(c.handlers))
+	for _, handler := range c.handlers {
+		handlers = append(handlers, handler)
+	}
+	c.sem.Release(1)
+
+	for _, handler := range handlers {
+		handler(actual)
+	}
 }
 
 // RegisterHandler registers a new handler and returns the handler signature for optional removal
 func (c *Control) RegisterHandler(handler StateUpdateHandler) string {
-	ctx := context.Background()
-	if err := c.sem.Acquire(ctx, 1); err != nil {
-		log.Errorf("unable to acquire handler lock: %v", err)
-		os.Exit(1)
-	}
+	c.acquireHandlerLock()
 	defer c.sem.Release(1)
 
 	addr := uuid.NewString()
@@ -107,12 +166,17 @@ func (c *Control) RegisterHandler(handler StateUpdateHandler) string {
 
 // RemoveHandler removes the handler using the signature received from the RegisterHandler method.
 func (c *Control) RemoveHandler(signature string) {
+	c.acquireHandlerLock()
+	defer c.sem.Release(1)
+
+	delete(c.handlers, signature)
+}
+
+// acquireHandlerLock acquires the lock guarding the handlers and the last known actual state.
+func (c *Control) acquireHandlerLock() {
 	ctx := context.Background()
 	if err := c.sem.Acquire(ctx, 1); err != nil {
 		log.Errorf("unable to acquire handler lock: %v", err)
 		os.Exit(1)
 	}
-	defer c.sem.Release(1)
-
-	delete(c.handlers, signature)
 }
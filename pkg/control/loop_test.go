@@ -4,7 +4,9 @@ import (
 	"log"
 	"sync"
 	"testing"
+	"time"
 
+	"github.com/mbaitar/gco/agent/internal/provider"
 	"github.com/mbaitar/gco/agent/internal/state"
 	"github.com/mbaitar/gco/agent/pkg/feature"
 	"github.com/mbaitar/gco/agent/pkg/resource"
@@ -63,3 +65,50 @@ func TestControl_RegisterAndRemoveHandler(t *testing.T) {
 	wg.Wait()
 	log.Printf("hello")
 }
+
+type ObservingProvider struct {
+	NilProvider
+	watching chan struct{}
+}
+
+func (o *ObservingProvider) Watch(exit <-chan struct{}, handler provider.ObserveHandler) {
+	handler(*state.EmptySpec())
+	close(o.watching)
+	<-exit
+}
+
+func TestControl_Start_watchesObserver(t *testing.T) {
+	p := &ObservingProvider{watching: make(chan struct{})}
+	control, _ := InitControl(p)
+
+	go control.Start()
+
+	select {
+	case <-p.watching:
+	case <-time.After(time.Second):
+		t.Fatalf("should have started watching the provider")
+	}
+
+	control.Stop()
+}
+
+func TestControl_Start_notifiesHandlers(t *testing.T) {
+	control, _ := InitControl(&NilProvider{})
+
+	notified := make(chan state.Spec, 1)
+	control.RegisterHandler(func(spec state.Spec) {
+		notified <- spec
+	})
+
+	go control.Start()
+	defer control.Stop()
+
+	control.Apply(*state.EmptySpec())
+
+	select {
+	case spec := <-notified:
+		assert.NotNil(t, spec.Applications, "should have received the actual state")
+	case <-time.After(time.Second):
+		t.Fatalf("should have notified the handler after reconciliation")
+	}
+}
@@ -0,0 +1,70 @@
+package control
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/mbaitar/gco/agent/internal/log"
+	"github.com/mbaitar/gco/agent/internal/metrics"
+)
+
+const (
+	// resyncJitter is the fraction of the interval used to randomize the time between two resyncs.
+	resyncJitter = 0.1
+	// maxResyncBackoff limits the delay between resyncs when the provider keeps failing.
+	maxResyncBackoff = 5 * time.Minute
+)
+
+// WithResyncInterval sets the interval used to periodically pull the actual state from the provider.
+// A zero or negative interval disables the periodic resync.
+func (c *Control) WithResyncInterval(interval time.Duration) *Control {
+	c.resyncInterval = interval
+	return c
+}
+
+// resync periodically retrieves the actual state from the provider and propagates it to the reconciler.
+// This acts as a safety net for missed provider events and retries failed changes.
+// This method will block until the 'exit' signal has been received.
+func (c *Control) resync() {
+	log.Infof("Periodic resync has been started (interval=%s)", c.resyncInterval)
+	delay := c.resyncInterval
+
+	for {
+		select {
+		case <-c.exit:
+			log.Debug("Stopped periodic resync")
+			return
+		case <-time.After(withJitter(delay)):
+		}
+
+		actual, err := c.provider.ActualState()
+		if err != nil {
+			delay = nextResyncBackoff(delay)
+			log.Warnf("Unable to resync actual state, retrying in %s: %v", delay, err)
+			continue
+		}
+
+		delay = c.resyncInterval
+
+		metrics.LastResync.SetToCurrentTime()
+
+		log.Debugf("Resynced actual state (applications=%d)", len(actual.Applications))
+		c.Observe(*actual)
+	}
+}
+
+// nextResyncBackoff doubles the delay until the maxResyncBackoff has been reached.
+func nextResyncBackoff(delay time.Duration) time.Duration {
+	next := delay * 2
+	if next > maxResyncBackoff {
+		return maxResyncBackoff
+	}
+
+	return next
+}
+
+// withJitter randomizes the duration by resyncJitter to avoid synchronized resyncs.
+func withJitter(d time.Duration) time.Duration {
+	jitter := time.Duration(float64(d) * resyncJitter * (rand.Float64()*2 - 1))
+	return d + jitter
+}
@@ -0,0 +1,78 @@
+package control
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mbaitar/gco/agent/internal/metrics"
+	"github.com/mbaitar/gco/agent/internal/state"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+type CountingProvider struct {
+	NilProvider
+	calls int32
+	err   error
+}
+
+func (c *CountingProvider) ActualState() (*state.Spec, error) {
+	atomic.AddInt32(&c.calls, 1)
+	if c.err != nil {
+		return nil, c.err
+	}
+
+	return state.EmptySpec(), nil
+}
+
+func TestControl_resync(t *testing.T) {
+	p := &CountingProvider{}
+	control, _ := InitControl(p)
+	control.WithResyncInterval(time.Millisecond * 10)
+	metrics.LastResync.Set(0)
+
+	go control.Start()
+	time.Sleep(time.Millisecond * 100)
+	control.Stop()
+
+	// initial call is done by InitControl()
+	assert.Greater(t, atomic.LoadInt32(&p.calls), int32(2), "should have periodically called ActualState()")
+	assert.False(t, testutil.ToFloat64(metrics.LastResync) == 0, "should have recorded the last resync")
+}
+
+func TestControl_resync_disabled(t *testing.T) {
+	p := &CountingProvider{}
+	control, _ := InitControl(p)
+	metrics.LastResync.Set(0)
+
+	go control.Start()
+	time.Sleep(time.Millisecond * 50)
+	control.Stop()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&p.calls), "should only have called ActualState() on init")
+	assert.Zero(t, testutil.ToFloat64(metrics.LastResync), "should not have resynced")
+}
+
+func TestControl_resync_providerError(t *testing.T) {
+	p := &CountingProvider{}
+	control, _ := InitControl(p)
+	control.WithResyncInterval(time.Millisecond * 10)
+
+	metrics.LastResync.Set(0)
+
+	p.err = errors.New("test error")
+	go control.Start()
+	time.Sleep(time.Millisecond * 100)
+	control.Stop()
+
+	// backoff: ~10ms, ~20ms, ~40ms, ~80ms
+	assert.LessOrEqual(t, atomic.LoadInt32(&p.calls), int32(5), "should have backed off after provider errors")
+	assert.Zero(t, testutil.ToFloat64(metrics.LastResync), "should not have recorded a failed resync")
+}
+
+func TestNextResyncBackoff(t *testing.T) {
+	assert.Equal(t, time.Second*2, nextResyncBackoff(time.Second))
+	assert.Equal(t, maxResyncBackoff, nextResyncBackoff(maxResyncBackoff))
+}
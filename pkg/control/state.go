@@ -1,16 +1,23 @@
 package control
 
 import (
+	"errors"
+	"fmt"
 	"os"
-	"path"
 
 	"github.com/mbaitar/gco/agent/internal/flag"
 	"github.com/mbaitar/gco/agent/internal/log"
+	"github.com/mbaitar/gco/agent/internal/provider"
 	"github.com/mbaitar/gco/agent/internal/state"
+	"github.com/mbaitar/gco/agent/internal/state/diff"
 	"github.com/mbaitar/gco/agent/internal/state/persistence"
+	"github.com/mbaitar/gco/agent/pkg/feature"
 	"github.com/mbaitar/gco/agent/pkg/resource"
 )
 
+// ErrRevisionsUnsupported is returned when the persistence backend does not keep revisions of the state.
+var ErrRevisionsUnsupported = errors.New("persistence backend does not keep revisions")
+
 // StateController is a controller structure which manages the internal desired state of the application.
 type StateController struct {
 	// desired defines the current internal desired state as it is known in memory.
@@ -21,25 +28,11 @@ type StateController struct {
 	persisted persistence.Controller
 }
 
-func NewStateController(ctrl *Control) *StateController {
+// NewStateController creates the controller managing the desired state, using the persistence controller
+// to read the initial state and to keep the desired state after restarts.
+func NewStateController(ctrl *Control, persisted persistence.Controller) *StateController {
 	controller := &StateController{ctrl: ctrl}
 
-	dir, err := os.UserConfigDir()
-	if err != nil {
-		log.Errorf("Unable to retriever user configuration directory: %v", err)
-		os.Exit(1)
-	}
-
-	gcoDir := path.Join(dir, "gco")
-	err = os.MkdirAll(gcoDir, 0744)
-	if err != nil {
-		log.Errorf("Unable to create configuration director '%s': %v", gcoDir, err)
-		os.Exit(1)
-	}
-
-	// create local persistence controller
-	persisted := persistence.NewLocalController(path.Join(gcoDir, "gco.state"))
-
 	// get initial state from persisted state
 	initial, err := persisted.Read()
 	if err != nil {
@@ -79,53 +72,166 @@ func NewStateController(ctrl *Control) *StateController {
 }
 
 func (s *StateController) CreateApplication(application resource.Application) (*state.Spec, error) {
-	err := s.desired.AddApplication(application)
+	desired := s.desired.Copy()
+	err := desired.AddApplication(application)
 	if err != nil {
 		return nil, err
 	}
 
-	err = s.persisted.Persist(s.desired)
+	return s.update(desired, "CreateApplication")
+}
+
+func (s *StateController) UpdateApplication(application resource.Application) (*state.Spec, error) {
+	desired := s.desired.Copy()
+	err := desired.UpdateApplication(application)
 	if err != nil {
 		return nil, err
 	}
 
-	return s.desired, nil
+	return s.update(desired, "UpdateApplication")
 }
 
-func (s *StateController) UpdateApplication(application resource.Application) (*state.Spec, error) {
-
-	err := s.desired.UpdateApplication(application)
+func (s *StateController) DeleteApplication(name string) (*state.Spec, error) {
+	desired := s.desired.Copy()
+	err := desired.RemoveApplication(name)
 	if err != nil {
 		return nil, err
 	}
 
-	err = s.persisted.Persist(s.desired)
+	return s.update(desired, "DeleteApplication")
+}
+
+func (s *StateController) EnableFeature(feat feature.Feature) (*state.Spec, error) {
+	if !s.ctrl.SupportsFeature(feat.Name()) {
+		return nil, fmt.Errorf("%w: %s", provider.ErrFeatureNotSupported, feat.Name())
+	}
+
+	desired := s.desired.Copy()
+	err := desired.EnableFeature(feat)
 	if err != nil {
 		return nil, err
 	}
 
-	return s.desired, nil
+	return s.update(desired, "EnableFeature")
 }
 
-func (s *StateController) DeleteApplication(name string) (*state.Spec, error) {
+func (s *StateController) UpdateFeature(feat feature.Feature) (*state.Spec, error) {
+	if !s.ctrl.SupportsFeature(feat.Name()) {
+		return nil, fmt.Errorf("%w: %s", provider.ErrFeatureNotSupported, feat.Name())
+	}
 
-	err := s.desired.RemoveApplication(name)
+	desired := s.desired.Copy()
+	err := desired.UpdateFeature(feat)
 	if err != nil {
 		return nil, err
 	}
 
-	err = s.persisted.Persist(s.desired)
+	return s.update(desired, "UpdateFeature")
+}
+
+func (s *StateController) DisableFeature(name string) (*state.Spec, error) {
+	desired := s.desired.Copy()
+	err := desired.DisableFeature(name)
 	if err != nil {
 		return nil, err
 	}
 
-	return s.desired, nil
+	return s.update(desired, "DisableFeature")
 }
 
 func (s *StateController) GetCurrentState() *state.Spec {
 	return s.desired
 }
 
+// GetActualState returns the actual state as it was known after the last reconciliation.
+func (s *StateController) GetActualState() state.Spec {
+	return s.ctrl.ActualState()
+}
+
+// GetBackoff returns the backoff of the application when it repeatedly failed to be created or updated.
+func (s *StateController) GetBackoff(name string) (diff.Backoff, bool) {
+	backoff, found := s.ctrl.Backoff()[name]
+	return backoff, found
+}
+
+// RegisterHandler registers a handler which will be called with the actual state after each reconciliation.
+func (s *StateController) RegisterHandler(handler StateUpdateHandler) string {
+	return s.ctrl.RegisterHandler(handler)
+}
+
+// RemoveHandler removes the handler using the signature received from the RegisterHandler method.
+func (s *StateController) RemoveHandler(signature string) {
+	s.ctrl.RemoveHandler(signature)
+}
+
+// ListRevisions returns the most recent revisions of the persisted state, a limit of 0 returns all revisions.
+func (s *StateController) ListRevisions(limit int) ([]persistence.Revision, error) {
+	revisions, ok := s.persisted.(persistence.RevisionController)
+	if !ok {
+		return nil, ErrRevisionsUnsupported
+	}
+
+	return revisions.ListRevisions(limit)
+}
+
+// GetRevision returns the revision of the persisted state including its state specification.
+func (s *StateController) GetRevision(number int64) (*persistence.Revision, error) {
+	revisions, ok := s.persisted.(persistence.RevisionController)
+	if !ok {
+		return nil, ErrRevisionsUnsupported
+	}
+
+	return revisions.GetRevision(number)
+}
+
+// RollbackToRevision persists the state of the revision as a new revision and applies it.
+func (s *StateController) RollbackToRevision(number int64) (*persistence.Revision, error) {
+	revisions, ok := s.persisted.(persistence.RevisionController)
+	if !ok {
+		return nil, ErrRevisionsUnsupported
+	}
+
+	previous, err := revisions.GetRevision(number)
+	if err != nil {
+		return nil, err
+	}
+
+	revision, err := revisions.PersistRevision(previous.Spec, fmt.Sprintf("RollbackToRevision(%d)", number))
+	if err != nil {
+		return nil, err
+	}
+
+	log.Infof("Rolled back to revision %d (revision=%d)", number, revision.Number)
+	s.desired = revision.Spec
+	s.ctrl.Apply(*s.desired)
+	return revision, nil
+}
+
+// update persists the modified desired state and applies it. The previous desired state is kept when the
+// modified state could not be persisted, e.g. after a concurrent modification of the persisted state.
+func (s *StateController) update(desired *state.Spec, origin string) (*state.Spec, error) {
+	if err := s.persist(desired, origin); err != nil {
+		return nil, err
+	}
+
+	s.desired = desired
+	s.ctrl.Apply(*s.desired)
+	return s.desired, nil
+}
+
+// persist persists the desired state, recording the API call it originates from when the backend keeps revisions.
+// The persistence backends do not emit the changes of the agent itself, the caller applies the persisted state.
+func (s *StateController) persist(desired *state.Spec, origin string) error {
+	if revisions, ok := s.persisted.(persistence.RevisionController); ok {
+		_, err := revisions.PersistRevision(desired, origin)
+		return err
+	}
+
+	return s.persisted.Persist(desired)
+}
+
+// handleChange applies a change of the persisted state, e.g. an edit of the state file or another agent sharing the state.
 func (s *StateController) handleChange(update state.Spec) {
+	s.desired = &update
 	s.ctrl.Apply(update)
 }
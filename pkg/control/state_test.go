@@ -0,0 +1,208 @@
+package control
+
+import (
+	"path"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mbaitar/gco/agent/internal/provider"
+	"github.com/mbaitar/gco/agent/internal/state"
+	"github.com/mbaitar/gco/agent/internal/state/persistence"
+	"github.com/mbaitar/gco/agent/pkg/feature"
+	"github.com/mbaitar/gco/agent/pkg/resource"
+	"github.com/stretchr/testify/assert"
+)
+
+// RecordingProvider keeps the applications in memory and records the calls made by the control loop.
+type RecordingProvider struct {
+	NilProvider
+	lock  sync.Mutex
+	apps  map[string]resource.Application
+	calls chan string
+}
+
+func NewRecordingProvider() *RecordingProvider {
+	return &RecordingProvider{
+		apps:  make(map[string]resource.Application),
+		calls: make(chan string, 10),
+	}
+}
+
+func (r *RecordingProvider) CreateApplication(app *resource.Application) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.apps[app.Name] = *app
+	r.calls <- "create:" + app.Name
+	return nil
+}
+
+func (r *RecordingProvider) UpdateApplication(app *resource.Application) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.apps[app.Name] = *app
+	r.calls <- "update:" + app.Name
+	return nil
+}
+
+func (r *RecordingProvider) RemoveApplication(app *resource.Application) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	delete(r.apps, app.Name)
+	r.calls <- "remove:" + app.Name
+	return nil
+}
+
+func (r *RecordingProvider) ActualState() (*state.Spec, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	spec := state.EmptySpec()
+	for _, app := range r.apps {
+		spec.Applications = append(spec.Applications, app)
+	}
+
+	return spec, nil
+}
+
+// application returns the application as it has last been created or updated.
+func (r *RecordingProvider) application(name string) resource.Application {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return r.apps[name]
+}
+
+// expectCall waits until the provider received the call.
+func (r *RecordingProvider) expectCall(t *testing.T, expected string) {
+	select {
+	case call := <-r.calls:
+		assert.Equal(t, expected, call)
+	case <-time.After(time.Second):
+		t.Fatalf("should have called the provider with %s", expected)
+	}
+}
+
+// FailingController is a persistence controller which rejects every write.
+type FailingController struct {
+	changes chan state.Spec
+}
+
+func (f *FailingController) GetChangeChannel() persistence.ChangeChannel {
+	return f.changes
+}
+
+func (f *FailingController) Persist(_ *state.Spec) error {
+	return persistence.ErrConflict
+}
+
+func (f *FailingController) Read() (*state.Spec, error) {
+	return nil, nil
+}
+
+// FeaturelessProvider is a provider which does not support any feature.
+type FeaturelessProvider struct {
+	NilProvider
+}
+
+func (f *FeaturelessProvider) SupportsFeature(_ string) bool {
+	return false
+}
+
+func newTestStateController(t *testing.T, persisted persistence.Controller) *StateController {
+	return newTestStateControllerWithProvider(t, persisted, &NilProvider{})
+}
+
+func newTestStateControllerWithProvider(t *testing.T, persisted persistence.Controller, p provider.Provider) *StateController {
+	control, _ := InitControl(p)
+	go control.Start()
+	t.Cleanup(control.Stop)
+
+	return NewStateController(control, persisted)
+}
+
+func TestStateController_appliesChanges(t *testing.T) {
+	p := NewRecordingProvider()
+	s := newTestStateControllerWithProvider(t, persistence.NewLocalController(path.Join(t.TempDir(), "gco.state")), p)
+
+	_, err := s.CreateApplication(resource.Application{Name: "nginx", Image: resource.Image{Name: "nginx", Tag: "1.24"}})
+	assert.Nil(t, err)
+	p.expectCall(t, "create:nginx")
+
+	_, err = s.UpdateApplication(resource.Application{Name: "nginx", Image: resource.Image{Name: "nginx", Tag: "1.25"}})
+	assert.Nil(t, err)
+	p.expectCall(t, "update:nginx")
+
+	_, err = s.DeleteApplication("nginx")
+	assert.Nil(t, err)
+	p.expectCall(t, "remove:nginx")
+}
+
+func TestStateController_keepsStateOnPersistError(t *testing.T) {
+	s := newTestStateController(t, &FailingController{changes: make(chan state.Spec)})
+
+	_, err := s.CreateApplication(resource.Application{Name: "nginx", Image: resource.Image{Name: "nginx", Tag: "1.24"}})
+	assert.ErrorIs(t, err, persistence.ErrConflict)
+	assert.Nil(t, s.GetCurrentState().GetApplication("nginx"), "should not have kept the rejected change")
+}
+
+func TestStateController_RollbackToRevision(t *testing.T) {
+	persisted, err := persistence.NewSqliteController(path.Join(t.TempDir(), "gco.db"))
+	if err != nil {
+		t.Fatalf("unable to create sqlite controller: %v", err)
+	}
+	defer persisted.Close()
+
+	p := NewRecordingProvider()
+	s := newTestStateControllerWithProvider(t, persisted, p)
+
+	_, err = s.CreateApplication(resource.Application{Name: "nginx", Image: resource.Image{Name: "nginx", Tag: "1.24"}})
+	assert.Nil(t, err)
+	p.expectCall(t, "create:nginx")
+	_, err = s.UpdateApplication(resource.Application{Name: "nginx", Image: resource.Image{Name: "nginx", Tag: "broken"}})
+	assert.Nil(t, err)
+	p.expectCall(t, "update:nginx")
+
+	revisions, err := s.ListRevisions(0)
+	if assert.Nil(t, err) && assert.Len(t, revisions, 2) {
+		assert.Equal(t, "UpdateApplication", revisions[0].Origin)
+		assert.Equal(t, "CreateApplication", revisions[1].Origin)
+	}
+
+	revision, err := s.RollbackToRevision(revisions[1].Number)
+	if assert.Nil(t, err) {
+		assert.Equal(t, int64(3), revision.Number, "should have persisted the rollback as a new revision")
+		assert.Equal(t, "RollbackToRevision(1)", revision.Origin)
+	}
+
+	assert.Equal(t, "1.24", s.GetCurrentState().GetApplication("nginx").Image.Tag)
+	p.expectCall(t, "update:nginx")
+	assert.Equal(t, "1.24", p.application("nginx").Image.Tag, "should have applied the rolled back state")
+
+	_, err = s.RollbackToRevision(42)
+	assert.ErrorIs(t, err, persistence.ErrRevisionNotFound)
+}
+
+func TestStateController_ListRevisions_unsupported(t *testing.T) {
+	s := newTestStateController(t, persistence.NewLocalController(path.Join(t.TempDir(), "gco.state")))
+
+	_, err := s.ListRevisions(0)
+	assert.ErrorIs(t, err, ErrRevisionsUnsupported)
+
+	_, err = s.RollbackToRevision(1)
+	assert.ErrorIs(t, err, ErrRevisionsUnsupported)
+}
+
+func TestStateController_EnableFeature_unsupported(t *testing.T) {
+	s := newTestStateControllerWithProvider(t, persistence.NewLocalController(path.Join(t.TempDir(), "gco.state")), &FeaturelessProvider{})
+
+	_, err := s.EnableFeature(&feature.Ingress{})
+	assert.ErrorIs(t, err, provider.ErrFeatureNotSupported)
+	assert.False(t, s.GetCurrentState().IsFeatureEnabled(feature.NameIngress), "should not have enabled the feature")
+
+	_, err = s.UpdateFeature(&feature.Ingress{})
+	assert.ErrorIs(t, err, provider.ErrFeatureNotSupported)
+}
@@ -9,6 +9,15 @@ import (
 	"github.com/mbaitar/gco/agent/pkg/resource"
 )
 
+func init() {
+	MustRegister(Definition{
+		Name:        NameFluentBit,
+		Aliases:     []string{"fluentBit"},
+		New:         func() Feature { return &FluentBit{} },
+		Materialize: materializeFluentBit,
+	})
+}
+
 type FluentBit struct {
 	hash string
 
@@ -37,6 +46,13 @@ func (fb *FluentBit) Name() string {
 	return NameFluentBit
 }
 
+// Evaluate forwards the logs of the application to fluent-bit unless it has its own logging configuration.
+func (fb *FluentBit) Evaluate(app *resource.Application) {
+	if app.LogConfig == nil {
+		app.LogConfig = fb.DefaultLogConfig()
+	}
+}
+
 // DefaultLogConfig creates the default logging configuration based on the current FluentBit config.
 func (fb *FluentBit) DefaultLogConfig() *resource.LogConfig {
 	return &resource.LogConfig{
@@ -76,6 +92,30 @@ func (fb *FluentBit) CreateConfig() string {
 	return strings.Trim(builder.String(), "\n") + "\n"
 }
 
+// materializeFluentBit creates the fluent-bit workload receiving the logs using the forward protocol.
+func materializeFluentBit(feat Feature) ([]Workload, error) {
+	fb, ok := feat.(*FluentBit)
+	if !ok {
+		return nil, ErrUnknownFeature
+	}
+
+	version := "latest"
+	if fb.Version != "" {
+		version = fb.Version
+	}
+
+	return []Workload{{
+		Image:  fmt.Sprintf("cr.fluentbit.io/fluent/fluent-bit:%s", version),
+		Global: true,
+		Ports: []resource.Port{
+			{ContainerPort: 24224, HostPort: 24224, Protocol: resource.TcpProtocol},
+		},
+		ConfigFiles: []ConfigFile{
+			{Name: "fluent-bit.conf", Target: "/fluent-bit/etc/fluent-bit.conf", Content: fb.CreateConfig()},
+		},
+	}}, nil
+}
+
 // writeConfigHeader writes a configuration header according to the fluent-bit syntax.
 func (fb *FluentBit) writeConfigHeader(key string) string {
 	header := fmt.Sprintf("[%s]", strings.ToUpper(key))
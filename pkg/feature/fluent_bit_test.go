@@ -1,8 +1,10 @@
 package feature
 
 import (
-	"github.com/stretchr/testify/assert"
 	"testing"
+
+	"github.com/mbaitar/gco/agent/pkg/resource"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestFluentBit_CreateConfig(t *testing.T) {
@@ -60,3 +62,18 @@ func TestFluentBit_CreateConfig_withOutput(t *testing.T) {
 
 	assert.Equal(t, expected, fb.CreateConfig(), "should match expected configuration")
 }
+
+func TestFluentBit_Evaluate(t *testing.T) {
+	fb := &FluentBit{}
+
+	app := &resource.Application{Name: "nginx"}
+	fb.Evaluate(app)
+	if assert.NotNil(t, app.LogConfig) {
+		assert.Equal(t, resource.FluentdLogDriver, app.LogConfig.Driver)
+	}
+
+	custom := &resource.LogConfig{Driver: "custom"}
+	app = &resource.Application{Name: "nginx", LogConfig: custom}
+	fb.Evaluate(app)
+	assert.Equal(t, custom, app.LogConfig, "should not have replaced the logging configuration")
+}
@@ -0,0 +1,136 @@
+package feature
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mbaitar/gco/agent/internal/hash"
+	"github.com/mbaitar/gco/agent/pkg/resource"
+)
+
+const (
+	NameIngress = "ingress"
+
+	// defaultIngressHttpPort is the host port on which the ingress proxy receives HTTP requests.
+	defaultIngressHttpPort = 80
+)
+
+func init() {
+	// traefik discovers the routing labels using the docker socket, which is only available to the docker provider
+	MustRegister(Definition{
+		Name:          NameIngress,
+		New:           func() Feature { return &Ingress{} },
+		Materializers: map[string]Materializer{"docker": materializeIngress},
+	})
+}
+
+// Ingress runs a Traefik reverse proxy which routes requests by hostname to the applications with an ingress.
+type Ingress struct {
+	hash string
+
+	// Version specifies the version of traefik to use.
+	Version string `json:"version"`
+
+	// HttpPort specifies the host port receiving the HTTP requests, defaults to port 80.
+	HttpPort uint16 `json:"httpPort"`
+}
+
+func (i *Ingress) ConfigHash() string {
+	if i.hash == "" {
+		i.hash = hash.CalculateHash(i)
+	}
+
+	return i.hash
+}
+
+func (i *Ingress) Name() string {
+	return NameIngress
+}
+
+// Evaluate attaches the routing labels to applications which define a valid ingress.
+func (i *Ingress) Evaluate(app *resource.Application) {
+	if app.Ingress == nil || app.Ingress.Validate() != nil {
+		return
+	}
+
+	// copy the labels as they might be shared with the original specification
+	labels := make(map[string]string, len(app.Labels)+4)
+	for key, value := range app.Labels {
+		labels[key] = value
+	}
+
+	router := strings.ReplaceAll(app.Name, ".", "-")
+	labels["traefik.enable"] = "true"
+	labels[fmt.Sprintf("traefik.http.routers.%s.rule", router)] = i.CreateRule(app.Ingress)
+	labels[fmt.Sprintf("traefik.http.routers.%s.entrypoints", router)] = "web"
+	labels[fmt.Sprintf("traefik.http.services.%s.loadbalancer.server.port", router)] = fmt.Sprintf("%d", app.Ingress.Port)
+
+	app.Labels = labels
+}
+
+// CreateRule creates the traefik routing rule matching the hosts and path prefix of the ingress.
+// See rule syntax at: https://doc.traefik.io/traefik/routing/routers/#rule
+func (i *Ingress) CreateRule(ingress *resource.Ingress) string {
+	hosts := make([]string, len(ingress.Hosts))
+	for idx, host := range ingress.Hosts {
+		hosts[idx] = fmt.Sprintf("Host(`%s`)", host)
+	}
+
+	rule := strings.Join(hosts, " || ")
+	if ingress.PathPrefix == "" {
+		return rule
+	}
+
+	if len(hosts) > 1 {
+		rule = fmt.Sprintf("(%s)", rule)
+	}
+
+	return fmt.Sprintf("%s && PathPrefix(`%s`)", rule, ingress.PathPrefix)
+}
+
+// CreateConfig creates the static traefik configuration, which only routes the containers managed by the agent.
+// See configuration syntax at: https://doc.traefik.io/traefik/reference/static-configuration/file/
+func (i *Ingress) CreateConfig() string {
+	var builder strings.Builder
+
+	builder.WriteString("entryPoints:\n")
+	builder.WriteString("  web:\n")
+	builder.WriteString("    address: \":80\"\n")
+	builder.WriteString("providers:\n")
+	builder.WriteString("  docker:\n")
+	builder.WriteString("    exposedByDefault: false\n")
+	builder.WriteString("    constraints: \"Label(`gco.io/managed-by`,`gco`)\"\n")
+
+	return builder.String()
+}
+
+// materializeIngress creates the traefik workload using the docker socket to discover the routing labels.
+func materializeIngress(feat Feature) ([]Workload, error) {
+	ingress, ok := feat.(*Ingress)
+	if !ok {
+		return nil, ErrUnknownFeature
+	}
+
+	version := "v2.10"
+	if ingress.Version != "" {
+		version = ingress.Version
+	}
+
+	httpPort := ingress.HttpPort
+	if httpPort == 0 {
+		httpPort = defaultIngressHttpPort
+	}
+
+	return []Workload{{
+		Image: fmt.Sprintf("traefik:%s", version),
+		Ports: []resource.Port{
+			{ContainerPort: 80, HostPort: httpPort, Protocol: resource.TcpProtocol},
+		},
+		Mounts: []resource.Mount{
+			{Type: resource.BindMountType, Source: "/var/run/docker.sock", Target: "/var/run/docker.sock", ReadOnly: true},
+		},
+		ConfigFiles: []ConfigFile{
+			{Name: "traefik.yml", Target: "/etc/traefik/traefik.yml", Content: ingress.CreateConfig()},
+		},
+	}}, nil
+}
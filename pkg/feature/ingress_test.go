@@ -0,0 +1,52 @@
+package feature
+
+import (
+	"testing"
+
+	"github.com/mbaitar/gco/agent/pkg/resource"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIngress_CreateRule(t *testing.T) {
+	ingress := &Ingress{}
+
+	rule := ingress.CreateRule(&resource.Ingress{Hosts: []string{"example.com"}})
+	assert.Equal(t, "Host(`example.com`)", rule)
+
+	rule = ingress.CreateRule(&resource.Ingress{Hosts: []string{"example.com", "www.example.com"}, PathPrefix: "/api"})
+	assert.Equal(t, "(Host(`example.com`) || Host(`www.example.com`)) && PathPrefix(`/api`)", rule)
+}
+
+func TestIngress_Evaluate(t *testing.T) {
+	ingress := &Ingress{}
+	labels := map[string]string{"team": "web"}
+
+	app := &resource.Application{
+		Name:    "nginx",
+		Ingress: &resource.Ingress{Hosts: []string{"example.com"}, Port: 8080},
+		Labels:  labels,
+	}
+
+	ingress.Evaluate(app)
+	assert.Equal(t, "true", app.Labels["traefik.enable"])
+	assert.Equal(t, "Host(`example.com`)", app.Labels["traefik.http.routers.nginx.rule"])
+	assert.Equal(t, "8080", app.Labels["traefik.http.services.nginx.loadbalancer.server.port"])
+	assert.Equal(t, "web", app.Labels["team"], "should have kept the existing labels")
+	assert.Equal(t, 1, len(labels), "should not have modified the original labels")
+
+	app = &resource.Application{Name: "no-ingress"}
+	ingress.Evaluate(app)
+	assert.Nil(t, app.Labels, "should not attach labels without an ingress")
+}
+
+func Test_materializeIngress(t *testing.T) {
+	workloads, err := materializeIngress(&Ingress{HttpPort: 8000})
+	if assert.Nil(t, err) && assert.Equal(t, 1, len(workloads)) {
+		workload := workloads[0]
+		assert.Equal(t, "traefik:v2.10", workload.Image)
+		assert.Equal(t, []resource.Port{{ContainerPort: 80, HostPort: 8000, Protocol: resource.TcpProtocol}}, workload.Ports)
+		if assert.Equal(t, 1, len(workload.ConfigFiles)) {
+			assert.Contains(t, workload.ConfigFiles[0].Content, "exposedByDefault: false")
+		}
+	}
+}
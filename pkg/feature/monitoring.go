@@ -0,0 +1,247 @@
+package feature
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mbaitar/gco/agent/internal/hash"
+	"github.com/mbaitar/gco/agent/pkg/resource"
+)
+
+const (
+	NameMonitoring = "monitoring"
+
+	// defaultCAdvisorPort is the host port on which cAdvisor exposes the container metrics.
+	defaultCAdvisorPort = 8080
+	// defaultNodeExporterPort is the host port on which node-exporter exposes the host metrics.
+	defaultNodeExporterPort = 9100
+	// defaultPrometheusPort is the host port on which the Prometheus server is exposed.
+	defaultPrometheusPort = 9090
+	// defaultScrapeInterval is the interval in seconds between two scrapes of the same target.
+	defaultScrapeInterval = 15
+
+	// dockerSocket is the location of the docker socket, used by Prometheus to discover the containers.
+	dockerSocket = "/var/run/docker.sock"
+)
+
+func init() {
+	// cAdvisor and Prometheus discover the containers using the docker socket, which is only available to the docker provider
+	MustRegister(Definition{
+		Name:          NameMonitoring,
+		New:           func() Feature { return &Monitoring{} },
+		Materializers: map[string]Materializer{"docker": materializeMonitoring},
+	})
+}
+
+// Monitoring runs cAdvisor and node-exporter to expose the container and host metrics, and optionally a
+// Prometheus server which scrapes the exporters and the applications which define a metrics port.
+type Monitoring struct {
+	hash string
+
+	// CAdvisorVersion specifies the version of cAdvisor to use.
+	CAdvisorVersion string `json:"cadvisorVersion"`
+
+	// CAdvisorPort specifies the host port of cAdvisor, defaults to port 8080.
+	CAdvisorPort uint16 `json:"cadvisorPort"`
+
+	// NodeExporterVersion specifies the version of node-exporter to use.
+	NodeExporterVersion string `json:"nodeExporterVersion"`
+
+	// NodeExporterPort specifies the host port of node-exporter, defaults to port 9100.
+	NodeExporterPort uint16 `json:"nodeExporterPort"`
+
+	// Prometheus enables the Prometheus server.
+	Prometheus bool `json:"prometheus"`
+
+	// PrometheusVersion specifies the version of Prometheus to use.
+	PrometheusVersion string `json:"prometheusVersion"`
+
+	// PrometheusPort specifies the host port of Prometheus, defaults to port 9090.
+	PrometheusPort uint16 `json:"prometheusPort"`
+
+	// ScrapeInterval specifies the interval in seconds between two scrapes, defaults to 15 seconds.
+	ScrapeInterval uint32 `json:"scrapeInterval"`
+
+	// Targets contains the applications discovered from the state specification, these are not part
+	// of the configuration but are included in the hash to update the scrape config when they change.
+	Targets []ScrapeTarget `json:"-"`
+}
+
+// ScrapeTarget describes an application exposing metrics.
+type ScrapeTarget struct {
+	Application string
+	Port        uint16
+	Path        string
+}
+
+func (m *Monitoring) ConfigHash() string {
+	if m.hash == "" {
+		// the targets are only included when present, as they are not kept when decoding an empty list
+		h := map[string]interface{}{"config": m}
+		if len(m.Targets) > 0 {
+			h["targets"] = m.Targets
+		}
+
+		m.hash = hash.CalculateHash(h)
+	}
+
+	return m.hash
+}
+
+func (m *Monitoring) Name() string {
+	return NameMonitoring
+}
+
+// Discover returns a copy of the configuration with the applications defining a metrics port as targets.
+func (m *Monitoring) Discover(apps []resource.Application) Feature {
+	targets := make([]ScrapeTarget, 0)
+	for _, app := range apps {
+		if app.Metrics == nil || app.Metrics.Port == 0 {
+			continue
+		}
+
+		targets = append(targets, ScrapeTarget{
+			Application: app.Name,
+			Port:        app.Metrics.Port,
+			Path:        app.Metrics.GetPath(),
+		})
+	}
+
+	// sort the targets for a stable hash
+	sort.Slice(targets, func(a, b int) bool {
+		return targets[a].Application < targets[b].Application
+	})
+
+	discovered := *m
+	discovered.hash = ""
+	discovered.Targets = targets
+	return &discovered
+}
+
+// CreateScrapeConfig creates the Prometheus configuration scraping the exporters and the discovered applications.
+// The containers are discovered using the docker socket and filtered using the labels set by the agent.
+// See configuration syntax at: https://prometheus.io/docs/prometheus/latest/configuration/configuration/
+func (m *Monitoring) CreateScrapeConfig() string {
+	var builder strings.Builder
+
+	builder.WriteString("global:\n")
+	builder.WriteString(fmt.Sprintf("  scrape_interval: %ds\n", m.getScrapeInterval()))
+	builder.WriteString("scrape_configs:\n")
+	// the exporters are part of the same feature and are told apart by their exposed port
+	exporters := fmt.Sprintf("gco.io/feature=%s", NameMonitoring)
+	m.writeScrapeJob(&builder, "cadvisor", resource.DefaultMetricsPath, exporters, 8080, true)
+	m.writeScrapeJob(&builder, "node-exporter", resource.DefaultMetricsPath, exporters, 9100, true)
+
+	for _, target := range m.Targets {
+		m.writeScrapeJob(&builder, target.Application, target.Path, fmt.Sprintf("gco.io/name=%s", target.Application), target.Port, false)
+	}
+
+	return builder.String()
+}
+
+// writeScrapeJob writes a scrape job for the containers matching the label, scraping the container port
+// on the container network address. When exposed is set, only containers exposing the port are kept.
+func (m *Monitoring) writeScrapeJob(builder *strings.Builder, job string, path string, label string, port uint16, exposed bool) {
+	builder.WriteString(fmt.Sprintf("  - job_name: %q\n", job))
+	builder.WriteString(fmt.Sprintf("    metrics_path: %q\n", path))
+	builder.WriteString("    docker_sd_configs:\n")
+	builder.WriteString(fmt.Sprintf("      - host: \"unix://%s\"\n", dockerSocket))
+	builder.WriteString("        filters:\n")
+	builder.WriteString("          - name: label\n")
+	builder.WriteString(fmt.Sprintf("            values: [\"gco.io/managed-by=gco\", %q]\n", label))
+	builder.WriteString("    relabel_configs:\n")
+	if exposed {
+		builder.WriteString("      - source_labels: [__meta_docker_port_private]\n")
+		builder.WriteString(fmt.Sprintf("        regex: \"%d\"\n", port))
+		builder.WriteString("        action: keep\n")
+	}
+	builder.WriteString("      - source_labels: [__meta_docker_container_label_gco_io_name]\n")
+	builder.WriteString("        target_label: application\n")
+	builder.WriteString("      - source_labels: [__meta_docker_network_ip]\n")
+	builder.WriteString("        target_label: __address__\n")
+	builder.WriteString(fmt.Sprintf("        replacement: \"${1}:%d\"\n", port))
+}
+
+func (m *Monitoring) getScrapeInterval() uint32 {
+	if m.ScrapeInterval == 0 {
+		return defaultScrapeInterval
+	}
+
+	return m.ScrapeInterval
+}
+
+// materializeMonitoring creates the exporter workloads and the Prometheus workload when it has been enabled.
+func materializeMonitoring(feat Feature) ([]Workload, error) {
+	monitoring, ok := feat.(*Monitoring)
+	if !ok {
+		return nil, ErrUnknownFeature
+	}
+
+	workloads := []Workload{
+		{
+			Name:   "cadvisor",
+			Image:  fmt.Sprintf("gcr.io/cadvisor/cadvisor:%s", valueOrDefault(monitoring.CAdvisorVersion, "v0.47.2")),
+			Global: true,
+			Ports: []resource.Port{
+				{ContainerPort: 8080, HostPort: portOrDefault(monitoring.CAdvisorPort, defaultCAdvisorPort), Protocol: resource.TcpProtocol},
+			},
+			Mounts: []resource.Mount{
+				{Type: resource.BindMountType, Source: "/", Target: "/rootfs", ReadOnly: true},
+				{Type: resource.BindMountType, Source: "/var/run", Target: "/var/run", ReadOnly: true},
+				{Type: resource.BindMountType, Source: "/sys", Target: "/sys", ReadOnly: true},
+				{Type: resource.BindMountType, Source: "/var/lib/docker", Target: "/var/lib/docker", ReadOnly: true},
+			},
+		},
+		{
+			Name:    "node-exporter",
+			Image:   fmt.Sprintf("prom/node-exporter:%s", valueOrDefault(monitoring.NodeExporterVersion, "v1.6.1")),
+			Command: []string{"--path.procfs=/host/proc", "--path.sysfs=/host/sys", "--path.rootfs=/rootfs"},
+			Global:  true,
+			Ports: []resource.Port{
+				{ContainerPort: 9100, HostPort: portOrDefault(monitoring.NodeExporterPort, defaultNodeExporterPort), Protocol: resource.TcpProtocol},
+			},
+			Mounts: []resource.Mount{
+				{Type: resource.BindMountType, Source: "/proc", Target: "/host/proc", ReadOnly: true},
+				{Type: resource.BindMountType, Source: "/sys", Target: "/host/sys", ReadOnly: true},
+				{Type: resource.BindMountType, Source: "/", Target: "/rootfs", ReadOnly: true},
+			},
+		},
+	}
+
+	if monitoring.Prometheus {
+		workloads = append(workloads, Workload{
+			Name:  "prometheus",
+			Image: fmt.Sprintf("prom/prometheus:%s", valueOrDefault(monitoring.PrometheusVersion, "v2.47.0")),
+			// the docker socket is only accessible by root
+			User: "root",
+			Ports: []resource.Port{
+				{ContainerPort: 9090, HostPort: portOrDefault(monitoring.PrometheusPort, defaultPrometheusPort), Protocol: resource.TcpProtocol},
+			},
+			Mounts: []resource.Mount{
+				{Type: resource.BindMountType, Source: dockerSocket, Target: dockerSocket, ReadOnly: true},
+			},
+			ConfigFiles: []ConfigFile{
+				{Name: "prometheus.yml", Target: "/etc/prometheus/prometheus.yml", Content: monitoring.CreateScrapeConfig()},
+			},
+		})
+	}
+
+	return workloads, nil
+}
+
+func valueOrDefault(value string, defaultValue string) string {
+	if value == "" {
+		return defaultValue
+	}
+
+	return value
+}
+
+func portOrDefault(port uint16, defaultPort uint16) uint16 {
+	if port == 0 {
+		return defaultPort
+	}
+
+	return port
+}
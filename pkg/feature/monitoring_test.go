@@ -0,0 +1,62 @@
+package feature
+
+import (
+	"testing"
+
+	"github.com/mbaitar/gco/agent/pkg/resource"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMonitoring_Discover(t *testing.T) {
+	monitoring := &Monitoring{Prometheus: true}
+	hash := monitoring.ConfigHash()
+
+	unchanged := monitoring.Discover([]resource.Application{{Name: "app-1"}})
+	assert.Equal(t, hash, unchanged.ConfigHash(), "should not change the hash without targets")
+
+	discovered := monitoring.Discover([]resource.Application{
+		{Name: "app-1", Metrics: &resource.Metrics{Port: 8080}},
+	})
+
+	assert.NotEqual(t, hash, discovered.ConfigHash(), "should change the hash when the targets changed")
+	assert.Equal(t, hash, monitoring.ConfigHash(), "should not have modified the original configuration")
+}
+
+func TestMonitoring_CreateScrapeConfig(t *testing.T) {
+	monitoring := &Monitoring{
+		ScrapeInterval: 30,
+		Targets: []ScrapeTarget{
+			{Application: "app-1", Port: 8000, Path: "/stats"},
+		},
+	}
+
+	config := monitoring.CreateScrapeConfig()
+	assert.Contains(t, config, "scrape_interval: 30s")
+	assert.Contains(t, config, `job_name: "cadvisor"`)
+	assert.Contains(t, config, `regex: "8080"`)
+	assert.Contains(t, config, `job_name: "node-exporter"`)
+	assert.Contains(t, config, `job_name: "app-1"`)
+	assert.Contains(t, config, `metrics_path: "/stats"`)
+	assert.Contains(t, config, `values: ["gco.io/managed-by=gco", "gco.io/name=app-1"]`)
+	assert.Contains(t, config, `replacement: "${1}:8000"`)
+}
+
+func Test_materializeMonitoring(t *testing.T) {
+	workloads, err := materializeMonitoring(&Monitoring{})
+	if assert.Nil(t, err) && assert.Equal(t, 2, len(workloads), "should only run the exporters") {
+		assert.Equal(t, "cadvisor", workloads[0].Name)
+		assert.Equal(t, "gcr.io/cadvisor/cadvisor:v0.47.2", workloads[0].Image)
+		assert.Equal(t, "node-exporter", workloads[1].Name)
+		assert.Equal(t, uint16(9100), workloads[1].Ports[0].HostPort)
+	}
+
+	workloads, err = materializeMonitoring(&Monitoring{Prometheus: true, PrometheusPort: 9999})
+	if assert.Nil(t, err) && assert.Equal(t, 3, len(workloads), "should run prometheus") {
+		prometheus := workloads[2]
+		assert.Equal(t, "prometheus", prometheus.Name)
+		assert.Equal(t, uint16(9999), prometheus.Ports[0].HostPort)
+		if assert.Equal(t, 1, len(prometheus.ConfigFiles)) {
+			assert.Equal(t, "/etc/prometheus/prometheus.yml", prometheus.ConfigFiles[0].Target)
+		}
+	}
+}
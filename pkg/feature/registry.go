@@ -0,0 +1,185 @@
+package feature
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/mbaitar/gco/agent/pkg/resource"
+)
+
+var ErrFeatureExists = errors.New("feature has already been registered")
+
+// Definition describes a feature which can be enabled within the state specification.
+type Definition struct {
+	// Name is the unique name of the feature, used as key within the state specification and provider labels.
+	Name string
+
+	// Aliases are alternative names used to read the feature from older state specifications.
+	Aliases []string
+
+	// New returns an empty configuration of the feature, used to decode persisted and provider state.
+	New func() Feature
+
+	// Materialize creates the workloads for the feature, used by every provider without a specific materializer.
+	Materialize Materializer
+
+	// Materializers contains the provider specific materializers keyed by the name of the provider.
+	Materializers map[string]Materializer
+}
+
+// MaterializerFor returns the materializer for the provider or the default materializer when none has been registered.
+// It returns nil when the feature is not supported by the provider.
+func (d *Definition) MaterializerFor(provider string) Materializer {
+	if materialize, found := d.Materializers[provider]; found {
+		return materialize
+	}
+
+	return d.Materialize
+}
+
+// Evaluator is implemented by features which modify the applications of the state specification before comparing.
+type Evaluator interface {
+	// Evaluate updates the application based on the feature configuration.
+	Evaluate(app *resource.Application)
+}
+
+// Discoverer is implemented by features whose configuration depends on the applications of the state specification.
+type Discoverer interface {
+	// Discover returns a copy of the feature with the configuration derived from the applications.
+	Discover(apps []resource.Application) Feature
+}
+
+// Materializer translates the feature configuration into the workloads a provider runs for it.
+type Materializer func(feat Feature) ([]Workload, error)
+
+// Workload describes a container a provider runs to materialize a feature.
+type Workload struct {
+	// Name distinguishes the workloads of a feature, the main workload of the feature leaves it empty.
+	Name string
+
+	Image   string
+	Command []string
+	User    string
+	Ports   []resource.Port
+	Mounts  []resource.Mount
+	Env     map[string]string
+
+	// Global runs the workload on every node when the provider manages multiple nodes, e.g. for log collectors
+	// and exporters. Other workloads run once.
+	Global bool
+
+	// ConfigFiles are written by the provider and mounted read-only into the container.
+	ConfigFiles []ConfigFile
+}
+
+// ConfigFile describes a generated configuration file of a feature.
+type ConfigFile struct {
+	// Name is the unique file name used when writing the file.
+	Name string
+	// Target is the location of the file within the container.
+	Target string
+	// Content contains the generated configuration.
+	Content string
+}
+
+var registry = struct {
+	sync.RWMutex
+	definitions map[string]Definition
+	aliases     map[string]string
+}{
+	definitions: make(map[string]Definition),
+	aliases:     make(map[string]string),
+}
+
+// Register adds the feature definition to the registry, making it available to the state and providers.
+func Register(def Definition) error {
+	if def.Name == "" || def.New == nil {
+		return fmt.Errorf("feature definition requires a name and constructor")
+	}
+
+	registry.Lock()
+	defer registry.Unlock()
+
+	if _, found := registry.definitions[def.Name]; found {
+		return fmt.Errorf("%w: %s", ErrFeatureExists, def.Name)
+	}
+
+	for _, alias := range def.Aliases {
+		if _, found := registry.aliases[alias]; found {
+			return fmt.Errorf("%w: %s", ErrFeatureExists, alias)
+		}
+	}
+
+	registry.definitions[def.Name] = def
+	for _, alias := range def.Aliases {
+		registry.aliases[alias] = def.Name
+	}
+
+	return nil
+}
+
+// unregister removes the feature definition matching the name and its aliases from the registry.
+func unregister(name string) {
+	registry.Lock()
+	defer registry.Unlock()
+
+	if def, found := registry.definitions[name]; found {
+		for _, alias := range def.Aliases {
+			delete(registry.aliases, alias)
+		}
+
+		delete(registry.definitions, name)
+	}
+}
+
+// MustRegister adds the feature definition to the registry and panics when it can not be registered.
+func MustRegister(def Definition) {
+	if err := Register(def); err != nil {
+		panic(err)
+	}
+}
+
+// Lookup returns the feature definition matching the name or one of its aliases.
+func Lookup(name string) (Definition, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	if alias, found := registry.aliases[name]; found {
+		name = alias
+	}
+
+	def, found := registry.definitions[name]
+	return def, found
+}
+
+// SupportedBy returns true when the feature matching the name has been registered with a materializer for the provider.
+func SupportedBy(name string, provider string) bool {
+	def, found := Lookup(name)
+	return found && def.MaterializerFor(provider) != nil
+}
+
+// Names returns the sorted names of the registered features.
+func Names() []string {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	names := make([]string, 0, len(registry.definitions))
+	for name := range registry.definitions {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// New returns an empty configuration of the registered feature matching the name.
+func New(name string) (Feature, error) {
+	def, found := Lookup(name)
+	if !found {
+		return nil, ErrUnknownFeature
+	}
+
+	return def.New(), nil
+}
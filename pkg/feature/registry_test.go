@@ -0,0 +1,83 @@
+package feature
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/mbaitar/gco/agent/pkg/resource"
+	"github.com/stretchr/testify/assert"
+)
+
+type testFeature struct {
+	Value string `json:"value"`
+}
+
+func (t *testFeature) ConfigHash() string {
+	return t.Value
+}
+
+func (t *testFeature) Name() string {
+	return "test-feature"
+}
+
+func TestRegister(t *testing.T) {
+	err := Register(Definition{
+		Name:    "test-feature",
+		Aliases: []string{"testFeature"},
+		New:     func() Feature { return &testFeature{} },
+	})
+	assert.Nil(t, err, "should have registered the feature")
+	t.Cleanup(func() { unregister("test-feature") })
+
+	err = Register(Definition{Name: "test-feature", New: func() Feature { return &testFeature{} }})
+	assert.True(t, errors.Is(err, ErrFeatureExists), "should not register the same feature twice")
+
+	err = Register(Definition{Name: "test-invalid"})
+	assert.NotNil(t, err, "should require a constructor")
+
+	def, found := Lookup("testFeature")
+	if assert.True(t, found, "should have found the feature by alias") {
+		assert.Equal(t, "test-feature", def.Name)
+	}
+
+	assert.Contains(t, Names(), "test-feature")
+	assert.Contains(t, Names(), NameFluentBit)
+}
+
+func TestNew(t *testing.T) {
+	feat, err := New(NameFluentBit)
+	if assert.Nil(t, err) {
+		assert.IsType(t, &FluentBit{}, feat)
+	}
+
+	_, err = New("unknown")
+	assert.Equal(t, ErrUnknownFeature, err)
+}
+
+func TestDefinition_MaterializerFor(t *testing.T) {
+	def, _ := Lookup(NameFluentBit)
+
+	workloads, err := def.MaterializerFor("docker")(&FluentBit{Version: "2.0.0"})
+	if assert.Nil(t, err) && assert.Equal(t, 1, len(workloads)) {
+		workload := workloads[0]
+		assert.Equal(t, "cr.fluentbit.io/fluent/fluent-bit:2.0.0", workload.Image)
+		assert.Equal(t, []resource.Port{{ContainerPort: 24224, HostPort: 24224, Protocol: resource.TcpProtocol}}, workload.Ports)
+		if assert.Equal(t, 1, len(workload.ConfigFiles)) {
+			assert.Equal(t, "/fluent-bit/etc/fluent-bit.conf", workload.ConfigFiles[0].Target)
+		}
+	}
+
+	custom := Definition{
+		Materialize: materializeFluentBit,
+		Materializers: map[string]Materializer{
+			"custom": func(feat Feature) ([]Workload, error) { return []Workload{{Image: "custom"}}, nil },
+		},
+	}
+
+	workloads, _ = custom.MaterializerFor("custom")(&FluentBit{})
+	assert.Equal(t, "custom", workloads[0].Image, "should have used the provider specific materializer")
+
+	ingress, _ := Lookup(NameIngress)
+	assert.NotNil(t, ingress.MaterializerFor("docker"))
+	assert.Nil(t, ingress.MaterializerFor("swarm"), "should only support the docker provider")
+}
@@ -1,9 +1,15 @@
 package feature
 
+import (
+	"errors"
+)
+
 const (
 	NameFluentBit = "fluent-bit"
 )
 
+var ErrUnknownFeature = errors.New("unknown feature")
+
 type Feature interface {
 	// ConfigHash calculates the hash for the feature configuration.
 	ConfigHash() string
@@ -5,6 +5,9 @@ import (
 	"github.com/mbaitar/gco/agent/internal/hash"
 )
 
+// DefaultInstances is the number of instances used when an application did not specify any instances.
+const DefaultInstances = 1
+
 // Application defines a structure which describes everything the application needs
 // to be translated to a container management system.
 type Application struct {
@@ -14,11 +17,35 @@ type Application struct {
 	Image Image  `json:"image"`
 	Ports []Port `json:"ports,omitempty"`
 
+	Mounts []Mount `json:"mounts,omitempty"`
+
 	Instances int `json:"instances"`
 
-	LogConfig *LogConfig `json:"logConfig,omitempty"`
+	// UnhealthyInstances is the number of instances which have been reported unhealthy by the provider (actual state only).
+	UnhealthyInstances int `json:"-"`
+
+	// ExitedInstances is the number of instances which exited or are being restarted by the provider (actual state only).
+	ExitedInstances int `json:"-"`
+
+	HealthCheck *HealthCheck `json:"healthCheck,omitempty"`
+
+	Resources *Resources `json:"resources,omitempty"`
+
+	RestartPolicy *RestartPolicy `json:"restartPolicy,omitempty"`
 
-	// TODO: custom labels
+	Ingress *Ingress `json:"ingress,omitempty"`
+
+	Metrics *Metrics `json:"metrics,omitempty"`
+
+	// Labels contains additional labels attached to the application instances.
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Env contains the environment variables, values starting with the SecretPrefix reference a secret.
+	Env map[string]string `json:"env,omitempty"`
+
+	UpdateStrategy UpdateStrategy `json:"updateStrategy,omitempty"`
+
+	LogConfig *LogConfig `json:"logConfig,omitempty"`
 }
 
 func (a *Application) CalculateHash() string {
@@ -35,19 +62,106 @@ func (a *Application) CalculateHash() string {
 			m["ports"] = a.Ports
 		}
 
+		if len(a.Mounts) > 0 {
+			m["mounts"] = a.Mounts
+		}
+
+		if a.HealthCheck != nil {
+			m["health_check"] = a.HealthCheck
+		}
+
+		if !a.Resources.IsEmpty() {
+			m["resources"] = a.Resources
+		}
+
+		if !a.RestartPolicy.IsDefault() {
+			m["restart_policy"] = a.RestartPolicy.normalized()
+		}
+
+		if a.Ingress != nil {
+			m["ingress"] = a.Ingress
+		}
+
+		if a.Metrics != nil {
+			m["metrics"] = a.Metrics
+		}
+
+		if len(a.Labels) > 0 {
+			m["labels"] = a.Labels
+		}
+
+		if len(a.Env) > 0 {
+			m["env"] = a.Env
+		}
+
 		a.hash = hash.CalculateHash(m)
 	}
 
 	return a.hash
 }
 
+// GetInstances returns the number of requested instances or DefaultInstances when none have been specified.
+func (a *Application) GetInstances() int {
+	if a.Instances <= 0 {
+		return DefaultInstances
+	}
+
+	return a.Instances
+}
+
+// Validate verifies the update strategy and ingress of the application.
+func (a *Application) Validate() error {
+	if err := a.UpdateStrategy.Validate(); err != nil {
+		return err
+	}
+
+	return a.Ingress.Validate()
+}
+
+// GetUpdateStrategy returns the configured UpdateStrategy or the RecreateUpdateStrategy when none has been specified.
+func (a *Application) GetUpdateStrategy() UpdateStrategy {
+	if a.UpdateStrategy == RollingUpdateStrategy {
+		return RollingUpdateStrategy
+	}
+
+	return RecreateUpdateStrategy
+}
+
 func (a *Application) ToApplicationV1() *applicationv1.Application {
-	return &applicationv1.Application{
-		Name:      a.Name,
-		Image:     a.Image.ToImageV1(),
-		Ports:     ToPortsV1(a.Ports),
-		Instances: uint32(a.Instances),
+	v1 := &applicationv1.Application{
+		Name:           a.Name,
+		Image:          a.Image.ToImageV1(),
+		Ports:          ToPortsV1(a.Ports),
+		Mounts:         ToMountsV1(a.Mounts),
+		Instances:      uint32(a.Instances),
+		UpdateStrategy: string(a.UpdateStrategy),
+		Env:            a.Env,
+		Labels:         a.Labels,
+
+		UnhealthyInstances: uint32(a.UnhealthyInstances),
+	}
+
+	if a.HealthCheck != nil {
+		v1.HealthCheck = a.HealthCheck.ToHealthCheckV1()
 	}
+
+	if a.Resources != nil {
+		v1.Resources = a.Resources.ToResourcesV1()
+	}
+
+	if a.RestartPolicy != nil {
+		v1.RestartPolicy = a.RestartPolicy.ToRestartPolicyV1()
+	}
+
+	if a.Ingress != nil {
+		v1.Ingress = a.Ingress.ToIngressV1()
+	}
+
+	if a.Metrics != nil {
+		v1.Metrics = a.Metrics.ToMetricsV1()
+	}
+
+	return v1
 }
 
 func FromApplicationV1(v1 *applicationv1.Application) *Application {
@@ -56,9 +170,18 @@ func FromApplicationV1(v1 *applicationv1.Application) *Application {
 	}
 
 	return &Application{
-		Name:      v1.Name,
-		Image:     *FromImageV1(v1.Image),
-		Ports:     FromPortsV1(v1.Ports),
-		Instances: int(v1.Instances),
+		Name:           v1.Name,
+		Image:          *FromImageV1(v1.Image),
+		Ports:          FromPortsV1(v1.Ports),
+		Mounts:         FromMountsV1(v1.Mounts),
+		Instances:      int(v1.Instances),
+		UpdateStrategy: UpdateStrategy(v1.UpdateStrategy),
+		Env:            v1.Env,
+		HealthCheck:    FromHealthCheckV1(v1.HealthCheck),
+		Resources:      FromResourcesV1(v1.Resources),
+		RestartPolicy:  FromRestartPolicyV1(v1.RestartPolicy),
+		Ingress:        FromIngressV1(v1.Ingress),
+		Metrics:        FromMetricsV1(v1.Metrics),
+		Labels:         v1.Labels,
 	}
 }
@@ -0,0 +1,17 @@
+package resource
+
+import "strings"
+
+// SecretPrefix marks an environment value as a reference to a secret instead of a plaintext value.
+// The referenced secret is only resolved when the container is created, e.g. 'secret:database-password'.
+const SecretPrefix = "secret:"
+
+// IsSecretReference returns true if the environment value references a secret.
+func IsSecretReference(value string) bool {
+	return strings.HasPrefix(value, SecretPrefix)
+}
+
+// SecretName returns the name of the secret referenced by the environment value.
+func SecretName(value string) string {
+	return strings.TrimPrefix(value, SecretPrefix)
+}
@@ -0,0 +1,108 @@
+package resource
+
+import (
+	"time"
+
+	applicationv1 "github.com/mbaitar/gco/agent/gen/proto/application/v1"
+)
+
+type HealthCheckType string
+
+const (
+	// CommandHealthCheck executes a command within the container, exit code 0 means healthy.
+	CommandHealthCheck HealthCheckType = "command"
+	// HttpHealthCheck performs an HTTP GET request within the container, a 2xx status means healthy.
+	HttpHealthCheck HealthCheckType = "http"
+	// TcpHealthCheck opens a TCP connection within the container, a successful connection means healthy.
+	TcpHealthCheck HealthCheckType = "tcp"
+)
+
+// HealthCheck defines how the health of an application instance is determined.
+type HealthCheck struct {
+	Type HealthCheckType `json:"type"`
+
+	// Command is executed when using the CommandHealthCheck.
+	Command []string `json:"command,omitempty"`
+	// Path is requested when using the HttpHealthCheck.
+	Path string `json:"path,omitempty"`
+	// Port is used by the HttpHealthCheck and TcpHealthCheck.
+	Port uint16 `json:"port,omitempty"`
+
+	// Interval is the number of seconds between two checks.
+	Interval uint32 `json:"interval,omitempty"`
+	// Timeout is the number of seconds before a check is considered failed.
+	Timeout uint32 `json:"timeout,omitempty"`
+	// Retries is the number of consecutive failures before an instance is considered unhealthy.
+	Retries uint32 `json:"retries,omitempty"`
+	// StartPeriod is the number of seconds for an instance to start before failures are counted.
+	StartPeriod uint32 `json:"startPeriod,omitempty"`
+}
+
+// IntervalDuration returns the interval as time.Duration.
+func (h *HealthCheck) IntervalDuration() time.Duration {
+	return time.Duration(h.Interval) * time.Second
+}
+
+// TimeoutDuration returns the timeout as time.Duration.
+func (h *HealthCheck) TimeoutDuration() time.Duration {
+	return time.Duration(h.Timeout) * time.Second
+}
+
+// StartPeriodDuration returns the start period as time.Duration.
+func (h *HealthCheck) StartPeriodDuration() time.Duration {
+	return time.Duration(h.StartPeriod) * time.Second
+}
+
+func (h *HealthCheck) ToHealthCheckV1() *applicationv1.HealthCheck {
+	v1 := &applicationv1.HealthCheck{
+		Command:            h.Command,
+		Path:               h.Path,
+		Port:               uint32(h.Port),
+		IntervalSeconds:    h.Interval,
+		TimeoutSeconds:     h.Timeout,
+		Retries:            h.Retries,
+		StartPeriodSeconds: h.StartPeriod,
+	}
+
+	switch h.Type {
+	case CommandHealthCheck:
+		v1.Type = applicationv1.HealthCheckType_HEALTH_CHECK_TYPE_COMMAND
+	case HttpHealthCheck:
+		v1.Type = applicationv1.HealthCheckType_HEALTH_CHECK_TYPE_HTTP
+	case TcpHealthCheck:
+		v1.Type = applicationv1.HealthCheckType_HEALTH_CHECK_TYPE_TCP
+	default:
+		v1.Type = applicationv1.HealthCheckType_HEALTH_CHECK_TYPE_UNSPECIFIED
+	}
+
+	return v1
+}
+
+func FromHealthCheckV1(v1 *applicationv1.HealthCheck) *HealthCheck {
+	if v1 == nil {
+		return nil
+	}
+
+	h := &HealthCheck{
+		Command:     v1.Command,
+		Path:        v1.Path,
+		Port:        uint16(v1.Port),
+		Interval:    v1.IntervalSeconds,
+		Timeout:     v1.TimeoutSeconds,
+		Retries:     v1.Retries,
+		StartPeriod: v1.StartPeriodSeconds,
+	}
+
+	switch v1.Type {
+	case applicationv1.HealthCheckType_HEALTH_CHECK_TYPE_COMMAND:
+		h.Type = CommandHealthCheck
+	case applicationv1.HealthCheckType_HEALTH_CHECK_TYPE_HTTP:
+		h.Type = HttpHealthCheck
+	case applicationv1.HealthCheckType_HEALTH_CHECK_TYPE_TCP:
+		h.Type = TcpHealthCheck
+	default:
+		h.Type = "unknown"
+	}
+
+	return h
+}
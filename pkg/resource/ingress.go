@@ -0,0 +1,81 @@
+package resource
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	applicationv1 "github.com/mbaitar/gco/agent/gen/proto/application/v1"
+)
+
+var ErrInvalidIngress = errors.New("invalid ingress")
+
+// Ingress exposes an application by hostname through the ingress feature, without claiming a host port.
+type Ingress struct {
+	// Hosts contains the hostnames routed to the application.
+	Hosts []string `json:"hosts"`
+	// PathPrefix optionally limits the routed requests to the path prefix.
+	PathPrefix string `json:"pathPrefix,omitempty"`
+	// Port is the container port receiving the requests.
+	Port uint16 `json:"port"`
+}
+
+// Validate verifies that the ingress can be turned into a routing rule, an application without ingress is valid.
+// The hosts and path prefix are quoted using backticks within the rule, as such they may not contain a backtick.
+func (i *Ingress) Validate() error {
+	if i == nil {
+		return nil
+	}
+
+	if len(i.Hosts) == 0 {
+		return fmt.Errorf("%w: requires at least one host", ErrInvalidIngress)
+	}
+
+	for _, host := range i.Hosts {
+		if strings.TrimSpace(host) == "" {
+			return fmt.Errorf("%w: host may not be empty", ErrInvalidIngress)
+		}
+
+		if strings.Contains(host, "`") {
+			return fmt.Errorf("%w: host '%s' may not contain a backtick", ErrInvalidIngress, host)
+		}
+	}
+
+	if strings.Contains(i.PathPrefix, "`") {
+		return fmt.Errorf("%w: path prefix '%s' may not contain a backtick", ErrInvalidIngress, i.PathPrefix)
+	}
+
+	if i.Port == 0 {
+		return fmt.Errorf("%w: requires a port between 1 and 65535", ErrInvalidIngress)
+	}
+
+	return nil
+}
+
+func (i *Ingress) ToIngressV1() *applicationv1.Ingress {
+	return &applicationv1.Ingress{
+		Hosts:      i.Hosts,
+		PathPrefix: i.PathPrefix,
+		Port:       uint32(i.Port),
+	}
+}
+
+func FromIngressV1(v1 *applicationv1.Ingress) *Ingress {
+	if v1 == nil {
+		return nil
+	}
+
+	ingress := &Ingress{
+		Hosts:      v1.Hosts,
+		PathPrefix: v1.PathPrefix,
+		Port:       uint16(v1.Port),
+	}
+
+	// an out of range port is kept as 0 to be rejected by the validation, instead of silently wrapping around
+	if v1.Port > math.MaxUint16 {
+		ingress.Port = 0
+	}
+
+	return ingress
+}
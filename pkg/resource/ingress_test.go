@@ -0,0 +1,36 @@
+package resource
+
+import (
+	"errors"
+	"testing"
+
+	applicationv1 "github.com/mbaitar/gco/agent/gen/proto/application/v1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIngress_Validate(t *testing.T) {
+	var ingress *Ingress
+	assert.Nil(t, ingress.Validate(), "should accept an application without ingress")
+
+	ingress = &Ingress{Hosts: []string{"example.com"}, PathPrefix: "/api", Port: 8080}
+	assert.Nil(t, ingress.Validate())
+
+	invalid := []*Ingress{
+		{Port: 8080},
+		{Hosts: []string{"example.com", " "}, Port: 8080},
+		{Hosts: []string{"example.com`) || Host(`other.com"}, Port: 8080},
+		{Hosts: []string{"example.com"}, PathPrefix: "/`api", Port: 8080},
+		{Hosts: []string{"example.com"}},
+	}
+
+	for _, ingress := range invalid {
+		err := ingress.Validate()
+		assert.True(t, errors.Is(err, ErrInvalidIngress), "should have rejected %+v", ingress)
+	}
+}
+
+func TestFromIngressV1_portOutOfRange(t *testing.T) {
+	ingress := FromIngressV1(&applicationv1.Ingress{Hosts: []string{"example.com"}, Port: 65617})
+	assert.Equal(t, uint16(0), ingress.Port, "should not have wrapped the port around")
+	assert.True(t, errors.Is(ingress.Validate(), ErrInvalidIngress))
+}
@@ -0,0 +1,42 @@
+package resource
+
+import applicationv1 "github.com/mbaitar/gco/agent/gen/proto/application/v1"
+
+// DefaultMetricsPath is the path used to scrape the metrics when none has been specified.
+const DefaultMetricsPath = "/metrics"
+
+// Metrics annotates the container port on which the application exposes Prometheus metrics,
+// applications with metrics are discovered by the monitoring feature.
+type Metrics struct {
+	// Port is the container port exposing the metrics.
+	Port uint16 `json:"port"`
+	// Path is the HTTP path of the metrics, defaults to '/metrics'.
+	Path string `json:"path,omitempty"`
+}
+
+// GetPath returns the configured path or the DefaultMetricsPath when none has been specified.
+func (m *Metrics) GetPath() string {
+	if m.Path == "" {
+		return DefaultMetricsPath
+	}
+
+	return m.Path
+}
+
+func (m *Metrics) ToMetricsV1() *applicationv1.Metrics {
+	return &applicationv1.Metrics{
+		Port: uint32(m.Port),
+		Path: m.Path,
+	}
+}
+
+func FromMetricsV1(v1 *applicationv1.Metrics) *Metrics {
+	if v1 == nil {
+		return nil
+	}
+
+	return &Metrics{
+		Port: uint16(v1.Port),
+		Path: v1.Path,
+	}
+}
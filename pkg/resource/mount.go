@@ -0,0 +1,91 @@
+package resource
+
+import applicationv1 "github.com/mbaitar/gco/agent/gen/proto/application/v1"
+
+type MountType string
+
+const (
+	// VolumeMountType mounts a named volume managed by the container system, which outlives the application.
+	VolumeMountType MountType = "volume"
+	// BindMountType mounts a file or directory from the host.
+	BindMountType MountType = "bind"
+	// TmpfsMountType mounts a temporary file system which only lives in memory.
+	TmpfsMountType MountType = "tmpfs"
+)
+
+// Mount defines a volume, bind or tmpfs mount of an application.
+type Mount struct {
+	Type     MountType `json:"type"`
+	Source   string    `json:"source,omitempty"`
+	Target   string    `json:"target"`
+	ReadOnly bool      `json:"readOnly,omitempty"`
+}
+
+func (m *Mount) ToMountV1() *applicationv1.Mount {
+	v1 := &applicationv1.Mount{
+		Source:   m.Source,
+		Target:   m.Target,
+		ReadOnly: m.ReadOnly,
+	}
+
+	switch m.Type {
+	case VolumeMountType:
+		v1.Type = applicationv1.MountType_MOUNT_TYPE_VOLUME
+	case BindMountType:
+		v1.Type = applicationv1.MountType_MOUNT_TYPE_BIND
+	case TmpfsMountType:
+		v1.Type = applicationv1.MountType_MOUNT_TYPE_TMPFS
+	default:
+		v1.Type = applicationv1.MountType_MOUNT_TYPE_UNSPECIFIED
+	}
+
+	return v1
+}
+
+func ToMountsV1(mounts []Mount) []*applicationv1.Mount {
+	v1s := make([]*applicationv1.Mount, 0)
+
+	for _, m := range mounts {
+		v1s = append(v1s, m.ToMountV1())
+	}
+
+	return v1s
+}
+
+func FromMountV1(v1 *applicationv1.Mount) *Mount {
+	if v1 == nil {
+		return nil
+	}
+
+	return &Mount{
+		Type:     FromMountTypeV1(v1.Type),
+		Source:   v1.Source,
+		Target:   v1.Target,
+		ReadOnly: v1.ReadOnly,
+	}
+}
+
+func FromMountsV1(v1 []*applicationv1.Mount) []Mount {
+	if v1 == nil {
+		return make([]Mount, 0)
+	}
+
+	mounts := make([]Mount, len(v1))
+	for i, m := range v1 {
+		mounts[i] = *FromMountV1(m)
+	}
+	return mounts
+}
+
+func FromMountTypeV1(v1 applicationv1.MountType) MountType {
+	switch v1 {
+	case applicationv1.MountType_MOUNT_TYPE_VOLUME:
+		return VolumeMountType
+	case applicationv1.MountType_MOUNT_TYPE_BIND:
+		return BindMountType
+	case applicationv1.MountType_MOUNT_TYPE_TMPFS:
+		return TmpfsMountType
+	default:
+		return "unknown"
+	}
+}
@@ -0,0 +1,42 @@
+package resource
+
+import applicationv1 "github.com/mbaitar/gco/agent/gen/proto/application/v1"
+
+// Resources defines the compute resources an application instance is allowed to use, zero values are unlimited.
+type Resources struct {
+	// CPULimit is the number of CPUs, e.g. 0.5 allows the instance to use half a CPU.
+	CPULimit float64 `json:"cpuLimit,omitempty"`
+	// MemoryLimit is the hard memory limit in bytes.
+	MemoryLimit int64 `json:"memoryLimit,omitempty"`
+	// MemoryReservation is the soft memory limit in bytes.
+	MemoryReservation int64 `json:"memoryReservation,omitempty"`
+	// PidsLimit is the maximum number of processes.
+	PidsLimit int64 `json:"pidsLimit,omitempty"`
+}
+
+// IsEmpty returns true when no limits have been defined.
+func (r *Resources) IsEmpty() bool {
+	return r == nil || *r == Resources{}
+}
+
+func (r *Resources) ToResourcesV1() *applicationv1.Resources {
+	return &applicationv1.Resources{
+		CpuLimit:          r.CPULimit,
+		MemoryLimit:       r.MemoryLimit,
+		MemoryReservation: r.MemoryReservation,
+		PidsLimit:         r.PidsLimit,
+	}
+}
+
+func FromResourcesV1(v1 *applicationv1.Resources) *Resources {
+	if v1 == nil {
+		return nil
+	}
+
+	return &Resources{
+		CPULimit:          v1.CpuLimit,
+		MemoryLimit:       v1.MemoryLimit,
+		MemoryReservation: v1.MemoryReservation,
+		PidsLimit:         v1.PidsLimit,
+	}
+}
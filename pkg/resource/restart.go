@@ -0,0 +1,76 @@
+package resource
+
+import applicationv1 "github.com/mbaitar/gco/agent/gen/proto/application/v1"
+
+type RestartPolicyName string
+
+const (
+	// NeverRestartPolicy never restarts a stopped container, this is the default.
+	NeverRestartPolicy RestartPolicyName = "never"
+	// OnFailureRestartPolicy restarts a container which exited with a non-zero exit code.
+	OnFailureRestartPolicy RestartPolicyName = "on-failure"
+	// AlwaysRestartPolicy always restarts a stopped container.
+	AlwaysRestartPolicy RestartPolicyName = "always"
+)
+
+// RestartPolicy defines how the container system handles stopped application instances.
+type RestartPolicy struct {
+	Name RestartPolicyName `json:"name"`
+	// MaxRetries limits the number of restarts when using the OnFailureRestartPolicy, 0 is unlimited.
+	MaxRetries int `json:"maxRetries,omitempty"`
+}
+
+// IsDefault returns true when the policy is equal to the NeverRestartPolicy.
+func (r *RestartPolicy) IsDefault() bool {
+	return r == nil || r.Name == "" || r.Name == NeverRestartPolicy
+}
+
+// normalized returns the policy without the maximum number of retries unless it applies to the policy,
+// container systems ignore the retries of the other policies.
+func (r *RestartPolicy) normalized() *RestartPolicy {
+	if r.Name == OnFailureRestartPolicy || r.MaxRetries == 0 {
+		return r
+	}
+
+	return &RestartPolicy{Name: r.Name}
+}
+
+func (r *RestartPolicy) ToRestartPolicyV1() *applicationv1.RestartPolicy {
+	v1 := &applicationv1.RestartPolicy{
+		MaxRetries: uint32(r.MaxRetries),
+	}
+
+	switch r.Name {
+	case NeverRestartPolicy:
+		v1.Type = applicationv1.RestartPolicyType_RESTART_POLICY_TYPE_NEVER
+	case OnFailureRestartPolicy:
+		v1.Type = applicationv1.RestartPolicyType_RESTART_POLICY_TYPE_ON_FAILURE
+	case AlwaysRestartPolicy:
+		v1.Type = applicationv1.RestartPolicyType_RESTART_POLICY_TYPE_ALWAYS
+	default:
+		v1.Type = applicationv1.RestartPolicyType_RESTART_POLICY_TYPE_UNSPECIFIED
+	}
+
+	return v1
+}
+
+func FromRestartPolicyV1(v1 *applicationv1.RestartPolicy) *RestartPolicy {
+	if v1 == nil {
+		return nil
+	}
+
+	r := &RestartPolicy{
+		MaxRetries: int(v1.MaxRetries),
+	}
+
+	switch v1.Type {
+	case applicationv1.RestartPolicyType_RESTART_POLICY_TYPE_ON_FAILURE:
+		r.Name = OnFailureRestartPolicy
+	case applicationv1.RestartPolicyType_RESTART_POLICY_TYPE_ALWAYS:
+		r.Name = AlwaysRestartPolicy
+	default:
+		r.Name = NeverRestartPolicy
+	}
+
+	return r.normalized()
+}
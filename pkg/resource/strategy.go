@@ -0,0 +1,28 @@
+package resource
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrInvalidUpdateStrategy = errors.New("invalid update strategy")
+
+// UpdateStrategy defines how an application is replaced when it has been changed.
+type UpdateStrategy string
+
+const (
+	// RecreateUpdateStrategy removes the running application before the new version is created.
+	RecreateUpdateStrategy UpdateStrategy = "recreate"
+	// RollingUpdateStrategy starts the new version and waits for it to be running before the old version is removed.
+	RollingUpdateStrategy UpdateStrategy = "rolling"
+)
+
+// Validate verifies that the strategy is known, an empty strategy uses the RecreateUpdateStrategy.
+func (s UpdateStrategy) Validate() error {
+	switch s {
+	case "", RecreateUpdateStrategy, RollingUpdateStrategy:
+		return nil
+	default:
+		return fmt.Errorf("%w: '%s', use '%s' or '%s'", ErrInvalidUpdateStrategy, s, RecreateUpdateStrategy, RollingUpdateStrategy)
+	}
+}
@@ -0,0 +1,26 @@
+package resource
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpdateStrategy_Validate(t *testing.T) {
+	assert.Nil(t, UpdateStrategy("").Validate(), "should accept the default strategy")
+	assert.Nil(t, RecreateUpdateStrategy.Validate())
+	assert.Nil(t, RollingUpdateStrategy.Validate())
+	assert.ErrorIs(t, UpdateStrategy("blue-green").Validate(), ErrInvalidUpdateStrategy)
+}
+
+func TestApplication_Validate(t *testing.T) {
+	app := &Application{Name: "nginx", UpdateStrategy: RollingUpdateStrategy}
+	assert.Nil(t, app.Validate())
+
+	app.UpdateStrategy = "Rolling"
+	assert.ErrorIs(t, app.Validate(), ErrInvalidUpdateStrategy, "should reject unknown strategies instead of recreating")
+
+	app.UpdateStrategy = RollingUpdateStrategy
+	app.Ingress = &Ingress{}
+	assert.ErrorIs(t, app.Validate(), ErrInvalidIngress)
+}